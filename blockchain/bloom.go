@@ -0,0 +1,64 @@
+package blockchain
+
+import (
+	"encoding/binary"
+	"math/big"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/ledger/types"
+	"github.com/thetatoken/theta/store"
+	score "github.com/thetatoken/thetasubchain/core"
+)
+
+// logsBloomKey constructs the DB key for the logs bloom of the finalized block at the given height.
+func logsBloomKey(height uint64) common.Bytes {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, height)
+	return append(common.Bytes("lbl/"), buf...)
+}
+
+// CreateLogsBloom returns the bloom filter of the addresses and topics of the logs.
+func CreateLogsBloom(logs []*types.Log) score.Bloom {
+	bin := new(big.Int)
+	for _, log := range logs {
+		bin.Or(bin, score.Bloom9(log.Address.Bytes()))
+		for _, topic := range log.Topics {
+			bin.Or(bin, score.Bloom9(topic[:]))
+		}
+	}
+	return score.BytesToBloom(bin.Bytes())
+}
+
+// addLogsBloom computes the logs bloom of the finalized block from the receipts of its txs,
+// and persists it so that log queries can skip the blocks without matching logs.
+func (ch *Chain) addLogsBloom(block *score.ExtendedBlock) {
+	logs := []*types.Log{}
+	blockHash := block.Hash()
+	for _, rawTx := range block.Txs {
+		receipt, found := ch.FindTxReceiptByHash(blockHash, crypto.Keccak256Hash(rawTx))
+		if found {
+			logs = append(logs, receipt.Logs...)
+		}
+	}
+
+	bloom := CreateLogsBloom(logs)
+	err := ch.store.Put(logsBloomKey(block.Height), common.Bytes(bloom.Bytes()))
+	if err != nil {
+		logger.Panic(err)
+	}
+}
+
+// FindLogsBloom returns the logs bloom of the finalized block at the given height. Blocks
+// finalized before the bloom index was introduced have no bloom.
+func (ch *Chain) FindLogsBloom(height uint64) (score.Bloom, bool) {
+	var raw common.Bytes
+	err := ch.store.Get(logsBloomKey(height), &raw)
+	if err != nil {
+		if err != store.ErrKeyNotFound {
+			logger.Error(err)
+		}
+		return score.Bloom{}, false
+	}
+	return score.BytesToBloom(raw), true
+}
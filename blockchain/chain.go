@@ -24,6 +24,9 @@ type Chain struct {
 	ChainID string
 	root    common.Hash
 
+	accountHistoryEnabled bool
+	stateHistoryEnabled   bool
+
 	mu *sync.RWMutex
 }
 
@@ -270,31 +273,48 @@ func (ch *Chain) CommitBlock(hash common.Hash) {
 	}
 }
 
+// FinalizePreviousBlocks finalizes the block and its ancestors which are not finalized yet.
+// The blocks are indexed in ascending height order, and the indexes of each block are written
+// before its status, so that a block is never finalized without being indexed. If the node
+// crashes midway, the remaining blocks are re-indexed when they are finalized again.
 func (ch *Chain) FinalizePreviousBlocks(hash common.Hash) error {
 	ch.mu.Lock()
 	defer ch.mu.Unlock()
 
-	status := score.BlockStatusDirectlyFinalized
+	blocks := []*score.ExtendedBlock{} // the newly finalized blocks, in descending height order
 	for !hash.IsEmpty() {
 		block, err := ch.findBlock(hash)
 		if err != nil || block.Status.IsFinalized() {
-			return nil
+			break
 		}
 		if block.Status == score.BlockStatusDisposed {
 			return errors.New("Cannot finalize disposed branch")
 		}
-		block.Status = status
-		status = score.BlockStatusIndirectlyFinalized // Only the first block is marked as directly finalized
-		err = ch.saveBlock(block)
-		if err != nil {
-			logger.Panic(err)
-		}
+		blocks = append(blocks, block)
+		hash = block.Parent
+	}
+
+	for i := len(blocks) - 1; i >= 0; i-- {
+		block := blocks[i]
+		blockHash := block.Hash()
 
 		// Force update TX index on block finalization so that the index doesn't point to
 		// duplicate TX in fork.
 		ch.AddTxsToIndex(block, true)
+		ch.addFinalizedBlockByHeightIndex(block.Height, blockHash)
+		ch.addLogsBloom(block)
+		ch.addAccountHistory(block)
+		ch.addStateHistory(block)
 
-		hash = block.Parent
+		if i == 0 {
+			block.Status = score.BlockStatusDirectlyFinalized // Only the first block is marked as directly finalized
+		} else {
+			block.Status = score.BlockStatusIndirectlyFinalized
+		}
+		err := ch.saveBlock(block)
+		if err != nil {
+			logger.Panic(err)
+		}
 	}
 	return nil
 }
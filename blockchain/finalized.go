@@ -0,0 +1,47 @@
+package blockchain
+
+import (
+	"encoding/binary"
+
+	"github.com/thetatoken/theta/common"
+	score "github.com/thetatoken/thetasubchain/core"
+)
+
+// finalizedBlockByHeightIndexKey constructs the DB key for the finalized block at the given height.
+func finalizedBlockByHeightIndexKey(height uint64) common.Bytes {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, height)
+	return append(common.Bytes("fbh/"), buf...)
+}
+
+// addFinalizedBlockByHeightIndex records the hash of the finalized block at the given height, so that
+// historical lookups do not need to scan all the forks at that height.
+func (ch *Chain) addFinalizedBlockByHeightIndex(height uint64, hash common.Hash) {
+	err := ch.store.Put(finalizedBlockByHeightIndexKey(height), hash)
+	if err != nil {
+		logger.Panic(err)
+	}
+}
+
+// FindFinalizedBlockByHeight returns the finalized block at the given height, if any.
+func (ch *Chain) FindFinalizedBlockByHeight(height uint64) (*score.ExtendedBlock, bool) {
+	ch.mu.RLock()
+	defer ch.mu.RUnlock()
+
+	var hash common.Hash
+	err := ch.store.Get(finalizedBlockByHeightIndexKey(height), &hash)
+	if err == nil {
+		block, err := ch.findBlock(hash)
+		if err == nil && block.Status.IsFinalized() {
+			return block, true
+		}
+	}
+
+	// Blocks finalized before the index was introduced need to be looked up from the height index
+	for _, block := range ch.findBlocksByHeight(height) {
+		if block.Status.IsFinalized() {
+			return block, true
+		}
+	}
+	return nil, false
+}
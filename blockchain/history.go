@@ -0,0 +1,203 @@
+package blockchain
+
+import (
+	"encoding/binary"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/ledger/types"
+	"github.com/thetatoken/theta/store"
+	score "github.com/thetatoken/thetasubchain/core"
+	stypes "github.com/thetatoken/thetasubchain/ledger/types"
+)
+
+// TxDirection tells whether an account sent or received the funds of a transaction.
+type TxDirection string
+
+const (
+	TxDirectionSent     TxDirection = "sent"
+	TxDirectionReceived TxDirection = "received"
+	TxDirectionSelf     TxDirection = "self" // the account is both a sender and a receiver
+)
+
+// AccountTxEntry records a finalized transaction involving an account.
+type AccountTxEntry struct {
+	TxHash      common.Hash
+	BlockHeight uint64
+	Direction   TxDirection
+}
+
+// accountTxCountKey constructs the DB key for the number of history entries of the account.
+func accountTxCountKey(address common.Address) common.Bytes {
+	key := append(common.Bytes("ahc/"), address[:]...)
+	return key
+}
+
+// accountTxKey constructs the DB key for the i-th history entry of the account.
+func accountTxKey(address common.Address, index uint64) common.Bytes {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, index)
+	key := append(common.Bytes("ah/"), address[:]...)
+	key = append(key, '/')
+	return append(key, buf...)
+}
+
+// SetAccountHistoryEnabled sets whether to index the finalized transactions by the
+// addresses of their senders and receivers.
+func (ch *Chain) SetAccountHistoryEnabled(enabled bool) {
+	ch.accountHistoryEnabled = enabled
+}
+
+// AccountHistoryEnabled returns whether the finalized transactions are indexed by account.
+func (ch *Chain) AccountHistoryEnabled() bool {
+	return ch.accountHistoryEnabled
+}
+
+// addAccountHistory indexes the transactions of the finalized block by account. Must be
+// called in ascending height order. Indexing a block again replaces its previous entries,
+// so a block can be re-indexed after a crash in the middle of its finalization.
+func (ch *Chain) addAccountHistory(block *score.ExtendedBlock) {
+	if !ch.accountHistoryEnabled {
+		return
+	}
+
+	blockHash := block.Hash()
+	truncated := make(map[common.Address]bool)
+	for _, rawTx := range block.Txs {
+		tx, err := stypes.TxFromBytes(rawTx)
+		if err != nil {
+			continue
+		}
+		txHash := crypto.Keccak256Hash(rawTx)
+
+		directions := make(map[common.Address]TxDirection)
+		addresses := []common.Address{} // keep the insertion order deterministic
+		record := func(address common.Address, direction TxDirection) {
+			if (address == common.Address{}) {
+				return
+			}
+			existing, ok := directions[address]
+			if !ok {
+				addresses = append(addresses, address)
+				directions[address] = direction
+			} else if existing != direction {
+				directions[address] = TxDirectionSelf
+			}
+		}
+
+		switch t := tx.(type) {
+		case *types.SendTx:
+			for _, input := range t.Inputs {
+				record(input.Address, TxDirectionSent)
+			}
+			for _, output := range t.Outputs {
+				record(output.Address, TxDirectionReceived)
+			}
+		case *stypes.MultisigSendTx:
+			for _, input := range t.Inputs {
+				record(input.Address, TxDirectionSent)
+			}
+			for _, output := range t.Outputs {
+				record(output.Address, TxDirectionReceived)
+			}
+		case *stypes.BatchSendTx:
+			record(t.Input.Address, TxDirectionSent)
+			for _, output := range t.Outputs {
+				record(output.Address, TxDirectionReceived)
+			}
+		case *types.SmartContractTx:
+			record(t.From.Address, TxDirectionSent)
+			record(t.To.Address, TxDirectionReceived)
+		}
+
+		// Internal transfers, e.g. value transfers made by contracts
+		if balanceChanges, found := ch.FindTxBalanceChangesByHash(blockHash, txHash); found {
+			for _, bc := range balanceChanges.BalanceChanges {
+				if bc.IsNegative {
+					record(bc.Address, TxDirectionSent)
+				} else {
+					record(bc.Address, TxDirectionReceived)
+				}
+			}
+		}
+
+		for _, address := range addresses {
+			if !truncated[address] {
+				ch.truncateAccountTxs(address, block.Height)
+				truncated[address] = true
+			}
+			ch.appendAccountTx(address, AccountTxEntry{
+				TxHash:      txHash,
+				BlockHeight: block.Height,
+				Direction:   directions[address],
+			})
+		}
+	}
+}
+
+func (ch *Chain) appendAccountTx(address common.Address, entry AccountTxEntry) {
+	count := ch.getAccountTxCount(address)
+	err := ch.store.Put(accountTxKey(address, count), entry)
+	if err != nil {
+		logger.Panic(err)
+	}
+	err = ch.store.Put(accountTxCountKey(address), count+1)
+	if err != nil {
+		logger.Panic(err)
+	}
+}
+
+// truncateAccountTxs removes the trailing history entries of the account at or above the
+// given height, i.e. the entries left by a previous attempt to index the block.
+func (ch *Chain) truncateAccountTxs(address common.Address, height uint64) {
+	count := ch.getAccountTxCount(address)
+	newCount := count
+	for newCount > 0 {
+		entry := AccountTxEntry{}
+		err := ch.store.Get(accountTxKey(address, newCount-1), &entry)
+		if err != nil || entry.BlockHeight < height {
+			break
+		}
+		newCount--
+	}
+	if newCount == count {
+		return
+	}
+	err := ch.store.Put(accountTxCountKey(address), newCount)
+	if err != nil {
+		logger.Panic(err)
+	}
+}
+
+func (ch *Chain) getAccountTxCount(address common.Address) uint64 {
+	var count uint64
+	err := ch.store.Get(accountTxCountKey(address), &count)
+	if err != nil {
+		if err != store.ErrKeyNotFound {
+			logger.Error(err)
+		}
+		return 0
+	}
+	return count
+}
+
+// FindAccountTxs returns up to limit history entries of the account, most recent first,
+// skipping the first skip entries. It also returns the total number of entries.
+func (ch *Chain) FindAccountTxs(address common.Address, skip, limit uint64) ([]AccountTxEntry, uint64) {
+	total := ch.getAccountTxCount(address)
+	entries := []AccountTxEntry{}
+	if skip >= total {
+		return entries, total
+	}
+
+	for i := total - skip; i > 0 && uint64(len(entries)) < limit; i-- {
+		entry := AccountTxEntry{}
+		err := ch.store.Get(accountTxKey(address, i-1), &entry)
+		if err != nil {
+			logger.Errorf("Failed to load the history of account %v, index: %v, err: %v", address.Hex(), i-1, err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, total
+}
@@ -0,0 +1,291 @@
+package blockchain
+
+import (
+	"encoding/binary"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/store"
+	score "github.com/thetatoken/thetasubchain/core"
+)
+
+// AccountDiff records the account resulting from a block. The account is empty if the block deleted it.
+type AccountDiff struct {
+	Address common.Address
+	Account common.Bytes // the encoded account
+}
+
+// StorageDiff records the value of a contract storage slot resulting from a block.
+type StorageDiff struct {
+	Address common.Address
+	Key     common.Hash
+	Value   common.Hash
+}
+
+// StateDiff records the accounts and the storage slots modified by a block.
+type StateDiff struct {
+	Accounts []AccountDiff
+	Storage  []StorageDiff
+}
+
+// AccountStateEntry records the account at the height it was modified.
+type AccountStateEntry struct {
+	Height  uint64
+	Account common.Bytes
+}
+
+// StorageStateEntry records the value of a storage slot at the height it was modified.
+type StorageStateEntry struct {
+	Height uint64
+	Value  common.Hash
+}
+
+// stateDiffKey constructs the DB key for the state diff of the block, kept until the block is finalized.
+func stateDiffKey(blockHash common.Hash) common.Bytes {
+	return append(common.Bytes("shd/"), blockHash[:]...)
+}
+
+// stateHistoryBaseHeightKey constructs the DB key for the height of the state the history is based on.
+func stateHistoryBaseHeightKey() common.Bytes {
+	return common.Bytes("shb")
+}
+
+// accountStateCountKey constructs the DB key for the number of history entries of the account.
+func accountStateCountKey(address common.Address) common.Bytes {
+	return append(common.Bytes("shac/"), address[:]...)
+}
+
+// accountStateKey constructs the DB key for the i-th history entry of the account.
+func accountStateKey(address common.Address, index uint64) common.Bytes {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, index)
+	key := append(common.Bytes("sha/"), address[:]...)
+	key = append(key, '/')
+	return append(key, buf...)
+}
+
+// storageStateCountKey constructs the DB key for the number of history entries of the storage slot.
+func storageStateCountKey(address common.Address, slot common.Hash) common.Bytes {
+	key := append(common.Bytes("shsc/"), address[:]...)
+	return append(key, slot[:]...)
+}
+
+// storageStateKey constructs the DB key for the i-th history entry of the storage slot.
+func storageStateKey(address common.Address, slot common.Hash, index uint64) common.Bytes {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, index)
+	key := append(common.Bytes("shs/"), address[:]...)
+	key = append(key, slot[:]...)
+	key = append(key, '/')
+	return append(key, buf...)
+}
+
+// SetStateHistoryEnabled sets whether to index the accounts and the storage slots modified by the
+// finalized blocks by address and height, e.g. for the archive nodes.
+func (ch *Chain) SetStateHistoryEnabled(enabled bool) {
+	ch.stateHistoryEnabled = enabled
+}
+
+// StateHistoryEnabled returns whether the state modified by the finalized blocks is indexed.
+func (ch *Chain) StateHistoryEnabled() bool {
+	return ch.stateHistoryEnabled
+}
+
+// AddStateDiff records the state modified by the block when it is applied. The diff is indexed
+// when the block is finalized. The diffs of the blocks that never get finalized are left behind.
+func (ch *Chain) AddStateDiff(blockHash common.Hash, diff *StateDiff) {
+	err := ch.store.Put(stateDiffKey(blockHash), diff)
+	if err != nil {
+		logger.Panic(err)
+	}
+}
+
+// addStateHistory indexes the state modified by the finalized block by address and height. Must be
+// called in ascending height order. If the state diff of the block was not recorded, e.g. the block was
+// applied before the history got enabled, the history is rebased on the state of the block instead, so
+// the lookups never return the entries made stale by the unrecorded changes.
+func (ch *Chain) addStateHistory(block *score.ExtendedBlock) {
+	if !ch.stateHistoryEnabled || block.Height == 0 {
+		return
+	}
+
+	blockHash := block.Hash()
+	diff := &StateDiff{}
+	err := ch.store.Get(stateDiffKey(blockHash), diff)
+	if err != nil {
+		if err != store.ErrKeyNotFound {
+			logger.Error(err)
+		}
+		logger.Infof("No state diff recorded for block %v at height %v, rebasing the state history", blockHash.Hex(), block.Height)
+		ch.setStateHistoryBaseHeight(block.Height)
+		return
+	}
+	if _, ok := ch.getStateHistoryBaseHeight(); !ok {
+		ch.setStateHistoryBaseHeight(block.Height - 1)
+	}
+
+	for _, ad := range diff.Accounts {
+		countKey := accountStateCountKey(ad.Address)
+		count := ch.truncateStateHistory(countKey, block.Height, func(index uint64) (uint64, error) {
+			entry := AccountStateEntry{}
+			err := ch.store.Get(accountStateKey(ad.Address, index), &entry)
+			return entry.Height, err
+		})
+		ch.appendStateHistory(countKey, accountStateKey(ad.Address, count), count, AccountStateEntry{
+			Height:  block.Height,
+			Account: ad.Account,
+		})
+	}
+	for _, sd := range diff.Storage {
+		countKey := storageStateCountKey(sd.Address, sd.Key)
+		count := ch.truncateStateHistory(countKey, block.Height, func(index uint64) (uint64, error) {
+			entry := StorageStateEntry{}
+			err := ch.store.Get(storageStateKey(sd.Address, sd.Key, index), &entry)
+			return entry.Height, err
+		})
+		ch.appendStateHistory(countKey, storageStateKey(sd.Address, sd.Key, count), count, StorageStateEntry{
+			Height: block.Height,
+			Value:  sd.Value,
+		})
+	}
+
+	// A re-index after a crash rebases the history on the block, which is always correct
+	err = ch.store.Delete(stateDiffKey(blockHash))
+	if err != nil {
+		logger.Error(err)
+	}
+}
+
+func (ch *Chain) appendStateHistory(countKey, entryKey common.Bytes, count uint64, entry interface{}) {
+	err := ch.store.Put(entryKey, entry)
+	if err != nil {
+		logger.Panic(err)
+	}
+	err = ch.store.Put(countKey, count+1)
+	if err != nil {
+		logger.Panic(err)
+	}
+}
+
+// truncateStateHistory removes the trailing history entries at or above the given height, i.e. the
+// entries left by a previous attempt to index the block, and returns the remaining number of entries.
+func (ch *Chain) truncateStateHistory(countKey common.Bytes, height uint64, entryHeight func(index uint64) (uint64, error)) uint64 {
+	count := ch.getStateHistoryCount(countKey)
+	newCount := count
+	for newCount > 0 {
+		h, err := entryHeight(newCount - 1)
+		if err != nil || h < height {
+			break
+		}
+		newCount--
+	}
+	if newCount == count {
+		return count
+	}
+	err := ch.store.Put(countKey, newCount)
+	if err != nil {
+		logger.Panic(err)
+	}
+	return newCount
+}
+
+func (ch *Chain) getStateHistoryCount(countKey common.Bytes) uint64 {
+	var count uint64
+	err := ch.store.Get(countKey, &count)
+	if err != nil {
+		if err != store.ErrKeyNotFound {
+			logger.Error(err)
+		}
+		return 0
+	}
+	return count
+}
+
+func (ch *Chain) setStateHistoryBaseHeight(height uint64) {
+	err := ch.store.Put(stateHistoryBaseHeightKey(), height)
+	if err != nil {
+		logger.Panic(err)
+	}
+}
+
+func (ch *Chain) getStateHistoryBaseHeight() (uint64, bool) {
+	var height uint64
+	err := ch.store.Get(stateHistoryBaseHeightKey(), &height)
+	if err != nil {
+		if err != store.ErrKeyNotFound {
+			logger.Error(err)
+		}
+		return 0, false
+	}
+	return height, true
+}
+
+// FindAccountStateAtHeight looks up the encoded account at the given finalized height in the state
+// history. The account is empty if it did not exist at that height. If the history does not cover the
+// lookup, i.e. the height precedes the history or the account has not been modified since the state the
+// history is based on, it returns false and the height of the state trie to read the account from.
+func (ch *Chain) FindAccountStateAtHeight(address common.Address, height uint64) (common.Bytes, uint64, bool) {
+	baseHeight, ok := ch.getStateHistoryBaseHeight()
+	if !ok || height <= baseHeight {
+		return nil, height, false
+	}
+
+	entry := AccountStateEntry{}
+	found := ch.findStateHistoryEntry(accountStateCountKey(address), height, func(index uint64) (uint64, error) {
+		err := ch.store.Get(accountStateKey(address, index), &entry)
+		return entry.Height, err
+	})
+	if !found || entry.Height <= baseHeight {
+		return nil, baseHeight, false
+	}
+	return entry.Account, height, true
+}
+
+// FindStorageStateAtHeight looks up the value of the storage slot at the given finalized height in the
+// state history, in the same way as FindAccountStateAtHeight().
+func (ch *Chain) FindStorageStateAtHeight(address common.Address, slot common.Hash, height uint64) (common.Hash, uint64, bool) {
+	baseHeight, ok := ch.getStateHistoryBaseHeight()
+	if !ok || height <= baseHeight {
+		return common.Hash{}, height, false
+	}
+
+	entry := StorageStateEntry{}
+	found := ch.findStateHistoryEntry(storageStateCountKey(address, slot), height, func(index uint64) (uint64, error) {
+		err := ch.store.Get(storageStateKey(address, slot, index), &entry)
+		return entry.Height, err
+	})
+	if !found || entry.Height <= baseHeight {
+		return common.Hash{}, baseHeight, false
+	}
+	return entry.Value, height, true
+}
+
+// findStateHistoryEntry binary searches for the last history entry at or below the given height. The
+// entry is left loaded by the loadEntry callback.
+func (ch *Chain) findStateHistoryEntry(countKey common.Bytes, height uint64, loadEntry func(index uint64) (uint64, error)) bool {
+	count := ch.getStateHistoryCount(countKey)
+
+	// Find the first entry above the height
+	lo, hi := uint64(0), count
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		h, err := loadEntry(mid)
+		if err != nil {
+			logger.Errorf("Failed to load the state history entry %v, err: %v", mid, err)
+			return false
+		}
+		if h <= height {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	if lo == 0 {
+		return false
+	}
+	_, err := loadEntry(lo - 1)
+	if err != nil {
+		logger.Errorf("Failed to load the state history entry %v, err: %v", lo-1, err)
+		return false
+	}
+	return true
+}
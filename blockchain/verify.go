@@ -0,0 +1,150 @@
+package blockchain
+
+import (
+	"fmt"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/ledger/types"
+	score "github.com/thetatoken/thetasubchain/core"
+	stypes "github.com/thetatoken/thetasubchain/ledger/types"
+)
+
+// IntegrityIssue describes an inconsistency found in the chain indexes.
+type IntegrityIssue struct {
+	Height     uint64
+	BlockHash  common.Hash
+	Problem    string
+	Repairable bool // whether the issue can be fixed by RepairFinalizedBlock()
+}
+
+func (issue IntegrityIssue) String() string {
+	return fmt.Sprintf("height %v, block %v: %v", issue.Height, issue.BlockHash.Hex(), issue.Problem)
+}
+
+// VerifyFinalizedBlock checks the height index, the parent/child links, the tx index, the tx receipts
+// and the votes of the given finalized block.
+func (ch *Chain) VerifyFinalizedBlock(block *score.ExtendedBlock) []IntegrityIssue {
+	ch.mu.RLock()
+	defer ch.mu.RUnlock()
+
+	hash := block.Hash()
+	issues := []IntegrityIssue{}
+	report := func(repairable bool, format string, args ...interface{}) {
+		issues = append(issues, IntegrityIssue{
+			Height:     block.Height,
+			BlockHash:  hash,
+			Problem:    fmt.Sprintf(format, args...),
+			Repairable: repairable,
+		})
+	}
+
+	// Height index
+	heightIndexEntry := BlockByHeightIndexEntry{Blocks: []common.Hash{}}
+	ch.store.Get(blockByHeightIndexKey(block.Height), &heightIndexEntry)
+	if !containsHash(heightIndexEntry.Blocks, hash) {
+		report(true, "missing from the height index")
+	}
+
+	// Parent/child links
+	if hash != ch.root && !block.Parent.IsEmpty() {
+		parent, err := ch.findBlock(block.Parent)
+		if err != nil {
+			report(false, "parent block %v not found", block.Parent.Hex())
+		} else {
+			if parent.Height+1 != block.Height {
+				report(false, "parent block %v is at height %v", block.Parent.Hex(), parent.Height)
+			}
+			if !containsHash(parent.Children, hash) {
+				report(true, "not listed as a child of parent block %v", block.Parent.Hex())
+			}
+			if !parent.Status.IsFinalized() {
+				report(false, "parent block %v is not finalized", block.Parent.Hex())
+			}
+		}
+	}
+	for _, child := range block.Children {
+		if _, err := ch.findBlock(child); err != nil {
+			report(true, "dead link to child block %v", child.Hex())
+		}
+	}
+
+	// Tx index and receipts
+	for idx, rawTx := range block.Txs {
+		txHash := crypto.Keccak256Hash(rawTx)
+		txIndexEntry := &TxIndexEntry{}
+		err := ch.store.Get(txIndexKey(txHash), txIndexEntry)
+		if err != nil {
+			report(true, "tx %v missing from the tx index", txHash.Hex())
+		} else if txIndexEntry.BlockHash != hash || txIndexEntry.Index != uint64(idx) {
+			report(true, "tx %v indexed to block %v, index %v", txHash.Hex(), txIndexEntry.BlockHash.Hex(), txIndexEntry.Index)
+		}
+
+		tx, err := stypes.TxFromBytes(rawTx)
+		if err != nil {
+			report(false, "failed to decode tx %v: %v", txHash.Hex(), err)
+			continue
+		}
+		if _, ok := tx.(*types.SmartContractTx); ok {
+			if _, found := ch.FindTxReceiptByHash(hash, txHash); !found {
+				report(false, "receipt of tx %v not found", txHash.Hex())
+			}
+		}
+	}
+
+	// Votes
+	if block.Status.IsDirectlyFinalized() && hash != ch.root {
+		voteSet := score.NewVoteSet()
+		err := ch.store.Get(voteIndexKey(hash), voteSet)
+		if err != nil || voteSet.IsEmpty() {
+			report(false, "no votes found for the directly finalized block")
+		}
+	}
+
+	return issues
+}
+
+// RepairFinalizedBlock rebuilds the height index, the tx index and the parent/child links
+// of the given finalized block.
+func (ch *Chain) RepairFinalizedBlock(block *score.ExtendedBlock) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	hash := block.Hash()
+
+	ch.AddBlockByHeightIndex(block.Height, hash)
+	ch.AddTxsToIndex(block, true)
+	ch.addFinalizedBlockByHeightIndex(block.Height, hash)
+
+	if hash != ch.root && !block.Parent.IsEmpty() {
+		parent, err := ch.findBlock(block.Parent)
+		if err == nil && !containsHash(parent.Children, hash) {
+			parent.Children = append(parent.Children, hash)
+			if err := ch.saveBlock(parent); err != nil {
+				logger.Panic(err)
+			}
+		}
+	}
+
+	children := []common.Hash{}
+	for _, child := range block.Children {
+		if _, err := ch.findBlock(child); err == nil {
+			children = append(children, child)
+		}
+	}
+	if len(children) != len(block.Children) {
+		block.Children = children
+		if err := ch.saveBlock(block); err != nil {
+			logger.Panic(err)
+		}
+	}
+}
+
+func containsHash(hashes []common.Hash, hash common.Hash) bool {
+	for _, h := range hashes {
+		if h == hash {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/thetatoken/theta/common"
+	scom "github.com/thetatoken/thetasubchain/common"
+	"github.com/thetatoken/thetasubchain/devnet"
+)
+
+var (
+	devnetNumValidators int
+	devnetMainchainID   string
+	devnetSubchainID    string
+	devnetRPCEnabled    bool
+)
+
+// devnetCmd represents the devnet command
+var devnetCmd = &cobra.Command{
+	Use:   "devnet",
+	Short: "Start a local subchain of multiple validator nodes in one process.",
+	Long: `Start a local subchain of multiple validator nodes in one process. The nodes are connected by an
+in-memory network and witness a simulated mainchain, so neither a Theta mainchain nor the ETH RPC adaptors
+are needed. The genesis snapshot is generated for freshly created validator keys. The node data is kept
+under the data path if set, otherwise in a temporary directory removed on exit.`,
+	Example: `thetasubchain devnet --config=../privatenet/node --validators=4`,
+	Run:     runDevnet,
+}
+
+func init() {
+	devnetCmd.Flags().IntVar(&devnetNumValidators, "validators", devnet.DefaultNumValidators, "number of validator nodes")
+	devnetCmd.Flags().StringVar(&devnetMainchainID, "mainchainID", devnet.DefaultMainchainID, "the ID of the simulated mainchain")
+	devnetCmd.Flags().StringVar(&devnetSubchainID, "subchainID", devnet.DefaultSubchainID, "the ID of the subchain")
+	devnetCmd.Flags().BoolVar(&devnetRPCEnabled, "rpc", true, "serve RPC and metrics on the first node")
+	RootCmd.AddCommand(devnetCmd)
+}
+
+func runDevnet(cmd *cobra.Command, args []string) {
+	d, err := devnet.NewDevnet(&devnet.Config{
+		MainchainID:   devnetMainchainID,
+		SubchainID:    devnetSubchainID,
+		NumValidators: devnetNumValidators,
+		DataPath:      viper.GetString(common.CfgDataPath),
+		RPCEnabled:    devnetRPCEnabled,
+	})
+	if err != nil {
+		log.Fatalf("Failed to create the devnet: %v", err)
+	}
+	defer d.Cleanup()
+
+	for i, key := range d.ValidatorKeys {
+		log.Infof("Validator %v: %v", i, key.PublicKey().Address().Hex())
+	}
+	if devnetRPCEnabled {
+		log.Infof("RPC served by validator 0 on port %v", viper.GetString(scom.CfgRPCPort))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt)
+	go func() {
+		<-c
+		signal.Stop(c)
+		cancel()
+	}()
+
+	d.Start(ctx)
+	d.Wait()
+	log.Infof("Devnet stopped")
+}
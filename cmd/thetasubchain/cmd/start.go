@@ -21,12 +21,14 @@ import (
 	msg "github.com/thetatoken/theta/p2p/messenger"
 	msgl "github.com/thetatoken/theta/p2pl/messenger"
 	"github.com/thetatoken/theta/rlp"
-	"github.com/thetatoken/theta/store/database/backend"
 	ks "github.com/thetatoken/theta/wallet/softwallet/keystore"
 	"github.com/thetatoken/thetasubchain/cmd/thetasubcli/cmd/utils"
+	scom "github.com/thetatoken/thetasubchain/common"
 	"github.com/thetatoken/thetasubchain/core"
 	"github.com/thetatoken/thetasubchain/node"
+	ssigner "github.com/thetatoken/thetasubchain/signer"
 	"github.com/thetatoken/thetasubchain/snapshot"
+	sbackend "github.com/thetatoken/thetasubchain/store/backend"
 	"github.com/thetatoken/thetasubchain/store/rollingdb"
 	"github.com/thetatoken/thetasubchain/version"
 )
@@ -52,23 +54,33 @@ func runStart(cmd *cobra.Command, args []string) {
 		log.Fatalf("Failed to load or create key: %v", err)
 	}
 
+	if viper.GetBool(scom.CfgStorageArchiveEnabled) {
+		log.Infof("Archive mode enabled, state rolling and pruning are disabled")
+		viper.Set(common.CfgStorageRollingEnabled, false)
+		viper.Set(common.CfgStorageStatePruningEnabled, false)
+	}
+
 	// Open database
 	dbPath := viper.GetString(common.CfgDataPath)
 	if dbPath == "" {
 		dbPath = cfgPath
 	}
 
-	mainDBPath := path.Join(dbPath, "db", "main")
-	refDBPath := path.Join(dbPath, "db", "ref")
-	db, err := backend.NewLDBDatabase(mainDBPath, refDBPath,
-		viper.GetInt(common.CfgStorageLevelDBCacheSize),
-		viper.GetInt(common.CfgStorageLevelDBHandles))
+	backendType := sbackend.Type()
+	if err := sbackend.CheckDataDir(backendType, dbPath); err != nil {
+		log.Fatalf("Failed to open the db: %v", err)
+	}
+	db, err := sbackend.NewDatabase(dbPath)
+	if err != nil {
+		log.Fatalf("Failed to connect to the db. backend: %v, main: %v, ref: %v, err: %v",
+			backendType, sbackend.MainDBPath(dbPath), sbackend.RefDBPath(dbPath), err)
+	}
 
 	rdb := rollingdb.NewRollingDB(dbPath, db)
 
+	signer, err := newSigner(privKey, dbPath)
 	if err != nil {
-		log.Fatalf("Failed to connect to the db. main: %v, ref: %v, err: %v",
-			mainDBPath, refDBPath, err)
+		log.Fatalf("Failed to create the signer: %v", err)
 	}
 
 	// load snapshot
@@ -137,12 +149,13 @@ func runStart(cmd *cobra.Command, args []string) {
 
 	params := &node.Params{
 		ChainID:             root.ChainID,
-		PrivateKey:          privKey,
+		Signer:              signer,
 		Root:                root,
 		NetworkOld:          networkOld,
 		Network:             network,
 		DB:                  db,
 		RollingDB:           rdb,
+		DataPath:            dbPath,
 		SnapshotPath:        snapshotPath,
 		ChainImportDirPath:  chainImportDirPath,
 		ChainCorrectionPath: chainCorrectionPath,
@@ -281,6 +294,38 @@ func loadOrCreateKey() (*crypto.PrivateKey, error) {
 	return nodePrivKey, nil
 }
 
+// newSigner creates the signer of the votes, proposals and transactions of the validator. With a remote
+// signer configured, the local key only identifies the node in the P2P network.
+func newSigner(privKey *crypto.PrivateKey, dataPath string) (core.Signer, error) {
+	if remoteAddress := viper.GetString(scom.CfgSignerRemoteAddress); remoteAddress != "" {
+		authToken := ""
+		if authTokenFile := viper.GetString(scom.CfgSignerRemoteAuthTokenFile); authTokenFile != "" {
+			var err error
+			authToken, err = ssigner.LoadAuthToken(authTokenFile, false)
+			if err != nil {
+				return nil, err
+			}
+		}
+		timeout := time.Duration(viper.GetInt(scom.CfgSignerRemoteTimeoutSecs)) * time.Second
+		remoteSigner, err := ssigner.NewRemoteSigner(remoteAddress, authToken, timeout)
+		if err != nil {
+			return nil, err
+		}
+		log.Infof("Using remote signer %v, validator address: %v", remoteAddress, remoteSigner.Address().Hex())
+		return remoteSigner, nil
+	}
+
+	var guard *ssigner.Guard
+	if viper.GetBool(scom.CfgSignerDoubleSignProtectionEnabled) {
+		var err error
+		guard, err = ssigner.NewGuard(path.Join(dataPath, "signer", "sign_state.json"))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ssigner.NewLocalSigner(privKey, guard), nil
+}
+
 func newMessenger(privKey *crypto.PrivateKey, seedPeerNetAddresses []string, port int, seedPeerOnly bool, ctx context.Context) *msgl.Messenger {
 	log.WithFields(log.Fields{
 		"pubKey":  fmt.Sprintf("%v", privKey.PublicKey().ToBytes()),
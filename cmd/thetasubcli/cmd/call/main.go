@@ -14,7 +14,12 @@ var (
 	gasPriceFlag string
 	gasLimitFlag uint64
 	dataFlag     string
+	heightFlag   uint64
 	verboseFlag  bool
+	abiFlag      string
+	methodFlag   string
+	argsFlag     []string
+	bytecodeFlag string
 )
 
 // CallCmd represents the call command
@@ -13,6 +13,7 @@ import (
 	"github.com/thetatoken/theta/common"
 	"github.com/thetatoken/theta/ledger/types"
 	"github.com/thetatoken/thetasubchain/cmd/thetasubcli/cmd/utils"
+	"github.com/thetatoken/thetasubchain/eth/abi"
 	stypes "github.com/thetatoken/thetasubchain/ledger/types"
 	"github.com/thetatoken/thetasubchain/rpc"
 )
@@ -25,6 +26,8 @@ import (
 //		thetacli call smart_contract --from=2E833968E5bB786Ae419c4d13189fB081Cc43bab --value=1680 --gas_price=3 --gas_limit=50000 --data=600a600c600039600a6000f3600360135360016013f3
 //   * Call an API of a smart contract (local only)
 //		thetacli call smart_contract --from=2E833968E5bB786Ae419c4d13189fB081Cc43bab --to=0x7ad6cea2bc3162e30a3c98d84f821b3233c22647 --gas_price=3 --gas_limit=50000
+//   * Call an API of a smart contract by its ABI, and decode the return value
+//		thetacli call smart_contract --from=2E833968E5bB786Ae419c4d13189fB081Cc43bab --to=0x7ad6cea2bc3162e30a3c98d84f821b3233c22647 --gas_limit=50000 --abi=Token.abi --method=balanceOf --arg=0x2E833968E5bB786Ae419c4d13189fB081Cc43bab
 
 var smartContractCmd = &cobra.Command{
 	Use:   "smart_contract",
@@ -35,6 +38,9 @@ var smartContractCmd = &cobra.Command{
 	
 	[Call an API of a smart contract (local only)]
 	thetacli call smart_contract --from=2E833968E5bB786Ae419c4d13189fB081Cc43bab --to=0x7ad6cea2bc3162e30a3c98d84f821b3233c22647 --gas_price=3 --gas_limit=50000
+
+	[Call an API of a smart contract by its ABI, and decode the return value]
+	thetacli call smart_contract --from=2E833968E5bB786Ae419c4d13189fB081Cc43bab --to=0x7ad6cea2bc3162e30a3c98d84f821b3233c22647 --gas_limit=50000 --abi=Token.abi --method=balanceOf --arg=0x2E833968E5bB786Ae419c4d13189fB081Cc43bab
 	`,
 	Long: `smartContractCmd represents the smart_contract command, which can be used to calls the specified smart contract.
 		However, calling a smart contract does NOT modify the globally consensus state. It can be used for dry run, or for retrieving info from smart contracts without actually spending gas.`,
@@ -60,9 +66,9 @@ func doSmartContractCmd(cmd *cobra.Command, args []string) {
 		utils.Error("Failed to parse gas price")
 	}
 
-	data, err := hex.DecodeString(dataFlag)
+	data, contractABI, err := utils.ResolveCallData(dataFlag, abiFlag, methodFlag, bytecodeFlag, argsFlag)
 	if err != nil {
-		utils.Error("Failed to decode data: %v, err: %v\n", dataFlag, err)
+		utils.Error("Failed to resolve the data: %v\n", err)
 	}
 
 	sctx := &types.SmartContractTx{
@@ -83,6 +89,7 @@ func doSmartContractCmd(cmd *cobra.Command, args []string) {
 
 	rpcCallArgs := rpc.CallSmartContractArgs{
 		SctxBytes: hex.EncodeToString(sctxBytes),
+		Height:    common.JSONUint64(heightFlag),
 	}
 
 	client := rpcc.NewRPCClient(viper.GetString(utils.CfgRemoteRPCEndpoint))
@@ -99,6 +106,34 @@ func doSmartContractCmd(cmd *cobra.Command, args []string) {
 		utils.Error("Failed to parse server response: %v\n%s\n", err, string(json))
 	}
 	fmt.Println(string(json))
+
+	if contractABI != nil && methodFlag != "" {
+		printDecodedOutput(res, contractABI)
+	}
+}
+
+// printDecodedOutput decodes the return value of the smart contract method with the ABI.
+func printDecodedOutput(res *rpcc.RPCResponse, contractABI *abi.ABI) {
+	result := &rpc.CallSmartContractResult{}
+	if err := res.GetObject(result); err != nil {
+		utils.Error("Failed to parse server response: %v\n", err)
+	}
+	if result.VmError != "" {
+		return
+	}
+	ret, err := hex.DecodeString(result.VmReturn)
+	if err != nil {
+		utils.Error("Failed to decode the return value: %v\n", err)
+	}
+	output, err := utils.DecodeMethodOutput(contractABI, methodFlag, ret)
+	if err != nil {
+		utils.Error("Failed to decode the return value: %v\n", err)
+	}
+	formatted, err := json.MarshalIndent(output, "", "    ")
+	if err != nil {
+		utils.Error("Failed to format the return value: %v\n", err)
+	}
+	fmt.Printf("Decoded return value:\n%s\n", formatted)
 }
 
 func init() {
@@ -109,7 +144,12 @@ func init() {
 	smartContractCmd.Flags().StringVar(&gasPriceFlag, "gas_price", fmt.Sprintf("%dwei", types.MinimumGasPriceJune2021), "The gas price")
 	smartContractCmd.Flags().Uint64Var(&gasLimitFlag, "gas_limit", 0, "The gas limit")
 	smartContractCmd.Flags().StringVar(&dataFlag, "data", "", "The data for the smart contract")
+	smartContractCmd.Flags().StringVar(&abiFlag, "abi", "", "Path to the ABI JSON file of the smart contract")
+	smartContractCmd.Flags().StringVar(&methodFlag, "method", "", "Name of the smart contract method to call, requires --abi")
+	smartContractCmd.Flags().StringArrayVar(&argsFlag, "arg", []string{}, "Argument of the method or constructor, can be repeated. Arrays are given in JSON, e.g. [1,2]")
+	smartContractCmd.Flags().StringVar(&bytecodeFlag, "bytecode", "", "Bytecode of the smart contract to deploy, as hex or the path of a file containing the hex")
 	smartContractCmd.Flags().Uint64Var(&seqFlag, "seq", 0, "Sequence number of the transaction")
+	smartContractCmd.Flags().Uint64Var(&heightFlag, "height", 0, "Height of the finalized state to execute against, 0 for the latest state")
 	smartContractCmd.Flags().BoolVar(&verboseFlag, "verbose", false, "")
 
 	smartContractCmd.MarkFlagRequired("from")
@@ -0,0 +1,25 @@
+package db
+
+import "github.com/spf13/cobra"
+
+var (
+	srcFlag     string
+	dstFlag     string
+	backendFlag string
+
+	dataFlag      string
+	repairFlag    bool
+	skipStateFlag bool
+)
+
+// DBCmd represents the db command
+var DBCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Manage the node database",
+	Long:  `Manage the node database. The node needs to be stopped before running these commands.`,
+}
+
+func init() {
+	DBCmd.AddCommand(migrateCmd)
+	DBCmd.AddCommand(verifyCmd)
+}
@@ -0,0 +1,133 @@
+package db
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+
+	"github.com/spf13/cobra"
+	"github.com/thetatoken/theta/store/database"
+	"github.com/thetatoken/thetasubchain/cmd/thetasubcli/cmd/utils"
+	sbackend "github.com/thetatoken/thetasubchain/store/backend"
+)
+
+// migrateCmd represents the migrate command, which copies an existing LevelDB data folder into
+// a new data folder using a different storage backend.
+// Example:
+//		thetasubcli db migrate --src=../privatenet/node --dst=../privatenet/node_badger --backend=badgerdb
+var migrateCmd = &cobra.Command{
+	Use:     "migrate",
+	Short:   "Migrate a LevelDB data folder to another storage backend",
+	Long:    `Migrate a LevelDB data folder to another storage backend. The node needs to be stopped before the migration.`,
+	Example: `thetasubcli db migrate --src=../privatenet/node --dst=../privatenet/node_badger --backend=badgerdb`,
+	Run:     doMigrateCmd,
+}
+
+func doMigrateCmd(cmd *cobra.Command, args []string) {
+	if !sbackend.IsSupported(backendFlag) || backendFlag == sbackend.MemDB {
+		utils.Error("Unsupported target backend: %v\n", backendFlag)
+	}
+	if _, err := os.Stat(sbackend.MainDBPath(srcFlag)); err != nil {
+		utils.Error("Failed to find the source database: %v\n", err)
+	}
+	if _, err := os.Stat(sbackend.MainDBPath(dstFlag)); err == nil {
+		utils.Error("The target data folder already contains a database: %v\n", sbackend.MainDBPath(dstFlag))
+	}
+	if err := sbackend.CheckDataDir(backendFlag, dstFlag); err != nil {
+		utils.Error("Failed to initialize the target data folder: %v\n", err)
+	}
+
+	// Main database
+	target, err := sbackend.NewDatabaseWithType(backendFlag, dstFlag)
+	if err != nil {
+		utils.Error("Failed to open the target database: %v\n", err)
+	}
+	numKeys, err := migrateLevelDB(sbackend.MainDBPath(srcFlag), target)
+	target.Close()
+	if err != nil {
+		utils.Error("Failed to migrate the main database: %v\n", err)
+	}
+	fmt.Printf("Migrated main database, %v keys\n", numKeys)
+
+	// Rolling DB layers
+	srcRollingPath := path.Join(srcFlag, "db", "rolling")
+	dstRollingPath := path.Join(dstFlag, "db", "rolling")
+	files, err := ioutil.ReadDir(srcRollingPath)
+	if err != nil && !os.IsNotExist(err) {
+		utils.Error("Failed to read rolling DB layers: %v\n", err)
+	}
+	if err := os.MkdirAll(dstRollingPath, 0700); err != nil {
+		utils.Error("Failed to create rolling DB folder: %v\n", err)
+	}
+	for _, file := range files {
+		if !file.IsDir() {
+			continue
+		}
+		if _, err := strconv.Atoi(file.Name()); err != nil {
+			continue
+		}
+
+		layer, err := sbackend.NewLayerDatabaseWithType(backendFlag, path.Join(dstRollingPath, file.Name()))
+		if err != nil {
+			utils.Error("Failed to open the target layer %v: %v\n", file.Name(), err)
+		}
+		numKeys, err := migrateLevelDB(path.Join(srcRollingPath, file.Name()), layer)
+		layer.Close()
+		if err != nil {
+			utils.Error("Failed to migrate layer %v: %v\n", file.Name(), err)
+		}
+		fmt.Printf("Migrated rolling DB layer %v, %v keys\n", file.Name(), numKeys)
+	}
+
+	// The reference counts are only used by the reference counting based state pruning, which is
+	// disabled in favor of the rolling DB, hence they are not migrated.
+	fmt.Printf("Migration completed. Please copy the config, key and snapshot files to %v, and set storage.backend to %v\n",
+		dstFlag, backendFlag)
+}
+
+// migrateLevelDB copies all the key/value pairs of the LevelDB database at srcPath into target.
+func migrateLevelDB(srcPath string, target database.Database) (uint64, error) {
+	src, err := sbackend.NewRawDB(srcPath)
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close()
+
+	it := src.NewIterator()
+	defer it.Release()
+
+	numKeys := uint64(0)
+	batch := target.NewBatch()
+	for it.Next() {
+		key := append([]byte{}, it.Key()...)
+		value := append([]byte{}, it.Value()...)
+		if err := batch.Put(key, value); err != nil {
+			return numKeys, err
+		}
+		numKeys++
+
+		if batch.ValueSize() > database.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return numKeys, err
+			}
+			batch.Reset()
+		}
+	}
+	if err := it.Error(); err != nil {
+		return numKeys, err
+	}
+	if err := batch.Write(); err != nil {
+		return numKeys, err
+	}
+	return numKeys, nil
+}
+
+func init() {
+	migrateCmd.Flags().StringVar(&srcFlag, "src", "", "Source LevelDB data folder")
+	migrateCmd.Flags().StringVar(&dstFlag, "dst", "", "Target data folder")
+	migrateCmd.Flags().StringVar(&backendFlag, "backend", sbackend.BadgerDB, "Target storage backend")
+	migrateCmd.MarkFlagRequired("src")
+	migrateCmd.MarkFlagRequired("dst")
+}
@@ -0,0 +1,164 @@
+package db
+
+import (
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/thetatoken/theta/rlp"
+	"github.com/thetatoken/theta/store/kvstore"
+	sbc "github.com/thetatoken/thetasubchain/blockchain"
+	"github.com/thetatoken/thetasubchain/cmd/thetasubcli/cmd/utils"
+	scom "github.com/thetatoken/thetasubchain/common"
+	score "github.com/thetatoken/thetasubchain/core"
+	slst "github.com/thetatoken/thetasubchain/ledger/state"
+	sbackend "github.com/thetatoken/thetasubchain/store/backend"
+	"github.com/thetatoken/thetasubchain/store/rollingdb"
+)
+
+// verifyCmd represents the verify command, which walks the finalized blocks from the snapshot root and
+// checks the consistency of the chain indexes and the state tries.
+// Example:
+//		thetasubcli db verify --data=../privatenet/node
+//		thetasubcli db verify --data=../privatenet/node --repair
+var verifyCmd = &cobra.Command{
+	Use:     "verify",
+	Aliases: []string{"verify-db"},
+	Short:   "Verify the integrity of the node database",
+	Long: `Verify the integrity of the node database. Walks the finalized blocks from the snapshot root, checks the height index,
+the parent/child links, the tx index, the tx receipts and the votes of each block, and the reachability of the state trie nodes.
+The node needs to be stopped before the verification. Without --repair, the data folder is not modified.`,
+	Example: `thetasubcli db verify --data=../privatenet/node --repair`,
+	Run:     doVerifyCmd,
+}
+
+func doVerifyCmd(cmd *cobra.Command, args []string) {
+	// Load the storage settings (e.g. archive mode) from the node config, if present
+	viper.SetConfigFile(path.Join(dataFlag, "config.yaml"))
+	if err := viper.MergeInConfig(); err == nil {
+		fmt.Println("Using node config file:", viper.ConfigFileUsed())
+	}
+
+	// Opening a missing database would create an empty one in the data folder
+	backendType := sbackend.DetectType(dataFlag)
+	if _, err := os.Stat(sbackend.MainDBPath(dataFlag)); err != nil {
+		utils.Error("Failed to find the database under %v: %v\n", dataFlag, err)
+	}
+	db, err := sbackend.NewDatabaseWithType(backendType, dataFlag)
+	if err != nil {
+		utils.Error("Failed to open the database: %v\n", err)
+	}
+	defer db.Close()
+
+	// Only the repair may change the data folder, the verification opens the existing rolling DB layers as is
+	var rdb *rollingdb.RollingDB
+	if repairFlag {
+		rdb = rollingdb.NewRollingDB(dataFlag, db)
+	} else {
+		rdb = rollingdb.OpenRollingDBForRead(dataFlag, db)
+	}
+	defer rdb.Close()
+
+	raw, err := db.Get([]byte("/snapshot_blockheader"))
+	if err != nil {
+		utils.Error("Failed to load the snapshot block header, has the node been started? %v\n", err)
+	}
+	rootHeader := &score.BlockHeader{}
+	if err := rlp.DecodeBytes(raw, rootHeader); err != nil {
+		utils.Error("Failed to decode the snapshot block header: %v\n", err)
+	}
+	root := &score.Block{BlockHeader: rootHeader}
+
+	store := kvstore.NewKVStore(db)
+	rootHash := root.Hash()
+	if err := store.Get(rootHash[:], &score.ExtendedBlock{}); err != nil && !repairFlag {
+		utils.Error("Failed to find the snapshot root block %v: %v\n", rootHash.Hex(), err)
+	}
+	chain := sbc.NewChain(root.ChainID, store, root)
+	rdb.SetChain(chain)
+
+	// Walk the finalized blocks from the snapshot root
+	finalizedBlocks := []*score.ExtendedBlock{}
+	numIssues, numRepaired := 0, 0
+	block := chain.Root()
+	for block != nil {
+		finalizedBlocks = append(finalizedBlocks, block)
+
+		issues := chain.VerifyFinalizedBlock(block)
+		repaired := false
+		for _, issue := range issues {
+			fmt.Printf("[chain] %v\n", issue)
+			if repairFlag && issue.Repairable {
+				repaired = true
+			}
+		}
+		numIssues += len(issues)
+		if repaired {
+			chain.RepairFinalizedBlock(block)
+			numRepaired++
+		}
+
+		next := findFinalizedChild(chain, block)
+		if next == nil {
+			// The child link might be broken, fall back to the height index
+			next, _ = chain.FindFinalizedBlockByHeight(block.Height + 1)
+			if next != nil && next.Parent != block.Hash() {
+				fmt.Printf("[chain] height %v, block %v: parent %v does not match the finalized block at height %v\n",
+					next.Height, next.Hash().Hex(), next.Parent.Hex(), block.Height)
+				numIssues++
+				next = nil
+			}
+		}
+		block = next
+	}
+	lastFinalized := finalizedBlocks[len(finalizedBlocks)-1]
+	fmt.Printf("Verified %v finalized blocks, height %v to %v\n", len(finalizedBlocks), root.Height, lastFinalized.Height)
+
+	// Only the recent states are retained unless the node runs in archive mode
+	if !skipStateFlag {
+		stateStartHeight := root.Height
+		retainedBlocks := uint64(viper.GetInt(scom.CfgStorageStatePruningRetainedBlocks))
+		if !viper.GetBool(scom.CfgStorageArchiveEnabled) && lastFinalized.Height > root.Height+retainedBlocks {
+			stateStartHeight = lastFinalized.Height - retainedBlocks
+		}
+
+		verifier := slst.NewStateTrieVerifier(rdb)
+		numStates := 0
+		for _, block := range finalizedBlocks {
+			if block.Height < stateStartHeight && block.Hash() != chain.Root().Hash() {
+				continue
+			}
+			if err := verifier.Verify(block.StateHash); err != nil {
+				fmt.Printf("[state] height %v, block %v, state root %v: %v\n", block.Height, block.Hash().Hex(), block.StateHash.Hex(), err)
+				numIssues++
+			}
+			numStates++
+		}
+		fmt.Printf("Verified %v state roots from height %v, %v distinct trie nodes\n", numStates, stateStartHeight, verifier.NumNodes)
+	}
+
+	fmt.Printf("Found %v issues", numIssues)
+	if repairFlag {
+		fmt.Printf(", repaired %v blocks", numRepaired)
+	}
+	fmt.Println()
+}
+
+func findFinalizedChild(chain *sbc.Chain, block *score.ExtendedBlock) *score.ExtendedBlock {
+	for _, hash := range block.Children {
+		child, err := chain.FindBlock(hash)
+		if err == nil && child.Status.IsFinalized() {
+			return child
+		}
+	}
+	return nil
+}
+
+func init() {
+	verifyCmd.Flags().StringVar(&dataFlag, "data", "", "Data folder of the node")
+	verifyCmd.Flags().BoolVar(&repairFlag, "repair", false, "Rebuild the height index, the tx index and the parent/child links of the inconsistent blocks")
+	verifyCmd.Flags().BoolVar(&skipStateFlag, "skip_state", false, "Skip the verification of the state tries")
+	verifyCmd.MarkFlagRequired("data")
+}
@@ -0,0 +1,158 @@
+package key
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"time"
+
+	ethkeystore "github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/spf13/cobra"
+
+	"github.com/thetatoken/thetasubchain/cmd/thetasubcli/cmd/utils"
+)
+
+const keyRingBackupVersion = 1
+
+// keyRingBackup is the backup file of a keystore. The key files, which are encrypted with their own
+// passwords, are bundled and encrypted again with the backup password.
+type keyRingBackup struct {
+	Version   int                    `json:"version"`
+	CreatedAt time.Time              `json:"created_at"`
+	NumKeys   int                    `json:"num_keys"`
+	Crypto    ethkeystore.CryptoJSON `json:"crypto"`
+}
+
+// keyRingFile is an encrypted key file of the keystore
+type keyRingFile struct {
+	Name    string          `json:"name"`
+	Content json.RawMessage `json:"content"`
+}
+
+// backupCmd backs up all the keys of the keystore into an encrypted file
+var backupCmd = &cobra.Command{
+	Use:     "backup",
+	Short:   "Back up the key ring to an encrypted file",
+	Long:    `Back up all the keys of the keystore to a single file encrypted with a backup password.`,
+	Example: "thetasubcli key backup --output=keyring.backup",
+	Run: func(cmd *cobra.Command, args []string) {
+		encryptedDir := path.Join(keysDir(cmd), "encrypted")
+		entries, err := ioutil.ReadDir(encryptedDir)
+		if err != nil {
+			utils.Error("Failed to read %v: %v\n", encryptedDir, err)
+		}
+		files := []keyRingFile{}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			content, err := ioutil.ReadFile(path.Join(encryptedDir, entry.Name()))
+			if err != nil {
+				utils.Error("Failed to read %v: %v\n", entry.Name(), err)
+			}
+			files = append(files, keyRingFile{Name: entry.Name(), Content: content})
+		}
+		if len(files) == 0 {
+			utils.Error("No key found in %v\n", encryptedDir)
+		}
+
+		bundle, err := json.Marshal(files)
+		if err != nil {
+			utils.Error("Failed to encode the key ring: %v\n", err)
+		}
+		password := getNewPassword("Please choose a password for the backup: ")
+		cryptoJSON, err := ethkeystore.EncryptDataV3(bundle, []byte(password), ethkeystore.StandardScryptN, ethkeystore.StandardScryptP)
+		if err != nil {
+			utils.Error("Failed to encrypt the key ring: %v\n", err)
+		}
+		backup, err := json.MarshalIndent(keyRingBackup{
+			Version:   keyRingBackupVersion,
+			CreatedAt: time.Now().UTC(),
+			NumKeys:   len(files),
+			Crypto:    cryptoJSON,
+		}, "", "    ")
+		if err != nil {
+			utils.Error("Failed to encode the backup: %v\n", err)
+		}
+		if err := ioutil.WriteFile(outputFlag, backup, 0600); err != nil {
+			utils.Error("Failed to write %v: %v\n", outputFlag, err)
+		}
+		fmt.Printf("Backed up %v keys to %v\n", len(files), outputFlag)
+	},
+}
+
+// restoreCmd restores the keys from a key ring backup
+var restoreCmd = &cobra.Command{
+	Use:     "restore",
+	Short:   "Restore the key ring from a backup file",
+	Long:    `Restore the keys from a backup file. Existing keys are kept as they are.`,
+	Example: "thetasubcli key restore --file=keyring.backup",
+	Run: func(cmd *cobra.Command, args []string) {
+		content, err := ioutil.ReadFile(fileFlag)
+		if err != nil {
+			utils.Error("Failed to read %v: %v\n", fileFlag, err)
+		}
+		backup := keyRingBackup{}
+		if err := json.Unmarshal(content, &backup); err != nil {
+			utils.Error("Failed to parse the backup: %v\n", err)
+		}
+		if backup.Version != keyRingBackupVersion {
+			utils.Error("Unsupported backup version: %v\n", backup.Version)
+		}
+
+		password := getPassword("Please enter the password of the backup: ")
+		bundle, err := ethkeystore.DecryptDataV3(backup.Crypto, password)
+		if err != nil {
+			utils.Error("Failed to decrypt the backup: %v\n", err)
+		}
+		files := []keyRingFile{}
+		if err := json.Unmarshal(bundle, &files); err != nil {
+			utils.Error("Failed to parse the key ring: %v\n", err)
+		}
+
+		encryptedDir := path.Join(keysDir(cmd), "encrypted")
+		if err := os.MkdirAll(encryptedDir, 0700); err != nil {
+			utils.Error("Failed to create %v: %v\n", encryptedDir, err)
+		}
+		if nodeFlag {
+			existing, err := ioutil.ReadDir(encryptedDir)
+			if err != nil {
+				utils.Error("Failed to read %v: %v\n", encryptedDir, err)
+			}
+			numNew := 0
+			for _, file := range files {
+				if _, err := os.Stat(path.Join(encryptedDir, path.Base(file.Name))); os.IsNotExist(err) {
+					numNew++
+				}
+			}
+			checkNodeKeyCount(len(existing), numNew)
+		}
+		restored := 0
+		for _, file := range files {
+			filePath := path.Join(encryptedDir, path.Base(file.Name))
+			if _, err := os.Stat(filePath); err == nil {
+				fmt.Printf("Skipped existing key %v\n", file.Name)
+				continue
+			}
+			if err := ioutil.WriteFile(filePath, file.Content, 0600); err != nil {
+				utils.Error("Failed to write %v: %v\n", filePath, err)
+			}
+			restored++
+		}
+		fmt.Printf("Restored %v of %v keys to %v\n", restored, len(files), encryptedDir)
+	},
+}
+
+func init() {
+	backupCmd.Flags().StringVar(&outputFlag, "output", "", "The backup file")
+	backupCmd.Flags().StringVar(&passwordFlag, "password", "", "Password to encrypt the backup")
+	addNodeFlags(backupCmd, "Back up the keystore of the node instead of the CLI wallet")
+	backupCmd.MarkFlagRequired("output")
+
+	restoreCmd.Flags().StringVar(&fileFlag, "file", "", "The backup file")
+	restoreCmd.Flags().StringVar(&passwordFlag, "password", "", "Password of the backup")
+	addNodeFlags(restoreCmd, "Restore into the keystore of the node instead of the CLI wallet")
+	restoreCmd.MarkFlagRequired("file")
+}
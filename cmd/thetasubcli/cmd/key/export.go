@@ -0,0 +1,80 @@
+package key
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	ethkeystore "github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/thetasubchain/cmd/thetasubcli/cmd/utils"
+)
+
+// web3KeyJSON is the Web3 Secret Storage (v3) format used by Ethereum wallets
+type web3KeyJSON struct {
+	Address string                 `json:"address"`
+	Crypto  ethkeystore.CryptoJSON `json:"crypto"`
+	ID      string                 `json:"id"`
+	Version int                    `json:"version"`
+}
+
+// exportCmd exports a key in the Web3 keystore v3 format
+var exportCmd = &cobra.Command{
+	Use:     "export",
+	Short:   "Export a key to a Web3 keystore file",
+	Long:    `Export a key to the standard Web3 keystore (v3) format, which can be imported by Ethereum wallets.`,
+	Example: "thetasubcli key export 2E833968E5bB786Ae419c4d13189fB081Cc43bab --output=key.json",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 1 {
+			utils.Error("Usage: thetasubcli key export <address>\n")
+		}
+		address := common.HexToAddress(args[0])
+
+		keystore := openKeystore(cmd)
+		password := getPassword("Please enter the password: ")
+		key, err := keystore.GetKey(address, password)
+		if err != nil {
+			utils.Error("Failed to unlock address %v: %v\n", address.Hex(), err)
+		}
+
+		exportPassword := exportPasswordFlag
+		if exportPassword == "" {
+			exportPassword = password
+		}
+		cryptoJSON, err := ethkeystore.EncryptDataV3(key.PrivateKey.ToBytes(), []byte(exportPassword),
+			ethkeystore.StandardScryptN, ethkeystore.StandardScryptP)
+		if err != nil {
+			utils.Error("Failed to encrypt the key: %v\n", err)
+		}
+		keyJSON, err := json.MarshalIndent(web3KeyJSON{
+			Address: strings.ToLower(hex.EncodeToString(address.Bytes())),
+			Crypto:  cryptoJSON,
+			ID:      uuid.New().String(),
+			Version: 3,
+		}, "", "    ")
+		if err != nil {
+			utils.Error("Failed to encode the key: %v\n", err)
+		}
+
+		if outputFlag == "" {
+			fmt.Println(string(keyJSON))
+			return
+		}
+		if err := ioutil.WriteFile(outputFlag, keyJSON, 0600); err != nil {
+			utils.Error("Failed to write %v: %v\n", outputFlag, err)
+		}
+		fmt.Printf("Exported key %v to %v\n", address.Hex(), outputFlag)
+	},
+}
+
+func init() {
+	exportCmd.Flags().StringVar(&outputFlag, "output", "", "File to write the keystore to, stdout if not specified")
+	exportCmd.Flags().StringVar(&passwordFlag, "password", "", "Password of the key")
+	exportCmd.Flags().StringVar(&exportPasswordFlag, "export_password", "", "Password to encrypt the exported keystore, the key password if not specified")
+	addNodeFlags(exportCmd, "Export from the keystore of the node instead of the CLI wallet")
+}
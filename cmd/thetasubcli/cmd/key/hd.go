@@ -0,0 +1,88 @@
+package key
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/thetatoken/theta/crypto"
+)
+
+// BIP-32 hierarchical deterministic key derivation, private keys only.
+
+var masterKeySeed = []byte("Bitcoin seed")
+
+var errInvalidChildKey = errors.New("invalid child key, please use the next index")
+
+type extendedKey struct {
+	key       []byte // 32 byte private key
+	chainCode []byte
+}
+
+func newMasterKey(seed []byte) (*extendedKey, error) {
+	mac := hmac.New(sha512.New, masterKeySeed)
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+
+	k := new(big.Int).SetBytes(sum[:32])
+	if k.Sign() == 0 || k.Cmp(ethcrypto.S256().Params().N) >= 0 {
+		return nil, errors.New("invalid seed")
+	}
+	return &extendedKey{key: sum[:32], chainCode: sum[32:]}, nil
+}
+
+func (ek *extendedKey) child(index uint32) (*extendedKey, error) {
+	var data []byte
+	if index >= accounts.HardenedBit {
+		data = append([]byte{0x0}, ek.key...)
+	} else {
+		priv, err := ethcrypto.ToECDSA(ek.key)
+		if err != nil {
+			return nil, err
+		}
+		data = ethcrypto.CompressPubkey(&priv.PublicKey)
+	}
+	indexBytes := make([]byte, 4)
+	binary.BigEndian.PutUint32(indexBytes, index)
+	data = append(data, indexBytes...)
+
+	mac := hmac.New(sha512.New, ek.chainCode)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+
+	n := ethcrypto.S256().Params().N
+	il := new(big.Int).SetBytes(sum[:32])
+	if il.Cmp(n) >= 0 {
+		return nil, errInvalidChildKey
+	}
+	k := il.Add(il, new(big.Int).SetBytes(ek.key))
+	k.Mod(k, n)
+	if k.Sign() == 0 {
+		return nil, errInvalidChildKey
+	}
+
+	key := make([]byte, 32)
+	kBytes := k.Bytes()
+	copy(key[32-len(kBytes):], kBytes)
+	return &extendedKey{key: key, chainCode: sum[32:]}, nil
+}
+
+// deriveKey derives the private key at the BIP-44 path from the BIP-39 seed.
+func deriveKey(seed []byte, derivationPath accounts.DerivationPath) (*crypto.PrivateKey, error) {
+	ek, err := newMasterKey(seed)
+	if err != nil {
+		return nil, err
+	}
+	for _, index := range derivationPath {
+		ek, err = ek.child(index)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return crypto.PrivateKeyFromBytes(ek.key)
+}
@@ -0,0 +1,68 @@
+package key
+
+import (
+	"io/ioutil"
+	"strings"
+
+	ethkeystore "github.com/ethereum/go-ethereum/accounts/keystore"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/spf13/cobra"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/thetasubchain/cmd/thetasubcli/cmd/utils"
+)
+
+// importCmd imports a key from a raw private key or an Ethereum JSON keystore file
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Import a private key",
+	Long:  `Import a key from a raw hex private key, or from an Ethereum (Web3 v3) JSON keystore file.`,
+	Example: `thetasubcli key import --private_key=0x93a90ea508331dfdf27fb79757d4250b4e84954927ba0073cd67454ac432c737
+thetasubcli key import --keystore_file=UTC--2022-06-01T00-00-00.000000000Z--2e833968e5bb786ae419c4d13189fb081cc43bab`,
+	Run: func(cmd *cobra.Command, args []string) {
+		var privKey *crypto.PrivateKey
+		var err error
+		if privateKeyFlag != "" {
+			privKey, err = crypto.PrivateKeyFromBytes(common.FromHex(strings.TrimSpace(privateKeyFlag)))
+			if err != nil {
+				utils.Error("Invalid private key: %v\n", err)
+			}
+		} else if keystoreFileFlag != "" {
+			keyJSON, err := ioutil.ReadFile(keystoreFileFlag)
+			if err != nil {
+				utils.Error("Failed to read %v: %v\n", keystoreFileFlag, err)
+			}
+			password := keystorePasswordFlag
+			if password == "" {
+				password, err = utils.GetPassword("Please enter the password of the keystore file: ")
+				if err != nil {
+					utils.Error("Failed to get password: %v\n", err)
+				}
+			}
+			ethKey, err := ethkeystore.DecryptKey(keyJSON, password)
+			if err != nil {
+				utils.Error("Failed to decrypt the keystore file: %v\n", err)
+			}
+			privKey, err = crypto.PrivateKeyFromBytes(ethcrypto.FromECDSA(ethKey.PrivateKey))
+			if err != nil {
+				utils.Error("Invalid private key: %v\n", err)
+			}
+		} else {
+			utils.Error("Either --private_key or --keystore_file needs to be specified\n")
+		}
+
+		keystore := openKeystore(cmd)
+		password := getNewPassword("Please choose a password for the key: ")
+		address, stored := storeKey(keystore, privKey, password)
+		printStoredKey(address, stored)
+	},
+}
+
+func init() {
+	importCmd.Flags().StringVar(&privateKeyFlag, "private_key", "", "The hex encoded private key")
+	importCmd.Flags().StringVar(&keystoreFileFlag, "keystore_file", "", "The Ethereum JSON keystore file")
+	importCmd.Flags().StringVar(&keystorePasswordFlag, "keystore_password", "", "Password of the JSON keystore file")
+	importCmd.Flags().StringVar(&passwordFlag, "password", "", "Password to encrypt the imported key")
+	addNodeFlags(importCmd, "Import into the keystore of the node instead of the CLI wallet")
+}
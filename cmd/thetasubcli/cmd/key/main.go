@@ -4,6 +4,25 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// Common flags used in Key sub commands.
+var (
+	passwordFlag           string
+	nodeFlag               bool
+	nodeKeyPathFlag        string
+	outputFlag             string
+	fileFlag               string
+	privateKeyFlag         string
+	keystoreFileFlag       string
+	keystorePasswordFlag   string
+	exportPasswordFlag     string
+	mnemonicFlag           string
+	mnemonicPassphraseFlag string
+	derivationPathFlag     string
+	wordsFlag              int
+	indexFlag              uint64
+	countFlag              uint64
+)
+
 // KeyCmd represents the key command
 var KeyCmd = &cobra.Command{
 	Use:   "key",
@@ -16,4 +35,10 @@ func init() {
 	KeyCmd.AddCommand(listCmd)
 	KeyCmd.AddCommand(deleteCmd)
 	KeyCmd.AddCommand(passwordCmd)
+	KeyCmd.AddCommand(recoverCmd)
+	KeyCmd.AddCommand(mnemonicCmd)
+	KeyCmd.AddCommand(importCmd)
+	KeyCmd.AddCommand(exportCmd)
+	KeyCmd.AddCommand(backupCmd)
+	KeyCmd.AddCommand(restoreCmd)
 }
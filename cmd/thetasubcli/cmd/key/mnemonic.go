@@ -0,0 +1,91 @@
+package key
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/spf13/cobra"
+	"github.com/tyler-smith/go-bip39"
+
+	"github.com/thetatoken/thetasubchain/cmd/thetasubcli/cmd/utils"
+)
+
+// mnemonicCmd generates a BIP-39 mnemonic and stores the first accounts derived from it
+var mnemonicCmd = &cobra.Command{
+	Use:     "mnemonic",
+	Short:   "Generate a mnemonic and derive keys from it",
+	Long:    `Generate a BIP-39 mnemonic, and store the keys of the first accounts derived from it along the BIP-44 path.`,
+	Example: "thetasubcli key mnemonic --words=24 --count=3",
+	Run: func(cmd *cobra.Command, args []string) {
+		checkNodeKeyCount(0, int(countFlag))
+		bitSize := wordsFlag / 3 * 32
+		if wordsFlag%3 != 0 || bitSize < 128 || bitSize > 256 {
+			utils.Error("The number of words needs to be 12, 15, 18, 21 or 24\n")
+		}
+		entropy, err := bip39.NewEntropy(bitSize)
+		if err != nil {
+			utils.Error("Failed to generate entropy: %v\n", err)
+		}
+		mnemonic, err := bip39.NewMnemonic(entropy)
+		if err != nil {
+			utils.Error("Failed to generate mnemonic: %v\n", err)
+		}
+
+		fmt.Println("-----------------------------------------------------------------------------------------------------")
+		fmt.Println("IMPORTANT: Please write down the mnemonic and store it securely. It is the only way to recover the keys.")
+		fmt.Println("-----------------------------------------------------------------------------------------------------")
+		fmt.Println(mnemonic)
+		fmt.Println("")
+
+		deriveAndStoreKeys(cmd, mnemonic)
+	},
+}
+
+// deriveAndStoreKeys derives --count keys from the mnemonic, starting at --index along --path, and stores them.
+func deriveAndStoreKeys(cmd *cobra.Command, mnemonic string) {
+	checkNodeKeyCount(0, int(countFlag))
+	mnemonic = strings.Join(strings.Fields(mnemonic), " ")
+	seed, err := bip39.NewSeedWithErrorChecking(mnemonic, mnemonicPassphraseFlag)
+	if err != nil {
+		utils.Error("Invalid mnemonic: %v\n", err)
+	}
+
+	basePath, err := accounts.ParseDerivationPath(derivationPathFlag)
+	if err != nil {
+		utils.Error("Invalid derivation path %v: %v\n", derivationPathFlag, err)
+	}
+
+	keystore := openKeystore(cmd)
+	password := getNewPassword("Please choose a password for the keys: ")
+	for i := indexFlag; i < indexFlag+countFlag; i++ {
+		derivationPath := make(accounts.DerivationPath, len(basePath), len(basePath)+1)
+		copy(derivationPath, basePath)
+		derivationPath = append(derivationPath, uint32(i))
+
+		privKey, err := deriveKey(seed, derivationPath)
+		if err != nil {
+			utils.Error("Failed to derive key %v: %v\n", derivationPath.String(), err)
+		}
+		address, stored := storeKey(keystore, privKey, password)
+		if stored {
+			fmt.Printf("%v: %v\n", derivationPath.String(), address.Hex())
+		} else {
+			fmt.Printf("%v: %v (already exists)\n", derivationPath.String(), address.Hex())
+		}
+	}
+}
+
+func addDerivationFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&derivationPathFlag, "path", "m/44'/60'/0'/0", "BIP-44 base derivation path, the account index is appended")
+	cmd.Flags().Uint64Var(&indexFlag, "index", 0, "Index of the first account to derive")
+	cmd.Flags().Uint64Var(&countFlag, "count", 1, "Number of accounts to derive")
+	cmd.Flags().StringVar(&mnemonicPassphraseFlag, "passphrase", "", "Optional BIP-39 passphrase")
+	cmd.Flags().StringVar(&passwordFlag, "password", "", "Password to encrypt the keys")
+	addNodeFlags(cmd, "Store the keys in the keystore of the node instead of the CLI wallet")
+}
+
+func init() {
+	mnemonicCmd.Flags().IntVar(&wordsFlag, "words", 24, "Number of words of the mnemonic (12|15|18|21|24)")
+	addDerivationFlags(mnemonicCmd)
+}
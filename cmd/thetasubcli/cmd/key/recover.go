@@ -1,17 +1,31 @@
 package key
 
 import (
-	"fmt"
-
 	"github.com/spf13/cobra"
+
+	"github.com/thetatoken/thetasubchain/cmd/thetasubcli/cmd/utils"
 )
 
 // recoverCmd recovers the key from the given seed phrase
 var recoverCmd = &cobra.Command{
-	Use:   "recover",
-	Short: "Recover a key from seed phrase",
-	Long:  `Recover a key from seed phrase.`,
+	Use:     "recover",
+	Short:   "Recover a key from seed phrase",
+	Long:    `Recover the keys derived from a BIP-39 seed phrase along the BIP-44 path.`,
+	Example: `thetasubcli key recover --mnemonic="abandon ability able ..." --count=3`,
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("key recover not supported yet")
+		mnemonic := mnemonicFlag
+		if mnemonic == "" {
+			var err error
+			mnemonic, err = utils.GetPassword("Please enter the seed phrase: ")
+			if err != nil {
+				utils.Error("Failed to get the seed phrase: %v\n", err)
+			}
+		}
+		deriveAndStoreKeys(cmd, mnemonic)
 	},
 }
+
+func init() {
+	recoverCmd.Flags().StringVar(&mnemonicFlag, "mnemonic", "", "The seed phrase, prompted for if not specified")
+	addDerivationFlags(recoverCmd)
+}
@@ -0,0 +1,110 @@
+package key
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/spf13/cobra"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/crypto"
+	ks "github.com/thetatoken/theta/wallet/softwallet/keystore"
+	"github.com/thetatoken/thetasubchain/cmd/thetasubcli/cmd/utils"
+	scom "github.com/thetatoken/thetasubchain/common"
+)
+
+// addNodeFlags adds the flags to operate on the keystore of a node instead of the CLI wallet.
+func addNodeFlags(cmd *cobra.Command, usage string) {
+	cmd.Flags().BoolVar(&nodeFlag, "node", false, usage)
+	cmd.Flags().StringVar(&nodeKeyPathFlag, "node_key_path", "",
+		fmt.Sprintf("Key path of the node, i.e. its %v config, or its config path if %v is not set", scom.CfgKeyPath, scom.CfgKeyPath))
+}
+
+// keysDir returns the keystore directory of the CLI wallet, or with the --node flag, the keystore
+// directory loaded by the node on startup, i.e. the "key" folder under the key path of the node.
+func keysDir(cmd *cobra.Command) string {
+	if nodeFlag {
+		if nodeKeyPathFlag == "" {
+			utils.Error("Please specify the key path of the node with --node_key_path\n")
+		}
+		return path.Join(nodeKeyPathFlag, "key")
+	}
+	cfgPath := cmd.Flag("config").Value.String()
+	return path.Join(cfgPath, "keys")
+}
+
+// checkNodeKeyCount exits if the keys to store would leave more than one key in the keystore of the
+// node, since the node refuses to start with multiple keys.
+func checkNodeKeyCount(numExisting, numNew int) {
+	if nodeFlag && numExisting+numNew > 1 {
+		utils.Error("The node keystore can only hold one key, it has %v key(s) and %v more would be stored\n",
+			numExisting, numNew)
+	}
+}
+
+func openKeystore(cmd *cobra.Command) *ks.KeystoreEncrypted {
+	keystore, err := ks.NewKeystoreEncrypted(keysDir(cmd), ks.StandardScryptN, ks.StandardScryptP)
+	if err != nil {
+		utils.Error("Failed to open the keystore: %v\n", err)
+	}
+	return keystore
+}
+
+// storeKey encrypts the private key with the password and stores it in the keystore, unless
+// the keystore already has the key.
+func storeKey(keystore *ks.KeystoreEncrypted, privKey *crypto.PrivateKey, password string) (common.Address, bool) {
+	address := privKey.PublicKey().Address()
+	addresses, err := keystore.ListKeyAddresses()
+	if err != nil {
+		utils.Error("Failed to list keys: %v\n", err)
+	}
+	for _, existing := range addresses {
+		if existing == address {
+			return address, false
+		}
+	}
+	checkNodeKeyCount(len(addresses), 1)
+
+	if err := keystore.StoreKey(ks.NewKey(privKey), password); err != nil {
+		utils.Error("Failed to store key %v: %v\n", address.Hex(), err)
+	}
+	return address, true
+}
+
+// getNewPassword prompts for a new password twice, unless it is given by the --password flag.
+func getNewPassword(prompt string) string {
+	if passwordFlag != "" {
+		return passwordFlag
+	}
+	password, err := utils.GetPassword(prompt)
+	if err != nil {
+		utils.Error("Failed to get password: %v\n", err)
+	}
+	password2, err := utils.GetPassword("Please enter the password again: ")
+	if err != nil {
+		utils.Error("Failed to get password: %v\n", err)
+	}
+	if password != password2 {
+		utils.Error("Passwords do not match, abort\n")
+	}
+	return password
+}
+
+func getPassword(prompt string) string {
+	if passwordFlag != "" {
+		return passwordFlag
+	}
+	password, err := utils.GetPassword(prompt)
+	if err != nil {
+		utils.Error("Failed to get password: %v\n", err)
+	}
+	return password
+}
+
+func printStoredKey(address common.Address, stored bool) {
+	if stored {
+		fmt.Printf("Successfully imported key: %v\n", address.Hex())
+	} else {
+		fmt.Printf("Key already exists: %v\n", address.Hex())
+	}
+}
@@ -0,0 +1,58 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/thetasubchain/cmd/thetasubcli/cmd/utils"
+	"github.com/thetatoken/thetasubchain/rpc"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	rpcc "github.com/ybbus/jsonrpc"
+)
+
+var (
+	pageFlag     uint64
+	pageSizeFlag uint64
+)
+
+// historyCmd represents the history command.
+// Example:
+//		thetasubcli query history --address=0x2E833968E5bB786Ae419c4d13189fB081Cc43bab --page=0 --page_size=20
+var historyCmd = &cobra.Command{
+	Use:     "history",
+	Short:   "Get the transactions sent or received by an account",
+	Long:    `Get the transactions sent or received by an account, most recent first. Requires the account history index to be enabled on the node.`,
+	Example: `thetasubcli query history --address=0x2E833968E5bB786Ae419c4d13189fB081Cc43bab --page=0 --page_size=20`,
+	Run:     doHistoryCmd,
+}
+
+func doHistoryCmd(cmd *cobra.Command, args []string) {
+	client := rpcc.NewRPCClient(viper.GetString(utils.CfgRemoteRPCEndpoint))
+
+	res, err := client.Call("theta.GetAccountHistory", rpc.GetAccountHistoryArgs{
+		Address:  addressFlag,
+		Page:     common.JSONUint64(pageFlag),
+		PageSize: common.JSONUint64(pageSizeFlag),
+	})
+	if err != nil {
+		utils.Error("Failed to get account history: %v\n", err)
+	}
+	if res.Error != nil {
+		utils.Error("Failed to get account history: %v\n", res.Error)
+	}
+	json, err := json.MarshalIndent(res.Result, "", "    ")
+	if err != nil {
+		utils.Error("Failed to parse server response: %v\n%v\n", err, string(json))
+	}
+	fmt.Println(string(json))
+}
+
+func init() {
+	historyCmd.Flags().StringVar(&addressFlag, "address", "", "Address of the account")
+	historyCmd.Flags().Uint64Var(&pageFlag, "page", uint64(0), "Page number, starting from 0")
+	historyCmd.Flags().Uint64Var(&pageSizeFlag, "page_size", uint64(20), "Number of transactions per page, at most 100")
+	historyCmd.MarkFlagRequired("address")
+}
@@ -33,4 +33,7 @@ func init() {
 	QueryCmd.AddCommand(peersCmd)
 	QueryCmd.AddCommand(versionCmd)
 	QueryCmd.AddCommand(tokenBankAddrCmd)
+	QueryCmd.AddCommand(rollingDBCmd)
+	QueryCmd.AddCommand(peerScoresCmd)
+	QueryCmd.AddCommand(historyCmd)
 }
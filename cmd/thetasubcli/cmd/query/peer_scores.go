@@ -0,0 +1,39 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/thetatoken/thetasubchain/cmd/thetasubcli/cmd/utils"
+	"github.com/thetatoken/thetasubchain/rpc"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	rpcc "github.com/ybbus/jsonrpc"
+)
+
+// peerScoresCmd represents the peer_scores command.
+// Example:
+//		thetasubcli query peer_scores
+var peerScoresCmd = &cobra.Command{
+	Use:     "peer_scores",
+	Short:   "Get peer reputation scores and current bans",
+	Long:    `Get peer reputation scores and current bans.`,
+	Example: `thetasubcli query peer_scores`,
+	Run: func(cmd *cobra.Command, args []string) {
+		client := rpcc.NewRPCClient(viper.GetString(utils.CfgRemoteRPCEndpoint))
+
+		res, err := client.Call("theta.GetPeerScores", rpc.GetPeerScoresArgs{})
+		if err != nil {
+			utils.Error("Failed to get peer scores: %v\n", err)
+		}
+		if res.Error != nil {
+			utils.Error("Failed to retrieve peer scores: %v\n", res.Error)
+		}
+		json, err := json.MarshalIndent(res.Result, "", "    ")
+		if err != nil {
+			utils.Error("Failed to parse server response: %v\n%v\n", err, string(json))
+		}
+		fmt.Println(string(json))
+	},
+}
@@ -0,0 +1,39 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/thetatoken/thetasubchain/cmd/thetasubcli/cmd/utils"
+	"github.com/thetatoken/thetasubchain/rpc"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	rpcc "github.com/ybbus/jsonrpc"
+)
+
+// rollingDBCmd represents the rollingdb command.
+// Example:
+//		thetasubcli query rollingdb
+var rollingDBCmd = &cobra.Command{
+	Use:     "rollingdb",
+	Short:   "Get rolling DB layers and compaction progress",
+	Long:    `Get rolling DB layers and compaction progress.`,
+	Example: `thetasubcli query rollingdb`,
+	Run: func(cmd *cobra.Command, args []string) {
+		client := rpcc.NewRPCClient(viper.GetString(utils.CfgRemoteRPCEndpoint))
+
+		res, err := client.Call("theta.GetRollingDBStatus", rpc.GetRollingDBStatusArgs{})
+		if err != nil {
+			utils.Error("Failed to get rolling DB status: %v\n", err)
+		}
+		if res.Error != nil {
+			utils.Error("Failed to retrieve rolling DB status: %v\n", res.Error)
+		}
+		json, err := json.MarshalIndent(res.Result, "", "    ")
+		if err != nil {
+			utils.Error("Failed to parse server response: %v\n%v\n", err, string(json))
+		}
+		fmt.Println(string(json))
+	},
+}
@@ -13,9 +13,11 @@ import (
 	"github.com/spf13/viper"
 	"github.com/thetatoken/thetasubchain/cmd/thetasubcli/cmd/call"
 	"github.com/thetatoken/thetasubchain/cmd/thetasubcli/cmd/daemon"
+	"github.com/thetatoken/thetasubchain/cmd/thetasubcli/cmd/db"
 	"github.com/thetatoken/thetasubchain/cmd/thetasubcli/cmd/key"
 	"github.com/thetatoken/thetasubchain/cmd/thetasubcli/cmd/query"
 	"github.com/thetatoken/thetasubchain/cmd/thetasubcli/cmd/tx"
+	"github.com/thetatoken/thetasubchain/cmd/thetasubcli/cmd/xchain"
 )
 
 var cfgPath string
@@ -47,6 +49,8 @@ func init() {
 	RootCmd.AddCommand(query.QueryCmd)
 	RootCmd.AddCommand(call.CallCmd)
 	RootCmd.AddCommand(backup.BackupCmd)
+	RootCmd.AddCommand(db.DBCmd)
+	RootCmd.AddCommand(xchain.XchainCmd)
 	RootCmd.AddCommand(versionCmd)
 }
 
@@ -0,0 +1,168 @@
+package tx
+
+import (
+	"encoding/csv"
+	"encoding/hex"
+	"io"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/ledger/types"
+	wtypes "github.com/thetatoken/theta/wallet/types"
+	"github.com/thetatoken/thetasubchain/cmd/thetasubcli/cmd/utils"
+	stypes "github.com/thetatoken/thetasubchain/ledger/types"
+)
+
+// batchSendCmd represents the batch_send command, which pays the recipients listed in a CSV file with a
+// single transaction. Each line of the CSV file has the recipient address and the TFuel amount, e.g.
+//		9F1233798E905E173560071255140b4A8aBd3Ec6,10
+//		0x8Be503bcdEd90ED42Eff31f56199399B2b0154CA,2500000000000000000wei
+// An optional header line is skipped.
+// Example:
+//		thetasubcli tx batch_send --chain="tsub360777" --from=2E833968E5bB786Ae419c4d13189fB081Cc43bab --csv=payroll.csv
+var batchSendCmd = &cobra.Command{
+	Use:     "batch_send",
+	Short:   "Send tokens to multiple recipients listed in a CSV file",
+	Example: `thetasubcli tx batch_send --chain="tsub360777" --from=2E833968E5bB786Ae419c4d13189fB081Cc43bab --csv=payroll.csv`,
+	Run:     doBatchSendCmd,
+}
+
+func doBatchSendCmd(cmd *cobra.Command, args []string) {
+	walletType := getWalletType(cmd)
+	if walletType == wtypes.WalletTypeSoft && len(fromFlag) == 0 {
+		utils.Error("The from address cannot be empty") // we don't need to specify the "from address" for hardware wallets
+		return
+	}
+
+	outputs := readBatchSendOutputs(csvFlag)
+	if len(outputs) == 0 {
+		utils.Error("No recipient found in %v\n", csvFlag)
+	}
+	if len(outputs) > stypes.MaxBatchSendTxOutputs {
+		utils.Error("Too many recipients: %v, at most %v are allowed per transaction\n", len(outputs), stypes.MaxBatchSendTxOutputs)
+	}
+
+	wallet, fromAddress, err := walletUnlockWithPath(cmd, fromFlag, pathFlag, passwordFlag)
+	if err != nil || wallet == nil {
+		return
+	}
+	defer wallet.Lock(fromAddress)
+
+	total := new(big.Int)
+	for _, output := range outputs {
+		if output.Address == fromAddress {
+			utils.Error("The from address cannot be a recipient")
+		}
+		total.Add(total, output.Coins.TFuelWei)
+	}
+
+	sequence := resolveSequence(cmd, fromAddress)
+	fee := resolveBatchSendTxFee(uint64(len(outputs)))
+	batchSendTx := &stypes.BatchSendTx{
+		Fee: types.Coins{
+			ThetaWei: new(big.Int).SetUint64(0),
+			TFuelWei: fee,
+		},
+		Input: types.TxInput{
+			Address: fromAddress,
+			Coins: types.Coins{
+				TFuelWei: new(big.Int).Add(total, fee),
+				ThetaWei: new(big.Int).SetUint64(0),
+			},
+			Sequence: sequence,
+		},
+		Outputs: outputs,
+	}
+
+	sig, err := wallet.Sign(fromAddress, batchSendTx.SignBytes(chainIDFlag))
+	if err != nil {
+		utils.Error("Failed to sign transaction: %v\n", err)
+	}
+	batchSendTx.SetSignature(fromAddress, sig)
+
+	raw, err := stypes.TxToBytes(batchSendTx)
+	if err != nil {
+		utils.Error("Failed to encode transaction: %v\n", err)
+	}
+	signedTx := hex.EncodeToString(raw)
+
+	if dryRunFlag {
+		printDryRun(signedTx, sequence)
+		return
+	}
+
+	broadcastRawTx(signedTx)
+}
+
+// readBatchSendOutputs reads the recipients and amounts from the CSV file.
+func readBatchSendOutputs(csvPath string) []types.TxOutput {
+	f, err := os.Open(csvPath)
+	if err != nil {
+		utils.Error("Failed to open %v: %v\n", csvPath, err)
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = 2
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+
+	outputs := []types.TxOutput{}
+	seen := make(map[common.Address]bool)
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			utils.Error("Failed to read %v: %v\n", csvPath, err)
+		}
+
+		addressStr := strings.TrimSpace(record[0])
+		if !common.IsHexAddress(addressStr) {
+			if line == 1 {
+				continue // header
+			}
+			utils.Error("Invalid address on line %v: %v\n", line, addressStr)
+		}
+		address := common.HexToAddress(addressStr)
+		if seen[address] {
+			utils.Error("Duplicated recipient on line %v: %v\n", line, address.Hex())
+		}
+		seen[address] = true
+
+		amount, ok := types.ParseCoinAmount(strings.TrimSpace(record[1]))
+		if !ok || amount.Sign() <= 0 {
+			utils.Error("Invalid amount on line %v: %v\n", line, record[1])
+		}
+
+		outputs = append(outputs, types.TxOutput{
+			Address: address,
+			Coins: types.Coins{
+				TFuelWei: amount,
+				ThetaWei: new(big.Int).SetUint64(0),
+			},
+		})
+	}
+	return outputs
+}
+
+func init() {
+	batchSendCmd.Flags().StringVar(&chainIDFlag, "chain", "", "Chain ID")
+	batchSendCmd.Flags().StringVar(&fromFlag, "from", "", "Address to send from")
+	batchSendCmd.Flags().StringVar(&csvFlag, "csv", "", "CSV file listing the recipient addresses and TFuel amounts")
+	batchSendCmd.Flags().StringVar(&pathFlag, "path", "", "Wallet derivation path")
+	batchSendCmd.Flags().Uint64Var(&seqFlag, "seq", 0, "Sequence number of the transaction, queried from the node if not specified")
+	batchSendCmd.Flags().StringVar(&feeFlag, "fee", "", "Fee, suggested by the node if not specified")
+	batchSendCmd.Flags().StringVar(&walletFlag, "wallet", "soft", "Wallet type (soft|nano|trezor)")
+	batchSendCmd.Flags().BoolVar(&asyncFlag, "async", false, "block until tx has been included in the blockchain")
+	batchSendCmd.Flags().StringVar(&passwordFlag, "password", "", "password to unlock the wallet")
+	batchSendCmd.Flags().BoolVar(&dryRunFlag, "dry-run", false, "Print the signed transaction without broadcasting it")
+
+	batchSendCmd.MarkFlagRequired("chain")
+	batchSendCmd.MarkFlagRequired("csv")
+}
@@ -36,6 +36,19 @@ var (
 	beneficiaryFlag              string
 	splitBasisPointFlag          uint64
 	passwordFlag                 string
+	dryRunFlag                   bool
+	outputFlag                   string
+	fileFlag                     string
+	txFlag                       string
+	abiFlag                      string
+	methodFlag                   string
+	argsFlag                     []string
+	bytecodeFlag                 string
+	addressFlag                  string
+	accountFlag                  string
+	thresholdFlag                uint64
+	pubKeysFlag                  []string
+	csvFlag                      string
 )
 
 // TxCmd represents the Tx command
@@ -45,7 +58,20 @@ var TxCmd = &cobra.Command{
 	Long:  `Manage transactions.`,
 }
 
+// addABIFlags adds the flags to encode the smart contract data from an ABI instead of raw hex.
+func addABIFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&abiFlag, "abi", "", "Path to the ABI JSON file of the smart contract")
+	cmd.Flags().StringVar(&methodFlag, "method", "", "Name of the smart contract method to call, requires --abi")
+	cmd.Flags().StringArrayVar(&argsFlag, "arg", []string{}, "Argument of the method or constructor, can be repeated. Arrays are given in JSON, e.g. [1,2]")
+	cmd.Flags().StringVar(&bytecodeFlag, "bytecode", "", "Bytecode of the smart contract to deploy, as hex or the path of a file containing the hex")
+}
+
 func init() {
 	TxCmd.AddCommand(sendCmd)
 	TxCmd.AddCommand(smartContractCmd)
+	TxCmd.AddCommand(buildCmd)
+	TxCmd.AddCommand(signCmd)
+	TxCmd.AddCommand(broadcastCmd)
+	TxCmd.AddCommand(multisigCmd)
+	TxCmd.AddCommand(batchSendCmd)
 }
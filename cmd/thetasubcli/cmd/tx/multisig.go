@@ -0,0 +1,318 @@
+package tx
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"path"
+
+	"github.com/spf13/cobra"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/ledger/types"
+	ks "github.com/thetatoken/theta/wallet/softwallet/keystore"
+	"github.com/thetatoken/thetasubchain/cmd/thetasubcli/cmd/utils"
+	stypes "github.com/thetatoken/thetasubchain/ledger/types"
+)
+
+// multisigTxFile is the file shared among the signers of a multisig account to collect their signatures.
+type multisigTxFile struct {
+	ChainID   string                 `json:"chain_id"`
+	Account   stypes.MultisigAccount `json:"account"`
+	Signers   []common.Address       `json:"signers"`
+	TxBytes   string                 `json:"tx_bytes"`
+	SignBytes string                 `json:"sign_bytes"`
+	Tx        json.RawMessage        `json:"tx"`
+}
+
+// multisigCmd represents the multisig command.
+var multisigCmd = &cobra.Command{
+	Use:   "multisig",
+	Short: "Manage multisig accounts and transactions",
+}
+
+// multisigPubKeyCmd prints the public key of a key in the keystore, to be shared with the other signers.
+// Example:
+//		thetasubcli tx multisig pubkey --address=2E833968E5bB786Ae419c4d13189fB081Cc43bab
+var multisigPubKeyCmd = &cobra.Command{
+	Use:     "pubkey",
+	Short:   "Print the public key of a key in the keystore",
+	Example: `thetasubcli tx multisig pubkey --address=2E833968E5bB786Ae419c4d13189fB081Cc43bab`,
+	Run:     doMultisigPubKeyCmd,
+}
+
+// multisigCreateCmd derives the multisig account from a set of public keys and a threshold.
+// Example:
+//		thetasubcli tx multisig create --threshold=2 --pubkeys=0x04a3...,0x04b1...,0x04c7... --output=treasury.json
+var multisigCreateCmd = &cobra.Command{
+	Use:     "create",
+	Short:   "Create a multisig account",
+	Example: `thetasubcli tx multisig create --threshold=2 --pubkeys=0x04a3...,0x04b1...,0x04c7... --output=treasury.json`,
+	Run:     doMultisigCreateCmd,
+}
+
+// multisigBuildCmd builds an unsigned send transaction from the multisig account.
+// Example:
+//		thetasubcli tx multisig build --chain="tsub360777" --account=treasury.json --to=9F1233798E905E173560071255140b4A8aBd3Ec6 --tfuel=9 --output=multisig_tx.json
+var multisigBuildCmd = &cobra.Command{
+	Use:     "build",
+	Short:   "Build a send transaction from a multisig account",
+	Example: `thetasubcli tx multisig build --chain="tsub360777" --account=treasury.json --to=9F1233798E905E173560071255140b4A8aBd3Ec6 --tfuel=9 --output=multisig_tx.json`,
+	Run:     doMultisigBuildCmd,
+}
+
+// multisigSignCmd adds the signature of one of the signers to the shared transaction file.
+// Example:
+//		thetasubcli tx multisig sign --file=multisig_tx.json --from=2E833968E5bB786Ae419c4d13189fB081Cc43bab
+var multisigSignCmd = &cobra.Command{
+	Use:     "sign",
+	Short:   "Add a signature to a multisig transaction",
+	Example: `thetasubcli tx multisig sign --file=multisig_tx.json --from=2E833968E5bB786Ae419c4d13189fB081Cc43bab`,
+	Run:     doMultisigSignCmd,
+}
+
+// multisigBroadcastCmd broadcasts the multisig transaction once enough signatures are collected.
+// Example:
+//		thetasubcli tx multisig broadcast --file=multisig_tx.json
+var multisigBroadcastCmd = &cobra.Command{
+	Use:     "broadcast",
+	Short:   "Broadcast a multisig transaction once the threshold is met",
+	Example: `thetasubcli tx multisig broadcast --file=multisig_tx.json`,
+	Run:     doMultisigBroadcastCmd,
+}
+
+func doMultisigPubKeyCmd(cmd *cobra.Command, args []string) {
+	cfgPath := cmd.Flag("config").Value.String()
+	keystore, err := ks.NewKeystoreEncrypted(path.Join(cfgPath, "keys"), ks.StandardScryptN, ks.StandardScryptP)
+	if err != nil {
+		utils.Error("Failed to open the keystore: %v\n", err)
+	}
+
+	password := passwordFlag
+	if password == "" {
+		password, err = utils.GetPassword("Please enter password: ")
+		if err != nil {
+			utils.Error("Failed to get password: %v\n", err)
+		}
+	}
+
+	address := common.HexToAddress(addressFlag)
+	key, err := keystore.GetKey(address, password)
+	if err != nil {
+		utils.Error("Failed to unlock address %v: %v\n", address.Hex(), err)
+	}
+	fmt.Println(key.PrivateKey.PublicKey().ToBytes().String())
+}
+
+func doMultisigCreateCmd(cmd *cobra.Command, args []string) {
+	pubKeys := make([]common.Bytes, len(pubKeysFlag))
+	for i, pubKeyStr := range pubKeysFlag {
+		pubKeys[i] = common.FromHex(pubKeyStr)
+	}
+	account, err := stypes.NewMultisigAccount(thresholdFlag, pubKeys)
+	if err != nil {
+		utils.Error("Failed to create the multisig account: %v\n", err)
+	}
+	formatted, err := json.MarshalIndent(account, "", "    ")
+	if err != nil {
+		utils.Error("Failed to format the multisig account: %v\n", err)
+	}
+	writeOutput(string(formatted))
+}
+
+func doMultisigBuildCmd(cmd *cobra.Command, args []string) {
+	content, err := ioutil.ReadFile(accountFlag)
+	if err != nil {
+		utils.Error("Failed to read %v: %v\n", accountFlag, err)
+	}
+	account := stypes.MultisigAccount{}
+	if err := json.Unmarshal(content, &account); err != nil {
+		utils.Error("Failed to parse the multisig account: %v\n", err)
+	}
+	if err := account.Validate(); err != nil {
+		utils.Error("Invalid multisig account: %v\n", err)
+	}
+	if len(toFlag) == 0 {
+		utils.Error("The to address cannot be empty")
+	}
+
+	fromAddress := account.Address()
+	sequence := resolveSequence(cmd, fromAddress)
+	tfuel, ok := types.ParseCoinAmount(tfuelAmountFlag)
+	if !ok {
+		utils.Error("Failed to parse tfuel amount")
+	}
+	fee := resolveSendTxFee(2 + account.Threshold - 1) // one input, one output, and the additional signatures
+
+	tx := &stypes.MultisigSendTx{
+		Fee: types.Coins{
+			ThetaWei: new(big.Int).SetUint64(0),
+			TFuelWei: fee,
+		},
+		Inputs: []types.TxInput{{
+			Address: fromAddress,
+			Coins: types.Coins{
+				TFuelWei: new(big.Int).Add(tfuel, fee),
+				ThetaWei: new(big.Int).SetUint64(0),
+			},
+			Sequence: sequence,
+		}},
+		Outputs: []types.TxOutput{{
+			Address: common.HexToAddress(toFlag),
+			Coins: types.Coins{
+				TFuelWei: tfuel,
+				ThetaWei: new(big.Int).SetUint64(0),
+			},
+		}},
+		Multisigs: []stypes.MultisigAuth{{
+			Account:    account,
+			Signatures: []stypes.MultisigSignature{},
+		}},
+	}
+	writeMultisigTxFile(chainIDFlag, tx)
+}
+
+func doMultisigSignCmd(cmd *cobra.Command, args []string) {
+	chainID, tx := readMultisigTxFile(fileFlag)
+	multisig := &tx.Multisigs[0]
+
+	wallet, signer, err := walletUnlockWithPath(cmd, fromFlag, pathFlag, passwordFlag)
+	if err != nil || wallet == nil {
+		return
+	}
+	defer wallet.Lock(signer)
+	if multisig.Account.IndexOf(signer) < 0 {
+		utils.Error("%v is not a signer of the multisig account %v\n", signer.Hex(), multisig.Account.Address().Hex())
+	}
+
+	sig, err := wallet.Sign(signer, tx.SignBytes(chainID))
+	if err != nil {
+		utils.Error("Failed to sign transaction: %v\n", err)
+	}
+	multisig.AddSignature(signer, sig)
+
+	if outputFlag == "" {
+		outputFlag = fileFlag // collect the signatures in the shared file
+	}
+	writeMultisigTxFile(chainID, tx)
+	fmt.Printf("Signatures collected: %v/%v\n", len(multisig.Signatures), multisig.Account.Threshold)
+}
+
+func doMultisigBroadcastCmd(cmd *cobra.Command, args []string) {
+	chainID, tx := readMultisigTxFile(fileFlag)
+	multisig := &tx.Multisigs[0]
+	if err := multisig.Verify(tx.SignBytes(chainID)); err != nil {
+		utils.Error("The multisig transaction is not ready: %v\n", err)
+	}
+
+	raw, err := stypes.TxToBytes(tx)
+	if err != nil {
+		utils.Error("Failed to encode transaction: %v\n", err)
+	}
+	broadcastRawTx(hex.EncodeToString(raw))
+}
+
+func readMultisigTxFile(filePath string) (string, *stypes.MultisigSendTx) {
+	content, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		utils.Error("Failed to read %v: %v\n", filePath, err)
+	}
+	txFile := multisigTxFile{}
+	if err := json.Unmarshal(content, &txFile); err != nil {
+		utils.Error("Failed to parse the multisig transaction: %v\n", err)
+	}
+	raw, err := hex.DecodeString(txFile.TxBytes)
+	if err != nil {
+		utils.Error("Failed to decode the transaction bytes: %v\n", err)
+	}
+	decoded, err := stypes.TxFromBytes(raw)
+	if err != nil {
+		utils.Error("Failed to decode the transaction: %v\n", err)
+	}
+	tx, ok := decoded.(*stypes.MultisigSendTx)
+	if !ok || len(tx.Multisigs) != 1 {
+		utils.Error("Not a multisig transaction of a single multisig account\n")
+	}
+
+	// The sign bytes in the file are only a sanity check, they are always recomputed from the transaction
+	if expected, err := hex.DecodeString(txFile.SignBytes); err != nil || !bytes.Equal(expected, tx.SignBytes(txFile.ChainID)) {
+		utils.Error("The sign bytes do not match the transaction\n")
+	}
+	return txFile.ChainID, tx
+}
+
+func writeMultisigTxFile(chainID string, tx *stypes.MultisigSendTx) {
+	raw, err := stypes.TxToBytes(tx)
+	if err != nil {
+		utils.Error("Failed to encode transaction: %v\n", err)
+	}
+	readable, err := json.Marshal(tx)
+	if err != nil {
+		utils.Error("Failed to format transaction: %v\n", err)
+	}
+
+	multisig := tx.Multisigs[0]
+	signers := []common.Address{}
+	signerAddresses := multisig.Account.SignerAddresses()
+	for _, sig := range multisig.Signatures {
+		signers = append(signers, signerAddresses[sig.Index])
+	}
+
+	txFile := multisigTxFile{
+		ChainID:   chainID,
+		Account:   multisig.Account,
+		Signers:   signers,
+		TxBytes:   hex.EncodeToString(raw),
+		SignBytes: hex.EncodeToString(tx.SignBytes(chainID)),
+		Tx:        readable,
+	}
+	formatted, err := json.MarshalIndent(txFile, "", "    ")
+	if err != nil {
+		utils.Error("Failed to format the multisig transaction: %v\n", err)
+	}
+	writeOutput(string(formatted))
+}
+
+func init() {
+	multisigPubKeyCmd.Flags().StringVar(&addressFlag, "address", "", "Address of the key")
+	multisigPubKeyCmd.Flags().StringVar(&passwordFlag, "password", "", "password to unlock the key")
+	multisigPubKeyCmd.MarkFlagRequired("address")
+
+	multisigCreateCmd.Flags().Uint64Var(&thresholdFlag, "threshold", 0, "Number of signatures required")
+	multisigCreateCmd.Flags().StringSliceVar(&pubKeysFlag, "pubkeys", []string{}, "Public keys of the signers")
+	multisigCreateCmd.Flags().StringVar(&outputFlag, "output", "", "File to write the multisig account to, stdout if not specified")
+	multisigCreateCmd.MarkFlagRequired("threshold")
+	multisigCreateCmd.MarkFlagRequired("pubkeys")
+
+	multisigBuildCmd.Flags().StringVar(&chainIDFlag, "chain", "", "Chain ID")
+	multisigBuildCmd.Flags().StringVar(&accountFlag, "account", "", "File containing the multisig account")
+	multisigBuildCmd.Flags().StringVar(&toFlag, "to", "", "Address to send to")
+	multisigBuildCmd.Flags().Uint64Var(&seqFlag, "seq", 0, "Sequence number of the transaction, queried from the node if not specified")
+	multisigBuildCmd.Flags().StringVar(&tfuelAmountFlag, "tfuel", "0", "TFuel amount")
+	multisigBuildCmd.Flags().StringVar(&feeFlag, "fee", "", "Fee, suggested by the node if not specified")
+	multisigBuildCmd.Flags().StringVar(&outputFlag, "output", "", "File to write the multisig transaction to, stdout if not specified")
+	multisigBuildCmd.MarkFlagRequired("chain")
+	multisigBuildCmd.MarkFlagRequired("account")
+	multisigBuildCmd.MarkFlagRequired("to")
+
+	multisigSignCmd.Flags().StringVar(&fileFlag, "file", "", "File containing the multisig transaction")
+	multisigSignCmd.Flags().StringVar(&fromFlag, "from", "", "Address of the signer")
+	multisigSignCmd.Flags().StringVar(&walletFlag, "wallet", "soft", "Wallet type (soft|nano|trezor)")
+	multisigSignCmd.Flags().StringVar(&pathFlag, "path", "", "Wallet derivation path")
+	multisigSignCmd.Flags().StringVar(&passwordFlag, "password", "", "password to unlock the wallet")
+	multisigSignCmd.Flags().StringVar(&outputFlag, "output", "", "File to write the multisig transaction to, the input file if not specified")
+	multisigSignCmd.MarkFlagRequired("file")
+
+	multisigBroadcastCmd.Flags().StringVar(&fileFlag, "file", "", "File containing the multisig transaction")
+	multisigBroadcastCmd.Flags().BoolVar(&asyncFlag, "async", false, "block until tx has been included in the blockchain")
+	multisigBroadcastCmd.MarkFlagRequired("file")
+
+	multisigCmd.AddCommand(multisigPubKeyCmd)
+	multisigCmd.AddCommand(multisigCreateCmd)
+	multisigCmd.AddCommand(multisigBuildCmd)
+	multisigCmd.AddCommand(multisigSignCmd)
+	multisigCmd.AddCommand(multisigBroadcastCmd)
+}
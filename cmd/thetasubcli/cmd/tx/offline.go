@@ -0,0 +1,274 @@
+package tx
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/ledger/types"
+	wtypes "github.com/thetatoken/theta/wallet/types"
+	"github.com/thetatoken/thetasubchain/cmd/thetasubcli/cmd/utils"
+	stypes "github.com/thetatoken/thetasubchain/ledger/types"
+)
+
+const (
+	unsignedTxTypeSend          = "send"
+	unsignedTxTypeSmartContract = "smart_contract"
+)
+
+// unsignedTx is the JSON representation of a transaction built by the tx build commands.
+// It carries everything needed to sign the transaction on an offline machine.
+type unsignedTx struct {
+	ChainID   string         `json:"chain_id"`
+	TxType    string         `json:"tx_type"`
+	Signer    common.Address `json:"signer"`
+	Sequence  uint64         `json:"sequence"`
+	TxBytes   string         `json:"tx_bytes"`
+	SignBytes string         `json:"sign_bytes"`
+	Tx        types.Tx       `json:"tx"`
+}
+
+// buildCmd represents the build command, which emits an unsigned transaction as JSON.
+// Example:
+//		thetasubcli tx build send --chain="tsub360777" --from=2E833968E5bB786Ae419c4d13189fB081Cc43bab --to=9F1233798E905E173560071255140b4A8aBd3Ec6 --tfuel=9 --output=tx.json
+//		thetasubcli tx build smart_contract --chain="tsub360777" --from=2E833968E5bB786Ae419c4d13189fB081Cc43bab --to=0x8Be503bcdEd90ED42Eff31f56199399B2b0154CA --data=7ff75b46 --gas_limit=100000
+var buildCmd = &cobra.Command{
+	Use:   "build",
+	Short: "Build an unsigned transaction for offline signing",
+}
+
+var buildSendCmd = &cobra.Command{
+	Use:     "send",
+	Short:   "Build an unsigned send transaction",
+	Example: `thetasubcli tx build send --chain="tsub360777" --from=2E833968E5bB786Ae419c4d13189fB081Cc43bab --to=9F1233798E905E173560071255140b4A8aBd3Ec6 --tfuel=9 --output=tx.json`,
+	Run:     doBuildSendCmd,
+}
+
+var buildSmartContractCmd = &cobra.Command{
+	Use:     "smart_contract",
+	Short:   "Build an unsigned smart contract transaction",
+	Example: `thetasubcli tx build smart_contract --chain="tsub360777" --from=2E833968E5bB786Ae419c4d13189fB081Cc43bab --to=0x8Be503bcdEd90ED42Eff31f56199399B2b0154CA --data=7ff75b46 --gas_limit=100000`,
+	Run:     doBuildSmartContractCmd,
+}
+
+// signCmd represents the sign command, which signs an unsigned transaction built by the build command.
+// It does not connect to the node, and hence can be run on an offline machine.
+// Example:
+//		thetasubcli tx sign --file=tx.json --output=signed_tx.hex
+//		thetasubcli tx sign --file=tx.json --wallet=nano --path "m/44'/60'/0'/0"
+var signCmd = &cobra.Command{
+	Use:     "sign",
+	Short:   "Sign an unsigned transaction offline",
+	Example: `thetasubcli tx sign --file=tx.json --output=signed_tx.hex`,
+	Run:     doSignCmd,
+}
+
+// broadcastCmd represents the broadcast command, which submits a signed transaction to the node.
+// Example:
+//		thetasubcli tx broadcast --file=signed_tx.hex
+//		thetasubcli tx broadcast --tx=02f8a4c78085e8d4a51000...
+var broadcastCmd = &cobra.Command{
+	Use:     "broadcast",
+	Short:   "Broadcast a signed transaction",
+	Example: `thetasubcli tx broadcast --file=signed_tx.hex`,
+	Run:     doBroadcastCmd,
+}
+
+func doBuildSendCmd(cmd *cobra.Command, args []string) {
+	if len(toFlag) == 0 {
+		utils.Error("The to address cannot be empty")
+	}
+	if fromFlag == toFlag {
+		utils.Error("The from and to address cannot be identical")
+	}
+
+	fromAddress := common.HexToAddress(fromFlag)
+	sequence := resolveSequence(cmd, fromAddress)
+	sendTx := newSendTx(fromAddress, sequence)
+	writeUnsignedTx(unsignedTxTypeSend, fromAddress, sequence, sendTx, sendTx.SignBytes(chainIDFlag))
+}
+
+func doBuildSmartContractCmd(cmd *cobra.Command, args []string) {
+	fromAddress := common.HexToAddress(fromFlag)
+	sequence := resolveSequence(cmd, fromAddress)
+	smartContractTx := newSmartContractTx(fromAddress, sequence)
+	writeUnsignedTx(unsignedTxTypeSmartContract, fromAddress, sequence, smartContractTx, smartContractTx.SignBytes(chainIDFlag))
+}
+
+func writeUnsignedTx(txType string, signer common.Address, sequence uint64, tx types.Tx, signBytes common.Bytes) {
+	raw, err := stypes.TxToBytes(tx)
+	if err != nil {
+		utils.Error("Failed to encode transaction: %v\n", err)
+	}
+	utx := unsignedTx{
+		ChainID:   chainIDFlag,
+		TxType:    txType,
+		Signer:    signer,
+		Sequence:  sequence,
+		TxBytes:   hex.EncodeToString(raw),
+		SignBytes: hex.EncodeToString(signBytes),
+		Tx:        tx,
+	}
+	formatted, err := json.MarshalIndent(utx, "", "    ")
+	if err != nil {
+		utils.Error("Failed to format the unsigned transaction: %v\n", err)
+	}
+	writeOutput(string(formatted))
+}
+
+func doSignCmd(cmd *cobra.Command, args []string) {
+	content, err := ioutil.ReadFile(fileFlag)
+	if err != nil {
+		utils.Error("Failed to read %v: %v\n", fileFlag, err)
+	}
+	utx := struct {
+		ChainID   string         `json:"chain_id"`
+		TxType    string         `json:"tx_type"`
+		Signer    common.Address `json:"signer"`
+		TxBytes   string         `json:"tx_bytes"`
+		SignBytes string         `json:"sign_bytes"`
+	}{}
+	if err := json.Unmarshal(content, &utx); err != nil {
+		utils.Error("Failed to parse the unsigned transaction: %v\n", err)
+	}
+	raw, err := hex.DecodeString(utx.TxBytes)
+	if err != nil {
+		utils.Error("Failed to decode the transaction bytes: %v\n", err)
+	}
+	tx, err := stypes.TxFromBytes(raw)
+	if err != nil {
+		utils.Error("Failed to decode the transaction: %v\n", err)
+	}
+
+	// The sign bytes are recomputed rather than taken from the file, and compared against the
+	// file as a sanity check, so that what gets signed always matches the transaction body.
+	var signBytes common.Bytes
+	var signer common.Address
+	switch t := tx.(type) {
+	case *types.SendTx:
+		if utx.TxType != unsignedTxTypeSend || len(t.Inputs) != 1 {
+			utils.Error("Only send transactions with a single input can be signed\n")
+		}
+		signer = t.Inputs[0].Address
+		signBytes = t.SignBytes(utx.ChainID)
+	case *types.SmartContractTx:
+		if utx.TxType != unsignedTxTypeSmartContract {
+			utils.Error("The transaction is a smart contract transaction, not %v\n", utx.TxType)
+		}
+		signer = t.From.Address
+		signBytes = t.SignBytes(utx.ChainID)
+	default:
+		utils.Error("Unsupported transaction type: %T\n", tx)
+	}
+	if expected, err := hex.DecodeString(utx.SignBytes); err != nil || !bytes.Equal(expected, signBytes) {
+		utils.Error("The sign bytes do not match the transaction\n")
+	}
+	if signer != utx.Signer {
+		utils.Error("The signer %v does not match the transaction, expected %v\n", utx.Signer.Hex(), signer.Hex())
+	}
+
+	wallet, address, err := walletUnlockWithPath(cmd, signer.Hex(), pathFlag, passwordFlag)
+	if err != nil || wallet == nil {
+		return
+	}
+	defer wallet.Lock(address)
+	if getWalletType(cmd) != wtypes.WalletTypeSoft && address != signer {
+		utils.Error("The wallet address %v does not match the signer %v\n", address.Hex(), signer.Hex())
+	}
+
+	sig, err := wallet.Sign(signer, signBytes)
+	if err != nil {
+		utils.Error("Failed to sign transaction: %v\n", err)
+	}
+	switch t := tx.(type) {
+	case *types.SendTx:
+		t.SetSignature(signer, sig)
+	case *types.SmartContractTx:
+		t.SetSignature(signer, sig)
+	}
+
+	signed, err := stypes.TxToBytes(tx)
+	if err != nil {
+		utils.Error("Failed to encode transaction: %v\n", err)
+	}
+	writeOutput(hex.EncodeToString(signed))
+}
+
+func doBroadcastCmd(cmd *cobra.Command, args []string) {
+	signedTx := txFlag
+	if fileFlag != "" {
+		content, err := ioutil.ReadFile(fileFlag)
+		if err != nil {
+			utils.Error("Failed to read %v: %v\n", fileFlag, err)
+		}
+		signedTx = string(content)
+	}
+	signedTx = strings.TrimPrefix(strings.TrimSpace(signedTx), "0x")
+	if signedTx == "" {
+		utils.Error("Either --tx or --file needs to be specified\n")
+	}
+	if _, err := hex.DecodeString(signedTx); err != nil {
+		utils.Error("Failed to decode the signed transaction: %v\n", err)
+	}
+
+	broadcastRawTx(signedTx)
+}
+
+// writeOutput writes the content to the file given by the --output flag, or to stdout if the flag is not set.
+func writeOutput(content string) {
+	if outputFlag == "" {
+		fmt.Println(content)
+		return
+	}
+	if err := ioutil.WriteFile(outputFlag, []byte(content+"\n"), 0600); err != nil {
+		utils.Error("Failed to write %v: %v\n", outputFlag, err)
+	}
+	fmt.Printf("Written to %v\n", outputFlag)
+}
+
+func init() {
+	buildSendCmd.Flags().StringVar(&chainIDFlag, "chain", "", "Chain ID")
+	buildSendCmd.Flags().StringVar(&fromFlag, "from", "", "Address to send from")
+	buildSendCmd.Flags().StringVar(&toFlag, "to", "", "Address to send to")
+	buildSendCmd.Flags().Uint64Var(&seqFlag, "seq", 0, "Sequence number of the transaction, queried from the node if not specified")
+	buildSendCmd.Flags().StringVar(&tfuelAmountFlag, "tfuel", "0", "TFuel amount")
+	buildSendCmd.Flags().StringVar(&feeFlag, "fee", "", "Fee, suggested by the node if not specified")
+	buildSendCmd.Flags().StringVar(&outputFlag, "output", "", "File to write the unsigned transaction to, stdout if not specified")
+	buildSendCmd.MarkFlagRequired("chain")
+	buildSendCmd.MarkFlagRequired("from")
+	buildSendCmd.MarkFlagRequired("to")
+
+	buildSmartContractCmd.Flags().StringVar(&chainIDFlag, "chain", "", "Chain ID")
+	buildSmartContractCmd.Flags().StringVar(&fromFlag, "from", "", "The caller address")
+	buildSmartContractCmd.Flags().StringVar(&toFlag, "to", "", "The smart contract address")
+	buildSmartContractCmd.Flags().StringVar(&valueFlag, "value", "0", "Value to be transferred")
+	buildSmartContractCmd.Flags().StringVar(&gasPriceFlag, "gas_price", "", "The gas price, suggested by the node if not specified")
+	buildSmartContractCmd.Flags().Uint64Var(&gasLimitFlag, "gas_limit", 0, "The gas limit")
+	buildSmartContractCmd.Flags().StringVar(&dataFlag, "data", "", "The data for the smart contract")
+	addABIFlags(buildSmartContractCmd)
+	buildSmartContractCmd.Flags().Uint64Var(&seqFlag, "seq", 0, "Sequence number of the transaction, queried from the node if not specified")
+	buildSmartContractCmd.Flags().StringVar(&outputFlag, "output", "", "File to write the unsigned transaction to, stdout if not specified")
+	buildSmartContractCmd.MarkFlagRequired("chain")
+	buildSmartContractCmd.MarkFlagRequired("from")
+	buildSmartContractCmd.MarkFlagRequired("gas_limit")
+
+	buildCmd.AddCommand(buildSendCmd)
+	buildCmd.AddCommand(buildSmartContractCmd)
+
+	signCmd.Flags().StringVar(&fileFlag, "file", "", "File containing the unsigned transaction")
+	signCmd.Flags().StringVar(&walletFlag, "wallet", "soft", "Wallet type (soft|nano|trezor)")
+	signCmd.Flags().StringVar(&pathFlag, "path", "", "Wallet derivation path")
+	signCmd.Flags().StringVar(&passwordFlag, "password", "", "password to unlock the wallet")
+	signCmd.Flags().StringVar(&outputFlag, "output", "", "File to write the signed transaction to, stdout if not specified")
+	signCmd.MarkFlagRequired("file")
+
+	broadcastCmd.Flags().StringVar(&txFlag, "tx", "", "The hex encoded signed transaction")
+	broadcastCmd.Flags().StringVar(&fileFlag, "file", "", "File containing the hex encoded signed transaction")
+	broadcastCmd.Flags().BoolVar(&asyncFlag, "async", false, "block until tx has been included in the blockchain")
+}
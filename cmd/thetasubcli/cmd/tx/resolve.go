@@ -0,0 +1,209 @@
+package tx
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	rpcc "github.com/ybbus/jsonrpc"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/ledger/types"
+	sbc "github.com/thetatoken/thetasubchain/blockchain"
+	"github.com/thetatoken/thetasubchain/cmd/thetasubcli/cmd/utils"
+	"github.com/thetatoken/thetasubchain/eth/abi"
+	"github.com/thetatoken/thetasubchain/rpc"
+)
+
+// resolveSequence returns the sequence given by the --seq flag, or if the flag is not set,
+// the next sequence of the address according to the node, which counts the pending txs.
+func resolveSequence(cmd *cobra.Command, address common.Address) uint64 {
+	if cmd.Flags().Changed("seq") {
+		return seqFlag
+	}
+
+	client := rpcc.NewRPCClient(viper.GetString(utils.CfgRemoteRPCEndpoint))
+	res, err := client.Call("theta.GetNextSequence", rpc.GetNextSequenceArgs{Address: address.Hex()})
+	if err != nil {
+		utils.Error("Failed to get the sequence of %v: %v\n", address.Hex(), err)
+	}
+	if res.Error != nil {
+		utils.Error("Failed to get the sequence of %v: %v\n", address.Hex(), res.Error)
+	}
+	result := &rpc.GetNextSequenceResult{}
+	if err = res.GetObject(result); err != nil {
+		utils.Error("Failed to parse server response: %v\n", err)
+	}
+	return uint64(result.Sequence)
+}
+
+// getSuggestedFee queries the node for the suggested gas price and send tx fee.
+func getSuggestedFee(args rpc.GetSuggestedFeeArgs) *rpc.GetSuggestedFeeResult {
+	client := rpcc.NewRPCClient(viper.GetString(utils.CfgRemoteRPCEndpoint))
+	res, err := client.Call("theta.GetSuggestedFee", args)
+	if err != nil {
+		utils.Error("Failed to get the suggested fee: %v\n", err)
+	}
+	if res.Error != nil {
+		utils.Error("Failed to get the suggested fee: %v\n", res.Error)
+	}
+	result := &rpc.GetSuggestedFeeResult{}
+	if err = res.GetObject(result); err != nil {
+		utils.Error("Failed to parse server response: %v\n", err)
+	}
+	return result
+}
+
+// resolveSendTxFee returns the fee given by the --fee flag, or the fee suggested by the node.
+func resolveSendTxFee(numAccounts uint64) *big.Int {
+	if feeFlag != "" {
+		fee, ok := types.ParseCoinAmount(feeFlag)
+		if !ok {
+			utils.Error("Failed to parse fee")
+		}
+		return fee
+	}
+	return getSuggestedFee(rpc.GetSuggestedFeeArgs{NumAccounts: numAccounts}).SendTxFee
+}
+
+// resolveBatchSendTxFee returns the fee given by the --fee flag, or the batch send tx fee suggested by the node.
+func resolveBatchSendTxFee(numOutputs uint64) *big.Int {
+	if feeFlag != "" {
+		fee, ok := types.ParseCoinAmount(feeFlag)
+		if !ok {
+			utils.Error("Failed to parse fee")
+		}
+		return fee
+	}
+	return getSuggestedFee(rpc.GetSuggestedFeeArgs{NumBatchOutputs: numOutputs}).BatchSendTxFee
+}
+
+// resolveGasPrice returns the gas price given by the --gas_price flag, or the gas price suggested by the node.
+func resolveGasPrice() *big.Int {
+	if gasPriceFlag != "" {
+		gasPrice, ok := types.ParseCoinAmount(gasPriceFlag)
+		if !ok {
+			utils.Error("Failed to parse gas price")
+		}
+		return gasPrice
+	}
+	return getSuggestedFee(rpc.GetSuggestedFeeArgs{}).GasPrice
+}
+
+// printDryRun prints the signed transaction instead of broadcasting it.
+func printDryRun(signedTx string, sequence uint64) {
+	fmt.Printf("Dry run, the transaction is NOT broadcasted\n")
+	fmt.Printf("Sequence : %v\n", sequence)
+	fmt.Printf("Signed tx: %v\n", signedTx)
+}
+
+// simulateSmartContractTx executes the smart contract transaction against the latest state
+// of the node without broadcasting it, and prints the result. The return value is decoded
+// if the ABI and the method are given.
+func simulateSmartContractTx(raw common.Bytes, contractABI *abi.ABI) {
+	client := rpcc.NewRPCClient(viper.GetString(utils.CfgRemoteRPCEndpoint))
+	res, err := client.Call("theta.CallSmartContract", rpc.CallSmartContractArgs{SctxBytes: hex.EncodeToString(raw)})
+	if err != nil {
+		utils.Error("Failed to simulate the smart contract transaction: %v\n", err)
+	}
+	if res.Error != nil {
+		utils.Error("Failed to simulate the smart contract transaction: %v\n", res.Error)
+	}
+	formatted, err := json.MarshalIndent(res.Result, "", "    ")
+	if err != nil {
+		utils.Error("Failed to parse server response: %v\n", err)
+	}
+	fmt.Printf("Simulation result:\n%s\n", formatted)
+
+	if contractABI == nil || methodFlag == "" {
+		return
+	}
+	result := &rpc.CallSmartContractResult{}
+	if err = res.GetObject(result); err != nil {
+		utils.Error("Failed to parse server response: %v\n", err)
+	}
+	printDecodedOutput(contractABI, methodFlag, result.VmReturn)
+}
+
+// printDecodedOutput prints the hex encoded return value of the method decoded with the ABI.
+func printDecodedOutput(contractABI *abi.ABI, method string, vmReturn string) {
+	ret, err := hex.DecodeString(vmReturn)
+	if err != nil {
+		utils.Error("Failed to decode the return value: %v\n", err)
+	}
+	output, err := utils.DecodeMethodOutput(contractABI, method, ret)
+	if err != nil {
+		utils.Error("Failed to decode the return value: %v\n", err)
+	}
+	formatted, err := json.MarshalIndent(output, "", "    ")
+	if err != nil {
+		utils.Error("Failed to format the return value: %v\n", err)
+	}
+	fmt.Printf("Decoded return value:\n%s\n", formatted)
+}
+
+// printTxEvents fetches the receipt of the transaction and prints its logs decoded with the ABI.
+func printTxEvents(txHash string, contractABI *abi.ABI) {
+	client := rpcc.NewRPCClient(viper.GetString(utils.CfgRemoteRPCEndpoint))
+	res, err := client.Call("theta.GetTransaction", rpc.GetTransactionArgs{Hash: txHash})
+	if err != nil {
+		utils.Error("Failed to get the transaction: %v\n", err)
+	}
+	if res.Error != nil {
+		utils.Error("Failed to get the transaction: %v\n", res.Error)
+	}
+	result := &struct {
+		Receipt *sbc.TxReceiptEntry `json:"receipt"`
+	}{}
+	if err = res.GetObject(result); err != nil {
+		utils.Error("Failed to parse server response: %v\n", err)
+	}
+	if result.Receipt == nil {
+		fmt.Printf("The receipt of the transaction is not available yet\n")
+		return
+	}
+	if result.Receipt.EvmErr != "" {
+		fmt.Printf("Execution error: %v\n", result.Receipt.EvmErr)
+	}
+	if result.Receipt.ContractAddress != (common.Address{}) {
+		fmt.Printf("Contract address: %v\n", result.Receipt.ContractAddress.Hex())
+	}
+	formatted, err := json.MarshalIndent(utils.DecodeLogs(contractABI, result.Receipt.Logs), "", "    ")
+	if err != nil {
+		utils.Error("Failed to format the events: %v\n", err)
+	}
+	fmt.Printf("Events:\n%s\n", formatted)
+}
+
+// broadcastRawTx submits the hex encoded signed transaction to the node, and prints the result.
+func broadcastRawTx(signedTx string) *rpc.BroadcastRawTransactionResult {
+	client := rpcc.NewRPCClient(viper.GetString(utils.CfgRemoteRPCEndpoint))
+
+	var res *rpcc.RPCResponse
+	var err error
+	if asyncFlag {
+		res, err = client.Call("theta.BroadcastRawTransactionAsync", rpc.BroadcastRawTransactionArgs{TxBytes: signedTx})
+	} else {
+		res, err = client.Call("theta.BroadcastRawTransaction", rpc.BroadcastRawTransactionArgs{TxBytes: signedTx})
+	}
+	if err != nil {
+		utils.Error("Failed to broadcast transaction: %v\n", err)
+	}
+	if res.Error != nil {
+		utils.Error("Server returned error: %v\n", res.Error)
+	}
+	result := &rpc.BroadcastRawTransactionResult{}
+	err = res.GetObject(result)
+	if err != nil {
+		utils.Error("Failed to parse server response: %v\n", err)
+	}
+	formatted, err := json.MarshalIndent(result, "", "    ")
+	if err != nil {
+		utils.Error("Failed to parse server response: %v\n", err)
+	}
+	fmt.Printf("Successfully broadcasted transaction:\n%s\n", formatted)
+	return result
+}
@@ -2,26 +2,20 @@ package tx
 
 import (
 	"encoding/hex"
-	"encoding/json"
-	"fmt"
 	"math/big"
 
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 	"github.com/thetatoken/theta/common"
 	"github.com/thetatoken/theta/ledger/types"
 	wtypes "github.com/thetatoken/theta/wallet/types"
 	"github.com/thetatoken/thetasubchain/cmd/thetasubcli/cmd/utils"
 	stypes "github.com/thetatoken/thetasubchain/ledger/types"
-	"github.com/thetatoken/thetasubchain/rpc"
-
-	"github.com/ybbus/jsonrpc"
-	rpcc "github.com/ybbus/jsonrpc"
 )
 
 // sendCmd represents the send command
 // Example:
 //		thetasubcli tx send --chain="privatenet" --from=2E833968E5bB786Ae419c4d13189fB081Cc43bab --to=9F1233798E905E173560071255140b4A8aBd3Ec6 --tfuel=9 --seq=1
+//		thetasubcli tx send --chain="privatenet" --from=2E833968E5bB786Ae419c4d13189fB081Cc43bab --to=9F1233798E905E173560071255140b4A8aBd3Ec6 --tfuel=9 --dry-run
 //		thetasubcli tx send --chain="privatenet" --path "m/44'/60'/0'/0/0" --to=9F1233798E905E173560071255140b4A8aBd3Ec6 --tfuel=9 --seq=1 --wallet=trezor
 //		thetasubcli tx send --chain="privatenet" --path "m/44'/60'/0'/0" --to=9F1233798E905E173560071255140b4A8aBd3Ec6 --tfuel=9 --seq=1 --wallet=nano
 var sendCmd = &cobra.Command{
@@ -53,21 +47,43 @@ func doSendCmd(cmd *cobra.Command, args []string) {
 	}
 	defer wallet.Lock(fromAddress)
 
+	sequence := resolveSequence(cmd, fromAddress)
+	sendTx := newSendTx(fromAddress, sequence)
+
+	sig, err := wallet.Sign(fromAddress, sendTx.SignBytes(chainIDFlag))
+	if err != nil {
+		utils.Error("Failed to sign transaction: %v\n", err)
+	}
+	sendTx.SetSignature(fromAddress, sig)
+
+	raw, err := stypes.TxToBytes(sendTx)
+	if err != nil {
+		utils.Error("Failed to encode transaction: %v\n", err)
+	}
+	signedTx := hex.EncodeToString(raw)
+
+	if dryRunFlag {
+		printDryRun(signedTx, sequence)
+		return
+	}
+
+	broadcastRawTx(signedTx)
+}
+
+// newSendTx creates an unsigned SendTx from the command flags.
+func newSendTx(fromAddress common.Address, sequence uint64) *types.SendTx {
 	tfuel, ok := types.ParseCoinAmount(tfuelAmountFlag)
 	if !ok {
 		utils.Error("Failed to parse tfuel amount")
 	}
-	fee, ok := types.ParseCoinAmount(feeFlag)
-	if !ok {
-		utils.Error("Failed to parse fee")
-	}
+	fee := resolveSendTxFee(2) // one input and one output
 	inputs := []types.TxInput{{
 		Address: fromAddress,
 		Coins: types.Coins{
 			TFuelWei: new(big.Int).Add(tfuel, fee),
 			ThetaWei: new(big.Int).SetUint64(0),
 		},
-		Sequence: uint64(seqFlag),
+		Sequence: sequence,
 	}}
 	outputs := []types.TxOutput{{
 		Address: common.HexToAddress(toFlag),
@@ -76,7 +92,7 @@ func doSendCmd(cmd *cobra.Command, args []string) {
 			ThetaWei: new(big.Int).SetUint64(0),
 		},
 	}}
-	sendTx := &types.SendTx{
+	return &types.SendTx{
 		Fee: types.Coins{
 			ThetaWei: new(big.Int).SetUint64(0),
 			TFuelWei: fee,
@@ -84,44 +100,6 @@ func doSendCmd(cmd *cobra.Command, args []string) {
 		Inputs:  inputs,
 		Outputs: outputs,
 	}
-
-	sig, err := wallet.Sign(fromAddress, sendTx.SignBytes(chainIDFlag))
-	if err != nil {
-		utils.Error("Failed to sign transaction: %v\n", err)
-	}
-	sendTx.SetSignature(fromAddress, sig)
-
-	raw, err := stypes.TxToBytes(sendTx)
-	if err != nil {
-		utils.Error("Failed to encode transaction: %v\n", err)
-	}
-	signedTx := hex.EncodeToString(raw)
-
-	client := rpcc.NewRPCClient(viper.GetString(utils.CfgRemoteRPCEndpoint))
-
-	var res *jsonrpc.RPCResponse
-	if asyncFlag {
-		res, err = client.Call("theta.BroadcastRawTransactionAsync", rpc.BroadcastRawTransactionArgs{TxBytes: signedTx})
-	} else {
-		res, err = client.Call("theta.BroadcastRawTransaction", rpc.BroadcastRawTransactionArgs{TxBytes: signedTx})
-	}
-
-	if err != nil {
-		utils.Error("Failed to broadcast transaction: %v\n", err)
-	}
-	if res.Error != nil {
-		utils.Error("Server returned error: %v\n", res.Error)
-	}
-	result := &rpc.BroadcastRawTransactionResult{}
-	err = res.GetObject(result)
-	if err != nil {
-		utils.Error("Failed to parse server response: %v\n", err)
-	}
-	formatted, err := json.MarshalIndent(result, "", "    ")
-	if err != nil {
-		utils.Error("Failed to parse server response: %v\n", err)
-	}
-	fmt.Printf("Successfully broadcasted transaction:\n%s\n", formatted)
 }
 
 func init() {
@@ -129,15 +107,15 @@ func init() {
 	sendCmd.Flags().StringVar(&fromFlag, "from", "", "Address to send from")
 	sendCmd.Flags().StringVar(&toFlag, "to", "", "Address to send to")
 	sendCmd.Flags().StringVar(&pathFlag, "path", "", "Wallet derivation path")
-	sendCmd.Flags().Uint64Var(&seqFlag, "seq", 0, "Sequence number of the transaction")
+	sendCmd.Flags().Uint64Var(&seqFlag, "seq", 0, "Sequence number of the transaction, queried from the node if not specified")
 	sendCmd.Flags().StringVar(&tfuelAmountFlag, "tfuel", "0", "TFuel amount")
-	sendCmd.Flags().StringVar(&feeFlag, "fee", fmt.Sprintf("%dwei", types.MinimumTransactionFeeTFuelWeiJune2021), "Fee")
+	sendCmd.Flags().StringVar(&feeFlag, "fee", "", "Fee, suggested by the node if not specified")
 	sendCmd.Flags().StringVar(&walletFlag, "wallet", "soft", "Wallet type (soft|nano|trezor)")
 	sendCmd.Flags().BoolVar(&asyncFlag, "async", false, "block until tx has been included in the blockchain")
 	sendCmd.Flags().StringVar(&passwordFlag, "password", "", "password to unlock the wallet")
+	sendCmd.Flags().BoolVar(&dryRunFlag, "dry-run", false, "Print the signed transaction without broadcasting it")
 
 	sendCmd.MarkFlagRequired("chain")
 	//sendCmd.MarkFlagRequired("from")
 	sendCmd.MarkFlagRequired("to")
-	sendCmd.MarkFlagRequired("seq")
 }
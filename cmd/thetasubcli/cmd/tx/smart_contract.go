@@ -2,21 +2,15 @@ package tx
 
 import (
 	"encoding/hex"
-	"encoding/json"
-	"fmt"
 	"math/big"
 
 	"github.com/thetatoken/theta/common"
 	"github.com/thetatoken/theta/ledger/types"
-	"github.com/thetatoken/thetasubchain/rpc"
 
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 	"github.com/thetatoken/thetasubchain/cmd/thetasubcli/cmd/utils"
-	scom "github.com/thetatoken/thetasubchain/common"
+	"github.com/thetatoken/thetasubchain/eth/abi"
 	stypes "github.com/thetatoken/thetasubchain/ledger/types"
-
-	rpcc "github.com/ybbus/jsonrpc"
 )
 
 // smartContractCmd represents the smart_contract command. It will submit a smart contract transaction
@@ -26,6 +20,9 @@ import (
 //      thetasubcli tx smart_contract --chain="tsub360777" --from=2E833968E5bB786Ae419c4d13189fB081Cc43bab --value=0 --gas_price=100000000wei --gas_limit=200000 --data=608060405234801561001057600080fd5b50610148806100206000396000f300608060405260043610610057576000357c0100000000000000000000000000000000000000000000000000000000900463ffffffff1680633fa4f2451461005c578063b5a0241a14610087578063ed8b0706146100b2575b600080fd5b34801561006857600080fd5b506100716100df565b6040518082815260200191505060405180910390f35b34801561009357600080fd5b5061009c6100e5565b6040518082815260200191505060405180910390f35b3480156100be57600080fd5b506100dd60048036038101908080359060200190929190505050610112565b005b60005481565b6000806000546000540290506000546000548281151561010157fe5b0414151561010b57fe5b8091505090565b80600081905550505600a165627a7a72305820459c07c1668e919ca760d663b8df04e80634c53ebd49393dca83e81c58ae2a660029 --seq=1
 //   * Call an API of a smart contract
 //		thetasubcli tx smart_contract --chain="tsub360777" --from=2E833968E5bB786Ae419c4d13189fB081Cc43bab --to=0x8Be503bcdEd90ED42Eff31f56199399B2b0154CA --data=7ff75b46 --gas_price=100000000wei --gas_limit=100000 --seq=9
+//   * Deploy a smart contract with constructor arguments, and call a method by its ABI
+//		thetasubcli tx smart_contract --chain="tsub360777" --from=2E833968E5bB786Ae419c4d13189fB081Cc43bab --abi=Token.abi --bytecode=Token.bin --arg="Test Token" --arg=1000000 --gas_limit=2000000
+//		thetasubcli tx smart_contract --chain="tsub360777" --from=2E833968E5bB786Ae419c4d13189fB081Cc43bab --to=0x8Be503bcdEd90ED42Eff31f56199399B2b0154CA --abi=Token.abi --method=transfer --arg=0x9F1233798E905E173560071255140b4A8aBd3Ec6 --arg=100 --gas_limit=100000
 var smartContractCmd = &cobra.Command{
 	Use:   "smart_contract",
 	Short: "Call or deploy a smart contract",
@@ -34,7 +31,10 @@ var smartContractCmd = &cobra.Command{
 	thetasubcli tx smart_contract --chain="privatenet" --from=2E833968E5bB786Ae419c4d13189fB081Cc43bab --value=1680 --gas_price=3 --gas_limit=50000 --data=600a600c600039600a6000f3600360135360016013f3 --seq=1	
 	
 	[Call an API of a smart contract]
-	thetasubcli tx smart_contract --chain="privatenet" --from=2E833968E5bB786Ae419c4d13189fB081Cc43bab --to=0x7ad6cea2bc3162e30a3c98d84f821b3233c22647 --gas_price=3 --gas_limit=50000 --seq=2`,
+	thetasubcli tx smart_contract --chain="privatenet" --from=2E833968E5bB786Ae419c4d13189fB081Cc43bab --to=0x7ad6cea2bc3162e30a3c98d84f821b3233c22647 --gas_price=3 --gas_limit=50000 --seq=2
+
+	[Call an API of a smart contract by its ABI]
+	thetasubcli tx smart_contract --chain="privatenet" --from=2E833968E5bB786Ae419c4d13189fB081Cc43bab --to=0x7ad6cea2bc3162e30a3c98d84f821b3233c22647 --abi=Token.abi --method=transfer --arg=0x9F1233798E905E173560071255140b4A8aBd3Ec6 --arg=100 --gas_limit=100000`,
 	Long: "smartContractCmd represents the smart_contract command. It will submit a smart contract transaction to the blockchain, which will modify the global consensus state when it is included in the blockchain",
 	Run:  doSmartContractCmd,
 }
@@ -46,78 +46,75 @@ func doSmartContractCmd(cmd *cobra.Command, args []string) {
 	}
 	defer wallet.Lock(fromAddress)
 
+	sequence := resolveSequence(cmd, fromAddress)
+	smartContractTx := newSmartContractTx(fromAddress, sequence)
+
+	sig, err := wallet.Sign(fromAddress, smartContractTx.SignBytes(chainIDFlag))
+	if err != nil {
+		utils.Error("Failed to sign transaction: %v\n", err)
+	}
+	smartContractTx.SetSignature(fromAddress, sig)
+
+	raw, err := stypes.TxToBytes(smartContractTx)
+	if err != nil {
+		utils.Error("Failed to encode transaction: %v\n", err)
+	}
+	signedTx := hex.EncodeToString(raw)
+
+	var contractABI *abi.ABI
+	if abiFlag != "" {
+		contractABI, err = utils.LoadABI(abiFlag)
+		if err != nil {
+			utils.Error("%v\n", err)
+		}
+	}
+
+	if dryRunFlag {
+		printDryRun(signedTx, sequence)
+		simulateSmartContractTx(raw, contractABI)
+		return
+	}
+
+	result := broadcastRawTx(signedTx)
+	if contractABI != nil && !asyncFlag {
+		printTxEvents(result.TxHash, contractABI)
+	}
+}
+
+// newSmartContractTx creates an unsigned SmartContractTx from the command flags.
+func newSmartContractTx(fromAddress common.Address, sequence uint64) *types.SmartContractTx {
 	value, ok := types.ParseCoinAmount(valueFlag)
 	if !ok {
 		utils.Error("Failed to parse value")
 	}
 
 	from := types.TxInput{
-		Address: common.HexToAddress(fromFlag),
+		Address: fromAddress,
 		Coins: types.Coins{
 			ThetaWei: new(big.Int).SetUint64(0),
 			TFuelWei: value,
 		},
-		Sequence: seqFlag,
+		Sequence: sequence,
 	}
 
 	to := types.TxOutput{
 		Address: common.HexToAddress(toFlag),
 	}
 
-	gasPrice, ok := types.ParseCoinAmount(gasPriceFlag)
-	if !ok {
-		utils.Error("Failed to parse gas price")
-	}
+	gasPrice := resolveGasPrice()
 
-	data, err := hex.DecodeString(dataFlag)
+	data, _, err := utils.ResolveCallData(dataFlag, abiFlag, methodFlag, bytecodeFlag, argsFlag)
 	if err != nil {
-		return
+		utils.Error("Failed to resolve the data: %v\n", err)
 	}
 
-	smartContractTx := &types.SmartContractTx{
+	return &types.SmartContractTx{
 		From:     from,
 		To:       to,
 		GasLimit: gasLimitFlag,
 		GasPrice: gasPrice,
 		Data:     data,
 	}
-
-	sig, err := wallet.Sign(fromAddress, smartContractTx.SignBytes(chainIDFlag))
-	if err != nil {
-		utils.Error("Failed to sign transaction: %v\n", err)
-	}
-	smartContractTx.SetSignature(fromAddress, sig)
-
-	raw, err := stypes.TxToBytes(smartContractTx)
-	if err != nil {
-		utils.Error("Failed to encode transaction: %v\n", err)
-	}
-	signedTx := hex.EncodeToString(raw)
-
-	client := rpcc.NewRPCClient(viper.GetString(utils.CfgRemoteRPCEndpoint))
-
-	var res *rpcc.RPCResponse
-	if asyncFlag {
-		res, err = client.Call("theta.BroadcastRawTransactionAsync", rpc.BroadcastRawTransactionArgs{TxBytes: signedTx})
-	} else {
-		res, err = client.Call("theta.BroadcastRawTransaction", rpc.BroadcastRawTransactionArgs{TxBytes: signedTx})
-	}
-	if err != nil {
-		utils.Error("Failed to broadcast transaction: %v\n", err)
-	}
-	if res.Error != nil {
-		utils.Error("Server returned error: %v\n", res.Error)
-	}
-	result := &rpc.BroadcastRawTransactionResult{}
-	err = res.GetObject(result)
-	if err != nil {
-		utils.Error("Failed to parse server response: %v\n", err)
-	}
-	formatted, err := json.MarshalIndent(result, "", "    ")
-	if err != nil {
-		utils.Error("Failed to parse server response: %v\n", err)
-	}
-	fmt.Printf("Successfully broadcasted transaction:\n%s\n", formatted)
 }
 
 func init() {
@@ -125,17 +122,17 @@ func init() {
 	smartContractCmd.Flags().StringVar(&fromFlag, "from", "", "The caller address")
 	smartContractCmd.Flags().StringVar(&toFlag, "to", "", "The smart contract address")
 	smartContractCmd.Flags().StringVar(&valueFlag, "value", "0", "Value to be transferred")
-	smartContractCmd.Flags().StringVar(&gasPriceFlag, "gas_price", fmt.Sprintf("%dwei", scom.MinimumGasPrice), "The gas price")
+	smartContractCmd.Flags().StringVar(&gasPriceFlag, "gas_price", "", "The gas price, suggested by the node if not specified")
 	smartContractCmd.Flags().Uint64Var(&gasLimitFlag, "gas_limit", 0, "The gas limit")
 	smartContractCmd.Flags().StringVar(&dataFlag, "data", "", "The data for the smart contract")
-	smartContractCmd.Flags().Uint64Var(&seqFlag, "seq", 0, "Sequence number of the transaction")
+	addABIFlags(smartContractCmd)
+	smartContractCmd.Flags().Uint64Var(&seqFlag, "seq", 0, "Sequence number of the transaction, queried from the node if not specified")
 	smartContractCmd.Flags().StringVar(&walletFlag, "wallet", "soft", "Wallet type (soft|nano)")
 	smartContractCmd.Flags().BoolVar(&asyncFlag, "async", false, "block until tx has been included in the blockchain")
 	smartContractCmd.Flags().StringVar(&passwordFlag, "password", "", "password to unlock the wallet")
+	smartContractCmd.Flags().BoolVar(&dryRunFlag, "dry-run", false, "Print the signed transaction and the simulation result without broadcasting it")
 
 	smartContractCmd.MarkFlagRequired("chain")
 	smartContractCmd.MarkFlagRequired("from")
-	smartContractCmd.MarkFlagRequired("gas_price")
 	smartContractCmd.MarkFlagRequired("gas_limit")
-	smartContractCmd.MarkFlagRequired("seq")
 }
@@ -0,0 +1,352 @@
+package utils
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/ledger/types"
+	"github.com/thetatoken/thetasubchain/eth/abi"
+)
+
+// DecodedEvent is the human readable form of a log emitted by a smart contract.
+type DecodedEvent struct {
+	Address   common.Address         `json:"address"`
+	Event     string                 `json:"event,omitempty"`
+	Signature string                 `json:"signature,omitempty"`
+	Args      map[string]interface{} `json:"args,omitempty"`
+	Topics    []common.Hash          `json:"topics,omitempty"` // only set if the log does not match any event of the ABI
+	Data      string                 `json:"data,omitempty"`   // only set if the log does not match any event of the ABI
+}
+
+// LoadABI reads a contract ABI from a JSON file.
+func LoadABI(path string) (*abi.ABI, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	contractABI, err := abi.JSON(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ABI %v: %v", path, err)
+	}
+	return &contractABI, nil
+}
+
+// ResolveCallData returns the data of a smart contract transaction. It packs the bytecode and the
+// constructor arguments if bytecode is given, the method call if method is given, and otherwise
+// decodes the raw hex data. The ABI is loaded from abiPath if specified.
+func ResolveCallData(data, abiPath, method, bytecode string, args []string) ([]byte, *abi.ABI, error) {
+	var contractABI *abi.ABI
+	if abiPath != "" {
+		var err error
+		contractABI, err = LoadABI(abiPath)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if bytecode != "" {
+		calldata, err := EncodeConstructor(contractABI, bytecode, args)
+		return calldata, contractABI, err
+	}
+	if method != "" {
+		if contractABI == nil {
+			return nil, nil, fmt.Errorf("the ABI is required to call method %v", method)
+		}
+		calldata, err := EncodeMethodCall(contractABI, method, args)
+		return calldata, contractABI, err
+	}
+	if len(args) > 0 {
+		return nil, nil, fmt.Errorf("arguments are given without a method or bytecode")
+	}
+	calldata, err := decodeHex(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode data %v: %v", data, err)
+	}
+	return calldata, contractABI, nil
+}
+
+// EncodeConstructor appends the packed constructor arguments to the contract bytecode. The bytecode
+// can be given either as a hex string, or as the path of a file containing the hex string.
+func EncodeConstructor(contractABI *abi.ABI, bytecode string, args []string) ([]byte, error) {
+	if content, err := ioutil.ReadFile(bytecode); err == nil {
+		bytecode = strings.TrimSpace(string(content))
+	}
+	code, err := decodeHex(bytecode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode bytecode: %v", err)
+	}
+	if contractABI == nil {
+		if len(args) > 0 {
+			return nil, fmt.Errorf("the ABI is required to pack the constructor arguments")
+		}
+		return code, nil
+	}
+
+	values, err := parseABIArgs(contractABI.Constructor.Inputs, args)
+	if err != nil {
+		return nil, fmt.Errorf("invalid constructor arguments: %v", err)
+	}
+	packed, err := contractABI.Pack("", values...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack the constructor arguments: %v", err)
+	}
+	return append(code, packed...), nil
+}
+
+// EncodeMethodCall packs the method selector and the arguments of a contract method call.
+func EncodeMethodCall(contractABI *abi.ABI, method string, args []string) ([]byte, error) {
+	m, ok := contractABI.Methods[method]
+	if !ok {
+		return nil, fmt.Errorf("method %v not found in the ABI", method)
+	}
+	values, err := parseABIArgs(m.Inputs, args)
+	if err != nil {
+		return nil, fmt.Errorf("invalid arguments for %v: %v", m.Sig, err)
+	}
+	calldata, err := contractABI.Pack(method, values...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack the arguments for %v: %v", m.Sig, err)
+	}
+	return calldata, nil
+}
+
+// DecodeMethodOutput unpacks the return value of a contract method call. Unnamed outputs are
+// keyed by their position.
+func DecodeMethodOutput(contractABI *abi.ABI, method string, ret []byte) (map[string]interface{}, error) {
+	m, ok := contractABI.Methods[method]
+	if !ok {
+		return nil, fmt.Errorf("method %v not found in the ABI", method)
+	}
+	values, err := m.Outputs.Unpack(ret)
+	if err != nil {
+		return nil, err
+	}
+	return namedValues(m.Outputs, values), nil
+}
+
+// DecodeLogs decodes the logs emitted by a smart contract transaction with the event definitions of the ABI.
+func DecodeLogs(contractABI *abi.ABI, logs []*types.Log) []*DecodedEvent {
+	decoded := []*DecodedEvent{}
+	for _, log := range logs {
+		decoded = append(decoded, decodeLog(contractABI, log))
+	}
+	return decoded
+}
+
+func decodeLog(contractABI *abi.ABI, log *types.Log) *DecodedEvent {
+	unknown := &DecodedEvent{
+		Address: log.Address,
+		Topics:  log.Topics,
+		Data:    "0x" + hex.EncodeToString(log.Data),
+	}
+	if len(log.Topics) == 0 {
+		return unknown // anonymous event
+	}
+	event, err := contractABI.EventByID(log.Topics[0])
+	if err != nil {
+		return unknown
+	}
+
+	nonIndexed := event.Inputs.NonIndexed()
+	values, err := nonIndexed.Unpack(log.Data)
+	if err != nil {
+		return unknown
+	}
+	args := namedValues(nonIndexed, values)
+
+	var indexed abi.Arguments
+	for _, input := range event.Inputs {
+		if input.Indexed {
+			indexed = append(indexed, input)
+		}
+	}
+	topicValues := make(map[string]interface{})
+	if err := abi.ParseTopicsIntoMap(topicValues, indexed, log.Topics[1:]); err != nil {
+		return unknown
+	}
+	for name, value := range topicValues {
+		args[name] = formatABIValue(value)
+	}
+
+	return &DecodedEvent{
+		Address:   log.Address,
+		Event:     event.Name,
+		Signature: event.Sig,
+		Args:      args,
+	}
+}
+
+func namedValues(arguments abi.Arguments, values []interface{}) map[string]interface{} {
+	named := make(map[string]interface{})
+	for i, value := range values {
+		name := arguments[i].Name
+		if name == "" {
+			name = strconv.Itoa(i)
+		}
+		named[name] = formatABIValue(value)
+	}
+	return named
+}
+
+// formatABIValue converts an unpacked ABI value into a JSON friendly form, i.e. integers as
+// decimal strings, and bytes and addresses as hex strings.
+func formatABIValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case *big.Int:
+		return v.String()
+	case common.Address:
+		return v.Hex()
+	case []byte:
+		return "0x" + hex.EncodeToString(v)
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, rv.Len())
+			reflect.Copy(reflect.ValueOf(b), rv)
+			return "0x" + hex.EncodeToString(b)
+		}
+		fallthrough
+	case reflect.Slice:
+		items := make([]interface{}, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			items[i] = formatABIValue(rv.Index(i).Interface())
+		}
+		return items
+	case reflect.Struct:
+		fields := make(map[string]interface{})
+		for i := 0; i < rv.NumField(); i++ {
+			fields[rv.Type().Field(i).Name] = formatABIValue(rv.Field(i).Interface())
+		}
+		return fields
+	}
+	return value
+}
+
+func parseABIArgs(arguments abi.Arguments, args []string) ([]interface{}, error) {
+	if len(arguments) != len(args) {
+		return nil, fmt.Errorf("expected %v arguments, got %v", len(arguments), len(args))
+	}
+	values := make([]interface{}, len(args))
+	for i, arg := range arguments {
+		value, err := parseABIArg(arg.Type, args[i])
+		if err != nil {
+			return nil, fmt.Errorf("argument %v (%v): %v", i, arg.Type.String(), err)
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+// parseABIArg converts a command line argument into the Go value expected by the ABI packer.
+// Arrays and slices are given as JSON arrays, e.g. ["0x2E83...", "0x9F12..."] or [1, 2, 3].
+func parseABIArg(typ abi.Type, raw string) (interface{}, error) {
+	switch typ.T {
+	case abi.IntTy, abi.UintTy:
+		n, ok := new(big.Int).SetString(raw, 0)
+		if !ok {
+			return nil, fmt.Errorf("invalid integer %v", raw)
+		}
+		if typ.T == abi.UintTy && n.Sign() < 0 {
+			return nil, fmt.Errorf("negative value %v for an unsigned integer", raw)
+		}
+		switch typ.Size {
+		case 8, 16, 32, 64:
+		default:
+			// The packer takes a *big.Int for the other sizes, so the range is checked here
+			bits := uint(typ.Size)
+			if typ.T == abi.IntTy {
+				bits--
+			}
+			limit := new(big.Int).Lsh(big.NewInt(1), bits)
+			if n.Cmp(limit) >= 0 || (typ.T == abi.IntTy && n.Cmp(new(big.Int).Neg(limit)) < 0) {
+				return nil, fmt.Errorf("%v overflows %v", raw, typ.String())
+			}
+			return n, nil
+		}
+		v := reflect.New(typ.GetType()).Elem()
+		if typ.T == abi.UintTy {
+			if !n.IsUint64() || v.OverflowUint(n.Uint64()) {
+				return nil, fmt.Errorf("%v overflows %v", raw, typ.String())
+			}
+			v.SetUint(n.Uint64())
+		} else {
+			if !n.IsInt64() || v.OverflowInt(n.Int64()) {
+				return nil, fmt.Errorf("%v overflows %v", raw, typ.String())
+			}
+			v.SetInt(n.Int64())
+		}
+		return v.Interface(), nil
+	case abi.BoolTy:
+		return strconv.ParseBool(raw)
+	case abi.StringTy:
+		return raw, nil
+	case abi.AddressTy:
+		if !common.IsHexAddress(raw) {
+			return nil, fmt.Errorf("invalid address %v", raw)
+		}
+		return common.HexToAddress(raw), nil
+	case abi.BytesTy:
+		return decodeHex(raw)
+	case abi.FixedBytesTy:
+		b, err := decodeHex(raw)
+		if err != nil {
+			return nil, err
+		}
+		if len(b) > typ.Size {
+			return nil, fmt.Errorf("%v bytes do not fit in %v", len(b), typ.String())
+		}
+		v := reflect.New(typ.GetType()).Elem()
+		reflect.Copy(v, reflect.ValueOf(b))
+		return v.Interface(), nil
+	case abi.SliceTy, abi.ArrayTy:
+		var items []interface{}
+		decoder := json.NewDecoder(strings.NewReader(raw))
+		decoder.UseNumber()
+		if err := decoder.Decode(&items); err != nil {
+			return nil, fmt.Errorf("expected a JSON array: %v", err)
+		}
+		var v reflect.Value
+		if typ.T == abi.ArrayTy {
+			if len(items) != typ.Size {
+				return nil, fmt.Errorf("expected %v elements, got %v", typ.Size, len(items))
+			}
+			v = reflect.New(typ.GetType()).Elem()
+		} else {
+			v = reflect.MakeSlice(typ.GetType(), len(items), len(items))
+		}
+		for i, item := range items {
+			itemStr, ok := item.(string)
+			if !ok {
+				encoded, err := json.Marshal(item)
+				if err != nil {
+					return nil, err
+				}
+				itemStr = string(encoded)
+			}
+			elem, err := parseABIArg(*typ.Elem, itemStr)
+			if err != nil {
+				return nil, fmt.Errorf("element %v: %v", i, err)
+			}
+			v.Index(i).Set(reflect.ValueOf(elem))
+		}
+		return v.Interface(), nil
+	}
+	return nil, fmt.Errorf("unsupported argument type %v", typ.String())
+}
+
+func decodeHex(s string) ([]byte, error) {
+	return hex.DecodeString(strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X"))
+}
@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/thetatoken/thetasubchain/eth/abi"
+)
+
+func TestParseABIIntArg(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		typ   string
+		raw   string
+		value interface{}
+		valid bool
+	}{
+		{"uint8", "255", uint8(255), true},
+		{"uint8", "256", nil, false},
+		{"int64", "-9223372036854775808", int64(-9223372036854775808), true},
+		{"uint24", "16777215", big.NewInt(16777215), true},
+		{"uint24", "16777216", nil, false},
+		{"uint24", "-1", nil, false},
+		{"int40", "-549755813888", big.NewInt(-549755813888), true},
+		{"int40", "549755813888", nil, false},
+		{"int40", "-549755813889", nil, false},
+		{"uint256", "0x10000000000000000", new(big.Int).Lsh(big.NewInt(1), 64), true},
+		{"int128", "170141183460469231731687303715884105728", nil, false},
+	}
+	for _, test := range tests {
+		typ, err := abi.NewType(test.typ, "", nil)
+		assert.Nil(err)
+
+		value, err := parseABIArg(typ, test.raw)
+		if !test.valid {
+			assert.NotNil(err, "%v %v", test.typ, test.raw)
+			continue
+		}
+		assert.Nil(err, "%v %v", test.typ, test.raw)
+		assert.Equal(test.value, value, "%v %v", test.typ, test.raw)
+
+		// The value must be accepted by the packer
+		_, err = abi.Arguments{{Type: typ}}.Pack(value)
+		assert.Nil(err, "%v %v", test.typ, test.raw)
+	}
+}
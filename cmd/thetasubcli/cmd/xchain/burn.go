@@ -0,0 +1,98 @@
+package xchain
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/spf13/cobra"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/thetasubchain/cmd/thetasubcli/cmd/utils"
+	score "github.com/thetatoken/thetasubchain/core"
+	ethtypes "github.com/thetatoken/thetasubchain/eth/core/types"
+	ct "github.com/thetatoken/thetasubchain/interchain/contracts/accessors"
+)
+
+// burnCmd burns the vouchers held on the source chain, so that the authentic tokens are
+// unlocked to the receiver on the chain the tokens originated from.
+// Example:
+//		thetasubcli xchain burn --source=subchain --token_type=tfuel --from=2E833968E5bB786Ae419c4d13189fB081Cc43bab --amount=1000000000000000000 --wait
+//		thetasubcli xchain burn --source=subchain --token_type=tnt1155 --from=2E833968E5bB786Ae419c4d13189fB081Cc43bab --contract=0x0ede92cAc9161F6C397A604DE508Dcd1e6f43E61 --token_id=7 --amount=2
+var burnCmd = &cobra.Command{
+	Use:     "burn",
+	Short:   "Burn vouchers on the source chain to unlock the authentic tokens on the target chain",
+	Example: `thetasubcli xchain burn --source=subchain --token_type=tfuel --from=2E833968E5bB786Ae419c4d13189fB081Cc43bab --amount=1000000000000000000 --wait`,
+	Run:     doBurnCmd,
+}
+
+func doBurnCmd(cmd *cobra.Command, args []string) {
+	tokenType := parseTokenType(tokenTypeFlag)
+	c := dialChains()
+	sourceClient, sourceChainID := c.source()
+	targetClient, _ := c.target()
+	tokenBank := c.sourceTokenBank(tokenType)
+
+	from := common.HexToAddress(fromFlag)
+	receiver := from
+	if receiverFlag != "" {
+		receiver = common.HexToAddress(receiverFlag)
+	}
+	voucherContract, tokenID, amount := parseTokenFlags(tokenType)
+	fee := parseBigInt(crossChainFeeFlag, "cross-chain fee")
+
+	key := loadKey(cmd, from, passwordFlag)
+	if err := ensureApproval(sourceClient, sourceChainID, key, tokenType, voucherContract, tokenBank, tokenID, amount); err != nil {
+		utils.Error("Failed to approve the token bank: %v\n", err)
+	}
+
+	targetHeight, err := targetClient.BlockNumber(context.Background())
+	if err != nil {
+		utils.Error("Failed to get the target chain height: %v\n", err)
+	}
+
+	var tx *ethtypes.Transaction
+	switch tokenType {
+	case score.CrossChainTokenTypeTFuel:
+		bank, bindErr := ct.NewTFuelTokenBank(tokenBank, sourceClient)
+		if bindErr != nil {
+			utils.Error("Failed to bind the token bank: %v\n", bindErr)
+		}
+		opts := newTransactOpts(sourceClient, sourceChainID, key, new(big.Int).Add(amount, fee))
+		tx, err = bank.BurnVouchers(opts, receiver)
+	case score.CrossChainTokenTypeTNT20:
+		bank, bindErr := ct.NewTNT20TokenBank(tokenBank, sourceClient)
+		if bindErr != nil {
+			utils.Error("Failed to bind the token bank: %v\n", bindErr)
+		}
+		opts := newTransactOpts(sourceClient, sourceChainID, key, fee)
+		tx, err = bank.BurnVouchers(opts, voucherContract, receiver, amount)
+	case score.CrossChainTokenTypeTNT721:
+		bank, bindErr := ct.NewTNT721TokenBank(tokenBank, sourceClient)
+		if bindErr != nil {
+			utils.Error("Failed to bind the token bank: %v\n", bindErr)
+		}
+		opts := newTransactOpts(sourceClient, sourceChainID, key, fee)
+		tx, err = bank.BurnVouchers(opts, voucherContract, receiver, tokenID)
+	case score.CrossChainTokenTypeTNT1155:
+		bank, bindErr := ct.NewTNT1155TokenBank(tokenBank, sourceClient)
+		if bindErr != nil {
+			utils.Error("Failed to bind the token bank: %v\n", bindErr)
+		}
+		opts := newTransactOpts(sourceClient, sourceChainID, key, fee)
+		tx, err = bank.BurnVouchers(opts, voucherContract, receiver, tokenID, amount)
+	}
+	if err != nil {
+		utils.Error("Failed to burn vouchers: %v\n", err)
+	}
+
+	fmt.Printf("Voucher burn tx hash (%v): %v\n", sourceFlag, tx.Hash().Hex())
+	if !waitFlag {
+		return
+	}
+	completeTransfer(c, tokenType, tx.Hash(), targetHeight)
+}
+
+func init() {
+	addTransferFlags(burnCmd)
+}
@@ -0,0 +1,156 @@
+package xchain
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/thetasubchain/cmd/thetasubcli/cmd/utils"
+	score "github.com/thetatoken/thetasubchain/core"
+	ethtypes "github.com/thetatoken/thetasubchain/eth/core/types"
+	ct "github.com/thetatoken/thetasubchain/interchain/contracts/accessors"
+)
+
+// lockCmd locks the tokens authentic to the source chain in the token bank, so that the
+// corresponding vouchers are minted to the receiver on the target chain.
+// Example:
+//		thetasubcli xchain lock --source=mainchain --token_type=tfuel --from=2E833968E5bB786Ae419c4d13189fB081Cc43bab --amount=1000000000000000000 --wait
+//		thetasubcli xchain lock --source=mainchain --token_type=tnt20 --from=2E833968E5bB786Ae419c4d13189fB081Cc43bab --contract=0x4fb87c52Bb6D194f78cd4896E3e574028fedBAB9 --amount=1000
+//		thetasubcli xchain lock --source=subchain --token_type=tnt721 --from=2E833968E5bB786Ae419c4d13189fB081Cc43bab --contract=0x0293801741ceF9465b2cf717578e57255863E8B2 --token_id=7
+var lockCmd = &cobra.Command{
+	Use:     "lock",
+	Short:   "Lock tokens on the source chain to mint vouchers on the target chain",
+	Example: `thetasubcli xchain lock --source=mainchain --token_type=tfuel --from=2E833968E5bB786Ae419c4d13189fB081Cc43bab --amount=1000000000000000000 --wait`,
+	Run:     doLockCmd,
+}
+
+func doLockCmd(cmd *cobra.Command, args []string) {
+	tokenType := parseTokenType(tokenTypeFlag)
+	c := dialChains()
+	sourceClient, sourceChainID := c.source()
+	targetClient, targetChainID := c.target()
+	tokenBank := c.sourceTokenBank(tokenType)
+
+	from := common.HexToAddress(fromFlag)
+	receiver := from
+	if receiverFlag != "" {
+		receiver = common.HexToAddress(receiverFlag)
+	}
+	contract, tokenID, amount := parseTokenFlags(tokenType)
+	fee := parseBigInt(crossChainFeeFlag, "cross-chain fee")
+
+	key := loadKey(cmd, from, passwordFlag)
+	if err := ensureApproval(sourceClient, sourceChainID, key, tokenType, contract, tokenBank, tokenID, amount); err != nil {
+		utils.Error("Failed to approve the token bank: %v\n", err)
+	}
+
+	targetHeight, err := targetClient.BlockNumber(context.Background())
+	if err != nil {
+		utils.Error("Failed to get the target chain height: %v\n", err)
+	}
+
+	var tx *ethtypes.Transaction
+	switch tokenType {
+	case score.CrossChainTokenTypeTFuel:
+		bank, bindErr := ct.NewTFuelTokenBank(tokenBank, sourceClient)
+		if bindErr != nil {
+			utils.Error("Failed to bind the token bank: %v\n", bindErr)
+		}
+		opts := newTransactOpts(sourceClient, sourceChainID, key, new(big.Int).Add(amount, fee))
+		tx, err = bank.LockTokens(opts, targetChainID, receiver)
+	case score.CrossChainTokenTypeTNT20:
+		bank, bindErr := ct.NewTNT20TokenBank(tokenBank, sourceClient)
+		if bindErr != nil {
+			utils.Error("Failed to bind the token bank: %v\n", bindErr)
+		}
+		opts := newTransactOpts(sourceClient, sourceChainID, key, fee)
+		tx, err = bank.LockTokens(opts, targetChainID, contract, receiver, amount)
+	case score.CrossChainTokenTypeTNT721:
+		bank, bindErr := ct.NewTNT721TokenBank(tokenBank, sourceClient)
+		if bindErr != nil {
+			utils.Error("Failed to bind the token bank: %v\n", bindErr)
+		}
+		opts := newTransactOpts(sourceClient, sourceChainID, key, fee)
+		tx, err = bank.LockTokens(opts, targetChainID, contract, receiver, tokenID)
+	case score.CrossChainTokenTypeTNT1155:
+		bank, bindErr := ct.NewTNT1155TokenBank(tokenBank, sourceClient)
+		if bindErr != nil {
+			utils.Error("Failed to bind the token bank: %v\n", bindErr)
+		}
+		opts := newTransactOpts(sourceClient, sourceChainID, key, fee)
+		tx, err = bank.LockTokens(opts, targetChainID, contract, receiver, tokenID, amount)
+	}
+	if err != nil {
+		utils.Error("Failed to lock tokens: %v\n", err)
+	}
+
+	fmt.Printf("Token lock tx hash (%v): %v\n", sourceFlag, tx.Hash().Hex())
+	if !waitFlag {
+		return
+	}
+	completeTransfer(c, tokenType, tx.Hash(), targetHeight)
+}
+
+// parseTokenFlags parses the token contract, token ID and amount flags required by the token type.
+func parseTokenFlags(tokenType score.CrossChainTokenType) (contract common.Address, tokenID *big.Int, amount *big.Int) {
+	tokenID = common.Big0
+	amount = common.Big0
+	if tokenType != score.CrossChainTokenTypeTFuel {
+		if contractFlag == "" {
+			utils.Error("The token contract address cannot be empty\n")
+		}
+		contract = common.HexToAddress(contractFlag)
+	}
+	if tokenType == score.CrossChainTokenTypeTNT721 || tokenType == score.CrossChainTokenTypeTNT1155 {
+		if tokenIDFlag == "" {
+			utils.Error("The token ID cannot be empty\n")
+		}
+		tokenID = parseBigInt(tokenIDFlag, "token ID")
+	}
+	if tokenType != score.CrossChainTokenTypeTNT721 {
+		amount = parseBigInt(amountFlag, "amount")
+		if amount.Sign() == 0 {
+			utils.Error("The amount must be positive\n")
+		}
+	}
+	return contract, tokenID, amount
+}
+
+// completeTransfer waits for the source chain transaction and then for the matching event on the target chain.
+func completeTransfer(c *chains, tokenType score.CrossChainTokenType, txHash common.Hash, targetFromHeight uint64) {
+	sourceClient, _ := c.source()
+	targetClient, _ := c.target()
+	timeout := time.Duration(waitTimeoutSecsFlag) * time.Second
+
+	receipt, err := waitForReceipt(sourceClient, txHash, timeout)
+	if err != nil {
+		utils.Error("Failed to confirm the transaction: %v\n", err)
+	}
+	event, err := findTransferEvent(sourceClient, c.sourceTokenBank(tokenType), tokenType, receipt)
+	if err != nil {
+		utils.Error("%v\n", err)
+	}
+	fmt.Printf("Transaction confirmed at height %v, %v nonce: %v\n", receipt.BlockNumber, event.Kind, event.Nonce)
+
+	completion, err := waitForTransferCompletion(targetClient, c.targetTokenBank(tokenType), tokenType, event, targetFromHeight, timeout)
+	if err != nil {
+		utils.Error("%v\n", err)
+	}
+	printTransferCompletion(completion)
+}
+
+func printTransferCompletion(completion *transferCompletion) {
+	fmt.Printf("Cross-chain transfer completed, target chain tx hash: %v, height: %v, receiver: %v\n",
+		completion.TxHash.Hex(), completion.BlockHeight, completion.Receiver.Hex())
+	if completion.VoucherContract != nil {
+		fmt.Printf("Voucher contract: %v\n", completion.VoucherContract.Hex())
+	}
+}
+
+func init() {
+	addTransferFlags(lockCmd)
+}
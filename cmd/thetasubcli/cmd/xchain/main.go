@@ -0,0 +1,68 @@
+package xchain
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// Common flags used in xchain sub commands.
+var (
+	mainchainEthRpcFlag     string
+	subchainEthRpcFlag      string
+	mainchainTokenBankFlag  string
+	subchainTokenBankFlag   string
+	sourceFlag              string
+	tokenTypeFlag           string
+	fromFlag                string
+	passwordFlag            string
+	receiverFlag            string
+	contractFlag            string
+	tokenIDFlag             string
+	amountFlag              string
+	crossChainFeeFlag       string
+	gasLimitFlag            uint64
+	waitFlag                bool
+	waitTimeoutSecsFlag     uint64
+	txHashFlag              string
+	targetFromHeightFlag    uint64
+	approvalTimeoutSecsFlag uint64
+)
+
+// XchainCmd represents the xchain command
+var XchainCmd = &cobra.Command{
+	Use:   "xchain",
+	Short: "Transfer tokens between the mainchain and the subchain",
+	Long: `Transfer TFuel, TNT20, TNT721 and TNT1155 tokens between the mainchain and the subchain through the token bank contracts.
+	Tokens authentic to the source chain are locked, and vouchers are minted on the target chain. Burning the vouchers unlocks the authentic tokens.`,
+}
+
+func init() {
+	XchainCmd.AddCommand(lockCmd)
+	XchainCmd.AddCommand(burnCmd)
+	XchainCmd.AddCommand(statusCmd)
+}
+
+func addChainFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&mainchainEthRpcFlag, "mainchain_eth_rpc", "", "Mainchain ETH RPC URL, defaults to the subchain.mainchainEthRpcURL config")
+	cmd.Flags().StringVar(&subchainEthRpcFlag, "subchain_eth_rpc", "", "Subchain ETH RPC URL, defaults to the subchain.subchainEthRpcURL config")
+	cmd.Flags().StringVar(&mainchainTokenBankFlag, "mainchain_token_bank", "", "Mainchain token bank address, defaults to the subchain.mainchain<TokenType>TB config")
+	cmd.Flags().StringVar(&subchainTokenBankFlag, "subchain_token_bank", "", "Subchain token bank address, queried from the subchain node if not specified")
+	cmd.Flags().StringVar(&sourceFlag, "source", sourceMainchain, "Chain the transfer starts from (mainchain|subchain)")
+	cmd.Flags().StringVar(&tokenTypeFlag, "token_type", "tfuel", "Token type (tfuel|tnt20|tnt721|tnt1155)")
+}
+
+func addTransferFlags(cmd *cobra.Command) {
+	addChainFlags(cmd)
+	cmd.Flags().StringVar(&fromFlag, "from", "", "Address to send from")
+	cmd.Flags().StringVar(&passwordFlag, "password", "", "Password to unlock the key")
+	cmd.Flags().StringVar(&receiverFlag, "receiver", "", "Receiver address on the target chain, defaults to the sender")
+	cmd.Flags().StringVar(&contractFlag, "contract", "", "Token contract (lock) or voucher contract (burn) address on the source chain, not needed for TFuel")
+	cmd.Flags().StringVar(&tokenIDFlag, "token_id", "", "Token ID, for TNT721 and TNT1155 only")
+	cmd.Flags().StringVar(&amountFlag, "amount", "0", "Amount in wei, not needed for TNT721")
+	cmd.Flags().StringVar(&crossChainFeeFlag, "cross_chain_fee", "10000000000000000000", "Cross-chain transfer fee in TFuelWei")
+	cmd.Flags().Uint64Var(&gasLimitFlag, "gas_limit", 3000000, "Gas limit of the transactions")
+	cmd.Flags().Uint64Var(&approvalTimeoutSecsFlag, "approval_timeout", 60, "Seconds to wait for the token approval transaction")
+	cmd.Flags().BoolVar(&waitFlag, "wait", false, "Wait until the vouchers are minted or the tokens are unlocked on the target chain")
+	cmd.Flags().Uint64Var(&waitTimeoutSecsFlag, "wait_timeout", 600, "Seconds to wait for the transfer to complete")
+
+	cmd.MarkFlagRequired("from")
+}
@@ -0,0 +1,95 @@
+package xchain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/thetasubchain/cmd/thetasubcli/cmd/utils"
+)
+
+const defaultStatusScanBlocks = uint64(20000)
+
+const (
+	transferStatusPending   = "pending"
+	transferStatusCompleted = "completed"
+)
+
+// transferStatus is the status of a cross-chain transfer initiated by a source chain transaction.
+type transferStatus struct {
+	Status      string              `json:"status"`
+	SourceTx    common.Hash         `json:"source_tx_hash"`
+	SourceEvent *transferEvent      `json:"source_event"`
+	Completion  *transferCompletion `json:"completion,omitempty"`
+	ScannedFrom uint64              `json:"scanned_from"`
+	ScannedTo   uint64              `json:"scanned_to"`
+}
+
+// statusCmd reports whether the cross-chain transfer initiated by a lock or burn transaction has completed.
+// Example:
+//		thetasubcli xchain status --source=mainchain --token_type=tnt20 --tx_hash=0x8a3b...
+var statusCmd = &cobra.Command{
+	Use:     "status",
+	Short:   "Get the status of a cross-chain transfer",
+	Example: `thetasubcli xchain status --source=mainchain --token_type=tnt20 --tx_hash=0x8a3b...`,
+	Run:     doStatusCmd,
+}
+
+func doStatusCmd(cmd *cobra.Command, args []string) {
+	tokenType := parseTokenType(tokenTypeFlag)
+	c := dialChains()
+	sourceClient, _ := c.source()
+	targetClient, _ := c.target()
+
+	txHash := common.HexToHash(txHashFlag)
+	receipt, err := sourceClient.TransactionReceipt(context.Background(), txHash)
+	if err != nil || receipt == nil {
+		utils.Error("Failed to get the receipt of transaction %v: %v\n", txHash.Hex(), err)
+	}
+	event, err := findTransferEvent(sourceClient, c.sourceTokenBank(tokenType), tokenType, receipt)
+	if err != nil {
+		utils.Error("%v\n", err)
+	}
+
+	toHeight, err := targetClient.BlockNumber(context.Background())
+	if err != nil {
+		utils.Error("Failed to get the target chain height: %v\n", err)
+	}
+	fromHeight := targetFromHeightFlag
+	if fromHeight == 0 && toHeight > defaultStatusScanBlocks {
+		fromHeight = toHeight - defaultStatusScanBlocks
+	}
+
+	completion, err := findTransferCompletion(targetClient, c.targetTokenBank(tokenType), tokenType, event, fromHeight, toHeight)
+	if err != nil {
+		utils.Error("Failed to scan the target chain: %v\n", err)
+	}
+
+	status := transferStatus{
+		Status:      transferStatusPending,
+		SourceTx:    txHash,
+		SourceEvent: event,
+		Completion:  completion,
+		ScannedFrom: fromHeight,
+		ScannedTo:   toHeight,
+	}
+	if completion != nil {
+		status.Status = transferStatusCompleted
+	}
+	formatted, err := json.MarshalIndent(status, "", "    ")
+	if err != nil {
+		utils.Error("Failed to format the status: %v\n", err)
+	}
+	fmt.Println(string(formatted))
+}
+
+func init() {
+	addChainFlags(statusCmd)
+	statusCmd.Flags().StringVar(&txHashFlag, "tx_hash", "", "Hash of the lock or burn transaction on the source chain")
+	statusCmd.Flags().Uint64Var(&targetFromHeightFlag, "target_from_height", 0,
+		fmt.Sprintf("Target chain height to start scanning from, defaults to the last %v blocks", defaultStatusScanBlocks))
+	statusCmd.MarkFlagRequired("tx_hash")
+}
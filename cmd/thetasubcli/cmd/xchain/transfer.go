@@ -0,0 +1,374 @@
+package xchain
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/crypto"
+	score "github.com/thetatoken/thetasubchain/core"
+	"github.com/thetatoken/thetasubchain/eth/abi/bind"
+	ethtypes "github.com/thetatoken/thetasubchain/eth/core/types"
+	"github.com/thetatoken/thetasubchain/eth/ethclient"
+	ct "github.com/thetatoken/thetasubchain/interchain/contracts/accessors"
+)
+
+const (
+	targetScanBatchSize    = uint64(5000) // max number of blocks per log query
+	targetScanPollInterval = 2 * time.Second
+)
+
+type transferKind string
+
+const (
+	transferKindLock transferKind = "lock"
+	transferKindBurn transferKind = "burn"
+)
+
+// transferEvent is the token lock or voucher burn event emitted by the source chain token bank.
+type transferEvent struct {
+	Kind          transferKind `json:"kind"`
+	Denom         string       `json:"denom"`
+	Nonce         *big.Int     `json:"nonce"`
+	TargetChainID *big.Int     `json:"target_chain_id,omitempty"` // set for token locks only
+}
+
+// transferCompletion is the voucher mint or token unlock event emitted by the target chain token bank.
+type transferCompletion struct {
+	TxHash          common.Hash     `json:"tx_hash"`
+	BlockHeight     uint64          `json:"block_height"`
+	Receiver        common.Address  `json:"receiver"`
+	VoucherContract *common.Address `json:"voucher_contract,omitempty"` // set for TNT20/721/1155 voucher mints only
+}
+
+// ensureApproval approves the token bank to transfer the tokens of the owner, unless it
+// already has the allowance. The TFuel transfers do not need an approval.
+func ensureApproval(client *ethclient.Client, chainID *big.Int, key *crypto.PrivateKey, tokenType score.CrossChainTokenType,
+	contract common.Address, tokenBank common.Address, tokenID *big.Int, amount *big.Int) error {
+	owner := key.PublicKey().Address()
+
+	var tx *ethtypes.Transaction
+	switch tokenType {
+	case score.CrossChainTokenTypeTNT20:
+		token, err := ct.NewMockTNT20(contract, client) // only the standard TNT20 methods are used
+		if err != nil {
+			return err
+		}
+		allowance, err := token.Allowance(nil, owner, tokenBank)
+		if err != nil {
+			return err
+		}
+		if allowance.Cmp(amount) >= 0 {
+			return nil
+		}
+		tx, err = token.Approve(newTransactOpts(client, chainID, key, common.Big0), tokenBank, amount)
+		if err != nil {
+			return err
+		}
+	case score.CrossChainTokenTypeTNT721:
+		token, err := ct.NewMockTNT721(contract, client)
+		if err != nil {
+			return err
+		}
+		approved, err := token.GetApproved(nil, tokenID)
+		if err != nil {
+			return err
+		}
+		if approved == tokenBank {
+			return nil
+		}
+		approvedForAll, err := token.IsApprovedForAll(nil, owner, tokenBank)
+		if err != nil {
+			return err
+		}
+		if approvedForAll {
+			return nil
+		}
+		tx, err = token.Approve(newTransactOpts(client, chainID, key, common.Big0), tokenBank, tokenID)
+		if err != nil {
+			return err
+		}
+	case score.CrossChainTokenTypeTNT1155:
+		token, err := ct.NewMockTNT1155(contract, client)
+		if err != nil {
+			return err
+		}
+		approvedForAll, err := token.IsApprovedForAll(nil, owner, tokenBank)
+		if err != nil {
+			return err
+		}
+		if approvedForAll {
+			return nil
+		}
+		tx, err = token.SetApprovalForAll(newTransactOpts(client, chainID, key, common.Big0), tokenBank, true)
+		if err != nil {
+			return err
+		}
+	default:
+		return nil
+	}
+
+	fmt.Printf("Approving token bank %v, tx hash: %v\n", tokenBank.Hex(), tx.Hash().Hex())
+	_, err := waitForReceipt(client, tx.Hash(), time.Duration(approvalTimeoutSecsFlag)*time.Second)
+	return err
+}
+
+// findTransferEvent extracts the token lock or voucher burn event from the receipt of the source chain transaction.
+func findTransferEvent(client *ethclient.Client, tokenBank common.Address, tokenType score.CrossChainTokenType,
+	receipt *ethtypes.Receipt) (*transferEvent, error) {
+	for _, log := range receipt.Logs {
+		if log.Address != tokenBank || len(log.Topics) == 0 {
+			continue
+		}
+		event, err := parseTransferEvent(client, tokenBank, tokenType, *log)
+		if err != nil {
+			return nil, err
+		}
+		if event != nil {
+			return event, nil
+		}
+	}
+	return nil, fmt.Errorf("no token lock or voucher burn event emitted by token bank %v in transaction %v", tokenBank.Hex(), receipt.TxHash.Hex())
+}
+
+func parseTransferEvent(client *ethclient.Client, tokenBank common.Address, tokenType score.CrossChainTokenType,
+	log ethtypes.Log) (*transferEvent, error) {
+	switch tokenType {
+	case score.CrossChainTokenTypeTFuel:
+		bank, err := ct.NewTFuelTokenBank(tokenBank, client)
+		if err != nil {
+			return nil, err
+		}
+		if ev, err := bank.ParseTFuelTokenLocked(log); err == nil {
+			return &transferEvent{Kind: transferKindLock, Denom: ev.Denom, Nonce: ev.TokenLockNonce, TargetChainID: ev.TargetChainID}, nil
+		}
+		if ev, err := bank.ParseTFuelVoucherBurned(log); err == nil {
+			return &transferEvent{Kind: transferKindBurn, Denom: ev.Denom, Nonce: ev.VoucherBurnNonce}, nil
+		}
+	case score.CrossChainTokenTypeTNT20:
+		bank, err := ct.NewTNT20TokenBank(tokenBank, client)
+		if err != nil {
+			return nil, err
+		}
+		if ev, err := bank.ParseTNT20TokenLocked(log); err == nil {
+			return &transferEvent{Kind: transferKindLock, Denom: ev.Denom, Nonce: ev.TokenLockNonce, TargetChainID: ev.TargetChainID}, nil
+		}
+		if ev, err := bank.ParseTNT20VoucherBurned(log); err == nil {
+			return &transferEvent{Kind: transferKindBurn, Denom: ev.Denom, Nonce: ev.VoucherBurnNonce}, nil
+		}
+	case score.CrossChainTokenTypeTNT721:
+		bank, err := ct.NewTNT721TokenBank(tokenBank, client)
+		if err != nil {
+			return nil, err
+		}
+		if ev, err := bank.ParseTNT721TokenLocked(log); err == nil {
+			return &transferEvent{Kind: transferKindLock, Denom: ev.Denom, Nonce: ev.TokenLockNonce, TargetChainID: ev.TargetChainID}, nil
+		}
+		if ev, err := bank.ParseTNT721VoucherBurned(log); err == nil {
+			return &transferEvent{Kind: transferKindBurn, Denom: ev.Denom, Nonce: ev.VoucherBurnNonce}, nil
+		}
+	case score.CrossChainTokenTypeTNT1155:
+		bank, err := ct.NewTNT1155TokenBank(tokenBank, client)
+		if err != nil {
+			return nil, err
+		}
+		if ev, err := bank.ParseTNT1155TokenLocked(log); err == nil {
+			return &transferEvent{Kind: transferKindLock, Denom: ev.Denom, Nonce: ev.TokenLockNonce, TargetChainID: ev.TargetChainID}, nil
+		}
+		if ev, err := bank.ParseTNT1155VoucherBurned(log); err == nil {
+			return &transferEvent{Kind: transferKindBurn, Denom: ev.Denom, Nonce: ev.VoucherBurnNonce}, nil
+		}
+	}
+	return nil, nil
+}
+
+// waitForTransferCompletion scans the target chain from the given height until the transfer completes.
+func waitForTransferCompletion(client *ethclient.Client, tokenBank common.Address, tokenType score.CrossChainTokenType,
+	event *transferEvent, fromHeight uint64, timeout time.Duration) (*transferCompletion, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		toHeight, err := client.BlockNumber(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		if toHeight >= fromHeight {
+			completion, err := findTransferCompletion(client, tokenBank, tokenType, event, fromHeight, toHeight)
+			if err != nil {
+				return nil, err
+			}
+			if completion != nil {
+				return completion, nil
+			}
+			fromHeight = toHeight + 1
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for the transfer to complete, last scanned height: %v", fromHeight-1)
+		}
+		fmt.Printf("Waiting for the cross-chain transfer to complete (scanned the target chain up to height %v)...\n", toHeight)
+		time.Sleep(targetScanPollInterval)
+	}
+}
+
+// findTransferCompletion looks for the voucher mint or token unlock event matching the source
+// chain event in the given block range of the target chain.
+func findTransferCompletion(client *ethclient.Client, tokenBank common.Address, tokenType score.CrossChainTokenType,
+	event *transferEvent, fromHeight, toHeight uint64) (*transferCompletion, error) {
+	for start := fromHeight; start <= toHeight; start += targetScanBatchSize {
+		end := start + targetScanBatchSize - 1
+		if end > toHeight {
+			end = toHeight
+		}
+		opts := &bind.FilterOpts{Start: start, End: &end, Context: context.Background()}
+		completion, err := scanTransferCompletion(client, tokenBank, tokenType, event, opts)
+		if err != nil || completion != nil {
+			return completion, err
+		}
+	}
+	return nil, nil
+}
+
+func scanTransferCompletion(client *ethclient.Client, tokenBank common.Address, tokenType score.CrossChainTokenType,
+	event *transferEvent, opts *bind.FilterOpts) (*transferCompletion, error) {
+	switch tokenType {
+	case score.CrossChainTokenTypeTFuel:
+		bank, err := ct.NewTFuelTokenBank(tokenBank, client)
+		if err != nil {
+			return nil, err
+		}
+		if event.Kind == transferKindLock {
+			it, err := bank.FilterTFuelVoucherMinted(opts)
+			if err != nil {
+				return nil, err
+			}
+			defer it.Close()
+			for it.Next() {
+				if event.matches(it.Event.Denom, it.Event.SourceChainTokenLockNonce) {
+					return newTransferCompletion(it.Event.Raw, it.Event.TargetChainVoucherReceiver, nil), nil
+				}
+			}
+			return nil, it.Error()
+		}
+		it, err := bank.FilterTFuelTokenUnlocked(opts)
+		if err != nil {
+			return nil, err
+		}
+		defer it.Close()
+		for it.Next() {
+			if event.matches(it.Event.Denom, it.Event.SourceChainVoucherBurnNonce) {
+				return newTransferCompletion(it.Event.Raw, it.Event.TargetChainTokenReceiver, nil), nil
+			}
+		}
+		return nil, it.Error()
+	case score.CrossChainTokenTypeTNT20:
+		bank, err := ct.NewTNT20TokenBank(tokenBank, client)
+		if err != nil {
+			return nil, err
+		}
+		if event.Kind == transferKindLock {
+			it, err := bank.FilterTNT20VoucherMinted(opts)
+			if err != nil {
+				return nil, err
+			}
+			defer it.Close()
+			for it.Next() {
+				if event.matches(it.Event.Denom, it.Event.SourceChainTokenLockNonce) {
+					return newTransferCompletion(it.Event.Raw, it.Event.TargetChainVoucherReceiver, &it.Event.VoucherContract), nil
+				}
+			}
+			return nil, it.Error()
+		}
+		it, err := bank.FilterTNT20TokenUnlocked(opts)
+		if err != nil {
+			return nil, err
+		}
+		defer it.Close()
+		for it.Next() {
+			if event.matches(it.Event.Denom, it.Event.SourceChainVoucherBurnNonce) {
+				return newTransferCompletion(it.Event.Raw, it.Event.TargetChainTokenReceiver, nil), nil
+			}
+		}
+		return nil, it.Error()
+	case score.CrossChainTokenTypeTNT721:
+		bank, err := ct.NewTNT721TokenBank(tokenBank, client)
+		if err != nil {
+			return nil, err
+		}
+		if event.Kind == transferKindLock {
+			it, err := bank.FilterTNT721VoucherMinted(opts)
+			if err != nil {
+				return nil, err
+			}
+			defer it.Close()
+			for it.Next() {
+				if event.matches(it.Event.Denom, it.Event.SourceChainTokenLockNonce) {
+					return newTransferCompletion(it.Event.Raw, it.Event.TargetChainVoucherReceiver, &it.Event.VoucherContract), nil
+				}
+			}
+			return nil, it.Error()
+		}
+		it, err := bank.FilterTNT721TokenUnlocked(opts)
+		if err != nil {
+			return nil, err
+		}
+		defer it.Close()
+		for it.Next() {
+			if event.matches(it.Event.Denom, it.Event.SourceChainVoucherBurnNonce) {
+				return newTransferCompletion(it.Event.Raw, it.Event.TargetChainTokenReceiver, nil), nil
+			}
+		}
+		return nil, it.Error()
+	case score.CrossChainTokenTypeTNT1155:
+		bank, err := ct.NewTNT1155TokenBank(tokenBank, client)
+		if err != nil {
+			return nil, err
+		}
+		if event.Kind == transferKindLock {
+			it, err := bank.FilterTNT1155VoucherMinted(opts)
+			if err != nil {
+				return nil, err
+			}
+			defer it.Close()
+			for it.Next() {
+				if event.matches(it.Event.Denom, it.Event.SourceChainTokenLockNonce) {
+					return newTransferCompletion(it.Event.Raw, it.Event.TargetChainVoucherReceiver, &it.Event.VoucherContract), nil
+				}
+			}
+			return nil, it.Error()
+		}
+		it, err := bank.FilterTNT1155TokenUnlocked(opts)
+		if err != nil {
+			return nil, err
+		}
+		defer it.Close()
+		for it.Next() {
+			if event.matches(it.Event.Denom, it.Event.SourceChainVoucherBurnNonce) {
+				return newTransferCompletion(it.Event.Raw, it.Event.TargetChainTokenReceiver, nil), nil
+			}
+		}
+		return nil, it.Error()
+	}
+	return nil, fmt.Errorf("unsupported token type: %v", tokenType)
+}
+
+// matches tells whether the target chain event with the given denom and source chain nonce
+// completes the transfer. The nonces are counted per source chain token bank, hence the denom,
+// which encodes the chain the token is authentic to, is compared as well.
+func (te *transferEvent) matches(denom string, sourceNonce *big.Int) bool {
+	return sourceNonce != nil && sourceNonce.Cmp(te.Nonce) == 0 && strings.EqualFold(denom, te.Denom)
+}
+
+func newTransferCompletion(log ethtypes.Log, receiver common.Address, voucherContract *common.Address) *transferCompletion {
+	completion := &transferCompletion{
+		TxHash:      log.TxHash,
+		BlockHeight: log.BlockNumber,
+		Receiver:    receiver,
+	}
+	if voucherContract != nil {
+		contract := *voucherContract
+		completion.VoucherContract = &contract
+	}
+	return completion
+}
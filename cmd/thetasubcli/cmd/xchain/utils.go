@@ -0,0 +1,234 @@
+package xchain
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	rpcc "github.com/ybbus/jsonrpc"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/crypto"
+	ks "github.com/thetatoken/theta/wallet/softwallet/keystore"
+	"github.com/thetatoken/thetasubchain/cmd/thetasubcli/cmd/utils"
+	scom "github.com/thetatoken/thetasubchain/common"
+	score "github.com/thetatoken/thetasubchain/core"
+	"github.com/thetatoken/thetasubchain/eth/abi/bind"
+	ethtypes "github.com/thetatoken/thetasubchain/eth/core/types"
+	"github.com/thetatoken/thetasubchain/eth/ethclient"
+	"github.com/thetatoken/thetasubchain/rpc"
+)
+
+const (
+	sourceMainchain = "mainchain"
+	sourceSubchain  = "subchain"
+)
+
+const receiptPollInterval = 1 * time.Second
+
+// chains holds the connections to the mainchain and the subchain ETH RPC adaptors.
+type chains struct {
+	mainchainClient *ethclient.Client
+	subchainClient  *ethclient.Client
+	mainchainID     *big.Int
+	subchainID      *big.Int
+}
+
+func dialChains() *chains {
+	if sourceFlag != sourceMainchain && sourceFlag != sourceSubchain {
+		utils.Error("Invalid source chain: %v, must be either %v or %v\n", sourceFlag, sourceMainchain, sourceSubchain)
+	}
+
+	mainchainEthRpcURL := mainchainEthRpcFlag
+	if mainchainEthRpcURL == "" {
+		mainchainEthRpcURL = viper.GetString(scom.CfgMainchainEthRpcURL)
+	}
+	subchainEthRpcURL := subchainEthRpcFlag
+	if subchainEthRpcURL == "" {
+		subchainEthRpcURL = viper.GetString(scom.CfgSubchainEthRpcURL)
+	}
+
+	mainchainClient, err := ethclient.Dial(mainchainEthRpcURL)
+	if err != nil {
+		utils.Error("Failed to connect to the mainchain ETH RPC %v: %v\n", mainchainEthRpcURL, err)
+	}
+	subchainClient, err := ethclient.Dial(subchainEthRpcURL)
+	if err != nil {
+		utils.Error("Failed to connect to the subchain ETH RPC %v: %v\n", subchainEthRpcURL, err)
+	}
+	mainchainID, err := mainchainClient.ChainID(context.Background())
+	if err != nil {
+		utils.Error("Failed to get the mainchain ID: %v\n", err)
+	}
+	subchainID, err := subchainClient.ChainID(context.Background())
+	if err != nil {
+		utils.Error("Failed to get the subchain ID: %v\n", err)
+	}
+
+	return &chains{
+		mainchainClient: mainchainClient,
+		subchainClient:  subchainClient,
+		mainchainID:     mainchainID,
+		subchainID:      subchainID,
+	}
+}
+
+func (c *chains) isSourceMainchain() bool {
+	return sourceFlag == sourceMainchain
+}
+
+func (c *chains) source() (*ethclient.Client, *big.Int) {
+	if c.isSourceMainchain() {
+		return c.mainchainClient, c.mainchainID
+	}
+	return c.subchainClient, c.subchainID
+}
+
+func (c *chains) target() (*ethclient.Client, *big.Int) {
+	if c.isSourceMainchain() {
+		return c.subchainClient, c.subchainID
+	}
+	return c.mainchainClient, c.mainchainID
+}
+
+func (c *chains) sourceTokenBank(tokenType score.CrossChainTokenType) common.Address {
+	return tokenBankAddress(c.isSourceMainchain(), tokenType)
+}
+
+func (c *chains) targetTokenBank(tokenType score.CrossChainTokenType) common.Address {
+	return tokenBankAddress(!c.isSourceMainchain(), tokenType)
+}
+
+func parseTokenType(tokenTypeStr string) score.CrossChainTokenType {
+	switch strings.ToLower(tokenTypeStr) {
+	case "tfuel":
+		return score.CrossChainTokenTypeTFuel
+	case "tnt20":
+		return score.CrossChainTokenTypeTNT20
+	case "tnt721":
+		return score.CrossChainTokenTypeTNT721
+	case "tnt1155":
+		return score.CrossChainTokenTypeTNT1155
+	}
+	utils.Error("Invalid token type: %v, must be one of tfuel, tnt20, tnt721 and tnt1155\n", tokenTypeStr)
+	return score.CrossChainTokenTypeInvalid
+}
+
+// tokenBankAddress returns the address of the token bank contract. The mainchain token banks
+// are read from the config, and the subchain token banks are queried from the subchain node.
+func tokenBankAddress(onMainchain bool, tokenType score.CrossChainTokenType) common.Address {
+	if onMainchain {
+		if mainchainTokenBankFlag != "" {
+			return common.HexToAddress(mainchainTokenBankFlag)
+		}
+		var cfgKey string
+		switch tokenType {
+		case score.CrossChainTokenTypeTFuel:
+			cfgKey = scom.CfgMainchainTFuelTokenBankContractAddress
+		case score.CrossChainTokenTypeTNT20:
+			cfgKey = scom.CfgMainchainTNT20TokenBankContractAddress
+		case score.CrossChainTokenTypeTNT721:
+			cfgKey = scom.CfgMainchainTNT721TokenBankContractAddress
+		case score.CrossChainTokenTypeTNT1155:
+			cfgKey = scom.CfgMainchainTNT1155TokenBankContractAddress
+		}
+		addressStr := viper.GetString(cfgKey)
+		if addressStr == "" {
+			utils.Error("The mainchain token bank address is not configured, please set %v or use --mainchain_token_bank\n", cfgKey)
+		}
+		return common.HexToAddress(addressStr)
+	}
+
+	if subchainTokenBankFlag != "" {
+		return common.HexToAddress(subchainTokenBankFlag)
+	}
+	client := rpcc.NewRPCClient(viper.GetString(utils.CfgRemoteRPCEndpoint))
+	res, err := client.Call("theta.GetTokenBankContractAddress", rpc.GetTokenBankContractAddressArgs{
+		TokenType: tokenType,
+	})
+	if err != nil {
+		utils.Error("Failed to get the subchain token bank address: %v\n", err)
+	}
+	if res.Error != nil {
+		utils.Error("Failed to get the subchain token bank address: %v\n", res.Error)
+	}
+	result := &rpc.GetTokenBankContractAddressResult{}
+	if err = res.GetObject(result); err != nil {
+		utils.Error("Failed to parse server response: %v\n", err)
+	}
+	return common.HexToAddress(result.Address)
+}
+
+// loadKey loads the private key of the given address from the encrypted keystore of the CLI.
+func loadKey(cmd *cobra.Command, address common.Address, password string) *crypto.PrivateKey {
+	cfgPath := cmd.Flag("config").Value.String()
+	keystore, err := ks.NewKeystoreEncrypted(path.Join(cfgPath, "keys"), ks.StandardScryptN, ks.StandardScryptP)
+	if err != nil {
+		utils.Error("Failed to open the keystore: %v\n", err)
+	}
+
+	if password == "" {
+		password, err = utils.GetPassword("Please enter password: ")
+		if err != nil {
+			utils.Error("Failed to get password: %v\n", err)
+		}
+	}
+
+	key, err := keystore.GetKey(address, password)
+	if err != nil {
+		utils.Error("Failed to unlock address %v: %v\n", address.Hex(), err)
+	}
+	return key.PrivateKey
+}
+
+// newTransactOpts creates the options to sign and send the next transaction of the key.
+func newTransactOpts(client *ethclient.Client, chainID *big.Int, key *crypto.PrivateKey, value *big.Int) *bind.TransactOpts {
+	opts, err := bind.NewKeyedTransactorWithChainID(key, chainID)
+	if err != nil {
+		utils.Error("Failed to create the transactor: %v\n", err)
+	}
+	nonce, err := client.PendingNonceAt(context.Background(), opts.From)
+	if err != nil {
+		utils.Error("Failed to get the nonce of %v: %v\n", opts.From.Hex(), err)
+	}
+	gasPrice, err := client.SuggestGasPrice(context.Background())
+	if err != nil {
+		utils.Error("Failed to get the gas price: %v\n", err)
+	}
+	opts.Nonce = new(big.Int).SetUint64(nonce)
+	opts.GasPrice = gasPrice
+	opts.GasLimit = gasLimitFlag
+	opts.Value = value
+	return opts
+}
+
+// waitForReceipt waits until the transaction is included in a block and checks that it succeeded.
+func waitForReceipt(client *ethclient.Client, txHash common.Hash, timeout time.Duration) (*ethtypes.Receipt, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		receipt, err := client.TransactionReceipt(context.Background(), txHash)
+		if err == nil && receipt != nil {
+			if receipt.Status != ethtypes.ReceiptStatusSuccessful {
+				return receipt, fmt.Errorf("transaction %v failed", txHash.Hex())
+			}
+			return receipt, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for transaction %v", txHash.Hex())
+		}
+		time.Sleep(receiptPollInterval)
+	}
+}
+
+func parseBigInt(valueStr, name string) *big.Int {
+	value, ok := new(big.Int).SetString(valueStr, 10)
+	if !ok || value.Sign() < 0 {
+		utils.Error("Failed to parse %v: %v\n", name, valueStr)
+	}
+	return value
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/spf13/cobra"
+)
+
+var cfgPath string
+
+// RootCmd represents the base command when called without any subcommands
+var RootCmd = &cobra.Command{
+	Use:   "thetasubsigner",
+	Short: "Reference remote signer for the Theta subchain validators",
+	Long: `Holds the validator key outside the node process, and signs the votes, block proposals
+and transactions requested by the node, refusing to sign conflicting votes and proposals.`,
+}
+
+// Execute adds all child commands to the root command and sets flags appropriately.
+// This is called by main.main(). It only needs to happen once to the rootCmd.
+func Execute() {
+	if err := RootCmd.Execute(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+func init() {
+	RootCmd.PersistentFlags().StringVar(&cfgPath, "config", getDefaultConfigPath(), "config path, the key is read from <config>/key")
+}
+
+// getDefaultConfigPath returns the default config path.
+func getDefaultConfigPath() string {
+	home, err := homedir.Dir()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	return path.Join(home, ".thetasubsigner")
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/util"
+	"github.com/thetatoken/theta/crypto"
+	ks "github.com/thetatoken/theta/wallet/softwallet/keystore"
+
+	"github.com/thetatoken/thetasubchain/cmd/thetasubcli/cmd/utils"
+	ssigner "github.com/thetatoken/thetasubchain/signer"
+)
+
+var (
+	listenFlag        string
+	passwordFlag      string
+	addressFlag       string
+	authTokenFileFlag string
+)
+
+// startCmd represents the start command
+var startCmd = &cobra.Command{
+	Use:   "start",
+	Short: "Start the signer",
+	Long: `Start serving the signing requests of a node. The key is stored under <config>/key in the same
+format as the node key, and can be managed with "thetasubcli key --node --config=<config>". The node
+connects to the signer with the signer.remoteAddress config. TCP listeners are limited to loopback
+addresses and require the node to present the auth token, generated under <config>/signer/auth_token
+if missing, and passed to the node with the signer.remoteAuthTokenFile config.`,
+	Example: `thetasubsigner start --config=~/.thetasubsigner --listen=unix:///var/run/thetasubsigner.sock`,
+	Run:     runStart,
+}
+
+func init() {
+	startCmd.Flags().StringVar(&listenFlag, "listen", "", "address to listen on, unix://<socket path> or <host>:<port> (default unix://<config>/signer.sock)")
+	startCmd.Flags().StringVar(&passwordFlag, "password", "", "password of the key")
+	startCmd.Flags().StringVar(&addressFlag, "address", "", "address of the key, required if there are multiple keys")
+	startCmd.Flags().StringVar(&authTokenFileFlag, "auth-token-file", "", "file of the token required from the node, always used for TCP listeners (default <config>/signer/auth_token)")
+	RootCmd.AddCommand(startCmd)
+}
+
+func runStart(cmd *cobra.Command, args []string) {
+	util.InitLog()
+
+	privKey, err := loadKey()
+	if err != nil {
+		log.Fatalf("Failed to load key: %v", err)
+	}
+
+	// The sign state is kept next to the key, so the protection follows the key rather than the node.
+	guard, err := ssigner.NewGuard(path.Join(cfgPath, "signer", "sign_state.json"))
+	if err != nil {
+		log.Fatalf("Failed to load the sign state: %v", err)
+	}
+
+	listen := listenFlag
+	if listen == "" {
+		listen = "unix://" + path.Join(cfgPath, "signer.sock")
+	}
+	authToken := ""
+	if authTokenFileFlag != "" || !strings.HasPrefix(listen, "unix://") {
+		authTokenFile := authTokenFileFlag
+		if authTokenFile == "" {
+			authTokenFile = path.Join(cfgPath, "signer", "auth_token")
+		}
+		authToken, err = ssigner.LoadAuthToken(authTokenFile, true)
+		if err != nil {
+			log.Fatalf("Failed to load the auth token: %v", err)
+		}
+	}
+	server := ssigner.NewServer(ssigner.NewLocalSigner(privKey, guard), listen, authToken)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := server.Start(ctx); err != nil {
+		log.Fatalf("Failed to start the signer: %v", err)
+	}
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt)
+	go func() {
+		<-c
+		signal.Stop(c)
+		cancel()
+	}()
+
+	server.Wait()
+	log.Infof("Signer stopped")
+}
+
+func loadKey() (*crypto.PrivateKey, error) {
+	keysDir := path.Join(cfgPath, "key")
+	keystore, err := ks.NewKeystoreEncrypted(keysDir, ks.StandardScryptN, ks.StandardScryptP)
+	if err != nil {
+		return nil, err
+	}
+	addresses, err := keystore.ListKeyAddresses()
+	if err != nil {
+		return nil, err
+	}
+
+	var address common.Address
+	if addressFlag != "" {
+		address = common.HexToAddress(addressFlag)
+	} else if len(addresses) == 1 {
+		address = addresses[0]
+	} else if len(addresses) == 0 {
+		return nil, fmt.Errorf("No key found under %v", path.Join(keysDir, "encrypted"))
+	} else {
+		return nil, fmt.Errorf("Multiple keys found under %v, please specify the key with --address", path.Join(keysDir, "encrypted"))
+	}
+
+	password := passwordFlag
+	if password == "" {
+		password, err = utils.GetPassword(fmt.Sprintf("Please enter the password of %v: ", address.Hex()))
+		if err != nil {
+			return nil, fmt.Errorf("Failed to get password: %v", err)
+		}
+	}
+	key, err := keystore.GetKey(address, password)
+	if err != nil {
+		return nil, err
+	}
+	return key.PrivateKey, nil
+}
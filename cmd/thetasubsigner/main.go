@@ -0,0 +1,7 @@
+package main
+
+import "github.com/thetatoken/thetasubchain/cmd/thetasubsigner/cmd"
+
+func main() {
+	cmd.Execute()
+}
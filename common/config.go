@@ -44,12 +44,21 @@ const (
 	CfgStorageStatePruningRetainedBlocks = "storage.statePruningRetainedBlocks"
 	// CfgStorageStatePruningSkipCheckpoints indicates if the checkpoint state trie should be retained
 	CfgStorageStatePruningSkipCheckpoints = "storage.statePruningSkipCheckpoints"
+	// CfgStorageBackend selects the storage backend of the node database: leveldb, badgerdb or memdb
+	CfgStorageBackend = "storage.backend"
 	// CfgStorageLevelDBCacheSize indicates Level DB cache size
 	CfgStorageLevelDBCacheSize = "storage.levelDBCacheSize"
 	// CfgStorageLevelDBHandles indicates Level DB handle count
 	CfgStorageLevelDBHandles = "storage.levelDBHandles"
 	// CfgStorageRollingInterval is the block interval that we start new db layer
 	CfgStorageRollingInterval = "storage.rollingInterval"
+	// CfgStorageCompactionMaxNodesPerSecond caps the number of trie nodes copied per second by the background compaction (0 for no limit)
+	CfgStorageCompactionMaxNodesPerSecond = "storage.compactionMaxNodesPerSecond"
+	// CfgStorageArchiveEnabled indicates whether the node retains the full historical state (disables rolling and pruning).
+	// The state of every height stays in the state tries, and is looked up through the finalized block height index.
+	CfgStorageArchiveEnabled = "storage.archive"
+	// CfgStorageAccountHistoryEnabled indicates whether to index the finalized transactions by account
+	CfgStorageAccountHistoryEnabled = "storage.accountHistoryEnabled"
 
 	// CfgSyncMessageQueueSize defines the capacity of Sync Manager message queue.
 	CfgSyncMessageQueueSize = "sync.messageQueueSize"
@@ -57,6 +66,12 @@ const (
 	CfgSyncDownloadByHash = "sync.downloadByHash"
 	// CfgSyncDownloadByHeader indicates whether should download blocks using header.
 	CfgSyncDownloadByHeader = "sync.downloadByHeader"
+	// CfgSyncDownloadWindowSize sets the max number of consecutive blocks assigned to a peer as one download window.
+	CfgSyncDownloadWindowSize = "sync.downloadWindowSize"
+	// CfgSyncMaxWindowsPerPeer sets the max number of outstanding download windows per peer.
+	CfgSyncMaxWindowsPerPeer = "sync.maxWindowsPerPeer"
+	// CfgSyncMaxInflightWindows sets the max number of outstanding download windows across all peers.
+	CfgSyncMaxInflightWindows = "sync.maxInflightWindows"
 
 	// CfgP2POpt sets which P2P network to use: p2p, libp2p, or both.
 	CfgP2POpt = "p2p.opt"
@@ -110,6 +125,37 @@ const (
 	// CfgSyncInboundResponseWhitelist filters inbound messages based on peer ID.
 	CfgSyncInboundResponseWhitelist = "sync.inboundResponseWhitelist"
 
+	// CfgMempoolMaxTxCount sets the max number of transactions the mempool holds.
+	CfgMempoolMaxTxCount = "mempool.maxTxCount"
+	// CfgMempoolMaxTxsPerAccount sets the max number of transactions the mempool holds for one sender.
+	CfgMempoolMaxTxsPerAccount = "mempool.maxTxsPerAccount"
+	// CfgMempoolTxLifetimeSecs sets how long a transaction can stay in the mempool before it expires.
+	CfgMempoolTxLifetimeSecs = "mempool.txLifetimeSecs"
+
+	// CfgMempoolReplacementPriceBump sets the min gas price increase, in percent, for a tx to replace a pending tx with the same sequence.
+	CfgMempoolReplacementPriceBump = "mempool.replacementPriceBump"
+
+	// CfgMempoolMaxQueuedTxCount sets the max number of future-sequence transactions the mempool queues.
+	CfgMempoolMaxQueuedTxCount = "mempool.maxQueuedTxCount"
+	// CfgMempoolMaxQueuedTxsPerAccount sets the max number of future-sequence transactions the mempool queues for one sender.
+	CfgMempoolMaxQueuedTxsPerAccount = "mempool.maxQueuedTxsPerAccount"
+	// CfgMempoolQueuedTxLifetimeSecs sets how long a future-sequence transaction can stay queued.
+	CfgMempoolQueuedTxLifetimeSecs = "mempool.queuedTxLifetimeSecs"
+
+	// CfgMempoolJournalEnabled sets whether to journal the mempool transactions to disk and replay them after restart.
+	CfgMempoolJournalEnabled = "mempool.journalEnabled"
+	// CfgMempoolJournalRotateIntervalSecs sets how often the journal is compacted to the transactions still in the mempool.
+	CfgMempoolJournalRotateIntervalSecs = "mempool.journalRotateIntervalSecs"
+
+	// CfgReputationEnabled sets whether to score peers and ban misbehaving ones.
+	CfgReputationEnabled = "reputation.enabled"
+	// CfgReputationDisconnectThreshold sets the score below which a peer is disconnected.
+	CfgReputationDisconnectThreshold = "reputation.disconnectThreshold"
+	// CfgReputationBanThreshold sets the score below which a peer is banned.
+	CfgReputationBanThreshold = "reputation.banThreshold"
+	// CfgReputationBanDurationSecs sets how long a banned peer stays banned.
+	CfgReputationBanDurationSecs = "reputation.banDurationSecs"
+
 	// CfgRPCEnabled sets whether to run RPC service.
 	CfgRPCEnabled = "rpc.enabled"
 	// CfgRPCAddress sets the binding address of RPC service.
@@ -120,6 +166,8 @@ const (
 	CfgRPCMaxConnections = "rpc.maxConnections"
 	// CfgRPCTimeoutSecs set a timeout for RPC.
 	CfgRPCTimeoutSecs = "rpc.timeoutSecs"
+	// CfgRPCTxStatusSubscriptionLifetimeSecs sets how long a tx_status subscription waits for the tx to be finalized.
+	CfgRPCTxStatusSubscriptionLifetimeSecs = "rpc.txStatusSubscriptionLifetimeSecs"
 
 	// CfgLogLevels sets the log level.
 	CfgLogLevels = "log.levels"
@@ -129,6 +177,28 @@ const (
 
 	// Graphite Server to collet metrics
 	CfgMetricsServer = "metrics.server"
+	// CfgMetricsEnabled sets whether to expose the node metrics at the /metrics HTTP endpoint for Prometheus.
+	CfgMetricsEnabled = "metrics.enabled"
+	// CfgMetricsAddress sets the binding address of the metrics endpoint.
+	CfgMetricsAddress = "metrics.address"
+	// CfgMetricsPort sets the port of the metrics endpoint.
+	CfgMetricsPort = "metrics.port"
+
+	// CfgHealthFinalizationTimeoutSecs sets how long the finalized height may stall before the node is reported unhealthy.
+	CfgHealthFinalizationTimeoutSecs = "health.finalizationTimeoutSecs"
+	// CfgHealthWitnessTimeoutSecs sets the timeout for probing the mainchain and subchain ETH RPC endpoints.
+	CfgHealthWitnessTimeoutSecs = "health.witnessTimeoutSecs"
+	// CfgHealthMaxOrchestratorBacklog sets the max number of pending inter-chain events for the node to be ready.
+	CfgHealthMaxOrchestratorBacklog = "health.maxOrchestratorBacklog"
+
+	// CfgSignerRemoteAddress sets the address of the remote signer ("unix://<socket path>" or "<host>:<port>"), the validator key is loaded into the node if empty.
+	CfgSignerRemoteAddress = "signer.remoteAddress"
+	// CfgSignerRemoteTimeoutSecs sets the timeout of the requests to the remote signer.
+	CfgSignerRemoteTimeoutSecs = "signer.remoteTimeoutSecs"
+	// CfgSignerRemoteAuthTokenFile sets the file of the token authenticating the node to the remote signer, required by the signers listening on TCP.
+	CfgSignerRemoteAuthTokenFile = "signer.remoteAuthTokenFile"
+	// CfgSignerDoubleSignProtectionEnabled sets whether the in-process signer persists the last signed vote and proposal, and refuses to sign conflicting ones.
+	CfgSignerDoubleSignProtectionEnabled = "signer.doubleSignProtectionEnabled"
 
 	// CfgProfEnabled to enable profiling
 	CfgProfEnabled = "prof.enabled"
@@ -183,15 +253,37 @@ func init() {
 	viper.SetDefault(CfgSyncMessageQueueSize, 512)
 	viper.SetDefault(CfgSyncDownloadByHash, false)
 	viper.SetDefault(CfgSyncDownloadByHeader, true)
+	viper.SetDefault(CfgSyncDownloadWindowSize, 16)
+	viper.SetDefault(CfgSyncMaxWindowsPerPeer, 2)
+	viper.SetDefault(CfgSyncMaxInflightWindows, 16)
+
+	viper.SetDefault(CfgMempoolMaxTxCount, 25600)
+	viper.SetDefault(CfgMempoolMaxTxsPerAccount, 256)
+	viper.SetDefault(CfgMempoolTxLifetimeSecs, 60)
+	viper.SetDefault(CfgMempoolReplacementPriceBump, 10)
+	viper.SetDefault(CfgMempoolMaxQueuedTxCount, 4096)
+	viper.SetDefault(CfgMempoolMaxQueuedTxsPerAccount, 64)
+	viper.SetDefault(CfgMempoolQueuedTxLifetimeSecs, 600)
+	viper.SetDefault(CfgMempoolJournalEnabled, true)
+	viper.SetDefault(CfgMempoolJournalRotateIntervalSecs, 3600)
+
+	viper.SetDefault(CfgReputationEnabled, true)
+	viper.SetDefault(CfgReputationDisconnectThreshold, -50)
+	viper.SetDefault(CfgReputationBanThreshold, -100)
+	viper.SetDefault(CfgReputationBanDurationSecs, 3600)
 
 	viper.SetDefault(CfgStorageRollingEnabled, true)
 	viper.SetDefault(CfgStorageStatePruningEnabled, true)
 	viper.SetDefault(CfgStorageStatePruningInterval, 16)
 	viper.SetDefault(CfgStorageStatePruningRetainedBlocks, 2048)
 	viper.SetDefault(CfgStorageStatePruningSkipCheckpoints, true)
+	viper.SetDefault(CfgStorageBackend, "leveldb")
 	viper.SetDefault(CfgStorageLevelDBCacheSize, 256)
 	viper.SetDefault(CfgStorageLevelDBHandles, 16)
 	viper.SetDefault(CfgStorageRollingInterval, 14400) // approximately 1 days by default
+	viper.SetDefault(CfgStorageCompactionMaxNodesPerSecond, 50000)
+	viper.SetDefault(CfgStorageArchiveEnabled, false)
+	viper.SetDefault(CfgStorageAccountHistoryEnabled, false)
 
 	viper.SetDefault(CfgRPCEnabled, false)
 	viper.SetDefault(CfgP2PMessageQueueSize, 512)
@@ -217,8 +309,20 @@ func init() {
 
 	viper.SetDefault(CfgRPCAddress, "0.0.0.0")
 	viper.SetDefault(CfgRPCPort, "16900")
+
+	viper.SetDefault(CfgMetricsEnabled, false)
+	viper.SetDefault(CfgMetricsAddress, "127.0.0.1")
+	viper.SetDefault(CfgMetricsPort, "16910")
+	viper.SetDefault(CfgHealthFinalizationTimeoutSecs, 300)
+	viper.SetDefault(CfgHealthWitnessTimeoutSecs, 5)
+	viper.SetDefault(CfgHealthMaxOrchestratorBacklog, 100)
+	viper.SetDefault(CfgSignerRemoteAddress, "")
+	viper.SetDefault(CfgSignerRemoteTimeoutSecs, 5)
+	viper.SetDefault(CfgSignerRemoteAuthTokenFile, "")
+	viper.SetDefault(CfgSignerDoubleSignProtectionEnabled, true)
 	viper.SetDefault(CfgRPCMaxConnections, 200)
 	viper.SetDefault(CfgRPCTimeoutSecs, 60)
+	viper.SetDefault(CfgRPCTxStatusSubscriptionLifetimeSecs, 600)
 
 	viper.SetDefault(CfgLogLevels, "*:debug")
 	viper.SetDefault(CfgLogPrintSelfID, false)
@@ -0,0 +1,68 @@
+package consensus
+
+import (
+	"time"
+
+	"github.com/thetatoken/theta/dispatcher"
+)
+
+// Clock provides the time to the consensus engine. The engine uses the system clock by default, and the
+// simulations replace it with a virtual clock.
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is a single-shot timer created by a Clock.
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+}
+
+type systemClock struct{}
+
+func (c systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (c systemClock) NewTimer(d time.Duration) Timer {
+	return &systemTimer{timer: time.NewTimer(d)}
+}
+
+type systemTimer struct {
+	timer *time.Timer
+}
+
+func (t *systemTimer) C() <-chan time.Time {
+	return t.timer.C
+}
+
+func (t *systemTimer) Stop() bool {
+	return t.timer.Stop()
+}
+
+// Transport delivers the votes and proposals of the consensus engine. The engine broadcasts them with the
+// dispatcher and adds them to its own message queue by default, and the simulations replace it to control
+// the delivery.
+type Transport interface {
+	// Broadcast sends the message to all the peers.
+	Broadcast(msg dispatcher.DataResponse)
+
+	// Loopback adds a message of the engine itself to its message queue without blocking.
+	Loopback(msg interface{})
+}
+
+type dispatcherTransport struct {
+	dispatcher *dispatcher.Dispatcher
+	engine     *ConsensusEngine
+}
+
+func (t *dispatcherTransport) Broadcast(msg dispatcher.DataResponse) {
+	t.dispatcher.SendData([]string{}, msg)
+}
+
+func (t *dispatcherTransport) Loopback(msg interface{}) {
+	go func() {
+		t.engine.AddMessage(msg)
+	}()
+}
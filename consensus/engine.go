@@ -17,7 +17,6 @@ import (
 	"github.com/thetatoken/theta/common"
 	"github.com/thetatoken/theta/common/result"
 	"github.com/thetatoken/theta/common/util"
-	"github.com/thetatoken/theta/crypto"
 	"github.com/thetatoken/theta/dispatcher"
 	"github.com/thetatoken/theta/rlp"
 	"github.com/thetatoken/theta/store"
@@ -26,6 +25,7 @@ import (
 	scom "github.com/thetatoken/thetasubchain/common"
 	score "github.com/thetatoken/thetasubchain/core"
 	"github.com/thetatoken/thetasubchain/interchain/witness"
+	srep "github.com/thetatoken/thetasubchain/reputation"
 )
 
 var logger = log.WithFields(log.Fields{"prefix": "consensus"})
@@ -36,13 +36,14 @@ var _ score.ConsensusEngine = (*ConsensusEngine)(nil)
 type ConsensusEngine struct {
 	logger *log.Entry
 
-	privateKey *crypto.PrivateKey
+	signer score.Signer
 
 	chain            *sbc.Chain
-	dispatcher       *dispatcher.Dispatcher
+	transport        Transport
 	validatorManager score.ValidatorManager
 	ledger           score.Ledger
 	metachainWitness witness.ChainWitness
+	reputation       *srep.Manager
 
 	incoming        chan interface{}
 	finalizedBlocks chan *score.Block
@@ -55,23 +56,26 @@ type ConsensusEngine struct {
 	stopped bool
 
 	mu         *sync.Mutex
-	voteTimer  *time.Timer
-	epochTimer *time.Timer
+	clock      Clock
+	voteTimer  Timer
+	epochTimer Timer
 
 	voteTimerReady bool
 	blockProcessed bool
+	epochStartTime time.Time
+
+	metrics *engineMetrics
 
 	state *State
 }
 
 // NewConsensusEngine creates a instance of ConsensusEngine.
-func NewConsensusEngine(privateKey *crypto.PrivateKey, db store.Store, chain *sbc.Chain, dispatcher *dispatcher.Dispatcher,
+func NewConsensusEngine(signer score.Signer, db store.Store, chain *sbc.Chain, dispatcher *dispatcher.Dispatcher,
 	validatorManager score.ValidatorManager, metachainWitness witness.ChainWitness) *ConsensusEngine {
 	e := &ConsensusEngine{
-		chain:      chain,
-		dispatcher: dispatcher,
+		chain: chain,
 
-		privateKey: privateKey,
+		signer: signer,
 
 		incoming:        make(chan interface{}, viper.GetInt(common.CfgConsensusMessageQueueSize)),
 		finalizedBlocks: make(chan *score.Block, viper.GetInt(common.CfgConsensusMessageQueueSize)),
@@ -79,6 +83,7 @@ func NewConsensusEngine(privateKey *crypto.PrivateKey, db store.Store, chain *sb
 		wg: &sync.WaitGroup{},
 
 		mu:    &sync.Mutex{},
+		clock: systemClock{},
 		state: NewState(db, chain),
 
 		validatorManager: validatorManager,
@@ -87,7 +92,10 @@ func NewConsensusEngine(privateKey *crypto.PrivateKey, db store.Store, chain *sb
 		blockProcessed: false,
 
 		metachainWitness: metachainWitness,
+
+		metrics: newEngineMetrics(),
 	}
+	e.transport = &dispatcherTransport{dispatcher: dispatcher, engine: e}
 
 	logger = util.GetLoggerForModule("consensus")
 	e.logger = logger
@@ -101,6 +109,21 @@ func (e *ConsensusEngine) SetLedger(ledger score.Ledger) {
 	e.ledger = ledger
 }
 
+// SetReputationManager sets the manager used to penalize peers relaying invalid blocks and votes.
+func (e *ConsensusEngine) SetReputationManager(reputation *srep.Manager) {
+	e.reputation = reputation
+}
+
+// SetClock sets the clock of the timers and the block timestamps, which is the system clock by default.
+func (e *ConsensusEngine) SetClock(clock Clock) {
+	e.clock = clock
+}
+
+// SetTransport sets the transport of the votes and proposals, which is the dispatcher by default.
+func (e *ConsensusEngine) SetTransport(transport Transport) {
+	e.transport = transport
+}
+
 // GetLedger returns the ledger instance attached to the consensus engine
 func (e *ConsensusEngine) GetLedger() score.Ledger {
 	return e.ledger
@@ -108,12 +131,12 @@ func (e *ConsensusEngine) GetLedger() score.Ledger {
 
 // ID returns the identifier of current node.
 func (e *ConsensusEngine) ID() string {
-	return e.privateKey.PublicKey().Address().Hex()
+	return e.signer.Address().Hex()
 }
 
-// PrivateKey returns the private key
-func (e *ConsensusEngine) PrivateKey() *crypto.PrivateKey {
-	return e.privateKey
+// Signer returns the signer of the votes and proposals
+func (e *ConsensusEngine) Signer() score.Signer {
+	return e.signer
 }
 
 // Chain return a pointer to the underlying chain store.
@@ -145,15 +168,20 @@ func (e *ConsensusEngine) Start(ctx context.Context) {
 		}).Fatal("Invalid configuration: max epoch length must be larger than minimal proposal wait")
 	}
 
+	e.restoreState()
+
+	e.wg.Add(1)
+	go e.mainLoop()
+}
+
+// restoreState resets the ledger to the state persisted by the engine before it starts processing messages.
+func (e *ConsensusEngine) restoreState() {
 	// Set ledger state pointer to initial state.
 	lastCC := e.autoRewind(e.state.GetHighestCCBlock())
 	//e.ledger.ResetState(lastCC.Height, lastCC.StateHash)
 	e.ledger.ResetState(lastCC.Block)
 
 	e.checkSyncStatus()
-
-	e.wg.Add(1)
-	go e.mainLoop()
 }
 
 func (e *ConsensusEngine) autoRewind(lastCC *score.ExtendedBlock) *score.ExtendedBlock {
@@ -263,20 +291,33 @@ func (e *ConsensusEngine) mainLoop() {
 				if endEpoch {
 					break Epoch
 				}
-			case <-e.voteTimer.C:
-				e.voteTimerReady = true
-				if e.blockProcessed {
-					e.vote()
-				}
-			case <-e.epochTimer.C:
-				e.logger.WithFields(log.Fields{"e.epoch": e.GetEpoch()}).Debug("Epoch timeout. Repeating epoch")
-				e.vote()
+			case <-e.voteTimer.C():
+				e.handleVoteTimeout()
+			case <-e.epochTimer.C():
+				e.handleEpochTimeout()
 				break Epoch
 			}
 		}
 	}
 }
 
+// handleVoteTimeout is called when the minimal block interval has passed in the current epoch.
+func (e *ConsensusEngine) handleVoteTimeout() {
+	e.voteTimerReady = true
+	if e.blockProcessed {
+		e.vote()
+	}
+}
+
+// handleEpochTimeout is called when the current epoch times out, after which the engine enters a new epoch.
+func (e *ConsensusEngine) handleEpochTimeout() {
+	e.logger.WithFields(log.Fields{"e.epoch": e.GetEpoch()}).Debug("Epoch timeout. Repeating epoch")
+	if !e.blockProcessed {
+		e.metrics.recordProposalMissed()
+	}
+	e.vote()
+}
+
 // enterEpoch is called when engine enters a new epoch.
 func (e *ConsensusEngine) enterEpoch() {
 	logger.Debugf("Enter epoch %v", e.GetEpoch())
@@ -285,15 +326,16 @@ func (e *ConsensusEngine) enterEpoch() {
 	if e.epochTimer != nil {
 		e.epochTimer.Stop()
 	}
-	e.epochTimer = time.NewTimer(time.Duration(viper.GetInt(common.CfgConsensusMaxEpochLength)) * time.Second)
+	e.epochTimer = e.clock.NewTimer(time.Duration(viper.GetInt(common.CfgConsensusMaxEpochLength)) * time.Second)
 
 	if e.voteTimer != nil {
 		e.voteTimer.Stop()
 	}
-	e.voteTimer = time.NewTimer(time.Duration(viper.GetInt(common.CfgConsensusMinBlockInterval)) * time.Second)
+	e.voteTimer = e.clock.NewTimer(time.Duration(viper.GetInt(common.CfgConsensusMinBlockInterval)) * time.Second)
 
 	e.voteTimerReady = false
 	e.blockProcessed = false
+	e.epochStartTime = e.clock.Now()
 }
 
 // GetChannelIDs implements the p2p.MessageHandler interface.
@@ -346,7 +388,7 @@ func (e *ConsensusEngine) checkSyncStatus() error {
 	// current finalized height is at most maxVoteHeight-1
 	currentHeight := uint64(maxVoteHeight - 1)
 
-	e.hasSynced = !isSyncing(e.GetLastFinalizedBlock(), currentHeight)
+	e.hasSynced = !isSyncing(e.GetLastFinalizedBlock(), currentHeight, e.clock.Now())
 
 	return nil
 }
@@ -579,6 +621,10 @@ func (e *ConsensusEngine) handleNormalBlock(eb *score.ExtendedBlock) {
 			"block.Hash": block.Hash().Hex(),
 		}).Warn("Block is invalid")
 		e.chain.MarkBlockInvalid(block.Hash())
+		// Blocks that are merely stale are not held against the peer
+		if block.Height > e.state.GetLastFinalizedBlock().Height {
+			e.reputation.ReportOrigin(block.Hash(), srep.OffenceInvalidBlock)
+		}
 		return
 	}
 	validateBlockTime := time.Since(start1)
@@ -615,6 +661,7 @@ func (e *ConsensusEngine) handleNormalBlock(eb *score.ExtendedBlock) {
 			"block.StateHash": block.StateHash.Hex(),
 		}).Error("Failed to apply block Txs")
 		e.chain.MarkBlockInvalid(block.Hash())
+		e.reputation.ReportOrigin(block.Hash(), srep.OffenceInvalidBlock)
 		return
 	} else if result.IsUndecided() {
 		e.logger.WithFields(log.Fields{
@@ -674,7 +721,7 @@ func (e *ConsensusEngine) handleNormalBlock(eb *score.ExtendedBlock) {
 }
 
 func (e *ConsensusEngine) shouldVote(block common.Hash) bool {
-	return e.shouldVoteByID(e.privateKey.PublicKey().Address(), block)
+	return e.shouldVoteByID(e.signer.Address(), block)
 }
 
 func (e *ConsensusEngine) shouldVoteByID(id common.Address, block common.Hash) bool {
@@ -719,19 +766,26 @@ func (e *ConsensusEngine) vote() {
 			log.Panic(err)
 		}
 		// Recreating vote so that it has updated epoch and signature.
-		vote = e.createVote(block.Block)
+		vote, err = e.createVote(block.Block)
+		if err != nil {
+			e.logger.WithFields(log.Fields{"error": err, "block": block.Hash().Hex()}).Error("Failed to sign vote")
+			return
+		}
 	} else {
-		vote = e.createVote(tip.Block)
+		var err error
+		vote, err = e.createVote(tip.Block)
+		if err != nil {
+			e.logger.WithFields(log.Fields{"error": err, "tip": tip.Hash().Hex()}).Error("Failed to sign vote")
+			return
+		}
 		e.state.SetLastVote(vote)
+		e.metrics.recordVote(e.clock.Now().Sub(e.epochStartTime))
 	}
 	e.logger.WithFields(log.Fields{
 		"vote": vote,
 	}).Debug("Sending vote")
 	e.broadcastVote(vote)
-
-	go func() {
-		e.AddMessage(vote)
-	}()
+	e.transport.Loopback(vote)
 }
 
 func (e *ConsensusEngine) broadcastVote(vote score.Vote) {
@@ -744,10 +798,10 @@ func (e *ConsensusEngine) broadcastVote(vote score.Vote) {
 		ChannelID: common.ChannelIDVote,
 		Payload:   payload,
 	}
-	e.dispatcher.SendData([]string{}, voteMsg)
+	e.transport.Broadcast(voteMsg)
 }
 
-func (e *ConsensusEngine) createVote(block *score.Block) score.Vote {
+func (e *ConsensusEngine) createVote(block *score.Block) (score.Vote, error) {
 	mainchainHeightBigInt, err := e.metachainWitness.GetMainchainBlockHeight()
 	var mainchainHeight uint64
 	if err != nil {
@@ -760,11 +814,11 @@ func (e *ConsensusEngine) createVote(block *score.Block) score.Vote {
 		Block:           block.Hash(),
 		Height:          block.Height,
 		MainchainHeight: mainchainHeight,
-		ID:              e.privateKey.PublicKey().Address(),
+		ID:              e.signer.Address(),
 		Epoch:           e.GetEpoch(),
 	}
-	vote.Sign(e.privateKey)
-	return vote
+	err = e.signer.SignVote(&vote)
+	return vote, err
 }
 
 func (e *ConsensusEngine) validateVote(vote score.Vote) bool {
@@ -772,6 +826,7 @@ func (e *ConsensusEngine) validateVote(vote score.Vote) bool {
 		e.logger.WithFields(log.Fields{
 			"err": res.String(),
 		}).Warn("Ignoring invalid vote")
+		e.reputation.ReportOrigin(vote.Hash(), srep.OffenceInvalidVote)
 		return false
 	}
 	return true
@@ -1101,8 +1156,8 @@ func (e *ConsensusEngine) createProposal(validatorMajorityInTheSameDynasty bool)
 	block.Epoch = e.GetEpoch()
 	block.Parent = parentBlockHash
 	block.Height = tip.Height + 1
-	block.Proposer = e.privateKey.PublicKey().Address()
-	block.Timestamp = big.NewInt(time.Now().Unix())
+	block.Proposer = e.signer.Address()
+	block.Timestamp = big.NewInt(e.clock.Now().Unix())
 	if block.Timestamp.Cmp(minBlockTimestamp) < 0 {
 		block.Timestamp.Set(minBlockTimestamp) // keep the block timestamp monotonically increasing to be compatible with Ethereum, block.timestamp >= parent.timestamp + 1
 	}
@@ -1120,11 +1175,9 @@ func (e *ConsensusEngine) createProposal(validatorMajorityInTheSameDynasty bool)
 	block.StateHash = newRoot
 
 	// Sign block.
-	sig, err := e.privateKey.Sign(block.SignBytes())
-	if err != nil {
-		e.logger.WithFields(log.Fields{"error": err}).Panic("Failed to sign vote")
+	if err := e.signer.SignProposal(block); err != nil {
+		return score.Proposal{}, fmt.Errorf("Failed to sign block: %v", err)
 	}
-	block.SetSignature(sig)
 
 	proposal := score.Proposal{
 		Block:      block,
@@ -1185,11 +1238,8 @@ func (e *ConsensusEngine) propose() {
 		ChannelID: common.ChannelIDProposal,
 		Payload:   payload,
 	}
-	e.dispatcher.SendData([]string{}, proposalMsg)
-
-	go func() {
-		e.AddMessage(proposal.Block)
-	}()
+	e.transport.Broadcast(proposalMsg)
+	e.transport.Loopback(proposal.Block)
 }
 
 func (e *ConsensusEngine) pruneState(currentBlockHeight uint64) {
@@ -1218,11 +1268,11 @@ func (e *ConsensusEngine) State() *State {
 	return e.state
 }
 
-func isSyncing(lastestFinalizedBlock *score.ExtendedBlock, currentHeight uint64) bool {
+func isSyncing(lastestFinalizedBlock *score.ExtendedBlock, currentHeight uint64, now time.Time) bool {
 	if lastestFinalizedBlock == nil {
 		return true
 	}
-	currentTime := big.NewInt(time.Now().Unix())
+	currentTime := big.NewInt(now.Unix())
 	maxDiff := new(big.Int).SetUint64(30) // thirty seconds, about 5 blocks
 	threshold := new(big.Int).Sub(currentTime, maxDiff)
 	isSyncing := lastestFinalizedBlock.Timestamp.Cmp(threshold) < 0
@@ -0,0 +1,53 @@
+package consensus
+
+import (
+	"sync"
+	"time"
+)
+
+// Metrics summarizes the voting and proposing activity of the consensus engine.
+type Metrics struct {
+	ProposalsMissed     uint64  // number of epochs that timed out before a block was processed
+	NumVotes            uint64  // number of new votes cast by this node
+	VoteLatencySecsSum  float64 // total time between entering an epoch and casting the vote
+	LastVoteLatencySecs float64
+}
+
+type engineMetrics struct {
+	mu      *sync.Mutex
+	metrics Metrics
+}
+
+func newEngineMetrics() *engineMetrics {
+	return &engineMetrics{
+		mu: &sync.Mutex{},
+	}
+}
+
+func (em *engineMetrics) recordVote(latency time.Duration) {
+	em.mu.Lock()
+	defer em.mu.Unlock()
+
+	em.metrics.NumVotes++
+	em.metrics.LastVoteLatencySecs = latency.Seconds()
+	em.metrics.VoteLatencySecsSum += latency.Seconds()
+}
+
+func (em *engineMetrics) recordProposalMissed() {
+	em.mu.Lock()
+	defer em.mu.Unlock()
+
+	em.metrics.ProposalsMissed++
+}
+
+func (em *engineMetrics) get() Metrics {
+	em.mu.Lock()
+	defer em.mu.Unlock()
+
+	return em.metrics
+}
+
+// GetMetrics returns the voting and proposing metrics of the engine.
+func (e *ConsensusEngine) GetMetrics() Metrics {
+	return e.metrics.get()
+}
@@ -0,0 +1,94 @@
+package consensus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/common"
+)
+
+func TestSimulationFinalizesBlocks(t *testing.T) {
+	assert := assert.New(t)
+
+	sim := NewSimulation(DefaultSimConfig(1))
+	assert.True(sim.RunUntil(func() bool { return sim.MinFinalizedHeight() >= 10 }, 5*time.Minute))
+	assert.Nil(sim.CheckSafety())
+}
+
+func TestSimulationRandomFaults(t *testing.T) {
+	assert := assert.New(t)
+
+	for seed := int64(1); seed <= 5; seed++ {
+		config := DefaultSimConfig(seed)
+		config.MaxLatency = 3 * time.Second
+		config.DropRate = 0.2
+		config.DuplicateRate = 0.2
+		sim := NewSimulation(config)
+
+		sim.At(30*time.Second, func() { sim.Crash(int(seed) % config.NumNodes) })
+		sim.At(90*time.Second, func() { sim.Restart(int(seed) % config.NumNodes) })
+		sim.RunFor(3 * time.Minute)
+
+		assert.Nil(sim.CheckSafety(), "seed %v", seed)
+		assert.True(sim.MinFinalizedHeight() > 0, "seed %v", seed)
+	}
+}
+
+func TestSimulationPartition(t *testing.T) {
+	assert := assert.New(t)
+
+	sim := NewSimulation(DefaultSimConfig(2))
+	assert.True(sim.RunUntil(func() bool { return sim.MinFinalizedHeight() >= 3 }, 5*time.Minute))
+
+	// Neither half has a two-thirds majority, so no block can be finalized.
+	sim.Partition([]int{0, 1}, []int{2, 3})
+	sim.RunFor(10 * time.Second)
+	height := len(sim.FinalizedChain())
+	sim.RunFor(2 * time.Minute)
+	assert.Equal(height, len(sim.FinalizedChain()))
+
+	sim.Heal()
+	assert.True(sim.RunUntil(func() bool { return len(sim.FinalizedChain()) >= height+3 }, 5*time.Minute))
+	assert.Nil(sim.CheckSafety())
+}
+
+func TestSimulationCrashRestart(t *testing.T) {
+	assert := assert.New(t)
+
+	sim := NewSimulation(DefaultSimConfig(3))
+	sim.Crash(3)
+	assert.True(sim.RunUntil(func() bool { return sim.MinFinalizedHeight() >= 5 }, 5*time.Minute))
+
+	sim.Restart(3)
+	target := sim.FinalizedHeight(0)
+	assert.True(sim.RunUntil(func() bool { return sim.FinalizedHeight(3) >= target }, 5*time.Minute))
+	assert.Nil(sim.CheckSafety())
+}
+
+func TestSimulationSilencedProposer(t *testing.T) {
+	assert := assert.New(t)
+
+	sim := NewSimulation(DefaultSimConfig(4))
+	sim.AddFilter(func(from int, to int, channelID common.ChannelIDEnum) bool {
+		return from == 0 && channelID == common.ChannelIDProposal
+	})
+	assert.True(sim.RunUntil(func() bool { return sim.MinFinalizedHeight() >= 5 }, 5*time.Minute))
+	assert.Nil(sim.CheckSafety())
+}
+
+func TestSimulationDeterminism(t *testing.T) {
+	assert := assert.New(t)
+
+	run := func() []common.Hash {
+		config := DefaultSimConfig(5)
+		config.DropRate = 0.1
+		config.DuplicateRate = 0.1
+		sim := NewSimulation(config)
+		sim.RunFor(2 * time.Minute)
+		return sim.FinalizedChain()
+	}
+	first := run()
+	assert.True(len(first) > 0)
+	assert.Equal(first, run())
+}
@@ -0,0 +1,808 @@
+package consensus
+
+import (
+	"container/heap"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"time"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/result"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/dispatcher"
+	"github.com/thetatoken/theta/rlp"
+	"github.com/thetatoken/theta/store/database"
+	"github.com/thetatoken/theta/store/database/backend"
+	"github.com/thetatoken/theta/store/kvstore"
+
+	sbc "github.com/thetatoken/thetasubchain/blockchain"
+	score "github.com/thetatoken/thetasubchain/core"
+	siu "github.com/thetatoken/thetasubchain/interchain/utils"
+	ssigner "github.com/thetatoken/thetasubchain/signer"
+)
+
+const (
+	simChainID          = "consensus_simulation"
+	simValidatorStake   = 100000000
+	simMaxBlocksPerSync = 100
+)
+
+// SimConfig is the configuration of a consensus simulation.
+type SimConfig struct {
+	NumNodes int
+
+	// Seed seeds all the random choices of the simulation, so that a run can be reproduced with the same seed.
+	Seed int64
+
+	// The latency of each message is uniformly distributed in [MinLatency, MaxLatency]. Messages sent at
+	// about the same time may hence be delivered out of order.
+	MinLatency time.Duration
+	MaxLatency time.Duration
+
+	// DropRate is the probability that a message is lost, and DuplicateRate is the probability that a
+	// message is delivered twice.
+	DropRate      float64
+	DuplicateRate float64
+
+	// SyncInterval is the interval at which the nodes catch up on the blocks they missed from their
+	// reachable peers, which is what netsync does in the nodes.
+	SyncInterval time.Duration
+}
+
+// DefaultSimConfig returns the config of a fault-free simulation of 4 nodes.
+func DefaultSimConfig(seed int64) *SimConfig {
+	return &SimConfig{
+		NumNodes:     4,
+		Seed:         seed,
+		MinLatency:   50 * time.Millisecond,
+		MaxLatency:   500 * time.Millisecond,
+		SyncInterval: 2 * time.Second,
+	}
+}
+
+// MessageFilter decides whether to drop a message sent from one node to another.
+type MessageFilter func(from int, to int, channelID common.ChannelIDEnum) (drop bool)
+
+// Simulation runs multiple consensus engines in one goroutine against a virtual clock and a message bus
+// controlled by the simulation, so that a run is deterministic given its seed. The message bus can drop,
+// delay, reorder and duplicate messages, the nodes can be crashed and restarted, and the network can be
+// partitioned. The finalized blocks of all the nodes are checked for conflicts throughout the run.
+type Simulation struct {
+	config *SimConfig
+	rnd    *rand.Rand
+	clock  *VirtualClock
+	events *simEventQueue
+	seq    uint64
+
+	root         *score.Block
+	validatorSet *score.ValidatorSet
+	nodes        []*SimNode
+
+	partition map[int]int // node index -> group, nodes in different groups cannot communicate
+	filters   []MessageFilter
+
+	finalizedBlocks  map[uint64]common.Hash // height -> hash of the block finalized by any node
+	safetyViolations []string
+}
+
+// SimNode is a validator node in the simulation.
+type SimNode struct {
+	Index  int
+	ID     string
+	Engine *ConsensusEngine
+
+	privKey    *crypto.PrivateKey
+	db         database.Database // survives crashes, as the disk of the node
+	chain      *sbc.Chain
+	crashed    bool
+	generation int // incremented by every restart, to discard the messages looped back before the crash
+
+	passedBlocks     map[common.Hash]bool // blocks passed to the engine, as the dump block cache of netsync
+	finalizedChecked uint64               // height up to which the finalized blocks have been checked
+}
+
+// Crashed returns whether the node is currently crashed.
+func (n *SimNode) Crashed() bool {
+	return n.crashed
+}
+
+// NewSimulation creates a simulation of the given config, with one validator of equal stake per node.
+func NewSimulation(config *SimConfig) *Simulation {
+	sim := &Simulation{
+		config:          config,
+		rnd:             rand.New(rand.NewSource(config.Seed)),
+		clock:           NewVirtualClock(time.Unix(1600000000, 0)),
+		events:          &simEventQueue{},
+		partition:       make(map[int]int),
+		finalizedBlocks: make(map[uint64]common.Hash),
+	}
+
+	sim.validatorSet = score.NewValidatorSet(big.NewInt(0))
+	for i := 0; i < config.NumNodes; i++ {
+		// The keys are derived from the node index, so that the blocks and votes are the same across runs.
+		privKey, err := crypto.PrivateKeyFromBytes(crypto.Keccak256([]byte(fmt.Sprintf("consensus simulation validator %v", i))))
+		if err != nil {
+			logger.Panic(err)
+		}
+		sim.nodes = append(sim.nodes, &SimNode{
+			Index:   i,
+			ID:      privKey.PublicKey().Address().Hex(),
+			privKey: privKey,
+			db:      backend.NewMemDatabase(),
+		})
+		sim.validatorSet.AddValidator(score.NewValidator(privKey.PublicKey().Address().Hex(), big.NewInt(simValidatorStake)))
+	}
+
+	sim.root = score.NewBlock()
+	sim.root.ChainID = simChainID
+	sim.root.Height = score.GenesisBlockHeight
+	sim.root.Epoch = sim.root.Height
+	sim.root.StateHash = crypto.Keccak256Hash([]byte(simChainID))
+	sim.root.Timestamp = big.NewInt(sim.clock.Now().Unix())
+
+	for _, node := range sim.nodes {
+		sim.startNode(node)
+	}
+	if config.SyncInterval > 0 {
+		sim.At(config.SyncInterval, sim.syncNodes)
+	}
+	return sim
+}
+
+// Nodes returns the nodes of the simulation.
+func (sim *Simulation) Nodes() []*SimNode {
+	return sim.nodes
+}
+
+// Now returns the virtual time of the simulation.
+func (sim *Simulation) Now() time.Time {
+	return sim.clock.Now()
+}
+
+// At schedules the action to run after the given duration of virtual time, e.g. to inject a fault.
+func (sim *Simulation) At(after time.Duration, action func()) {
+	sim.schedule(&simEvent{time: sim.clock.Now().Add(after), action: action})
+}
+
+// AddFilter adds a filter to drop selected messages, on top of the random faults.
+func (sim *Simulation) AddFilter(filter MessageFilter) {
+	sim.filters = append(sim.filters, filter)
+}
+
+// ClearFilters removes all the message filters.
+func (sim *Simulation) ClearFilters() {
+	sim.filters = nil
+}
+
+// Partition splits the network into the given groups of nodes, which cannot communicate with each other.
+// The nodes not in any of the groups form another group.
+func (sim *Simulation) Partition(groups ...[]int) {
+	sim.partition = make(map[int]int)
+	for i, group := range groups {
+		for _, idx := range group {
+			sim.partition[idx] = i + 1
+		}
+	}
+}
+
+// Heal removes the network partition.
+func (sim *Simulation) Heal() {
+	sim.partition = make(map[int]int)
+}
+
+// Crash stops the node, which loses all its state but the data persisted in its database.
+func (sim *Simulation) Crash(idx int) {
+	node := sim.nodes[idx]
+	if node.crashed {
+		return
+	}
+	node.crashed = true
+	node.Engine = nil
+	node.chain = nil
+}
+
+// Restart restarts a crashed node from the data persisted in its database.
+func (sim *Simulation) Restart(idx int) {
+	node := sim.nodes[idx]
+	if !node.crashed {
+		return
+	}
+	node.crashed = false
+	node.generation++
+	sim.startNode(node)
+}
+
+// RunFor runs the simulation for the given duration of virtual time.
+func (sim *Simulation) RunFor(d time.Duration) {
+	end := sim.clock.Now().Add(d)
+	for sim.step(end) {
+	}
+	sim.clock.advanceTo(end)
+}
+
+// RunUntil runs the simulation until the condition holds or the given duration of virtual time has passed,
+// and returns whether the condition holds.
+func (sim *Simulation) RunUntil(cond func() bool, d time.Duration) bool {
+	end := sim.clock.Now().Add(d)
+	for !cond() {
+		if !sim.step(end) {
+			sim.clock.advanceTo(end)
+			return cond()
+		}
+	}
+	return true
+}
+
+// FinalizedHeight returns the height of the last block finalized by the node.
+func (sim *Simulation) FinalizedHeight(idx int) uint64 {
+	node := sim.nodes[idx]
+	if node.crashed {
+		return node.finalizedChecked
+	}
+	return node.Engine.GetLastFinalizedBlock().Height
+}
+
+// MinFinalizedHeight returns the lowest last finalized block height among the nodes not crashed.
+func (sim *Simulation) MinFinalizedHeight() uint64 {
+	var height uint64
+	first := true
+	for _, node := range sim.nodes {
+		if node.crashed {
+			continue
+		}
+		if h := sim.FinalizedHeight(node.Index); first || h < height {
+			height = h
+			first = false
+		}
+	}
+	return height
+}
+
+// FinalizedChain returns the hashes of the blocks finalized by any of the nodes, ordered by height.
+func (sim *Simulation) FinalizedChain() []common.Hash {
+	hashes := []common.Hash{}
+	for h := sim.root.Height + 1; ; h++ {
+		hash, ok := sim.finalizedBlocks[h]
+		if !ok {
+			return hashes
+		}
+		hashes = append(hashes, hash)
+	}
+}
+
+// CheckSafety returns an error if any two nodes have finalized conflicting blocks.
+func (sim *Simulation) CheckSafety() error {
+	if len(sim.safetyViolations) > 0 {
+		return fmt.Errorf("conflicting finalized blocks: %v", sim.safetyViolations)
+	}
+	return nil
+}
+
+func (sim *Simulation) startNode(node *SimNode) {
+	store := kvstore.NewKVStore(node.db)
+	node.chain = sbc.NewChain(simChainID, store, sim.root)
+
+	validatorManager := NewRotatingValidatorManager()
+	engine := NewConsensusEngine(ssigner.NewLocalSigner(node.privKey, nil), store, node.chain, nil, validatorManager, &simWitness{})
+	validatorManager.SetConsensusEngine(engine)
+	engine.SetLedger(&simLedger{validatorSet: sim.validatorSet})
+	engine.SetClock(sim.clock)
+	engine.SetTransport(&simTransport{sim: sim, node: node})
+	engine.restoreState()
+
+	node.Engine = engine
+	node.passedBlocks = make(map[common.Hash]bool)
+	sim.enterEpoch(node)
+}
+
+// step runs the next event if it is due before the end time, and returns false otherwise.
+func (sim *Simulation) step(end time.Time) bool {
+	var next *simEvent
+	if sim.events.Len() > 0 {
+		next = (*sim.events)[0]
+	}
+	timerNode, timer, isVoteTimer := sim.nextTimer()
+	if timer != nil && (next == nil || timer.deadline.Before(next.time)) {
+		if timer.deadline.After(end) {
+			return false
+		}
+		sim.clock.advanceTo(timer.deadline)
+		timer.fired = true
+		if isVoteTimer {
+			timerNode.Engine.handleVoteTimeout()
+		} else {
+			timerNode.Engine.handleEpochTimeout()
+			sim.enterEpoch(timerNode)
+		}
+		sim.checkFinalizedBlocks(timerNode)
+		return true
+	}
+	if next == nil || next.time.After(end) {
+		return false
+	}
+
+	heap.Pop(sim.events)
+	sim.clock.advanceTo(next.time)
+	if next.action != nil {
+		next.action()
+	} else {
+		sim.deliver(next.msg)
+	}
+	return true
+}
+
+// nextTimer returns the active timer with the earliest deadline. The ties are broken by the node index,
+// with the vote timer before the epoch timer.
+func (sim *Simulation) nextTimer() (*SimNode, *virtualTimer, bool) {
+	var nextNode *SimNode
+	var next *virtualTimer
+	var isVoteTimer bool
+	for _, node := range sim.nodes {
+		if node.crashed {
+			continue
+		}
+		for _, t := range []Timer{node.Engine.voteTimer, node.Engine.epochTimer} {
+			timer, ok := t.(*virtualTimer)
+			if !ok || !timer.active() {
+				continue
+			}
+			if next == nil || timer.deadline.Before(next.deadline) {
+				nextNode, next, isVoteTimer = node, timer, t == node.Engine.voteTimer
+			}
+		}
+	}
+	return nextNode, next, isVoteTimer
+}
+
+func (sim *Simulation) enterEpoch(node *SimNode) {
+	node.Engine.enterEpoch()
+	node.Engine.propose()
+}
+
+func (sim *Simulation) schedule(event *simEvent) {
+	sim.seq++
+	event.seq = sim.seq
+	heap.Push(sim.events, event)
+}
+
+// send sends the message over the simulated network, subject to the partition, the filters and the
+// random faults.
+func (sim *Simulation) send(from int, to int, msg dispatcher.DataResponse) {
+	if sim.partition[from] != sim.partition[to] {
+		return
+	}
+	for _, filter := range sim.filters {
+		if filter(from, to, msg.ChannelID) {
+			return
+		}
+	}
+	if sim.rnd.Float64() < sim.config.DropRate {
+		return
+	}
+	copies := 1
+	if sim.rnd.Float64() < sim.config.DuplicateRate {
+		copies = 2
+	}
+	for i := 0; i < copies; i++ {
+		sim.schedule(&simEvent{
+			time: sim.clock.Now().Add(sim.latency()),
+			msg:  &simMessage{from: from, to: to, data: msg},
+		})
+	}
+}
+
+func (sim *Simulation) latency() time.Duration {
+	latency := sim.config.MinLatency
+	if spread := sim.config.MaxLatency - sim.config.MinLatency; spread > 0 {
+		latency += time.Duration(sim.rnd.Int63n(int64(spread) + 1))
+	}
+	return latency
+}
+
+// deliver delivers the message to the node, which handles it the same way as netsync does.
+func (sim *Simulation) deliver(msg *simMessage) {
+	node := sim.nodes[msg.to]
+	if node.crashed {
+		return
+	}
+	if msg.loopback != nil {
+		if msg.generation == node.generation {
+			sim.process(node, msg.loopback)
+		}
+		return
+	}
+
+	switch msg.data.ChannelID {
+	case common.ChannelIDVote:
+		vote := score.Vote{}
+		if err := rlp.DecodeBytes(msg.data.Payload, &vote); err != nil {
+			logger.Panicf("Failed to decode vote: %v", err)
+		}
+		sim.handleVote(node, vote)
+	case common.ChannelIDProposal:
+		proposal := &score.Proposal{}
+		if err := rlp.DecodeBytes(msg.data.Payload, proposal); err != nil {
+			logger.Panicf("Failed to decode proposal: %v", err)
+		}
+		if proposal.Votes != nil {
+			for _, vote := range proposal.Votes.Votes() {
+				sim.handleVote(node, vote)
+			}
+		}
+		sim.handleBlock(node, proposal.Block)
+	case common.ChannelIDBlock:
+		block := score.NewBlock()
+		if err := rlp.DecodeBytes(msg.data.Payload, block); err != nil {
+			logger.Panicf("Failed to decode block: %v", err)
+		}
+		sim.handleBlock(node, block)
+	}
+}
+
+func (sim *Simulation) handleVote(node *SimNode, vote score.Vote) {
+	for _, v := range node.chain.FindVotesByHash(vote.Block).Votes() {
+		if v.Block == vote.Block && v.Epoch == vote.Epoch && v.Height == vote.Height && v.ID == vote.ID {
+			return
+		}
+	}
+	if b, err := node.chain.FindBlock(vote.Block); err == nil && b.Status == score.BlockStatusDisposed {
+		return
+	}
+	sim.process(node, vote)
+}
+
+func (sim *Simulation) handleBlock(node *SimNode, block *score.Block) {
+	if eb, err := node.chain.FindBlock(block.Hash()); err == nil && !eb.Status.IsPending() {
+		return
+	}
+	if res := block.Validate(node.chain.ChainID); res.IsError() {
+		return
+	}
+	node.chain.AddBlock(block)
+	sim.passReadyBlocks(node)
+}
+
+// passReadyBlocks passes the pending blocks whose parents have been processed to the engine.
+func (sim *Simulation) passReadyBlocks(node *SimNode) {
+	for passed := true; passed && !node.crashed; {
+		passed = false
+		lfb := node.Engine.GetLastFinalizedBlock()
+		parents := []*score.ExtendedBlock{lfb}
+		for height := lfb.Height + 1; ; height++ {
+			blocks := node.chain.FindBlocksByHeight(height)
+			if len(blocks) == 0 {
+				break
+			}
+			for _, block := range blocks {
+				if node.passedBlocks[block.Hash()] || !block.Status.IsPending() {
+					continue
+				}
+				for _, parent := range parents {
+					if parent.Hash() == block.Parent && parent.Status.IsValid() {
+						node.passedBlocks[block.Hash()] = true
+						sim.process(node, block.Block)
+						passed = true
+						break
+					}
+				}
+			}
+			parents = node.chain.FindBlocksByHeight(height)
+		}
+	}
+}
+
+func (sim *Simulation) process(node *SimNode, msg interface{}) {
+	if endEpoch := node.Engine.processMessage(msg); endEpoch {
+		sim.enterEpoch(node)
+	}
+	sim.checkFinalizedBlocks(node)
+}
+
+// syncNodes sends each node the blocks it misses from its peers, and schedules the next round.
+func (sim *Simulation) syncNodes() {
+	for _, node := range sim.nodes {
+		if node.crashed {
+			continue
+		}
+		lfbHeight := node.Engine.GetLastFinalizedBlock().Height
+		for _, peer := range sim.nodes {
+			if peer == node || peer.crashed {
+				continue
+			}
+			for height := lfbHeight + 1; height <= lfbHeight+simMaxBlocksPerSync; height++ {
+				blocks := peer.chain.FindBlocksByHeight(height)
+				if len(blocks) == 0 {
+					break
+				}
+				for _, block := range blocks {
+					if !block.Status.IsValid() {
+						continue
+					}
+					if eb, err := node.chain.FindBlock(block.Hash()); err == nil && !eb.Status.IsPending() {
+						continue
+					}
+					payload, err := rlp.EncodeToBytes(block.Block)
+					if err != nil {
+						logger.Panicf("Failed to encode block: %v", err)
+					}
+					sim.send(peer.Index, node.Index, dispatcher.DataResponse{ChannelID: common.ChannelIDBlock, Payload: payload})
+				}
+			}
+		}
+	}
+	sim.At(sim.config.SyncInterval, sim.syncNodes)
+}
+
+// checkFinalizedBlocks records the blocks newly finalized by the node, and any conflict with the blocks
+// finalized by the other nodes.
+func (sim *Simulation) checkFinalizedBlocks(node *SimNode) {
+	if node.crashed {
+		return
+	}
+	for drained := false; !drained; {
+		select {
+		case <-node.Engine.FinalizedBlocks():
+		default:
+			drained = true
+		}
+	}
+
+	lfb := node.Engine.GetLastFinalizedBlock()
+	if lfb.Height <= node.finalizedChecked {
+		return
+	}
+	for block := lfb; block.Height > node.finalizedChecked && block.Height > sim.root.Height; {
+		if hash, ok := sim.finalizedBlocks[block.Height]; !ok {
+			sim.finalizedBlocks[block.Height] = block.Hash()
+		} else if hash != block.Hash() {
+			sim.safetyViolations = append(sim.safetyViolations, fmt.Sprintf("node %v finalized %v at height %v, conflicting with %v",
+				node.Index, block.Hash().Hex(), block.Height, hash.Hex()))
+		}
+		parent, err := node.chain.FindBlock(block.Parent)
+		if err != nil {
+			logger.Panicf("Failed to find the parent of finalized block %v: %v", block.Hash().Hex(), err)
+		}
+		block = parent
+	}
+	node.finalizedChecked = lfb.Height
+}
+
+//
+// -------------------------------- Virtual clock ----------------------------------
+//
+
+// VirtualClock is a clock whose time only moves when advanced by the simulation.
+type VirtualClock struct {
+	now    time.Time
+	timers []*virtualTimer
+}
+
+// NewVirtualClock creates a virtual clock starting at the given time.
+func NewVirtualClock(start time.Time) *VirtualClock {
+	return &VirtualClock{now: start}
+}
+
+// Now implements the Clock interface.
+func (c *VirtualClock) Now() time.Time {
+	return c.now
+}
+
+// NewTimer implements the Clock interface.
+func (c *VirtualClock) NewTimer(d time.Duration) Timer {
+	t := &virtualTimer{deadline: c.now.Add(d), c: make(chan time.Time, 1)}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+// advanceTo moves the time forward, and sends the time on the channels of the timers that are due. The
+// simulation runs the timeouts itself, in a deterministic order, instead of reading the channels.
+func (c *VirtualClock) advanceTo(t time.Time) {
+	if t.After(c.now) {
+		c.now = t
+	}
+	timers := c.timers[:0]
+	for _, timer := range c.timers {
+		if timer.stopped {
+			continue
+		}
+		if timer.deadline.After(c.now) {
+			timers = append(timers, timer)
+			continue
+		}
+		timer.c <- timer.deadline
+	}
+	c.timers = timers
+}
+
+type virtualTimer struct {
+	deadline time.Time
+	c        chan time.Time
+	stopped  bool
+	fired    bool // set when the simulation runs the timeout
+}
+
+func (t *virtualTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *virtualTimer) Stop() bool {
+	wasActive := t.active()
+	t.stopped = true
+	return wasActive
+}
+
+func (t *virtualTimer) active() bool {
+	return !t.stopped && !t.fired
+}
+
+//
+// -------------------------------- Message bus ----------------------------------
+//
+
+type simMessage struct {
+	from int
+	to   int
+	data dispatcher.DataResponse
+
+	loopback   interface{} // message of the node to itself, delivered without going through the network
+	generation int
+}
+
+type simEvent struct {
+	time   time.Time
+	seq    uint64
+	msg    *simMessage
+	action func()
+}
+
+// simEventQueue orders the events by time, and by the order they were scheduled for the same time.
+type simEventQueue []*simEvent
+
+func (q simEventQueue) Len() int { return len(q) }
+
+func (q simEventQueue) Less(i, j int) bool {
+	if !q[i].time.Equal(q[j].time) {
+		return q[i].time.Before(q[j].time)
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q simEventQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *simEventQueue) Push(x interface{}) { *q = append(*q, x.(*simEvent)) }
+
+func (q *simEventQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	event := old[n-1]
+	*q = old[:n-1]
+	return event
+}
+
+type simTransport struct {
+	sim  *Simulation
+	node *SimNode
+}
+
+func (t *simTransport) Broadcast(msg dispatcher.DataResponse) {
+	for _, peer := range t.sim.nodes {
+		if peer != t.node {
+			t.sim.send(t.node.Index, peer.Index, msg)
+		}
+	}
+}
+
+func (t *simTransport) Loopback(msg interface{}) {
+	t.sim.schedule(&simEvent{
+		time: t.sim.clock.Now(),
+		msg:  &simMessage{from: t.node.Index, to: t.node.Index, loopback: msg, generation: t.node.generation},
+	})
+}
+
+//
+// -------------------------------- Ledger and witness stubs ----------------------------------
+//
+
+// simLedger is a ledger without transactions, whose state hash is derived from the parent state hash and
+// the block height, so that the engines can validate each other's blocks.
+type simLedger struct {
+	validatorSet *score.ValidatorSet
+	currentBlock *score.Block
+}
+
+var _ score.Ledger = (*simLedger)(nil)
+
+func (l *simLedger) stateHash(parentStateHash common.Hash, height uint64) common.Hash {
+	heightBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(heightBytes, height)
+	return crypto.Keccak256Hash(parentStateHash[:], heightBytes)
+}
+
+func (l *simLedger) GetCurrentBlock() *score.Block {
+	return l.currentBlock
+}
+
+func (l *simLedger) GetDynasty() *big.Int {
+	return big.NewInt(0)
+}
+
+func (l *simLedger) ScreenTxUnsafe(rawTx common.Bytes) result.Result {
+	return result.OK
+}
+
+func (l *simLedger) ScreenTx(rawTx common.Bytes) (*score.TxInfo, result.Result) {
+	return nil, result.OK
+}
+
+func (l *simLedger) ScreenReplacementTx(rawTx common.Bytes) (*score.TxInfo, result.Result) {
+	return nil, result.OK
+}
+
+func (l *simLedger) ScreenFutureTx(rawTx common.Bytes) (*score.TxInfo, result.Result) {
+	return nil, result.OK
+}
+
+func (l *simLedger) ResetScreenedState() {}
+
+func (l *simLedger) ProposeBlockTxs(block *score.Block, shouldIncludeValidatorUpdateTxs bool) (common.Hash, []common.Bytes, result.Result) {
+	return l.stateHash(l.currentBlock.StateHash, block.Height), []common.Bytes{}, result.OK
+}
+
+func (l *simLedger) ApplyBlockTxs(block *score.Block) result.Result {
+	if block.StateHash != l.stateHash(l.currentBlock.StateHash, block.Height) {
+		return result.Error("State hash mismatch")
+	}
+	l.currentBlock = block
+	return result.OK
+}
+
+func (l *simLedger) ApplyBlockTxsForChainCorrection(block *score.Block) (common.Hash, result.Result) {
+	return block.StateHash, result.OK
+}
+
+func (l *simLedger) ResetState(block *score.Block) result.Result {
+	l.currentBlock = block
+	return result.OK
+}
+
+func (l *simLedger) FinalizeState(height uint64, rootHash common.Hash) result.Result {
+	return result.OK
+}
+
+func (l *simLedger) GetFinalizedValidatorSet(blockHash common.Hash, isNext bool) (*score.ValidatorSet, error) {
+	return l.validatorSet.Copy(), nil
+}
+
+func (l *simLedger) PruneState(endHeight uint64) error {
+	return nil
+}
+
+func (l *simLedger) GetTokenBankContractAddress(tokenType score.CrossChainTokenType) *common.Address {
+	return nil
+}
+
+// simWitness witnesses a mainchain which stays at the genesis dynasty.
+type simWitness struct{}
+
+func (w *simWitness) Start(ctx context.Context) {}
+
+func (w *simWitness) Stop() {}
+
+func (w *simWitness) Wait() {}
+
+func (w *simWitness) SetSubchainTokenBanks(ledger score.Ledger) {}
+
+func (w *simWitness) GetMainchainBlockHeight() (*big.Int, error) {
+	return big.NewInt(0), nil
+}
+
+func (w *simWitness) GetValidatorSetByDynasty(dynasty *big.Int) (*score.ValidatorSet, error) {
+	return nil, fmt.Errorf("no validator set update in the simulation")
+}
+
+func (w *simWitness) GetInterChainEventCache() *siu.InterChainEventCache {
+	return nil
+}
@@ -32,6 +32,9 @@ type Ledger interface {
 	GetDynasty() *big.Int
 	ScreenTxUnsafe(rawTx common.Bytes) result.Result
 	ScreenTx(rawTx common.Bytes) (priority *TxInfo, res result.Result)
+	ScreenReplacementTx(rawTx common.Bytes) (priority *TxInfo, res result.Result)
+	ScreenFutureTx(rawTx common.Bytes) (priority *TxInfo, res result.Result)
+	ResetScreenedState()
 	ProposeBlockTxs(block *Block, shouldIncludeValidatorUpdateTxs bool) (stateRootHash common.Hash, blockRawTxs []common.Bytes, res result.Result)
 	ApplyBlockTxs(block *Block) result.Result
 	ApplyBlockTxsForChainCorrection(block *Block) (common.Hash, result.Result)
@@ -0,0 +1,30 @@
+package core
+
+import (
+	"math/big"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/ledger/types"
+	ethtypes "github.com/thetatoken/thetasubchain/eth/core/types"
+)
+
+// Signer signs the votes, block proposals and transactions on behalf of the validator. The
+// private key may be held in the node process, or by an external signing service.
+type Signer interface {
+	// Address returns the address of the validator key.
+	Address() common.Address
+
+	// SignVote signs the vote. It fails if the vote conflicts with a vote signed earlier.
+	SignVote(vote *Vote) error
+
+	// SignProposal signs the header of a proposed block. It fails if the block conflicts
+	// with a block proposed earlier.
+	SignProposal(block *Block) error
+
+	// SignTx signs a native transaction of the subchain.
+	SignTx(chainID string, tx types.Tx) (*crypto.Signature, error)
+
+	// SignEthTx signs an Ethereum transaction sent to the mainchain or the subchain EVM.
+	SignEthTx(chainID *big.Int, tx *ethtypes.Transaction) (*ethtypes.Transaction, error)
+}
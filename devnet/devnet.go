@@ -0,0 +1,403 @@
+package devnet
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/crypto"
+	p2psim "github.com/thetatoken/theta/p2p/simulation"
+	msgl "github.com/thetatoken/theta/p2pl/messenger"
+	"github.com/thetatoken/theta/store/database/backend"
+
+	scom "github.com/thetatoken/thetasubchain/common"
+	score "github.com/thetatoken/thetasubchain/core"
+	ec "github.com/thetatoken/thetasubchain/eth/ethclient"
+	scta "github.com/thetatoken/thetasubchain/interchain/contracts/accessors"
+	"github.com/thetatoken/thetasubchain/interchain/orchestrator"
+	siu "github.com/thetatoken/thetasubchain/interchain/utils"
+	"github.com/thetatoken/thetasubchain/interchain/witness"
+	"github.com/thetatoken/thetasubchain/node"
+	ssigner "github.com/thetatoken/thetasubchain/signer"
+	ssnst "github.com/thetatoken/thetasubchain/snapshot"
+	srollingdb "github.com/thetatoken/thetasubchain/store/rollingdb"
+)
+
+var logger *log.Entry = log.WithFields(log.Fields{"prefix": "devnet"})
+
+const (
+	DefaultMainchainID    = "privatenet"
+	DefaultSubchainID     = "tsub360777"
+	DefaultNumValidators  = 4
+	DefaultValidatorStake = 100000000
+)
+
+// The addresses of the token banks of the simulated mainchain
+var mainchainTokenBankAddrs = map[score.CrossChainTokenType]common.Address{
+	score.CrossChainTokenTypeTFuel:   common.HexToAddress("0x0000000000000000000000000000000000001000"),
+	score.CrossChainTokenTypeTNT20:   common.HexToAddress("0x0000000000000000000000000000000000001001"),
+	score.CrossChainTokenTypeTNT721:  common.HexToAddress("0x0000000000000000000000000000000000001002"),
+	score.CrossChainTokenTypeTNT1155: common.HexToAddress("0x0000000000000000000000000000000000001003"),
+}
+
+// Config is the configuration of a devnet.
+type Config struct {
+	MainchainID   string
+	SubchainID    string
+	NumValidators int
+
+	// ValidatorKeys are the keys of the validators, generated if not provided. If provided, there must be
+	// exactly NumValidators keys.
+	ValidatorKeys []*crypto.PrivateKey
+
+	// DataPath is the directory for the genesis snapshot and the node data. A temporary directory is
+	// created, and removed by Cleanup(), if not provided.
+	DataPath string
+
+	// RPCEnabled enables the RPC and metrics services on the first node, using the ports configured by
+	// rpc.port and metrics.port. The other nodes do not serve RPC, since they share the same config.
+	RPCEnabled bool
+}
+
+// Devnet runs a subchain of multiple validator nodes in one process. The nodes are connected by an
+// in-memory network, and witness a simulated mainchain instead of a real one. The orchestrator of each
+// node calls the token banks through in-process ETH RPC backends, so the vouchers are minted on the
+// subchain for the tokens locked on the simulated mainchain, and the simulated mainchain token banks
+// unlock the tokens for the vouchers burned on the subchain.
+type Devnet struct {
+	Mainchain           *witness.SimulatedMainchain
+	Nodes               []*node.Node
+	ValidatorKeys       []*crypto.PrivateKey
+	GenesisSnapshotPath string
+
+	config                *Config
+	simnet                *p2psim.Simnet
+	dataPath              string
+	removeDataPath        bool
+	mainchainEthRpcClient *ec.Client
+	subchainEthRpcClients []*ec.Client
+
+	// Life cycle
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewDevnet creates a devnet with the given config. The genesis snapshot is generated with the validators
+// as the initial validator set, which is also registered with the simulated mainchain for the genesis dynasty.
+func NewDevnet(config *Config) (*Devnet, error) {
+	if config.MainchainID == "" {
+		config.MainchainID = DefaultMainchainID
+	}
+	if config.SubchainID == "" {
+		config.SubchainID = DefaultSubchainID
+	}
+	if config.NumValidators <= 0 {
+		config.NumValidators = DefaultNumValidators
+	}
+
+	keys := config.ValidatorKeys
+	if len(keys) == 0 {
+		for i := 0; i < config.NumValidators; i++ {
+			privKey, _, err := crypto.GenerateKeyPair()
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, privKey)
+		}
+	} else if len(keys) != config.NumValidators {
+		return nil, fmt.Errorf("%v validator keys provided for %v validators", len(keys), config.NumValidators)
+	}
+
+	d := &Devnet{
+		ValidatorKeys: keys,
+		config:        config,
+		simnet:        p2psim.NewSimnetWithHandler(nil),
+		dataPath:      config.DataPath,
+	}
+	if d.dataPath == "" {
+		dataPath, err := ioutil.TempDir("", "thetasubchain_devnet")
+		if err != nil {
+			return nil, err
+		}
+		d.dataPath = dataPath
+		d.removeDataPath = true
+	}
+
+	validatorSet := score.NewValidatorSet(big.NewInt(0))
+	for _, key := range keys {
+		validatorSet.AddValidator(score.NewValidator(key.PublicKey().Address().Hex(), big.NewInt(DefaultValidatorStake)))
+	}
+
+	root, err := d.generateGenesis(validatorSet)
+	if err != nil {
+		d.Cleanup()
+		return nil, err
+	}
+	d.Mainchain = witness.NewSimulatedMainchain(config.MainchainID, config.SubchainID, validatorSet)
+	mainchainBackend, err := newMainchainEthRpcBackend(d.Mainchain, scom.MapChainID(config.MainchainID), mainchainTokenBankAddrs)
+	if err != nil {
+		d.Cleanup()
+		return nil, err
+	}
+	d.mainchainEthRpcClient, err = newEthRpcClient("mainchain", mainchainBackend.handle)
+	if err != nil {
+		d.Cleanup()
+		return nil, err
+	}
+
+	// All the nodes share the global config, which is adjusted for running the nodes in one process.
+	viper.Set(common.CfgGenesisChainID, root.ChainID)
+	viper.Set(scom.CfgSubchainID, scom.MapChainID(config.SubchainID).Int64())
+	viper.Set(common.CfgStorageRollingEnabled, false)
+	viper.Set(scom.CfgMainchainTFuelTokenBankContractAddress, mainchainTokenBankAddrs[score.CrossChainTokenTypeTFuel].Hex())
+	viper.Set(scom.CfgMainchainTNT20TokenBankContractAddress, mainchainTokenBankAddrs[score.CrossChainTokenTypeTNT20].Hex())
+	viper.Set(scom.CfgMainchainTNT721TokenBankContractAddress, mainchainTokenBankAddrs[score.CrossChainTokenTypeTNT721].Hex())
+	viper.Set(scom.CfgMainchainTNT1155TokenBankContractAddress, mainchainTokenBankAddrs[score.CrossChainTokenTypeTNT1155].Hex())
+	for i, key := range keys {
+		viper.Set(common.CfgRPCEnabled, config.RPCEnabled && i == 0)
+		viper.Set(scom.CfgMetricsEnabled, config.RPCEnabled && i == 0)
+
+		n, err := d.newNode(i, key, root)
+		if err != nil {
+			d.Cleanup()
+			return nil, err
+		}
+		d.Nodes = append(d.Nodes, n)
+	}
+
+	return d, nil
+}
+
+func (d *Devnet) generateGenesis(validatorSet *score.ValidatorSet) (*score.Block, error) {
+	admin := d.ValidatorKeys[0].PublicKey().Address()
+	db, sv, metadata, err := ssnst.GenerateGenesisSnapshot(d.config.MainchainID, d.config.SubchainID, validatorSet, admin, admin)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate the genesis snapshot: %v", err)
+	}
+	d.GenesisSnapshotPath = path.Join(d.dataPath, "genesis")
+	if err := ssnst.WriteGenesisSnapshot(db, sv, metadata, d.GenesisSnapshotPath); err != nil {
+		return nil, fmt.Errorf("failed to write the genesis snapshot: %v", err)
+	}
+	// The genesis block is validated against the configured genesis hash, which is the one just generated.
+	viper.Set(common.CfgGenesisHash, metadata.TailTrio.Second.Header.Hash().Hex())
+	snapshotBlockHeader, err := ssnst.ValidateSnapshot(d.GenesisSnapshotPath, "", "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate the genesis snapshot: %v", err)
+	}
+	return &score.Block{BlockHeader: snapshotBlockHeader}, nil
+}
+
+func (d *Devnet) newNode(idx int, privKey *crypto.PrivateKey, root *score.Block) (*node.Node, error) {
+	nodePath := path.Join(d.dataPath, fmt.Sprintf("node%v", idx))
+	if err := os.MkdirAll(path.Join(nodePath, "db"), 0700); err != nil {
+		return nil, err
+	}
+
+	db := backend.NewMemDatabase()
+	guard, err := ssigner.NewGuard(path.Join(nodePath, "signer", "sign_state.json"))
+	if err != nil {
+		return nil, err
+	}
+	signer := ssigner.NewLocalSigner(privKey, guard)
+	network := d.simnet.AddEndpoint(privKey.PublicKey().Address().Hex())
+
+	subchainBackend := &subchainEthRpcBackend{subchainID: scom.MapChainID(d.config.SubchainID)}
+	subchainEthRpcClient, err := newEthRpcClient(fmt.Sprintf("subchain-node%v", idx), subchainBackend.handle)
+	if err != nil {
+		return nil, err
+	}
+	d.subchainEthRpcClients = append(d.subchainEthRpcClients, subchainEthRpcClient)
+	mainchainWitness := witness.NewSimulatedMetachainWitnessForMainchain(d.Mainchain, siu.NewInterChainEventCache(db), subchainEthRpcClient)
+	chainOrchestrator := orchestrator.NewOrchestratorWithEthRpcClients(db, viper.GetInt(scom.CfgSubchainUpdateIntervalInMilliseconds),
+		mainchainWitness.GetInterChainEventCache(), mainchainWitness, signer, d.mainchainEthRpcClient, subchainEthRpcClient)
+
+	params := &node.Params{
+		ChainID:          root.ChainID,
+		Signer:           signer,
+		Root:             root,
+		NetworkOld:       network,
+		Network:          (*msgl.Messenger)(nil),
+		DB:               db,
+		RollingDB:        srollingdb.NewRollingDB(nodePath, db),
+		SnapshotPath:     d.GenesisSnapshotPath,
+		MainchainWitness: mainchainWitness,
+		Orchestrator:     chainOrchestrator,
+	}
+	n := node.NewNode(params)
+	subchainBackend.node = n
+	return n, nil
+}
+
+// Start starts the in-memory network and all the nodes.
+func (d *Devnet) Start(ctx context.Context) {
+	c, cancel := context.WithCancel(ctx)
+	d.ctx = c
+	d.cancel = cancel
+
+	d.simnet.Start(d.ctx)
+	for _, n := range d.Nodes {
+		n.Start(d.ctx)
+	}
+	logger.Infof("Started devnet with %v validators, data path: %v", len(d.Nodes), d.dataPath)
+}
+
+// Stop notifies all the nodes to stop without blocking.
+func (d *Devnet) Stop() {
+	if d.cancel != nil {
+		d.cancel()
+	}
+}
+
+// Wait blocks until all the nodes stop.
+func (d *Devnet) Wait() {
+	wg := &sync.WaitGroup{}
+	for _, n := range d.Nodes {
+		wg.Add(1)
+		go func(n *node.Node) {
+			defer wg.Done()
+			n.Wait()
+		}(n)
+	}
+	wg.Wait()
+}
+
+// Cleanup removes the data of the devnet if it was created in a temporary directory.
+func (d *Devnet) Cleanup() {
+	if d.removeDataPath {
+		os.RemoveAll(d.dataPath)
+	}
+}
+
+// DataPath returns the directory of the genesis snapshot and the node data.
+func (d *Devnet) DataPath() string {
+	return d.dataPath
+}
+
+// GetFinalizedHeight returns the lowest last finalized block height among the nodes.
+func (d *Devnet) GetFinalizedHeight() uint64 {
+	var height uint64
+	for i, n := range d.Nodes {
+		h := n.Consensus.GetLastFinalizedBlock().Height
+		if i == 0 || h < height {
+			height = h
+		}
+	}
+	return height
+}
+
+// WaitForHeight blocks until all the nodes have finalized the block at the given height.
+func (d *Devnet) WaitForHeight(height uint64, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		if d.GetFinalizedHeight() >= height {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out waiting for height %v, finalized height: %v", height, d.GetFinalizedHeight())
+		}
+		<-ticker.C
+	}
+}
+
+// WaitForEvent blocks until all the nodes have witnessed the given inter-chain message event.
+func (d *Devnet) WaitForEvent(event *score.InterChainMessageEvent, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		witnessed := true
+		for _, n := range d.Nodes {
+			exists, err := n.InterChainEventCache.Exists(event.SourceChainID, event.Type, event.Nonce)
+			if err != nil || !exists {
+				witnessed = false
+				break
+			}
+		}
+		if witnessed {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out waiting for the event %v", event)
+		}
+		<-ticker.C
+	}
+}
+
+// WaitForVoucherMint blocks until the token banks of all the nodes have processed the given token lock event
+// of the simulated mainchain, i.e. the vouchers have been minted on the subchain.
+func (d *Devnet) WaitForVoucherMint(event *score.InterChainMessageEvent, timeout time.Duration) error {
+	var tokenType score.CrossChainTokenType
+	switch event.Type {
+	case score.IMCEventTypeCrossChainTokenLockTFuel:
+		tokenType = score.CrossChainTokenTypeTFuel
+	case score.IMCEventTypeCrossChainTokenLockTNT20:
+		tokenType = score.CrossChainTokenTypeTNT20
+	case score.IMCEventTypeCrossChainTokenLockTNT721:
+		tokenType = score.CrossChainTokenTypeTNT721
+	case score.IMCEventTypeCrossChainTokenLockTNT1155:
+		tokenType = score.CrossChainTokenTypeTNT1155
+	default:
+		return fmt.Errorf("%v is not a token lock event", event)
+	}
+
+	deadline := time.Now().Add(timeout)
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		minted := true
+		for i, n := range d.Nodes {
+			tokenBank, err := scta.NewTokenBank(*n.Ledger.GetTokenBankContractAddress(tokenType), d.subchainEthRpcClients[i])
+			if err != nil {
+				return err
+			}
+			nonce, err := tokenBank.GetMaxProcessedTokenLockNonce(nil, event.SourceChainID)
+			if err != nil || nonce.Cmp(event.Nonce) < 0 {
+				minted = false
+				break
+			}
+		}
+		if minted {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out waiting for the vouchers of the event %v", event)
+		}
+		<-ticker.C
+	}
+}
+
+// SubchainEthRpcClient returns the in-process ETH RPC client of the subchain served by the node at the given index.
+func (d *Devnet) SubchainEthRpcClient(idx int) *ec.Client {
+	return d.subchainEthRpcClients[idx]
+}
+
+// WaitForTokenUnlock blocks until the token bank of the given type on the simulated mainchain has unlocked the
+// tokens for the voucher burn of the subchain with the given nonce, and returns the unlock.
+func (d *Devnet) WaitForTokenUnlock(tokenType score.CrossChainTokenType, voucherBurnNonce *big.Int, timeout time.Duration) (*witness.SimulatedTokenUnlock, error) {
+	subchainID := scom.MapChainID(d.config.SubchainID)
+	deadline := time.Now().Add(timeout)
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		for _, unlock := range d.Mainchain.GetTokenUnlocks() {
+			if unlock.TokenType == tokenType && unlock.SourceChainID.Cmp(subchainID) == 0 &&
+				unlock.VoucherBurnNonce.Cmp(voucherBurnNonce) == 0 {
+				return unlock, nil
+			}
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for the tokens of the voucher burn %v of token type %v", voucherBurnNonce, tokenType)
+		}
+		<-ticker.C
+	}
+}
@@ -0,0 +1,151 @@
+package devnet_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/crypto"
+
+	scom "github.com/thetatoken/thetasubchain/common"
+	score "github.com/thetatoken/thetasubchain/core"
+	"github.com/thetatoken/thetasubchain/devnet"
+	"github.com/thetatoken/thetasubchain/devnet/devnettest"
+	"github.com/thetatoken/thetasubchain/eth/abi/bind"
+	scta "github.com/thetatoken/thetasubchain/interchain/contracts/accessors"
+	sld "github.com/thetatoken/thetasubchain/ledger"
+)
+
+func TestTFuelLockMintsVouchers(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping the devnet test in short mode")
+	}
+	assert := assert.New(t)
+
+	d, stop := devnettest.StartTestDevnet(t, 4)
+	defer stop()
+	if !assert.Nil(d.WaitForHeight(3, 60*time.Second)) {
+		return
+	}
+
+	sender := common.HexToAddress("0x2E833968E5bB786Ae419c4d13189fB081Cc43bab")
+	receiver := common.HexToAddress("0x9F1233798E905E173560071255140b4A8aBd3Ec6")
+	amount := new(big.Int).Mul(big.NewInt(12), big.NewInt(1e18))
+	event := d.Mainchain.LockTFuel(sender, receiver, amount)
+
+	if !assert.Nil(d.WaitForEvent(event, 30*time.Second)) {
+		return
+	}
+	if !assert.Nil(d.WaitForVoucherMint(event, 120*time.Second)) {
+		return
+	}
+
+	// The TFuel vouchers are minted as the TFuel of the receiver on the subchain
+	for _, n := range d.Nodes {
+		view, err := n.Ledger.(*sld.Ledger).GetDeliveredSnapshot()
+		assert.Nil(err)
+		account := view.GetAccount(receiver)
+		if assert.NotNil(account) {
+			assert.Equal(0, amount.Cmp(account.Balance.TFuelWei), "balance: %v", account.Balance)
+		}
+	}
+}
+
+func TestTNT20LockMintsVouchers(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping the devnet test in short mode")
+	}
+	assert := assert.New(t)
+
+	d, stop := devnettest.StartTestDevnet(t, 4)
+	defer stop()
+	if !assert.Nil(d.WaitForHeight(3, 60*time.Second)) {
+		return
+	}
+
+	tokenSource := common.HexToAddress("0x1336739B05C7Ab8a526D40DCC0d04a826b5f8B03")
+	sender := common.HexToAddress("0x2E833968E5bB786Ae419c4d13189fB081Cc43bab")
+	receiver := common.HexToAddress("0x9F1233798E905E173560071255140b4A8aBd3Ec6")
+	amount := new(big.Int).Mul(big.NewInt(66), big.NewInt(1e18))
+	event := d.Mainchain.LockTNT20(tokenSource, sender, receiver, "TDrop", "TDROP", 18, amount)
+
+	if !assert.Nil(d.WaitForEvent(event, 30*time.Second)) {
+		return
+	}
+	if !assert.Nil(d.WaitForVoucherMint(event, 120*time.Second)) {
+		return
+	}
+
+	// The vouchers are minted by the voucher contract of the denom
+	client := d.SubchainEthRpcClient(0)
+	tokenBankAddr := d.Nodes[0].Ledger.GetTokenBankContractAddress(score.CrossChainTokenTypeTNT20)
+	tokenBank, err := scta.NewTNT20TokenBank(*tokenBankAddr, client)
+	assert.Nil(err)
+	voucherAddr, err := tokenBank.GetVoucher(nil, score.TNT20Denom(scom.MapChainID(devnet.DefaultMainchainID), tokenSource))
+	assert.Nil(err)
+	voucher, err := scta.NewTNT20VoucherContract(voucherAddr, client)
+	assert.Nil(err)
+	balance, err := voucher.BalanceOf(nil, receiver)
+	assert.Nil(err)
+	assert.Equal(0, amount.Cmp(balance), "balance: %v", balance)
+}
+
+func TestTFuelVoucherBurnUnlocksTokens(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping the devnet test in short mode")
+	}
+	assert := assert.New(t)
+
+	d, stop := devnettest.StartTestDevnet(t, 4)
+	defer stop()
+	if !assert.Nil(d.WaitForHeight(3, 60*time.Second)) {
+		return
+	}
+
+	// Send TFuel to the subchain for the voucher owner, who pays the gas and the cross-chain fee of the burn
+	ownerKey, _, err := crypto.GenerateKeyPair()
+	if !assert.Nil(err) {
+		return
+	}
+	owner := ownerKey.PublicKey().Address()
+	lockEvent := d.Mainchain.LockTFuel(owner, owner, new(big.Int).Mul(big.NewInt(100), big.NewInt(1e18)))
+	if !assert.Nil(d.WaitForVoucherMint(lockEvent, 120*time.Second)) {
+		return
+	}
+
+	// Burn the vouchers on the subchain to send the TFuel back to a mainchain receiver
+	client := d.SubchainEthRpcClient(0)
+	subchainID := scom.MapChainID(devnet.DefaultSubchainID)
+	tokenBankAddr := d.Nodes[0].Ledger.GetTokenBankContractAddress(score.CrossChainTokenTypeTFuel)
+	tokenBank, err := scta.NewTFuelTokenBank(*tokenBankAddr, client)
+	assert.Nil(err)
+	opts, err := bind.NewKeyedTransactorWithChainID(ownerKey, subchainID)
+	assert.Nil(err)
+	nonce, err := client.PendingNonceAt(context.Background(), owner)
+	assert.Nil(err)
+	opts.Nonce = new(big.Int).SetUint64(nonce)
+	opts.GasPrice = scom.GetMinimumGasPrice()
+	opts.GasLimit = 1000000
+	burnedAmount := new(big.Int).Mul(big.NewInt(50), big.NewInt(1e18))
+	crossChainFee := new(big.Int).Mul(big.NewInt(10), big.NewInt(1e18))
+	opts.Value = new(big.Int).Add(burnedAmount, crossChainFee)
+	receiver := common.HexToAddress("0x9F1233798E905E173560071255140b4A8aBd3Ec6")
+	_, err = tokenBank.BurnVouchers(opts, receiver)
+	if !assert.Nil(err) {
+		return
+	}
+
+	// The burn is witnessed on the subchain, and the validators unlock the TFuel on the mainchain
+	unlock, err := d.WaitForTokenUnlock(score.CrossChainTokenTypeTFuel, big.NewInt(1), 120*time.Second)
+	if !assert.Nil(err) {
+		return
+	}
+	assert.Equal(0, subchainID.Cmp(unlock.SourceChainID))
+	assert.Equal(score.TFuelDenom(scom.MapChainID(devnet.DefaultMainchainID)), unlock.Denom)
+	assert.Equal(receiver, unlock.Receiver)
+	assert.Equal(0, burnedAmount.Cmp(unlock.Amount), "unlocked amount: %v", unlock.Amount)
+	assert.Equal(0, big.NewInt(1).Cmp(d.Mainchain.GetMaxProcessedVoucherBurnNonce(score.CrossChainTokenTypeTFuel, subchainID)))
+}
@@ -0,0 +1,24 @@
+package devnettest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/thetatoken/thetasubchain/devnet"
+)
+
+// StartTestDevnet creates and starts a devnet of the given number of validators for an end-to-end test. The
+// returned function stops the devnet and removes its data, and should be deferred by the test.
+func StartTestDevnet(t testing.TB, numValidators int) (*devnet.Devnet, func()) {
+	d, err := devnet.NewDevnet(&devnet.Config{NumValidators: numValidators})
+	if err != nil {
+		t.Fatalf("Failed to create the devnet: %v", err)
+	}
+	d.Start(context.Background())
+
+	return d, func() {
+		d.Stop()
+		d.Wait()
+		d.Cleanup()
+	}
+}
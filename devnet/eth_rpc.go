@@ -0,0 +1,500 @@
+package devnet
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/hexutil"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/ledger/types"
+
+	scom "github.com/thetatoken/thetasubchain/common"
+	score "github.com/thetatoken/thetasubchain/core"
+	"github.com/thetatoken/thetasubchain/eth/abi"
+	ethtypes "github.com/thetatoken/thetasubchain/eth/core/types"
+	ec "github.com/thetatoken/thetasubchain/eth/ethclient"
+	"github.com/thetatoken/thetasubchain/eth/rpc"
+	scta "github.com/thetatoken/thetasubchain/interchain/contracts/accessors"
+	"github.com/thetatoken/thetasubchain/interchain/witness"
+	sld "github.com/thetatoken/thetasubchain/ledger"
+	stypes "github.com/thetatoken/thetasubchain/ledger/types"
+	svm "github.com/thetatoken/thetasubchain/ledger/vm"
+	smp "github.com/thetatoken/thetasubchain/mempool"
+	"github.com/thetatoken/thetasubchain/node"
+	srpc "github.com/thetatoken/thetasubchain/rpc"
+)
+
+// The ETH RPC backends serve the requests of the orchestrator in-process, so that the devnet runs
+// without the ETH RPC adaptors of the mainchain and the subchain.
+
+const ethRpcErrorCode = -32000
+
+type ethRpcHandler func(method string, params []json.RawMessage) (interface{}, error)
+
+type ethRpcMessage struct {
+	Version string          `json:"jsonrpc,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Error   *ethRpcError    `json:"error,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+}
+
+type ethRpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type ethRpcCallArgs struct {
+	From *common.Address `json:"from"`
+	To   *common.Address `json:"to"`
+	Data hexutil.Bytes   `json:"data"`
+}
+
+type ethRpcFilterArgs struct {
+	FromBlock string           `json:"fromBlock"`
+	ToBlock   string           `json:"toBlock"`
+	Addresses []common.Address `json:"address"`
+	Topics    [][]common.Hash  `json:"topics"`
+}
+
+// ethRpcTransport hands the HTTP requests of the ETH RPC client to the handler, without a network connection.
+type ethRpcTransport struct {
+	handle ethRpcHandler
+}
+
+func (t *ethRpcTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	defer req.Body.Close()
+
+	var msg ethRpcMessage
+	if err := json.NewDecoder(req.Body).Decode(&msg); err != nil {
+		return nil, err
+	}
+	var params []json.RawMessage
+	if len(msg.Params) > 0 {
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+	}
+
+	resp := ethRpcMessage{Version: "2.0", ID: msg.ID}
+	result, err := t.handle(msg.Method, params)
+	if err == nil {
+		resp.Result, err = json.Marshal(result)
+	}
+	if err != nil {
+		resp.Error = &ethRpcError{Code: ethRpcErrorCode, Message: err.Error()}
+	}
+	body, err := json.Marshal(resp)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         req.Proto,
+		ProtoMajor:    req.ProtoMajor,
+		ProtoMinor:    req.ProtoMinor,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+func newEthRpcClient(name string, handle ethRpcHandler) (*ec.Client, error) {
+	client, err := rpc.DialHTTPWithClient("http://"+name, &http.Client{Transport: &ethRpcTransport{handle: handle}})
+	if err != nil {
+		return nil, err
+	}
+	return ec.NewClient(client), nil
+}
+
+func decodeEthRpcParam(params []json.RawMessage, idx int, v interface{}) error {
+	if idx >= len(params) {
+		return fmt.Errorf("missing parameter %v", idx)
+	}
+	return json.Unmarshal(params[idx], v)
+}
+
+// mainchainTokenBank is a token bank of the simulated mainchain, served by the mainchainEthRpcBackend.
+type mainchainTokenBank struct {
+	tokenType score.CrossChainTokenType
+	abi       abi.ABI
+}
+
+// mainchainEthRpcBackend serves the token bank queries and transactions of the orchestrators with the token banks
+// of the simulated mainchain. A transaction is executed as soon as it is sent, and one which would revert is
+// rejected instead, without consuming the nonce of the sender.
+type mainchainEthRpcBackend struct {
+	mainchain   *witness.SimulatedMainchain
+	mainchainID *big.Int
+	tokenBanks  map[common.Address]*mainchainTokenBank
+
+	mu     *sync.Mutex
+	nonces map[common.Address]uint64
+}
+
+func newMainchainEthRpcBackend(mainchain *witness.SimulatedMainchain, mainchainID *big.Int,
+	tokenBankAddrs map[score.CrossChainTokenType]common.Address) (*mainchainEthRpcBackend, error) {
+	tokenBankABIs := map[score.CrossChainTokenType]string{
+		score.CrossChainTokenTypeTFuel:   scta.TFuelTokenBankABI,
+		score.CrossChainTokenTypeTNT20:   scta.TNT20TokenBankABI,
+		score.CrossChainTokenTypeTNT721:  scta.TNT721TokenBankABI,
+		score.CrossChainTokenTypeTNT1155: scta.TNT1155TokenBankABI,
+	}
+	tokenBanks := make(map[common.Address]*mainchainTokenBank)
+	for tokenType, tokenBankABI := range tokenBankABIs {
+		address, ok := tokenBankAddrs[tokenType]
+		if !ok {
+			return nil, fmt.Errorf("no address for the mainchain token bank of token type %v", tokenType)
+		}
+		parsed, err := abi.JSON(strings.NewReader(tokenBankABI))
+		if err != nil {
+			return nil, err
+		}
+		tokenBanks[address] = &mainchainTokenBank{tokenType: tokenType, abi: parsed}
+	}
+	return &mainchainEthRpcBackend{
+		mainchain:   mainchain,
+		mainchainID: mainchainID,
+		tokenBanks:  tokenBanks,
+		mu:          &sync.Mutex{},
+		nonces:      make(map[common.Address]uint64),
+	}, nil
+}
+
+func (mb *mainchainEthRpcBackend) handle(method string, params []json.RawMessage) (interface{}, error) {
+	switch method {
+	case "eth_chainId":
+		return (*hexutil.Big)(mb.mainchainID), nil
+	case "eth_gasPrice":
+		return (*hexutil.Big)(scom.GetMinimumGasPrice()), nil
+	case "eth_getTransactionCount":
+		var address common.Address
+		if err := decodeEthRpcParam(params, 0, &address); err != nil {
+			return nil, err
+		}
+		mb.mu.Lock()
+		defer mb.mu.Unlock()
+		return hexutil.Uint64(mb.nonces[address]), nil
+	case "eth_call":
+		var args ethRpcCallArgs
+		if err := decodeEthRpcParam(params, 0, &args); err != nil {
+			return nil, err
+		}
+		return mb.call(args)
+	case "eth_sendRawTransaction":
+		var rawTx hexutil.Bytes
+		if err := decodeEthRpcParam(params, 0, &rawTx); err != nil {
+			return nil, err
+		}
+		return mb.sendRawTransaction(rawTx)
+	default:
+		return nil, fmt.Errorf("the simulated mainchain does not support %v", method)
+	}
+}
+
+// getTokenBankMethod returns the token bank called and the method of the call data.
+func (mb *mainchainEthRpcBackend) getTokenBankMethod(to *common.Address, data []byte) (*mainchainTokenBank, *abi.Method, error) {
+	if to == nil {
+		return nil, nil, errors.New("the contract address is required")
+	}
+	tokenBank, ok := mb.tokenBanks[*to]
+	if !ok {
+		return nil, nil, fmt.Errorf("no contract deployed at %v on the simulated mainchain", to.Hex())
+	}
+	if len(data) < 4 {
+		return nil, nil, errors.New("invalid call data")
+	}
+	method, err := tokenBank.abi.MethodById(data[:4])
+	if err != nil {
+		return nil, nil, err
+	}
+	return tokenBank, method, nil
+}
+
+func (mb *mainchainEthRpcBackend) call(args ethRpcCallArgs) (hexutil.Bytes, error) {
+	tokenBank, method, err := mb.getTokenBankMethod(args.To, args.Data)
+	if err != nil {
+		return nil, err
+	}
+	switch method.Name {
+	case "getMaxProcessedTokenLockNonce":
+		// The simulated mainchain does not mint vouchers for the tokens locked on the subchain
+		return method.Outputs.Pack(big.NewInt(0))
+	case "getMaxProcessedVoucherBurnNonce":
+		inputs, err := method.Inputs.Unpack(args.Data[4:])
+		if err != nil {
+			return nil, err
+		}
+		return method.Outputs.Pack(mb.mainchain.GetMaxProcessedVoucherBurnNonce(tokenBank.tokenType, inputs[0].(*big.Int)))
+	case "getAdjustedValidatorSet":
+		inputs, err := method.Inputs.Unpack(args.Data[4:])
+		if err != nil {
+			return nil, err
+		}
+		validatorSet, err := mb.mainchain.GetValidatorSet(inputs[1].(*big.Int))
+		if err != nil {
+			return nil, err
+		}
+		validators := []common.Address{}
+		shareAmounts := []*big.Int{}
+		for _, v := range validatorSet.Validators() {
+			validators = append(validators, v.Address)
+			shareAmounts = append(shareAmounts, v.Stake)
+		}
+		return method.Outputs.Pack(validators, shareAmounts)
+	default:
+		return nil, fmt.Errorf("the simulated mainchain does not support the token bank method %v", method.Name)
+	}
+}
+
+func (mb *mainchainEthRpcBackend) sendRawTransaction(rawTx []byte) (common.Hash, error) {
+	tx := &ethtypes.Transaction{}
+	if err := tx.UnmarshalBinary(rawTx); err != nil {
+		return common.Hash{}, err
+	}
+	sender, err := ethtypes.Sender(ethtypes.LatestSignerForChainID(mb.mainchainID), tx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	if tx.Nonce() != mb.nonces[sender] {
+		return common.Hash{}, fmt.Errorf("invalid nonce %v for %v, expected: %v", tx.Nonce(), sender.Hex(), mb.nonces[sender])
+	}
+	tokenBank, method, err := mb.getTokenBankMethod(tx.To(), tx.Data())
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if method.Name != "unlockTokens" {
+		return common.Hash{}, fmt.Errorf("the simulated mainchain does not support the token bank method %v", method.Name)
+	}
+	inputs, err := method.Inputs.Unpack(tx.Data()[4:])
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	// The arguments of unlockTokens(): the source chain ID, the denom (except for TFuel), the receiver, the token ID
+	// (for TNT721 and TNT1155) and the amount (except for TNT721), followed by the dynasty and the voucher burn nonce.
+	unlock := &witness.SimulatedTokenUnlock{
+		TokenType:        tokenBank.tokenType,
+		SourceChainID:    inputs[0].(*big.Int),
+		VoucherBurnNonce: inputs[len(inputs)-1].(*big.Int),
+	}
+	dynasty := inputs[len(inputs)-2].(*big.Int)
+	switch tokenBank.tokenType {
+	case score.CrossChainTokenTypeTFuel:
+		unlock.Denom = score.TFuelDenom(mb.mainchainID)
+		unlock.Receiver = inputs[1].(common.Address)
+		unlock.Amount = inputs[2].(*big.Int)
+	case score.CrossChainTokenTypeTNT20:
+		unlock.Denom = inputs[1].(string)
+		unlock.Receiver = inputs[2].(common.Address)
+		unlock.Amount = inputs[3].(*big.Int)
+	case score.CrossChainTokenTypeTNT721:
+		unlock.Denom = inputs[1].(string)
+		unlock.Receiver = inputs[2].(common.Address)
+		unlock.TokenID = inputs[3].(*big.Int)
+	case score.CrossChainTokenTypeTNT1155:
+		unlock.Denom = inputs[1].(string)
+		unlock.Receiver = inputs[2].(common.Address)
+		unlock.TokenID = inputs[3].(*big.Int)
+		unlock.Amount = inputs[4].(*big.Int)
+	}
+	if err := mb.mainchain.UnlockTokens(sender, dynasty, unlock); err != nil {
+		return common.Hash{}, err
+	}
+
+	mb.nonces[sender]++
+	return tx.Hash(), nil
+}
+
+// subchainEthRpcBackend serves the token bank queries and transactions of the orchestrator with the ledger
+// and the mempool of a node.
+type subchainEthRpcBackend struct {
+	subchainID *big.Int
+	node       *node.Node // set once the node is created, before it starts
+}
+
+func (sb *subchainEthRpcBackend) handle(method string, params []json.RawMessage) (interface{}, error) {
+	if sb.node == nil {
+		return nil, errors.New("the node is not created yet")
+	}
+	ledger := sb.node.Ledger.(*sld.Ledger)
+
+	switch method {
+	case "eth_chainId":
+		return (*hexutil.Big)(sb.subchainID), nil
+	case "eth_gasPrice":
+		return (*hexutil.Big)(scom.GetMinimumGasPrice()), nil
+	case "eth_getTransactionCount":
+		var address common.Address
+		if err := decodeEthRpcParam(params, 0, &address); err != nil {
+			return nil, err
+		}
+		return sb.getNonce(ledger, address)
+	case "eth_getCode":
+		var address common.Address
+		if err := decodeEthRpcParam(params, 0, &address); err != nil {
+			return nil, err
+		}
+		view, err := ledger.GetDeliveredSnapshot()
+		if err != nil {
+			return nil, err
+		}
+		return hexutil.Bytes(view.GetCode(address)), nil
+	case "eth_call":
+		var args ethRpcCallArgs
+		if err := decodeEthRpcParam(params, 0, &args); err != nil {
+			return nil, err
+		}
+		return sb.call(ledger, args)
+	case "eth_sendRawTransaction":
+		var rawTx string
+		if err := decodeEthRpcParam(params, 0, &rawTx); err != nil {
+			return nil, err
+		}
+		return sb.sendRawTransaction(rawTx)
+	case "eth_blockNumber":
+		return hexutil.Uint64(sb.node.Consensus.GetLastFinalizedBlock().Height), nil
+	case "eth_getLogs":
+		var args ethRpcFilterArgs
+		if err := decodeEthRpcParam(params, 0, &args); err != nil {
+			return nil, err
+		}
+		return sb.getLogs(args)
+	default:
+		return nil, fmt.Errorf("the devnet subchain does not support %v", method)
+	}
+}
+
+// getNonce returns the nonce for the next ETH transaction of the account. The sequence of the subchain
+// transaction translated from an ETH transaction is the nonce plus one.
+func (sb *subchainEthRpcBackend) getNonce(ledger *sld.Ledger, address common.Address) (hexutil.Uint64, error) {
+	sequence := uint64(0)
+	view, err := ledger.GetScreenedSnapshot()
+	if err != nil {
+		return 0, err
+	}
+	if account := view.GetAccount(address); account != nil {
+		sequence = account.Sequence
+	}
+	if pendingSequence, ok := sb.node.Mempool.GetPendingSequence(address); ok && pendingSequence > sequence {
+		sequence = pendingSequence
+	}
+	return hexutil.Uint64(sequence), nil
+}
+
+func (sb *subchainEthRpcBackend) call(ledger *sld.Ledger, args ethRpcCallArgs) (hexutil.Bytes, error) {
+	if args.To == nil {
+		return nil, errors.New("the contract address is required")
+	}
+	from := common.Address{}
+	if args.From != nil {
+		from = *args.From
+	}
+	view, err := ledger.GetDeliveredSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	pb := ledger.State().ParentBlock()
+	sctx := &types.SmartContractTx{
+		From: types.TxInput{
+			Address: from,
+			Coins:   types.NewCoins(0, 0),
+		},
+		To:       types.TxOutput{Address: *args.To},
+		GasLimit: uint64(10000000),
+		GasPrice: big.NewInt(0),
+		Data:     common.Bytes(args.Data),
+	}
+	vmRet, _, _, vmErr := svm.Execute(svm.NewBlockInfo(pb.Height, pb.Timestamp, pb.ChainID), sctx, view)
+	if vmErr != nil {
+		return nil, vmErr
+	}
+	return hexutil.Bytes(vmRet), nil
+}
+
+func (sb *subchainEthRpcBackend) sendRawTransaction(rawTx string) (common.Hash, error) {
+	ethTxBytes, err := hex.DecodeString(strings.TrimPrefix(rawTx, "0x"))
+	if err != nil {
+		return common.Hash{}, err
+	}
+	sctx, err := types.TranslateEthTx(rawTx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	txBytes, err := stypes.TxToBytes(sctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	err = sb.node.Mempool.InsertTransaction(txBytes)
+	if err != nil && err != smp.FastsyncSkipTxError {
+		return common.Hash{}, err
+	}
+	sb.node.Mempool.BroadcastTx(txBytes)
+	return crypto.Keccak256Hash(ethTxBytes), nil
+}
+
+// getLogs returns the logs of the finalized blocks in the range which pass the filter. The range ends at the last
+// finalized block if the end is not a block number.
+func (sb *subchainEthRpcBackend) getLogs(args ethRpcFilterArgs) ([]*ethtypes.Log, error) {
+	fromHeight, err := hexutil.DecodeUint64(args.FromBlock)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fromBlock %v: %v", args.FromBlock, err)
+	}
+	toHeight := sb.node.Consensus.GetLastFinalizedBlock().Height
+	if height, err := hexutil.DecodeUint64(args.ToBlock); err == nil && height < toHeight {
+		toHeight = height
+	}
+
+	filter := &srpc.LogFilter{
+		Addresses: args.Addresses,
+		Topics:    args.Topics,
+	}
+	logs := []*ethtypes.Log{}
+	for height := fromHeight; height <= toHeight; height++ {
+		block, found := sb.node.Chain.FindFinalizedBlockByHeight(height)
+		if !found {
+			continue
+		}
+		blockHash := block.Hash()
+		logIndex := uint(0)
+		for txIndex, rawTx := range block.Txs {
+			txHash := crypto.Keccak256Hash(rawTx)
+			receipt, found := sb.node.Chain.FindTxReceiptByHash(blockHash, txHash)
+			if !found {
+				continue
+			}
+			for _, log := range receipt.Logs {
+				if filter.Matches(log) {
+					logs = append(logs, &ethtypes.Log{
+						Address:     log.Address,
+						Topics:      log.Topics,
+						Data:        log.Data,
+						BlockNumber: height,
+						TxHash:      txHash,
+						TxIndex:     uint(txIndex),
+						BlockHash:   blockHash,
+						Index:       logIndex,
+					})
+				}
+				logIndex++
+			}
+		}
+	}
+	return logs, nil
+}
@@ -4,6 +4,7 @@ require (
 	github.com/bgentry/speakeasy v0.1.0
 	github.com/ethereum/go-ethereum v1.10.16
 	github.com/fsnotify/fsnotify v1.5.4 // indirect
+	github.com/google/uuid v1.1.5
 	github.com/gorilla/mux v1.8.0
 	github.com/hashicorp/golang-lru v0.5.5-0.20210104140557-80c98217689d
 	github.com/mattn/go-isatty v0.0.12
@@ -19,12 +20,15 @@ require (
 	github.com/thetatoken/theta v0.0.0
 	github.com/thetatoken/theta/common v0.0.0
 	github.com/thetatoken/theta/rpc/lib/rpc-codec/jsonrpc2 v0.0.0
+	github.com/tyler-smith/go-bip39 v1.0.1-0.20181017060643-dbb3b84ba2ef
 	github.com/ybbus/jsonrpc v1.1.1
 	golang.org/x/crypto v0.0.0-20210322153248-0c34fe9e7dc2
 	golang.org/x/net v0.0.0-20210805182204-aaa1db679c0d
 	golang.org/x/sys v0.0.0-20220520151302-bc2c85ada10a // indirect
 )
 
+// The theta ledger is not published as a versioned module. Check out its sc-privatenet branch
+// next to this repository, i.e. at $GOPATH/src/github.com/thetatoken/theta, see the README.
 replace github.com/thetatoken/theta v0.0.0 => ../theta
 
 replace github.com/thetatoken/theta/common v0.0.0 => ../theta/common
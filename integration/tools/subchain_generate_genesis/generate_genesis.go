@@ -1,33 +1,24 @@
 package main
 
 import (
-	"bufio"
 	"bytes"
-	"encoding/hex"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"io/ioutil"
 	"math/big"
 	"os"
-	"strings"
-	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/thetatoken/theta/common"
 	"github.com/thetatoken/theta/ledger/types"
 	"github.com/thetatoken/theta/rlp"
 	"github.com/thetatoken/theta/store/database"
-	"github.com/thetatoken/theta/store/database/backend"
 	"github.com/thetatoken/theta/store/trie"
 
-	scom "github.com/thetatoken/thetasubchain/common"
 	score "github.com/thetatoken/thetasubchain/core"
-	"github.com/thetatoken/thetasubchain/eth/abi"
-	"github.com/thetatoken/thetasubchain/interchain/contracts/predeployed"
 	slst "github.com/thetatoken/thetasubchain/ledger/state"
-	svm "github.com/thetatoken/thetasubchain/ledger/vm"
-	ststore "github.com/thetatoken/thetasubchain/store/treestore"
+	ssnst "github.com/thetatoken/thetasubchain/snapshot"
 )
 
 var logger *log.Entry = log.WithFields(log.Fields{"prefix": "genesis"})
@@ -69,7 +60,7 @@ func main() {
 		logger.Infof("Sanity checks all passed.")
 	}
 
-	err = writeGenesisSnapshot(db, sv, metadata, genesisSnapshotFilePath)
+	err = ssnst.WriteGenesisSnapshot(db, sv, metadata, genesisSnapshotFilePath)
 	if err != nil {
 		panic(fmt.Sprintf("Failed to write genesis snapshot: %v", err))
 	}
@@ -106,36 +97,11 @@ func parseArguments() (mainchainID, subchainID, initValidatorSetPath, genesisSna
 // generateGenesisSnapshot generates the genesis snapshot.
 func generateGenesisSnapshot(mainchainID, subchainID, initValidatorSetFilePath, genesisSnapshotFilePath string,
 	admin common.Address, fallbackReceiver common.Address) (database.Database, *slst.StoreView, *score.SnapshotMetadata, error) {
-
-	metadata := &score.SnapshotMetadata{}
-	genesisHeight := score.GenesisBlockHeight
-
-	db := backend.NewMemDatabase()
-	sv := slst.NewStoreView(0, common.Hash{}, db)
-
-	setInitialValidatorSet(subchainID, initValidatorSetFilePath, genesisHeight, sv)
-	deployInitialSmartContracts(mainchainID, subchainID, admin, fallbackReceiver, sv)
-
-	stateHash := sv.Hash()
-
-	genesisBlock := score.NewBlock()
-	genesisBlock.ChainID = subchainID
-	genesisBlock.Height = genesisHeight
-	genesisBlock.Epoch = genesisBlock.Height
-	genesisBlock.Parent = common.Hash{}
-	genesisBlock.StateHash = stateHash
-	genesisBlock.Timestamp = big.NewInt(time.Now().Unix())
-
-	metadata.TailTrio = score.SnapshotBlockTrio{
-		First:  score.SnapshotFirstBlock{},
-		Second: score.SnapshotSecondBlock{Header: genesisBlock.BlockHeader},
-		Third:  score.SnapshotThirdBlock{},
-	}
-
-	return db, sv, metadata, nil
+	validatorSet := loadInitialValidatorSet(initValidatorSetFilePath)
+	return ssnst.GenerateGenesisSnapshot(mainchainID, subchainID, validatorSet, admin, fallbackReceiver)
 }
 
-func setInitialValidatorSet(subchainID string, initValidatorSetFilePath string, genesisHeight uint64, sv *slst.StoreView) *score.ValidatorSet {
+func loadInitialValidatorSet(initValidatorSetFilePath string) *score.ValidatorSet {
 	var validators []Validator
 	initValidatorSetFile, err := os.Open(initValidatorSetFilePath)
 	if err != nil {
@@ -146,14 +112,6 @@ func setInitialValidatorSet(subchainID string, initValidatorSetFilePath string,
 		panic(fmt.Sprintf("failed to read initial stake deposit file: %v", err))
 	}
 
-	// // need to create an account with a small amount of TFuel Vouchers for the initial validators,
-	// // so that they can vote for the cross-chain transfers
-	// //     validatorInitialBalance = crossChainTFuelTransferGasPerTx * minGasPrice * numInitialTFuelCrossChainTransfers
-	// crossChainTFuelTransferGasPerTx := big.NewInt(CrossChainTFuelTransferGasPerTx)
-	// numInitialTFuelCrossChainTransfers := big.NewInt(NumInitialTFuelCrossChainTransfers)
-	// validatorInitialBalance := big.NewInt(1).Mul(crossChainTFuelTransferGasPerTx, scom.GetMinimumGasPrice())
-	// validatorInitialBalance = big.NewInt(1).Mul(validatorInitialBalance, numInitialTFuelCrossChainTransfers)
-
 	json.Unmarshal(initValidatorSetByteValue, &validators)
 	initialDynasty := big.NewInt(0)
 	validatorSet := score.NewValidatorSet(initialDynasty)
@@ -164,33 +122,10 @@ func setInitialValidatorSet(subchainID string, initValidatorSetFilePath string,
 		}
 		validator := score.NewValidator(v.Address, stake)
 		validatorSet.AddValidator(validator)
-
-		setInitialBalance(sv, common.HexToAddress(v.Address), common.Big0) // need to create accounts with zero balances for the inital validators
 	}
-	subchainIDInt := scom.MapChainID(subchainID)
-	sv.UpdateValidatorSet(subchainIDInt, validatorSet)
-
-	hl := &types.HeightList{}
-	hl.Append(genesisHeight)
-	sv.UpdateValidatorSetUpdateTxHeightList(hl)
-	sv.Save()
-
 	return validatorSet
 }
 
-func setInitialBalance(sv *slst.StoreView, address common.Address, tfuelBalance *big.Int) {
-	acc := &types.Account{
-		Address:  address,
-		Root:     common.Hash{},
-		CodeHash: types.EmptyCodeHash,
-		Balance: types.Coins{
-			ThetaWei: big.NewInt(0),
-			TFuelWei: tfuelBalance,
-		},
-	}
-	sv.SetAccount(acc.Address, acc)
-}
-
 func proveValidatorSet(sv *slst.StoreView) (*score.ValidatorSetProof, error) {
 	vp := &score.ValidatorSetProof{}
 	vsKey := slst.CurrentValidatorSetKey()
@@ -198,220 +133,6 @@ func proveValidatorSet(sv *slst.StoreView) (*score.ValidatorSetProof, error) {
 	return vp, err
 }
 
-func deployInitialSmartContracts(mainchainID, subchainID string, admin common.Address, fallbackReceiver common.Address, sv *slst.StoreView) {
-	mainchainIDInt := scom.MapChainID(mainchainID)
-	deployer := common.Address{}
-
-	//
-	// Deploy the ChainRegistrar contract
-	//
-
-	sequence := 0
-	numMainchainBlockPerDynastyBigInt := big.NewInt(scom.NumMainchainBlocksPerDynasty)
-	dec18, _ := big.NewInt(0).SetString("1000000000000000000", 10)
-	initialCrossChainFee := big.NewInt(0).Mul(big.NewInt(10), dec18)
-	chainRegistrarContractAddr, err := deploySmartContract(subchainID, sv, addConstructorArgumentForChainRegistrarBytecode(predeployed.ChainRegistrarContractBytecode, numMainchainBlockPerDynastyBigInt, initialCrossChainFee, admin, fallbackReceiver),
-		deployer, sequence, slst.ChainRegistrarContractAddressKey())
-	if err != nil {
-		logger.Panicf("Failed to deploy the chain registrar smart contract (sequence = %v): %v", sequence, err)
-	}
-
-	sequence += 1
-	_, err = deploySmartContract(subchainID, sv, addConstructorArgumentForTokenBankBytecode(predeployed.TFuelTokenBankContractBytecode, mainchainIDInt, chainRegistrarContractAddr), deployer, sequence, slst.TFuelTokenBankContractAddressKey())
-	if err != nil {
-		logger.Panicf("Failed to deploy TokenBank1 smart contract (sequence = %v): %v", sequence, err)
-	}
-
-	sequence += 1
-	_, err = deploySmartContract(subchainID, sv, addConstructorArgumentForTokenBankBytecode(predeployed.TNT20TokenBankContractBytecode, mainchainIDInt, chainRegistrarContractAddr), deployer, sequence, slst.TNT20TokenBankContractAddressKey())
-	if err != nil {
-		logger.Panicf("Failed to deploy TokenBank smart contract (sequence = %v): %v", sequence, err)
-	}
-
-	sequence += 1
-	_, err = deploySmartContract(subchainID, sv, addConstructorArgumentForTokenBankBytecode(predeployed.TNT721TokenBankContractBytecode, mainchainIDInt, chainRegistrarContractAddr), deployer, sequence, slst.TNT721TokenBankContractAddressKey())
-	if err != nil {
-		logger.Panicf("Failed to deploy TokenBank smart contract (sequence = %v): %v", sequence, err)
-	}
-
-	sequence += 1
-	_, err = deploySmartContract(subchainID, sv, addConstructorArgumentForTokenBankBytecode(predeployed.TNT1155TokenBankContractBytecode, mainchainIDInt, chainRegistrarContractAddr), deployer, sequence, slst.TNT1155TokenBankContractAddressKey())
-	if err != nil {
-		logger.Panicf("Failed to deploy TokenBank smart contract (sequence = %v): %v", sequence, err)
-	}
-}
-
-// Reference: https://docs.blockscout.com/for-users/abi-encoded-constructor-arguments
-func addConstructorArgumentForChainRegistrarBytecode(contractBytecode string, numBlocksPerDynasty, crossChainFee *big.Int, admin common.Address, fallbackReceiver common.Address) string {
-	rawABI := `[
-		{
-		"inputs": [
-			{
-			"internalType": "uint256",
-			"name": "numBlocksPerDynasty_",
-			"type": "uint256"
-			},
-			{
-				"internalType": "uint256",
-				"name": "crossChainFee_",
-				"type": "uint256"
-			},
-			{
-				"internalType": "uint256",
-				"name": "admin_",
-				"type": "address"
-			},
-			{
-				"internalType": "uint256",
-				"name": "fallbackReceiver_",
-				"type": "address"
-			}
-		],
-		"stateMutability": "nonpayable",
-		"type": "constructor"
-		}
-    ]`
-	parsed, err := abi.JSON(strings.NewReader(rawABI))
-	if err != nil {
-		panic(err)
-	}
-	encodedConstructorArgument, err := parsed.Pack("", numBlocksPerDynasty, crossChainFee, admin, fallbackReceiver)
-	if err != nil {
-		panic(err)
-	}
-	encodedConstructorArgumentString := hex.EncodeToString(encodedConstructorArgument)
-	ff := contractBytecode + encodedConstructorArgumentString
-	return ff
-}
-
-// Reference: https://docs.blockscout.com/for-users/abi-encoded-constructor-arguments
-func addConstructorArgumentForTokenBankBytecode(contractBytecode string, mainchainIDInt *big.Int, chainRegistrarContractAddr common.Address) string {
-	rawABI := `[
-		{
-		"inputs": [
-			{
-			"internalType": "uint256",
-			"name": "mainchainID_",
-			"type": "uint256"
-			},
-			{
-			"internalType": "contract ChainRegistrar",
-			"name": "chainRegistrar_",
-			"type": "address"
-			}
-		],
-		"stateMutability": "nonpayable",
-		"type": "constructor"
-		}
-    ]`
-	parsed, err := abi.JSON(strings.NewReader(rawABI))
-	if err != nil {
-		panic(err)
-	}
-	encodedConstructorArgument, err := parsed.Pack("", mainchainIDInt, chainRegistrarContractAddr)
-	if err != nil {
-		panic(err)
-	}
-	encodedConstructorArgumentString := hex.EncodeToString(encodedConstructorArgument)
-	ff := contractBytecode + encodedConstructorArgumentString
-	return ff
-}
-
-func deploySmartContract(subchainID string, sv *slst.StoreView, contractBytecodeStr string, deployer common.Address, sequence int, contractAddressKey common.Bytes) (common.Address, error) {
-	dummyGasLimit := uint64(10000000)
-	dummyGasPrice := big.NewInt(1)
-
-	// Token Bank contract
-	contractBytecode, err := hex.DecodeString(contractBytecodeStr)
-	if err != nil {
-		return common.Address{}, err
-	}
-	deploySCTx := types.SmartContractTx{
-		From:     types.NewTxInput(deployer, types.NewCoins(0, 0), sequence),
-		To:       types.TxOutput{Address: common.Address{}}, // deploy contract
-		GasLimit: dummyGasLimit,
-		GasPrice: dummyGasPrice,
-		Data:     contractBytecode,
-	}
-	parentBlockInfo := svm.NewBlockInfo(0, big.NewInt(0), subchainID)
-	_, contractAddr, _, evmErr := svm.Execute(parentBlockInfo, &deploySCTx, sv)
-	if evmErr != nil {
-		return common.Address{}, evmErr
-	}
-
-	tbcaBytes, err := types.ToBytes(contractAddr)
-	if err != nil {
-		return common.Address{}, err
-	}
-	sv.Set(contractAddressKey, tbcaBytes)
-
-	return contractAddr, nil
-}
-
-// writeGenesisSnapshot writes genesis snapshot to file system.
-func writeGenesisSnapshot(db database.Database, sv *slst.StoreView, metadata *score.SnapshotMetadata, genesisSnapshotFilePath string) error {
-	file, err := os.Create(genesisSnapshotFilePath)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-	writer := bufio.NewWriter(file)
-	err = score.WriteMetadata(writer, metadata)
-	if err != nil {
-		return err
-	}
-	writeStoreView(db, sv, true, writer)
-	return err
-}
-
-func writeStoreView(db database.Database, sv *slst.StoreView, needAccountStorage bool, writer *bufio.Writer) {
-	height := score.Itobytes(sv.Height())
-	err := score.WriteRecord(writer, []byte{score.SVStart}, height)
-	if err != nil {
-		panic(err)
-	}
-	sv.GetStore().Traverse(nil, func(k, v common.Bytes) bool {
-		err = score.WriteRecord(writer, k, v)
-		if err != nil {
-			panic(err)
-		}
-
-		if needAccountStorage && bytes.HasPrefix(k, []byte("ls/a")) {
-			account := &types.Account{}
-			err := types.FromBytes([]byte(v), account)
-			if err != nil {
-				logger.Errorf("Failed to parse account for %v", []byte(v))
-				panic(err)
-			}
-			if account.Root != (common.Hash{}) {
-				err = score.WriteRecord(writer, []byte{score.SVStart}, height)
-				if err != nil {
-					panic(err)
-				}
-				storage := ststore.NewTreeStore(account.Root, db)
-				storage.Traverse(nil, func(ak, av common.Bytes) bool {
-					err = score.WriteRecord(writer, ak, av)
-					if err != nil {
-						panic(err)
-					}
-					return true
-				})
-				err = score.WriteRecord(writer, []byte{score.SVEnd}, height)
-				if err != nil {
-					panic(err)
-				}
-			}
-		}
-
-		return true
-	})
-	err = score.WriteRecord(writer, []byte{score.SVEnd}, height)
-	if err != nil {
-		panic(err)
-	}
-	writer.Flush()
-}
-
 func sanityChecks(sv *slst.StoreView) error {
 	vsAnalyzed := false
 	sv.GetStore().Traverse(nil, func(key, val common.Bytes) bool {
@@ -2,10 +2,13 @@ package orchestrator
 
 import (
 	"context"
+
+	score "github.com/thetatoken/thetasubchain/core"
 )
 
 type ChainOrchestrator interface {
 	Start(ctx context.Context)
 	Stop()
 	Wait()
+	SetLedgerAndSubchainTokenBanks(ledger score.Ledger)
 }
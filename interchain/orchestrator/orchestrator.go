@@ -9,7 +9,6 @@ import (
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
-	"github.com/thetatoken/theta/crypto"
 	ts "github.com/thetatoken/theta/store"
 	"github.com/thetatoken/theta/store/database"
 	"github.com/thetatoken/thetasubchain/eth/abi/bind"
@@ -19,6 +18,7 @@ import (
 	scom "github.com/thetatoken/thetasubchain/common"
 	score "github.com/thetatoken/thetasubchain/core"
 	scta "github.com/thetatoken/thetasubchain/interchain/contracts/accessors"
+	ssigner "github.com/thetatoken/thetasubchain/signer"
 
 	"github.com/thetatoken/theta/common"
 	ec "github.com/thetatoken/thetasubchain/eth/ethclient"
@@ -33,7 +33,7 @@ var (
 
 type Orchestrator struct {
 	updateInterval        int
-	privateKey            *crypto.PrivateKey
+	signer                score.Signer
 	ledger                score.Ledger
 	eventProcessingTicker *time.Ticker
 	metachainWitness      witness.ChainWitness
@@ -65,6 +65,7 @@ type Orchestrator struct {
 	subchainTNT1155TokenBank     *scta.TNT1155TokenBank
 	// Inter-chain messaging
 	interChainEventCache *siu.InterChainEventCache
+	pipelineStats        *pipelineStatsTracker
 
 	// Life cycle
 	wg     *sync.WaitGroup
@@ -74,13 +75,30 @@ type Orchestrator struct {
 
 // NewOrchestrator creates a new Orchestrator
 func NewOrchestrator(db database.Database, updateInterval int, interChainEventCache *siu.InterChainEventCache,
-	metachainWitness witness.ChainWitness, privateKey *crypto.PrivateKey) *Orchestrator {
+	metachainWitness witness.ChainWitness, signer score.Signer) *Orchestrator {
 
 	mainchainEthRpcURL := viper.GetString(scom.CfgMainchainEthRpcURL)
 	mainchainEthRpcClient, err := ec.Dial(mainchainEthRpcURL)
 	if err != nil {
 		logger.Fatalf("the ETH client failed to connect to the mainchain ETH RPC %v\n", err)
 	}
+	subchainEthRpcURL := viper.GetString(scom.CfgSubchainEthRpcURL)
+	subchainEthRpcClient, err := ec.Dial(subchainEthRpcURL)
+	if err != nil {
+		logger.Fatalf("the ETH client failed to connect to the subchain ETH RPC: %v\n", err)
+	}
+	oc := NewOrchestratorWithEthRpcClients(db, updateInterval, interChainEventCache, metachainWitness, signer,
+		mainchainEthRpcClient, subchainEthRpcClient)
+	oc.mainchainEthRpcURL = mainchainEthRpcURL
+	oc.subchainEthRpcURL = subchainEthRpcURL
+	return oc
+}
+
+// NewOrchestratorWithEthRpcClients creates a new Orchestrator which calls the token banks through the given
+// ETH RPC clients of the mainchain and the subchain, e.g. the in-process ETH RPC backends of the devnet.
+func NewOrchestratorWithEthRpcClients(db database.Database, updateInterval int, interChainEventCache *siu.InterChainEventCache,
+	metachainWitness witness.ChainWitness, signer score.Signer, mainchainEthRpcClient *ec.Client, subchainEthRpcClient *ec.Client) *Orchestrator {
+
 	mainchainID, err := mainchainEthRpcClient.ChainID(context.Background())
 	if err != nil {
 		logger.Fatalf("failed to get the chainID of the mainchain, is the mainchain RPC API service running? error: %v\n", err)
@@ -106,20 +124,14 @@ func NewOrchestrator(db database.Database, updateInterval int, interChainEventCa
 		logger.Fatalf("failed to create MainchainTNT1155TokenBank contract %v\n", err)
 	}
 	subchainID := big.NewInt(viper.GetInt64(scom.CfgSubchainID))
-	subchainEthRpcURL := viper.GetString(scom.CfgSubchainEthRpcURL)
-	subchainEthRpcClient, err := ec.Dial(subchainEthRpcURL)
-	if err != nil {
-		logger.Fatalf("the ETH client failed to connect to the subchain ETH RPC: %v\n", err)
-	}
 	eventProcessedTime := make(map[string]time.Time)
 	oc := &Orchestrator{
 		updateInterval:     updateInterval,
-		privateKey:         privateKey,
+		signer:             signer,
 		metachainWitness:   metachainWitness,
 		eventProcessedTime: eventProcessedTime,
 
 		mainchainID:                   mainchainID,
-		mainchainEthRpcURL:            mainchainEthRpcURL,
 		mainchainEthRpcClient:         mainchainEthRpcClient,
 		mainchainTFuelTokenBankAddr:   mainchainTFuelTokenBankAddr,
 		mainchainTFuelTokenBank:       mainchainTFuelTokenBank,
@@ -131,10 +143,10 @@ func NewOrchestrator(db database.Database, updateInterval int, interChainEventCa
 		mainchainTNT1155TokenBank:     mainchainTNT1155TokenBank,
 
 		subchainID:           subchainID,
-		subchainEthRpcURL:    subchainEthRpcURL,
 		subchainEthRpcClient: subchainEthRpcClient,
 
 		interChainEventCache: interChainEventCache,
+		pipelineStats:        newPipelineStatsTracker(),
 
 		wg: &sync.WaitGroup{},
 	}
@@ -332,8 +344,11 @@ func (oc *Orchestrator) processNextEvent(sourceChainID *big.Int, targetChainID *
 	nextNonce := big.NewInt(0).Add(maxProcessedNonce, big.NewInt(1))
 	sourceEvent, err := oc.interChainEventCache.Get(sourceChainID, sourceChainEventType, nextNonce)
 	if err == ts.ErrKeyNotFound {
+		oc.pipelineStats.setPendingEvents(sourceChainID, targetChainID, sourceChainEventType, 0)
 		return // the next event (e.g. Token Lock, or Voucher Burn) has not occurred yet
 	}
+	oc.pipelineStats.setPendingEvents(sourceChainID, targetChainID, sourceChainEventType,
+		oc.countPendingEvents(sourceChainID, sourceChainEventType, nextNonce))
 
 	logger.Debugf("Process next event, sourceChainID: %v, targetChainID: %v, sourceChainEventType: %v, nextNonce: %v",
 		sourceChainID, targetChainID, sourceChainEventType, nextNonce)
@@ -346,6 +361,7 @@ func (oc *Orchestrator) processNextEvent(sourceChainID *big.Int, targetChainID *
 			oc.updateEventProcessedTime(sourceEvent)
 		} else {
 			logger.Warnf("Failed to call target contract: %v", err)
+			oc.pipelineStats.recordSubmissionFailure(sourceChainID, targetChainID, sourceChainEventType)
 		}
 	}
 }
@@ -620,11 +636,11 @@ func (oc *Orchestrator) buildTxOpts(chainID *big.Int, ecClient *ec.Client) (*bin
 		gasPrice = common.Big0
 	}
 
-	nonce, err := ecClient.PendingNonceAt(context.Background(), oc.privateKey.PublicKey().Address())
+	nonce, err := ecClient.PendingNonceAt(context.Background(), oc.signer.Address())
 	if err != nil {
 		return nil, err
 	}
-	txOpts, err := bind.NewKeyedTransactorWithChainID(oc.privateKey, chainID) //chainID)
+	txOpts, err := ssigner.NewTransactor(oc.signer, chainID)
 	if err != nil {
 		return nil, err
 	}
@@ -632,7 +648,7 @@ func (oc *Orchestrator) buildTxOpts(chainID *big.Int, ecClient *ec.Client) (*bin
 	txOpts.Value = big.NewInt(0)       // in wei
 	txOpts.GasLimit = uint64(10000000) // in units
 	txOpts.GasPrice = gasPrice
-	logger.Debugf("building tx opts with address %v", oc.privateKey.PublicKey().Address())
+	logger.Debugf("building tx opts with address %v", oc.signer.Address())
 	return txOpts, nil
 }
 
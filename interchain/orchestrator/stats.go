@@ -0,0 +1,97 @@
+package orchestrator
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+
+	score "github.com/thetatoken/thetasubchain/core"
+)
+
+const maxPendingEventsProbe = 1024 // cap on the number of cached events counted per pipeline
+
+// PipelineStats tracks the relaying of one type of inter-chain events from a source chain to a target chain.
+type PipelineStats struct {
+	SourceChainID      string
+	TargetChainID      string
+	EventType          score.InterChainMessageEventType
+	PendingEvents      uint64 // witnessed events not yet processed by the target chain
+	SubmissionFailures uint64 // failed attempts to submit the target chain transactions
+}
+
+type pipelineStatsTracker struct {
+	mu    *sync.Mutex
+	stats map[string]*PipelineStats
+}
+
+func newPipelineStatsTracker() *pipelineStatsTracker {
+	return &pipelineStatsTracker{
+		mu:    &sync.Mutex{},
+		stats: make(map[string]*PipelineStats),
+	}
+}
+
+// getUnsafe returns the stats of the pipeline, creating it if needed. Must be called with pt.mu held.
+func (pt *pipelineStatsTracker) getUnsafe(sourceChainID, targetChainID *big.Int, eventType score.InterChainMessageEventType) *PipelineStats {
+	key := fmt.Sprintf("%v/%v/%v", sourceChainID, targetChainID, eventType)
+	stats, ok := pt.stats[key]
+	if !ok {
+		stats = &PipelineStats{
+			SourceChainID: sourceChainID.String(),
+			TargetChainID: targetChainID.String(),
+			EventType:     eventType,
+		}
+		pt.stats[key] = stats
+	}
+	return stats
+}
+
+func (pt *pipelineStatsTracker) setPendingEvents(sourceChainID, targetChainID *big.Int, eventType score.InterChainMessageEventType, numPending uint64) {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	pt.getUnsafe(sourceChainID, targetChainID, eventType).PendingEvents = numPending
+}
+
+func (pt *pipelineStatsTracker) recordSubmissionFailure(sourceChainID, targetChainID *big.Int, eventType score.InterChainMessageEventType) {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	pt.getUnsafe(sourceChainID, targetChainID, eventType).SubmissionFailures++
+}
+
+func (pt *pipelineStatsTracker) getAll() []PipelineStats {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+
+	ret := []PipelineStats{}
+	for _, stats := range pt.stats {
+		ret = append(ret, *stats)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].SourceChainID != ret[j].SourceChainID {
+			return ret[i].SourceChainID < ret[j].SourceChainID
+		}
+		return ret[i].EventType < ret[j].EventType
+	})
+	return ret
+}
+
+// GetPipelineStats returns the stats of the event relaying pipelines.
+func (oc *Orchestrator) GetPipelineStats() []PipelineStats {
+	return oc.pipelineStats.getAll()
+}
+
+// countPendingEvents counts the consecutive cached events of the source chain starting from the given nonce.
+func (oc *Orchestrator) countPendingEvents(sourceChainID *big.Int, eventType score.InterChainMessageEventType, nextNonce *big.Int) uint64 {
+	numPending := uint64(0)
+	nonce := new(big.Int).Set(nextNonce)
+	for numPending < maxPendingEventsProbe {
+		exists, err := oc.interChainEventCache.Exists(sourceChainID, eventType, nonce)
+		if err != nil || !exists {
+			break
+		}
+		numPending++
+		nonce.Add(nonce, big.NewInt(1))
+	}
+	return numPending
+}
@@ -125,8 +125,18 @@ func QueryInterChainEventLog(queriedChainID *big.Int, fromBlock *big.Int, toBloc
 		fmt.Printf("response: %q\n", body)
 	}
 
-	for _, logData := range rpcres.Result {
+	return ExtractInterChainEvents(queriedChainID, rpcres.Result)
+}
+
+// ExtractInterChainEvents converts the token bank logs emitted on the queried chain into inter-chain message events.
+// The logs of other events are skipped.
+func ExtractInterChainEvents(queriedChainID *big.Int, logs []LogData) []*score.InterChainMessageEvent {
+	var events []*score.InterChainMessageEvent
+	for _, logData := range logs {
 		logData := logData
+		if len(logData.Topics) == 0 {
+			continue
+		}
 		switch logData.Topics[0] {
 
 		// TokenLock events
@@ -12,6 +12,7 @@ type ChainWitness interface {
 	Start(ctx context.Context)
 	Stop()
 	Wait()
+	SetSubchainTokenBanks(ledger score.Ledger)
 	GetMainchainBlockHeight() (*big.Int, error)
 	GetValidatorSetByDynasty(dynasty *big.Int) (*score.ValidatorSet, error)
 	GetInterChainEventCache() *siu.InterChainEventCache
@@ -242,6 +242,23 @@ func (mw *MetachainWitness) GetSubchainBlockHeight() (*big.Int, error) {
 	return mw.subchainBlockHeight, nil
 }
 
+// GetScanHeights returns the last block height scanned for inter-chain message events on the
+// mainchain and the subchain, keyed by chain ID.
+func (mw *MetachainWitness) GetScanHeights() map[string]uint64 {
+	heights := make(map[string]uint64)
+	for _, chainID := range []*big.Int{mw.mainchainID, mw.subchainID} {
+		if chainID == nil {
+			continue
+		}
+		height, err := mw.witnessState.getLastQueryedHeightForType(chainID)
+		if err != nil {
+			continue
+		}
+		heights[chainID.String()] = height.Uint64()
+	}
+	return heights
+}
+
 func (mw *MetachainWitness) GetValidatorSetByDynasty(dynasty *big.Int) (*score.ValidatorSet, error) {
 	validatorSet, ok := mw.validatorSetCache[dynasty.String()]
 	if ok && validatorSet != nil && validatorSet.Dynasty() == dynasty {
@@ -284,6 +301,17 @@ func (mw *MetachainWitness) update() {
 	mw.updateSubchainBlockHeight()
 }
 
+// CheckConnectivity verifies that the ETH RPC endpoints of both the mainchain and the subchain respond.
+func (mw *MetachainWitness) CheckConnectivity(ctx context.Context) error {
+	if _, err := mw.mainchainEthRpcClient.BlockNumber(ctx); err != nil {
+		return fmt.Errorf("mainchain ETH RPC %v is unreachable: %v", mw.mainchainEthRpcUrl, err)
+	}
+	if _, err := mw.subchainEthRpcClient.BlockNumber(ctx); err != nil {
+		return fmt.Errorf("subchain ETH RPC %v is unreachable: %v", mw.subchainEthRpcUrl, err)
+	}
+	return nil
+}
+
 func (mw *MetachainWitness) updateMainchainBlockHeight() {
 	mbh, err := mw.mainchainEthRpcClient.BlockNumber(context.Background())
 	if err != nil {
@@ -0,0 +1,400 @@
+package witness
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	scom "github.com/thetatoken/thetasubchain/common"
+	score "github.com/thetatoken/thetasubchain/core"
+	"github.com/thetatoken/thetasubchain/eth/abi"
+	scta "github.com/thetatoken/thetasubchain/interchain/contracts/accessors"
+
+	"github.com/thetatoken/theta/common"
+)
+
+// SimulatedMainchain simulates the parts of the mainchain observed by the subchain validators, i.e. the validator
+// sets registered with the chain registrar, and the token banks which emit the token lock events and unlock the
+// tokens for the vouchers burned on the subchain. It can be shared by the witnesses and the orchestrators of multiple
+// nodes running in the same process, so that they all interact with the same mainchain.
+type SimulatedMainchain struct {
+	mu *sync.RWMutex
+
+	mainchainID *big.Int
+	subchainID  *big.Int
+
+	startingTime    time.Time
+	heightOffset    *big.Int
+	validatorSets   []*score.ValidatorSet // sorted by dynasty, each one is effective from its dynasty on
+	events          []*score.InterChainMessageEvent
+	lastEventNonces map[score.InterChainMessageEventType]*big.Int
+
+	// The token banks
+	maxProcessedVoucherBurnNonces map[string]*big.Int                   // keyed by token type and source chain ID
+	unlockVotes                   map[string]map[string]map[string]bool // the voters of the pending unlocks, keyed by token type and source chain ID
+	unlocks                       []*SimulatedTokenUnlock               // the executed unlocks, in order
+}
+
+// SimulatedTokenUnlock is a token unlock executed by the token banks of the simulated mainchain for the vouchers
+// burned on a subchain.
+type SimulatedTokenUnlock struct {
+	TokenType        score.CrossChainTokenType
+	SourceChainID    *big.Int
+	Denom            string
+	Receiver         common.Address
+	TokenID          *big.Int // TNT721 and TNT1155 only
+	Amount           *big.Int // TFuel, TNT20 and TNT1155 only
+	VoucherBurnNonce *big.Int
+}
+
+func (u *SimulatedTokenUnlock) String() string {
+	return fmt.Sprintf("{TokenType: %v, SourceChainID: %v, Denom: %v, Receiver: %v, TokenID: %v, Amount: %v, VoucherBurnNonce: %v}",
+		u.TokenType, u.SourceChainID, u.Denom, u.Receiver.Hex(), u.TokenID, u.Amount, u.VoucherBurnNonce)
+}
+
+// NewSimulatedMainchain creates a new SimulatedMainchain with the validator set registered for the genesis dynasty.
+func NewSimulatedMainchain(mainchainIDStr string, subchainIDStr string, initValidatorSet *score.ValidatorSet) *SimulatedMainchain {
+	mc := &SimulatedMainchain{
+		mu:              &sync.RWMutex{},
+		mainchainID:     scom.MapChainID(mainchainIDStr),
+		subchainID:      scom.MapChainID(subchainIDStr),
+		startingTime:    time.Now(),
+		heightOffset:    big.NewInt(0),
+		lastEventNonces: make(map[score.InterChainMessageEventType]*big.Int),
+
+		maxProcessedVoucherBurnNonces: make(map[string]*big.Int),
+		unlockVotes:                   make(map[string]map[string]map[string]bool),
+	}
+	mc.SetValidatorSet(big.NewInt(0), initValidatorSet.Validators())
+	return mc
+}
+
+// GetBlockHeight returns the current block height of the simulated mainchain, which grows with the time
+// elapsed since the mainchain was created, plus the blocks skipped with AdvanceBlocks().
+func (mc *SimulatedMainchain) GetBlockHeight() *big.Int {
+	mc.mu.RLock()
+	defer mc.mu.RUnlock()
+
+	return mc.getBlockHeight()
+}
+
+func (mc *SimulatedMainchain) getBlockHeight() *big.Int {
+	blockHeight := big.NewInt(int64(time.Since(mc.startingTime).Milliseconds()) / mainchainBlockIntervalMilliseconds)
+	return blockHeight.Add(blockHeight, mc.heightOffset)
+}
+
+// AdvanceBlocks fast-forwards the simulated mainchain by the given number of blocks.
+func (mc *SimulatedMainchain) AdvanceBlocks(numBlocks int64) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	mc.heightOffset = new(big.Int).Add(mc.heightOffset, big.NewInt(numBlocks))
+}
+
+// AdvanceToDynasty fast-forwards the simulated mainchain to the first block of the given dynasty. It is a no-op
+// if the mainchain has already reached the dynasty.
+func (mc *SimulatedMainchain) AdvanceToDynasty(dynasty *big.Int) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	target := new(big.Int).Mul(dynasty, big.NewInt(scom.NumMainchainBlocksPerDynasty))
+	height := mc.getBlockHeight()
+	if height.Cmp(target) < 0 {
+		mc.heightOffset = new(big.Int).Add(mc.heightOffset, new(big.Int).Sub(target, height))
+	}
+}
+
+// SetValidatorSet registers the validators of the subchain for the given dynasty and all the dynasties after it,
+// until another validator set is registered.
+func (mc *SimulatedMainchain) SetValidatorSet(dynasty *big.Int, validators []score.Validator) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	validatorSet := score.NewValidatorSet(new(big.Int).Set(dynasty))
+	for _, v := range validators {
+		validatorSet.AddValidator(v)
+	}
+
+	for i, vs := range mc.validatorSets {
+		if vs.Dynasty().Cmp(dynasty) == 0 {
+			mc.validatorSets[i] = validatorSet
+			return
+		}
+	}
+	mc.validatorSets = append(mc.validatorSets, validatorSet)
+	sort.Slice(mc.validatorSets, func(i, j int) bool {
+		return mc.validatorSets[i].Dynasty().Cmp(mc.validatorSets[j].Dynasty()) < 0
+	})
+}
+
+// GetValidatorSet returns the validator set of the subchain for the given dynasty.
+func (mc *SimulatedMainchain) GetValidatorSet(dynasty *big.Int) (*score.ValidatorSet, error) {
+	mc.mu.RLock()
+	defer mc.mu.RUnlock()
+
+	return mc.getValidatorSet(dynasty)
+}
+
+func (mc *SimulatedMainchain) getValidatorSet(dynasty *big.Int) (*score.ValidatorSet, error) {
+	for i := len(mc.validatorSets) - 1; i >= 0; i-- {
+		vs := mc.validatorSets[i]
+		if vs.Dynasty().Cmp(dynasty) <= 0 {
+			validatorSet := score.NewValidatorSet(new(big.Int).Set(dynasty))
+			for _, v := range vs.Validators() {
+				validatorSet.AddValidator(v)
+			}
+			return validatorSet, nil
+		}
+	}
+	return nil, fmt.Errorf("no validator set registered for dynasty %v", dynasty)
+}
+
+// LockTFuel simulates locking TFuel in the TFuel token bank of the mainchain, so that TFuel vouchers are
+// minted for the receiver on the subchain.
+func (mc *SimulatedMainchain) LockTFuel(sender common.Address, receiver common.Address, amount *big.Int) *score.InterChainMessageEvent {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	nonce := mc.nextEventNonce(score.IMCEventTypeCrossChainTokenLockTFuel)
+	event := generateInterChainEventForTFuelLock(mc.mainchainID, mc.subchainID, sender, receiver, amount, nonce, mc.getBlockHeight())
+	mc.events = append(mc.events, event)
+	return event
+}
+
+// LockTNT20 simulates locking TNT20 tokens in the TNT20 token bank of the mainchain.
+func (mc *SimulatedMainchain) LockTNT20(tokenSourceAddress common.Address, sender common.Address, receiver common.Address,
+	tokenName string, tokenSymbol string, tokenDecimals uint8, tokenAmount *big.Int) *score.InterChainMessageEvent {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	nonce := mc.nextEventNonce(score.IMCEventTypeCrossChainTokenLockTNT20)
+	event := generateInterChainEventForTNT20Lock(mc.mainchainID, mc.subchainID, tokenSourceAddress, sender, receiver,
+		tokenName, tokenSymbol, tokenDecimals, tokenAmount, nonce, mc.getBlockHeight())
+	mc.events = append(mc.events, event)
+	return event
+}
+
+// LockTNT721 simulates locking a TNT721 token in the TNT721 token bank of the mainchain.
+func (mc *SimulatedMainchain) LockTNT721(tokenSourceAddress common.Address, sender common.Address, receiver common.Address,
+	tokenName string, tokenSymbol string, tokenID *big.Int, tokenURI string) *score.InterChainMessageEvent {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	nonce := mc.nextEventNonce(score.IMCEventTypeCrossChainTokenLockTNT721)
+	event := generateInterChainEventForTNT721Lock(mc.mainchainID, mc.subchainID, tokenSourceAddress, sender, receiver,
+		tokenName, tokenSymbol, tokenID, tokenURI, nonce, mc.getBlockHeight())
+	mc.events = append(mc.events, event)
+	return event
+}
+
+// LockTNT1155 simulates locking TNT1155 tokens in the TNT1155 token bank of the mainchain.
+func (mc *SimulatedMainchain) LockTNT1155(tokenSourceAddress common.Address, sender common.Address, receiver common.Address,
+	tokenID *big.Int, tokenAmount *big.Int, tokenURI string) *score.InterChainMessageEvent {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	nonce := mc.nextEventNonce(score.IMCEventTypeCrossChainTokenLockTNT1155)
+	event := generateInterChainEventForTNT1155Lock(mc.mainchainID, mc.subchainID, tokenSourceAddress, sender, receiver,
+		tokenID, tokenAmount, tokenURI, nonce, mc.getBlockHeight())
+	mc.events = append(mc.events, event)
+	return event
+}
+
+// UnlockTokens simulates a validator calling unlockTokens() of a token bank on the mainchain for the vouchers burned
+// on a subchain. Like the token banks, the unlock is executed once validators with more than 2/3 of the stake of the
+// dynasty have voted for it, and the voucher burns of each source chain are processed strictly in nonce order.
+// Votes for a voucher burn which has already been processed are ignored.
+func (mc *SimulatedMainchain) UnlockTokens(validator common.Address, dynasty *big.Int, unlock *SimulatedTokenUnlock) error {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	validatorSet, err := mc.getValidatorSet(dynasty)
+	if err != nil {
+		return err
+	}
+	if _, err := validatorSet.GetValidator(validator); err != nil {
+		return fmt.Errorf("%v is not a validator of the subchain for dynasty %v", validator.Hex(), dynasty)
+	}
+
+	nonceKey := voucherBurnNonceKey(unlock.TokenType, unlock.SourceChainID)
+	maxProcessedNonce := mc.getMaxProcessedVoucherBurnNonce(nonceKey)
+	if unlock.VoucherBurnNonce.Cmp(maxProcessedNonce) <= 0 {
+		return nil // already unlocked
+	}
+	if unlock.VoucherBurnNonce.Cmp(new(big.Int).Add(maxProcessedNonce, big.NewInt(1))) != 0 {
+		return fmt.Errorf("voucher burn nonce %v is out of order, max processed nonce: %v", unlock.VoucherBurnNonce, maxProcessedNonce)
+	}
+
+	// Only the next voucher burn can be pending. Validators voting for different parameters of it vote for
+	// different unlocks.
+	pendingUnlocks, ok := mc.unlockVotes[nonceKey]
+	if !ok {
+		pendingUnlocks = make(map[string]map[string]bool)
+		mc.unlockVotes[nonceKey] = pendingUnlocks
+	}
+	unlockKey := unlock.String()
+	votes, ok := pendingUnlocks[unlockKey]
+	if !ok {
+		votes = make(map[string]bool)
+		pendingUnlocks[unlockKey] = votes
+	}
+	votes[validator.Hex()] = true
+
+	votedStake := big.NewInt(0)
+	for _, v := range validatorSet.Validators() {
+		if votes[v.Address.Hex()] {
+			votedStake.Add(votedStake, v.Stake)
+		}
+	}
+	threshold := new(big.Int).Mul(validatorSet.TotalStake(), big.NewInt(2))
+	if new(big.Int).Mul(votedStake, big.NewInt(3)).Cmp(threshold) <= 0 {
+		return nil
+	}
+
+	mc.unlocks = append(mc.unlocks, unlock)
+	mc.maxProcessedVoucherBurnNonces[nonceKey] = new(big.Int).Set(unlock.VoucherBurnNonce)
+	delete(mc.unlockVotes, nonceKey)
+	logger.Infof("Unlocked tokens on the simulated mainchain: %v", unlock)
+	return nil
+}
+
+// GetMaxProcessedVoucherBurnNonce returns the nonce of the last voucher burn of the source chain for which the
+// token bank of the given type has unlocked the tokens.
+func (mc *SimulatedMainchain) GetMaxProcessedVoucherBurnNonce(tokenType score.CrossChainTokenType, sourceChainID *big.Int) *big.Int {
+	mc.mu.RLock()
+	defer mc.mu.RUnlock()
+
+	return new(big.Int).Set(mc.getMaxProcessedVoucherBurnNonce(voucherBurnNonceKey(tokenType, sourceChainID)))
+}
+
+func (mc *SimulatedMainchain) getMaxProcessedVoucherBurnNonce(nonceKey string) *big.Int {
+	nonce, ok := mc.maxProcessedVoucherBurnNonces[nonceKey]
+	if !ok {
+		return big.NewInt(0)
+	}
+	return nonce
+}
+
+// GetTokenUnlocks returns the token unlocks executed by the token banks so far, in order.
+func (mc *SimulatedMainchain) GetTokenUnlocks() []*SimulatedTokenUnlock {
+	mc.mu.RLock()
+	defer mc.mu.RUnlock()
+
+	unlocks := make([]*SimulatedTokenUnlock, len(mc.unlocks))
+	copy(unlocks, mc.unlocks)
+	return unlocks
+}
+
+func voucherBurnNonceKey(tokenType score.CrossChainTokenType, sourceChainID *big.Int) string {
+	return fmt.Sprintf("%v/%v", tokenType, sourceChainID)
+}
+
+// getEventsSince returns the events emitted since the given index, and the index of the next event.
+func (mc *SimulatedMainchain) getEventsSince(index int) ([]*score.InterChainMessageEvent, int) {
+	mc.mu.RLock()
+	defer mc.mu.RUnlock()
+
+	if index >= len(mc.events) {
+		return nil, index
+	}
+	events := make([]*score.InterChainMessageEvent, len(mc.events)-index)
+	copy(events, mc.events[index:])
+	return events, len(mc.events)
+}
+
+func (mc *SimulatedMainchain) nextEventNonce(eventType score.InterChainMessageEventType) *big.Int {
+	lastNonce, ok := mc.lastEventNonces[eventType]
+	if !ok {
+		lastNonce = big.NewInt(0)
+	}
+	nonce := new(big.Int).Add(lastNonce, big.NewInt(1))
+	mc.lastEventNonces[eventType] = nonce
+	return nonce
+}
+
+// packTokenBankEvent encodes the data of a token bank event the same way as the event logs on the mainchain.
+func packTokenBankEvent(tokenBankABI string, eventName string, args ...interface{}) []byte {
+	contractAbi, err := abi.JSON(strings.NewReader(tokenBankABI))
+	if err != nil {
+		logger.Panicf("failed to parse the token bank ABI: %v", err)
+	}
+	data, err := contractAbi.Events[eventName].Inputs.Pack(args...)
+	if err != nil {
+		logger.Panicf("failed to encode the %v event data: %v", eventName, err)
+	}
+	return data
+}
+
+func generateInterChainEventForTFuelLock(mainchainID *big.Int, subchainID *big.Int, sender common.Address, receiver common.Address,
+	amount *big.Int, nonce *big.Int, mainchainBlockNumber *big.Int) *score.InterChainMessageEvent {
+	tfuelDenom := score.TFuelDenom(mainchainID)
+	data := packTokenBankEvent(scta.TFuelTokenBankABI, "TFuelTokenLocked",
+		tfuelDenom, sender, subchainID, receiver, amount, nonce)
+
+	return score.NewInterChainMessageEvent(
+		score.IMCEventTypeCrossChainTokenLockTFuel,
+		mainchainID,
+		subchainID,
+		sender,
+		receiver,
+		data,
+		nonce,
+		mainchainBlockNumber)
+}
+
+func generateInterChainEventForTNT20Lock(mainchainID *big.Int, subchainID *big.Int, tokenSourceAddress common.Address,
+	sender common.Address, receiver common.Address, tokenName string, tokenSymbol string, tokenDecimals uint8,
+	tokenAmount *big.Int, nonce *big.Int, mainchainBlockNumber *big.Int) *score.InterChainMessageEvent {
+	tnt20Denom := score.TNT20Denom(mainchainID, tokenSourceAddress)
+	data := packTokenBankEvent(scta.TNT20TokenBankABI, "TNT20TokenLocked",
+		tnt20Denom, sender, subchainID, receiver, tokenAmount, tokenName, tokenSymbol, tokenDecimals, nonce)
+
+	return score.NewInterChainMessageEvent(
+		score.IMCEventTypeCrossChainTokenLockTNT20,
+		mainchainID,
+		subchainID,
+		sender,
+		receiver,
+		data,
+		nonce,
+		mainchainBlockNumber)
+}
+
+func generateInterChainEventForTNT721Lock(mainchainID *big.Int, subchainID *big.Int, tokenSourceAddress common.Address,
+	sender common.Address, receiver common.Address, tokenName string, tokenSymbol string, tokenID *big.Int,
+	tokenURI string, nonce *big.Int, mainchainBlockNumber *big.Int) *score.InterChainMessageEvent {
+	tnt721Denom := score.TNT721Denom(mainchainID, tokenSourceAddress)
+	data := packTokenBankEvent(scta.TNT721TokenBankABI, "TNT721TokenLocked",
+		tnt721Denom, sender, subchainID, receiver, tokenID, tokenName, tokenSymbol, tokenURI, nonce)
+
+	return score.NewInterChainMessageEvent(
+		score.IMCEventTypeCrossChainTokenLockTNT721,
+		mainchainID,
+		subchainID,
+		sender,
+		receiver,
+		data,
+		nonce,
+		mainchainBlockNumber)
+}
+
+func generateInterChainEventForTNT1155Lock(mainchainID *big.Int, subchainID *big.Int, tokenSourceAddress common.Address,
+	sender common.Address, receiver common.Address, tokenID *big.Int, tokenAmount *big.Int, tokenURI string,
+	nonce *big.Int, mainchainBlockNumber *big.Int) *score.InterChainMessageEvent {
+	tnt1155Denom := score.TNT1155Denom(mainchainID, tokenSourceAddress)
+	data := packTokenBankEvent(scta.TNT1155TokenBankABI, "TNT1155TokenLocked",
+		tnt1155Denom, sender, subchainID, receiver, tokenID, tokenAmount, tokenURI, nonce)
+
+	return score.NewInterChainMessageEvent(
+		score.IMCEventTypeCrossChainTokenLockTNT1155,
+		mainchainID,
+		subchainID,
+		sender,
+		receiver,
+		data,
+		nonce,
+		mainchainBlockNumber)
+}
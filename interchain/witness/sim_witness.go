@@ -6,16 +6,21 @@ import (
 	"sync"
 	"time"
 
-	"github.com/ethereum/go-ethereum/rlp"
 	scom "github.com/thetatoken/thetasubchain/common"
 	score "github.com/thetatoken/thetasubchain/core"
+	ethereum "github.com/thetatoken/thetasubchain/eth"
+	ec "github.com/thetatoken/thetasubchain/eth/ethclient"
 	siu "github.com/thetatoken/thetasubchain/interchain/utils"
 
 	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/hexutil"
 )
 
 const mainchainBlockIntervalMilliseconds int64 = 2000 // millseconds
 
+// simAccount is the sender and receiver of the test events generated by the SimulatedMetachainWitness
+var simAccount = common.HexToAddress("0x2E833968E5bB786Ae419c4d13189fB081Cc43bab")
+
 // SimulatedMetachainWitness is a simulated mainchain witness for end-to-end testing
 type SimulatedMetachainWitness struct {
 	mainchainID *big.Int
@@ -35,6 +40,14 @@ type SimulatedMetachainWitness struct {
 	lastSimEventNonce    map[score.InterChainMessageEventType]*big.Int
 	hasTransferredTNT721 bool
 	testId               int
+
+	mainchain         *SimulatedMainchain // optional, the mainchain shared with the witnesses of the other nodes
+	mainchainEventIdx int
+
+	// Optional, the subchain is witnessed only if the ETH RPC client is provided
+	subchainEthRpcClient    *ec.Client
+	subchainTokenBankAddrs  []common.Address
+	subchainNextQueryHeight uint64
 }
 
 // NewSimulatedMetachainWitness creates a new SimulatedMetachainWitness
@@ -73,13 +86,36 @@ func NewSimulatedMetachainWitness(
 	return mw
 }
 
+// NewSimulatedMetachainWitnessForMainchain creates a new SimulatedMetachainWitness which witnesses the validator
+// sets and the token lock events of the given simulated mainchain, instead of generating the test events itself.
+// If the subchain ETH RPC client is not nil, the witness also collects the events emitted by the subchain token
+// banks, e.g. the voucher burns, like the MetachainWitness does.
+func NewSimulatedMetachainWitnessForMainchain(
+	mainchain *SimulatedMainchain,
+	crossChainEventCache *siu.InterChainEventCache,
+	subchainEthRpcClient *ec.Client,
+) *SimulatedMetachainWitness {
+	return &SimulatedMetachainWitness{
+		mainchainID:          mainchain.mainchainID,
+		subchainID:           mainchain.subchainID,
+		witnessedDynasty:     nil, // will be updated in the first update() call
+		validatorSetCache:    make(map[string]*score.ValidatorSet),
+		startingTime:         mainchain.startingTime,
+		crossChainEventCache: crossChainEventCache,
+		wg:                   &sync.WaitGroup{},
+		lastSimEventNonce:    make(map[score.InterChainMessageEventType]*big.Int),
+		mainchain:            mainchain,
+		subchainEthRpcClient: subchainEthRpcClient,
+	}
+}
+
 func (mw *SimulatedMetachainWitness) Start(ctx context.Context) {
 	c, cancel := context.WithCancel(ctx)
 	mw.ctx = c
 	mw.cancel = cancel
 
 	mw.wg.Add(1)
-	go mw.mainloop(ctx)
+	go mw.mainloop(c)
 }
 
 func (mw *SimulatedMetachainWitness) Stop() {
@@ -94,9 +130,28 @@ func (mw *SimulatedMetachainWitness) Wait() {
 }
 
 func (mw *SimulatedMetachainWitness) SetSubchainTokenBanks(ledger score.Ledger) {
+	if mw.subchainEthRpcClient == nil {
+		return
+	}
+	tokenTypes := []score.CrossChainTokenType{
+		score.CrossChainTokenTypeTFuel,
+		score.CrossChainTokenTypeTNT20,
+		score.CrossChainTokenTypeTNT721,
+		score.CrossChainTokenTypeTNT1155,
+	}
+	for _, tokenType := range tokenTypes {
+		tokenBankAddr := ledger.GetTokenBankContractAddress(tokenType)
+		if tokenBankAddr == nil {
+			logger.Fatalf("failed to obtain the subchain token bank contract address for token type %v", tokenType)
+		}
+		mw.subchainTokenBankAddrs = append(mw.subchainTokenBankAddrs, *tokenBankAddr)
+	}
 }
 
 func (mw *SimulatedMetachainWitness) GetMainchainBlockHeight() (*big.Int, error) {
+	if mw.mainchain != nil {
+		return mw.mainchain.GetBlockHeight(), nil
+	}
 	blockHeight := int64((time.Since(mw.startingTime)).Milliseconds()) / mainchainBlockIntervalMilliseconds
 	return big.NewInt(int64(blockHeight)), nil
 }
@@ -117,6 +172,8 @@ func (mw *SimulatedMetachainWitness) GetValidatorSetByDynasty(dynasty *big.Int)
 }
 
 func (mw *SimulatedMetachainWitness) mainloop(ctx context.Context) {
+	defer mw.wg.Done()
+
 	mw.updateTicker = time.NewTicker(time.Duration(1000) * time.Millisecond)
 	for {
 		select {
@@ -143,6 +200,17 @@ func (mw *SimulatedMetachainWitness) update() {
 		mw.witnessedDynasty = dynasty
 		logger.Infof("updated the witnessed dynasty to %v", dynasty)
 	}
+	if mw.mainchain != nil {
+		var events []*score.InterChainMessageEvent
+		events, mw.mainchainEventIdx = mw.mainchain.getEventsSince(mw.mainchainEventIdx)
+		if len(events) > 0 {
+			if err := mw.crossChainEventCache.InsertList(events); err != nil {
+				logger.Warnf("failed to insert the witnessed inter-chain message events: %v", err)
+			}
+		}
+		mw.collectInterChainMessageEventsOnSubchain()
+		return
+	}
 	if mw.testId == 0 {
 		// TFuel cross-chain transfers
 		for i := 0; i < 1; i++ {
@@ -207,7 +275,70 @@ func (mw *SimulatedMetachainWitness) update() {
 	}
 }
 
+// collectInterChainMessageEventsOnSubchain queries the logs of the subchain token banks in the blocks since the
+// last query.
+func (mw *SimulatedMetachainWitness) collectInterChainMessageEventsOnSubchain() {
+	if mw.subchainEthRpcClient == nil || len(mw.subchainTokenBankAddrs) == 0 {
+		return
+	}
+	toBlock, err := mw.subchainEthRpcClient.BlockNumber(context.Background())
+	if err != nil {
+		logger.Warnf("failed to get the subchain block height %v", err)
+		return
+	}
+	if toBlock < mw.subchainNextQueryHeight {
+		return
+	}
+
+	topics := []common.Hash{}
+	for _, eventTopicString := range siu.EventSelectors {
+		topics = append(topics, common.HexToHash(eventTopicString))
+	}
+	logs, err := mw.subchainEthRpcClient.FilterLogs(context.Background(), ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(mw.subchainNextQueryHeight),
+		ToBlock:   new(big.Int).SetUint64(toBlock),
+		Addresses: mw.subchainTokenBankAddrs,
+		Topics:    [][]common.Hash{topics},
+	})
+	if err != nil {
+		logger.Warnf("failed to query the subchain token bank logs %v", err)
+		return // ignore, the query is repeated periodically anyway
+	}
+
+	logData := []siu.LogData{}
+	for _, log := range logs {
+		ld := siu.LogData{
+			LogIndex:         hexutil.EncodeUint64(uint64(log.Index)),
+			TransactionIndex: hexutil.EncodeUint64(uint64(log.TxIndex)),
+			TransactionHash:  log.TxHash.Hex(),
+			BlockHash:        log.BlockHash.Hex(),
+			BlockNumber:      hexutil.EncodeUint64(log.BlockNumber),
+			Address:          log.Address.Hex(),
+			Data:             hexutil.Encode(log.Data),
+		}
+		for _, topic := range log.Topics {
+			ld.Topics = append(ld.Topics, topic.Hex())
+		}
+		logData = append(logData, ld)
+	}
+	events := siu.ExtractInterChainEvents(mw.subchainID, logData)
+	if err := mw.crossChainEventCache.InsertList(events); err != nil {
+		logger.Warnf("failed to insert the witnessed inter-chain message events: %v", err)
+		return
+	}
+	mw.subchainNextQueryHeight = toBlock + 1
+}
+
 func (mw *SimulatedMetachainWitness) updateValidatorSetCache(dynasty *big.Int) (*score.ValidatorSet, error) {
+	if mw.mainchain != nil {
+		validatorSet, err := mw.mainchain.GetValidatorSet(dynasty)
+		if err != nil {
+			return nil, err
+		}
+		mw.validatorSetCache[dynasty.String()] = validatorSet
+		return validatorSet, nil
+	}
+
 	// Simulate validator set updates
 	validatorAddrList := []string{
 		"0x2E833968E5bB786Ae419c4d13189fB081Cc43bab",
@@ -251,86 +382,17 @@ func (mw *SimulatedMetachainWitness) GetInterChainEventCache() *siu.InterChainEv
 }
 
 func (mw *SimulatedMetachainWitness) generateInterChainEventForTFuelLock(amount *big.Int, nonce *big.Int, mainchainBlockNumber *big.Int) *score.InterChainMessageEvent {
-	tfuelDenom := score.TFuelDenom(mw.mainchainID)
-	data, err := rlp.EncodeToBytes(score.CrossChainTFuelTokenLockedEvent{
-		Denom:                      tfuelDenom,
-		SourceChainTokenSender:     common.HexToAddress("0x2E833968E5bB786Ae419c4d13189fB081Cc43bab"),
-		TargetChainID:              mw.mainchainID,
-		TargetChainVoucherReceiver: common.HexToAddress("0x2E833968E5bB786Ae419c4d13189fB081Cc43bab"),
-		LockedAmount:               amount,
-		TokenLockNonce:             nonce,
-	})
-	if err != nil {
-		logger.Panicf("failed to get encode inter-chain message event data for TFuel transfer: %v", err)
-	}
-
-	event := score.NewInterChainMessageEvent(
-		score.IMCEventTypeCrossChainTokenLockTFuel,
-		mw.mainchainID,
-		mw.subchainID,
-		common.HexToAddress("0x2E833968E5bB786Ae419c4d13189fB081Cc43bab"),
-		common.HexToAddress("0x2E833968E5bB786Ae419c4d13189fB081Cc43bab"),
-		data,
-		nonce,
-		mainchainBlockNumber)
-
-	return event
+	return generateInterChainEventForTFuelLock(mw.mainchainID, mw.subchainID, simAccount, simAccount, amount, nonce, mainchainBlockNumber)
 }
 
 func (mw *SimulatedMetachainWitness) generateInterChainEventForTNT20Lock(tokenSourceAddress common.Address,
 	tokenName string, tokenSymbol string, tokenDecimals uint8, tokenAmount *big.Int, nonce *big.Int, mainchainBlockNumber *big.Int) *score.InterChainMessageEvent {
-	tnt20Denom := score.TNT20Denom(mw.mainchainID, tokenSourceAddress)
-	data, err := rlp.EncodeToBytes(score.CrossChainTNT20TokenLockedEvent{
-		Denom:                      tnt20Denom,
-		SourceChainTokenSender:     common.HexToAddress("0x2E833968E5bB786Ae419c4d13189fB081Cc43bab"),
-		TargetChainID:              mw.subchainID,
-		TargetChainVoucherReceiver: common.HexToAddress("0x2E833968E5bB786Ae419c4d13189fB081Cc43bab"),
-		LockedAmount:               tokenAmount,
-		Name:                       tokenName,
-		Symbol:                     tokenSymbol,
-		Decimals:                   tokenDecimals,
-		TokenLockNonce:             nonce,
-	})
-	if err != nil {
-		logger.Panicf("failed to get encode inter-chain message event data for TNT20 token transfer: %v", err)
-	}
-
-	event := score.NewInterChainMessageEvent(
-		score.IMCEventTypeCrossChainTokenLockTNT20,
-		mw.mainchainID,
-		mw.subchainID,
-		common.HexToAddress("0x2E833968E5bB786Ae419c4d13189fB081Cc43bab"),
-		common.HexToAddress("0x2E833968E5bB786Ae419c4d13189fB081Cc43bab"),
-		data,
-		nonce,
-		mainchainBlockNumber)
-
-	return event
+	return generateInterChainEventForTNT20Lock(mw.mainchainID, mw.subchainID, tokenSourceAddress, simAccount, simAccount,
+		tokenName, tokenSymbol, tokenDecimals, tokenAmount, nonce, mainchainBlockNumber)
 }
 
 func (mw *SimulatedMetachainWitness) generateInterChainEventForTNT721Lock(tokenSourceAddress common.Address,
 	tokenName string, tokenSymbol string, tokenID *big.Int, tokenURI string, nonce *big.Int, mainchainBlockNumber *big.Int) *score.InterChainMessageEvent {
-	tnt721Denom := score.TNT721Denom(mw.mainchainID, tokenSourceAddress)
-	data, err := rlp.EncodeToBytes(score.CrossChainTNT721TokenLockedEvent{
-		Denom:    tnt721Denom,
-		Name:     tokenName,
-		Symbol:   tokenSymbol,
-		TokenID:  tokenID,
-		TokenURI: tokenURI,
-	})
-	if err != nil {
-		logger.Panicf("failed to get encode inter-chain message event data for TNT721 token transfer: %v", err)
-	}
-
-	event := score.NewInterChainMessageEvent(
-		score.IMCEventTypeCrossChainTokenLockTNT721,
-		mw.mainchainID,
-		mw.subchainID,
-		common.HexToAddress("0x2E833968E5bB786Ae419c4d13189fB081Cc43bab"),
-		common.HexToAddress("0x2E833968E5bB786Ae419c4d13189fB081Cc43bab"),
-		data,
-		nonce,
-		mainchainBlockNumber)
-
-	return event
+	return generateInterChainEventForTNT721Lock(mw.mainchainID, mw.subchainID, tokenSourceAddress, simAccount, simAccount,
+		tokenName, tokenSymbol, tokenID, tokenURI, nonce, mainchainBlockNumber)
 }
@@ -13,6 +13,7 @@ import (
 	scom "github.com/thetatoken/thetasubchain/common"
 	score "github.com/thetatoken/thetasubchain/core"
 	slst "github.com/thetatoken/thetasubchain/ledger/state"
+	stypes "github.com/thetatoken/thetasubchain/ledger/types"
 )
 
 // --------------------------------- Execution Utilities -------------------------------------
@@ -170,15 +171,23 @@ func validateInputsBasic(ins []types.TxInput) result.Result {
 	return result.OK
 }
 
-// Validate inputs and compute total amount of coins
-func validateInputsAdvanced(accounts map[string]*types.Account, signBytes []byte, ins []types.TxInput, blockHeight uint64) (total types.Coins, res result.Result) {
+// Validate inputs and compute total amount of coins. The inputs owned by multisig accounts
+// are authorized by the multisig signatures instead of the input signatures.
+func validateInputsAdvanced(accounts map[string]*types.Account, signBytes []byte, ins []types.TxInput, multisigs []stypes.MultisigAuth, blockHeight uint64) (total types.Coins, res result.Result) {
 	total = types.NewCoins(0, 0)
 	for _, in := range ins {
 		acc := accounts[string(in.Address[:])]
 		if acc == nil {
 			panic("validateInputsAdvanced() expects account in accounts")
 		}
-		res = validateInputAdvanced(acc, signBytes, in, blockHeight)
+		var multisig *stypes.MultisigAuth
+		for i := range multisigs {
+			if multisigs[i].Account.Address() == in.Address {
+				multisig = &multisigs[i]
+				break
+			}
+		}
+		res = validateInputAdvanced(acc, signBytes, in, multisig, blockHeight)
 		if res.IsError() {
 			return
 		}
@@ -188,7 +197,7 @@ func validateInputsAdvanced(accounts map[string]*types.Account, signBytes []byte
 	return total, result.OK
 }
 
-func validateInputAdvanced(acc *types.Account, signBytes []byte, in types.TxInput, blockHeight uint64) result.Result {
+func validateInputAdvanced(acc *types.Account, signBytes []byte, in types.TxInput, multisig *stypes.MultisigAuth, blockHeight uint64) result.Result {
 	// Check sequence/coins
 	seq, balance := acc.Sequence, acc.Balance
 	if seq+1 != in.Sequence {
@@ -202,6 +211,26 @@ func validateInputAdvanced(acc *types.Account, signBytes []byte, in types.TxInpu
 			balance, in.Coins).WithErrorCode(result.CodeInsufficientFund)
 	}
 
+	// Check multisig signatures
+	if multisig != nil {
+		if in.Signature != nil {
+			return result.Error("Multisig input %v must not carry a signature", acc.Address).
+				WithErrorCode(result.CodeInvalidSignature)
+		}
+		err := multisig.Verify(signBytes)
+		if err != nil && blockHeight >= common.HeightTxWrapperExtension {
+			signBytesV2 := types.ChangeEthereumTxWrapper(signBytes, 2)
+			if multisig.Verify(signBytesV2) == nil {
+				err = nil
+			}
+		}
+		if err != nil {
+			return result.Error("Multisig verification failed for %v: %v, SignBytes: %v",
+				acc.Address, err, hex.EncodeToString(signBytes)).WithErrorCode(result.CodeInvalidSignature)
+		}
+		return result.OK
+	}
+
 	// Check signatures
 	signatureValid := in.Signature.Verify(signBytes, acc.Address)
 	if blockHeight >= common.HeightTxWrapperExtension {
@@ -354,9 +383,15 @@ func sanityCheckForFee(fee types.Coins, blockHeight uint64) (minimumFee *big.Int
 	return minimumFee, success
 }
 
-func sanityCheckForSendTxFee(fee types.Coins, numAccountsAffected uint64, blockHeight uint64) (minimumFee *big.Int, success bool) {
+// sanityCheckForSendTxFee checks the fee of a transfer. Besides the fee of the accounts affected, each
+// batch output, i.e. an output of a BatchSendTx beyond the first one, is charged a reduced per-output fee.
+func sanityCheckForSendTxFee(fee types.Coins, numAccountsAffected uint64, numBatchOutputs uint64, blockHeight uint64) (minimumFee *big.Int, success bool) {
 	fee = fee.NoNil()
 	minimumFee = types.GetSendTxMinimumTransactionFeeTFuelWei(numAccountsAffected, blockHeight)
+	if numBatchOutputs > 0 {
+		outputFee := stypes.GetBatchSendTxOutputFeeTFuelWei(blockHeight)
+		minimumFee = new(big.Int).Add(minimumFee, new(big.Int).Mul(outputFee, new(big.Int).SetUint64(numBatchOutputs)))
+	}
 	success = (fee.ThetaWei.Cmp(types.Zero) == 0 && fee.TFuelWei.Cmp(minimumFee) >= 0)
 
 	return minimumFee, success
@@ -1,6 +1,8 @@
 package execution
 
 import (
+	"math/big"
+
 	log "github.com/sirupsen/logrus"
 
 	"github.com/thetatoken/theta/common"
@@ -39,6 +41,8 @@ type Executor struct {
 	coinbaseTxExec                   *CoinbaseTxExecutor
 	subchainValidatorSetUpdateTxExec *SubchainValidatorSetUpdateTxExecutor
 	sendTxExec                       *SendTxExecutor
+	multisigSendTxExec               *MultisigSendTxExecutor
+	batchSendTxExec                  *BatchSendTxExecutor
 	smartContractTxExec              *SmartContractTxExecutor
 
 	skipSanityCheck bool
@@ -56,6 +60,8 @@ func NewExecutor(db database.Database, chain *sbc.Chain, state *slst.LedgerState
 		coinbaseTxExec:                   NewCoinbaseTxExecutor(db, chain, state, consensus, valMgr),
 		subchainValidatorSetUpdateTxExec: NewSubchainValidatorSetUpdateTxExecutor(db, chain, state, consensus, valMgr, metachainWitness),
 		sendTxExec:                       NewSendTxExecutor(state),
+		multisigSendTxExec:               NewMultisigSendTxExecutor(state),
+		batchSendTxExec:                  NewBatchSendTxExecutor(state),
 		smartContractTxExec:              NewSmartContractTxExecutor(chain, state, ledger, valMgr),
 		skipSanityCheck:                  false,
 	}
@@ -84,6 +90,39 @@ func (exec *Executor) ScreenTx(tx types.Tx) (common.Hash, result.Result) {
 	return exec.processTx(tx, score.ScreenedView)
 }
 
+// ScreenTxAtSequence checks the validity of a transaction as if the sender sequence in the
+// screened view were right before the tx sequence. It is used to screen txs that replace
+// pending txs, or that are queued until the preceding txs arrive. The coins spent by the
+// replaced tx, if any, are credited back to the sender for the check. The screened view is
+// not modified. It also returns the sender sequence in the screened view.
+func (exec *Executor) ScreenTxAtSequence(tx types.Tx, txInfo *score.TxInfo, replacedTx types.Tx) (uint64, result.Result) {
+	if txInfo.Sequence == 0 {
+		return 0, result.Error("Invalid sequence %v", txInfo.Sequence).WithErrorCode(result.CodeInvalidSequence)
+	}
+
+	view, err := exec.state.Screened().Copy()
+	if err != nil {
+		return 0, result.Error("Failed to copy the screened view: %v", err)
+	}
+	acc := view.GetAccount(txInfo.Address)
+	if acc == nil {
+		return 0, result.Error("Failed to get the account (the address has no Theta nor TFuel)")
+	}
+	accSeq := acc.Sequence
+	acc.Sequence = txInfo.Sequence - 1
+	if replacedTx != nil {
+		acc.Balance = acc.Balance.NoNil().Plus(getSpentCoins(replacedTx, txInfo.Address))
+	}
+	accBytes, err := types.ToBytes(acc)
+	if err != nil {
+		return accSeq, result.Error("Failed to encode account: %v", err)
+	}
+	view.Set(slst.AccountKey(txInfo.Address), accBytes) // skip the state trie ref count update, the view is discarded
+
+	chainID := exec.state.GetChainID()
+	return accSeq, exec.sanityCheck(chainID, view, score.ScreenedView, tx)
+}
+
 // GetTxInfo extracts tx information used by mempool to sort Txs.
 func (exec *Executor) GetTxInfo(tx types.Tx) (*score.TxInfo, result.Result) {
 	txExecutor := exec.getTxExecutor(tx)
@@ -180,6 +219,36 @@ func (exec *Executor) isTxTypeSupported(view *slst.StoreView, tx types.Tx) bool
 	return true
 }
 
+// getSpentCoins returns the coins, including the fee, that the tx takes from the account at most.
+// For smart contract txs the fee is charged for the gas used, which is at most the gas limit.
+func getSpentCoins(tx types.Tx, address common.Address) types.Coins {
+	spent := types.NewCoins(0, 0)
+	switch tx := tx.(type) {
+	case *types.SendTx:
+		for _, input := range tx.Inputs {
+			if input.Address == address {
+				spent = spent.Plus(input.Coins.NoNil())
+			}
+		}
+	case *stypes.MultisigSendTx:
+		for _, input := range tx.Inputs {
+			if input.Address == address {
+				spent = spent.Plus(input.Coins.NoNil())
+			}
+		}
+	case *stypes.BatchSendTx:
+		if tx.Input.Address == address {
+			spent = spent.Plus(tx.Input.Coins.NoNil())
+		}
+	case *types.SmartContractTx:
+		if tx.From.Address == address {
+			feeLimit := new(big.Int).Mul(tx.GasPrice, new(big.Int).SetUint64(tx.GasLimit))
+			spent = spent.Plus(tx.From.Coins.NoNil()).Plus(types.Coins{ThetaWei: big.NewInt(0), TFuelWei: feeLimit})
+		}
+	}
+	return spent
+}
+
 func (exec *Executor) getTxExecutor(tx types.Tx) TxExecutor {
 	var txExecutor TxExecutor
 	switch tx.(type) {
@@ -189,6 +258,10 @@ func (exec *Executor) getTxExecutor(tx types.Tx) TxExecutor {
 		txExecutor = exec.subchainValidatorSetUpdateTxExec
 	case *types.SendTx:
 		txExecutor = exec.sendTxExec
+	case *stypes.MultisigSendTx:
+		txExecutor = exec.multisigSendTxExec
+	case *stypes.BatchSendTx:
+		txExecutor = exec.batchSendTxExec
 	case *types.SmartContractTx:
 		txExecutor = exec.smartContractTxExec
 	default:
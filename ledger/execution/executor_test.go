@@ -0,0 +1,68 @@
+package execution
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/common/result"
+	"github.com/thetatoken/theta/ledger/types"
+)
+
+// newTestSendTx creates a SendTx from et.accIn to et.accOut which spends the given total, fee included.
+func newTestSendTx(et *execTest, total *big.Int, fee *big.Int, sequence uint64) *types.SendTx {
+	tx := &types.SendTx{
+		Fee: types.Coins{ThetaWei: big.NewInt(0), TFuelWei: fee},
+		Inputs: []types.TxInput{{
+			Address:  et.accIn.Account.Address,
+			Coins:    types.Coins{ThetaWei: big.NewInt(0), TFuelWei: total},
+			Sequence: sequence,
+		}},
+		Outputs: []types.TxOutput{{
+			Address: et.accOut.Account.Address,
+			Coins:   types.Coins{ThetaWei: big.NewInt(0), TFuelWei: new(big.Int).Sub(total, fee)},
+		}},
+	}
+	et.signSendTx(tx, et.accIn)
+	return tx
+}
+
+func TestScreenReplacementTxSpendingAlmostAllBalance(t *testing.T) {
+	assert := assert.New(t)
+
+	et := NewExecTest()
+	et.acc2State(et.accIn, et.accOut)
+
+	blockHeight := et.state().Delivered().Height() + 1
+	minFee := types.GetSendTxMinimumTransactionFeeTFuelWei(2, blockHeight)
+	balance := et.state().Delivered().GetAccount(et.accIn.Account.Address).Balance.TFuelWei
+
+	// The pending tx spends almost all the balance
+	pendingTx := newTestSendTx(et, new(big.Int).Sub(balance, big.NewInt(1)), minFee, 1)
+	_, res := et.executor.ScreenTx(pendingTx)
+	assert.True(res.IsOK(), res.Message)
+
+	// The replacement pays a higher fee out of the same balance
+	replacementFee := new(big.Int).Mul(minFee, big.NewInt(2))
+	replacementTx := newTestSendTx(et, balance, replacementFee, 1)
+	txInfo, res := et.executor.GetTxInfo(replacementTx)
+	assert.True(res.IsOK(), res.Message)
+
+	accSeq, res := et.executor.ScreenTxAtSequence(replacementTx, txInfo, pendingTx)
+	assert.True(res.IsOK(), res.Message)
+	assert.Equal(uint64(1), accSeq)
+
+	// Without crediting back the pending tx, the balance in the screened view is insufficient
+	_, res = et.executor.ScreenTxAtSequence(replacementTx, txInfo, nil)
+	assert.Equal(result.CodeInsufficientFund, res.Code)
+
+	// The replacement still cannot spend more than the balance
+	overspendingTx := newTestSendTx(et, new(big.Int).Add(balance, big.NewInt(1)), replacementFee, 1)
+	_, res = et.executor.ScreenTxAtSequence(overspendingTx, txInfo, pendingTx)
+	assert.True(res.IsError())
+
+	// The screened view is not modified
+	account := et.state().Screened().GetAccount(et.accIn.Account.Address)
+	assert.Equal(uint64(1), account.Sequence)
+	assert.Equal(0, account.Balance.TFuelWei.Cmp(big.NewInt(1)))
+}
@@ -19,16 +19,18 @@ import (
 	sbc "github.com/thetatoken/thetasubchain/blockchain"
 	score "github.com/thetatoken/thetasubchain/core"
 	slst "github.com/thetatoken/thetasubchain/ledger/state"
+	ssigner "github.com/thetatoken/thetasubchain/signer"
 )
 
 // --------------- Test Utilities with Mocked Consensus Engine --------------- //
 
 type TestConsensusEngine struct {
 	privKey *crypto.PrivateKey
+	signer  score.Signer
 }
 
 func (tce *TestConsensusEngine) ID() string                         { return tce.privKey.PublicKey().Address().Hex() }
-func (tce *TestConsensusEngine) PrivateKey() *crypto.PrivateKey     { return tce.privKey }
+func (tce *TestConsensusEngine) Signer() score.Signer               { return tce.signer }
 func (tce *TestConsensusEngine) GetTip(bool) *score.ExtendedBlock   { return nil }
 func (tce *TestConsensusEngine) GetEpoch() uint64                   { return 100 }
 func (tce *TestConsensusEngine) AddMessage(msg interface{})         {}
@@ -40,7 +42,7 @@ func (tce *TestConsensusEngine) GetLastFinalizedBlock() *score.ExtendedBlock {
 
 func NewTestConsensusEngine(seed string) *TestConsensusEngine {
 	privKey, _, _ := crypto.TEST_GenerateKeyPairWithSeed(seed)
-	return &TestConsensusEngine{privKey}
+	return &TestConsensusEngine{privKey, ssigner.NewLocalSigner(privKey, nil)}
 }
 
 type TestValidatorManager struct {
@@ -0,0 +1,130 @@
+package execution
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/result"
+	"github.com/thetatoken/theta/ledger/types"
+
+	score "github.com/thetatoken/thetasubchain/core"
+	slst "github.com/thetatoken/thetasubchain/ledger/state"
+	stypes "github.com/thetatoken/thetasubchain/ledger/types"
+)
+
+var _ TxExecutor = (*BatchSendTxExecutor)(nil)
+
+// ------------------------------- Batch Send Transaction -----------------------------------
+
+// BatchSendTxExecutor implements the TxExecutor interface
+type BatchSendTxExecutor struct {
+	state *slst.LedgerState
+}
+
+// NewBatchSendTxExecutor creates a new instance of BatchSendTxExecutor
+func NewBatchSendTxExecutor(state *slst.LedgerState) *BatchSendTxExecutor {
+	return &BatchSendTxExecutor{
+		state: state,
+	}
+}
+
+func (exec *BatchSendTxExecutor) sanityCheck(chainID string, view *slst.StoreView, viewSel score.ViewSelector, transaction types.Tx) result.Result {
+	tx := transaction.(*stypes.BatchSendTx)
+	inputs := []types.TxInput{tx.Input}
+
+	// Validate inputs and outputs, basic
+	res := validateInputsBasic(inputs)
+	if res.IsError() {
+		return res
+	}
+	res = validateOutputsBasic(tx.Outputs)
+	if res.IsError() {
+		return res
+	}
+
+	numOutputs := uint64(len(tx.Outputs))
+	if numOutputs == 0 {
+		return result.Error("Invalid batchSendTx, Outputs are empty")
+	}
+	if numOutputs > stypes.MaxBatchSendTxOutputs {
+		return result.Error("Too many outputs. At most %v outputs are allowed per batch send transaction",
+			stypes.MaxBatchSendTxOutputs)
+	}
+
+	// Get input
+	accounts, res := getInputs(view, inputs)
+	if res.IsError() {
+		return res
+	}
+
+	// Get or make outputs, which also rejects duplicated recipients
+	accounts, res = getOrMakeOutputs(view, accounts, tx.Outputs)
+	if res.IsError() {
+		return res
+	}
+
+	blockHeight := view.Height() + 1
+	for _, outAcc := range accounts {
+		if outAcc.IsASmartContract() {
+			return result.Error(
+				fmt.Sprintf("Sending TFuel to a smart contract (%v) through a BatchSendTx transaction is not allowed", outAcc.Address))
+		}
+	}
+
+	// Validate input, advanced
+	signBytes := tx.SignBytes(chainID)
+	inTotal, res := validateInputsAdvanced(accounts, signBytes, inputs, nil, blockHeight)
+	if res.IsError() {
+		return res
+	}
+
+	if minTxFee, success := sanityCheckForSendTxFee(tx.Fee, 2, numOutputs-1, blockHeight); !success {
+		return result.Error("Insufficient fee. Transaction fee needs to be at least %v TFuelWei",
+			minTxFee).WithErrorCode(result.CodeInvalidFee)
+	}
+
+	outTotal := sumOutputs(tx.Outputs)
+	outPlusFees := outTotal.Plus(tx.Fee)
+	if !inTotal.IsEqual(outPlusFees) {
+		return result.Error("Input total (%v) != output total + fees (%v)", inTotal, outPlusFees)
+	}
+
+	return result.OK
+}
+
+func (exec *BatchSendTxExecutor) process(chainID string, view *slst.StoreView, viewSel score.ViewSelector, transaction types.Tx) (common.Hash, result.Result) {
+	tx := transaction.(*stypes.BatchSendTx)
+
+	res := processTransfer(view, []types.TxInput{tx.Input}, tx.Outputs)
+	if res.IsError() {
+		return common.Hash{}, res
+	}
+
+	txHash := types.TxID(chainID, tx)
+	return txHash, result.OK
+}
+
+func (exec *BatchSendTxExecutor) getTxInfo(transaction types.Tx) *score.TxInfo {
+	tx := transaction.(*stypes.BatchSendTx)
+	return &score.TxInfo{
+		Address:           tx.Input.Address,
+		Sequence:          tx.Input.Sequence,
+		EffectiveGasPrice: exec.calculateEffectiveGasPrice(transaction),
+	}
+}
+
+func (exec *BatchSendTxExecutor) calculateEffectiveGasPrice(transaction types.Tx) *big.Int {
+	tx := transaction.(*stypes.BatchSendTx)
+	fee := tx.Fee
+
+	// Charged as a SendTx with one input and one output, plus the reduced gas of each extra output
+	gasSendTxPerAccount := getRegularTxGas(exec.state) / 2
+	gasUint64 := 2 * gasSendTxPerAccount
+	if len(tx.Outputs) > 1 {
+		gasUint64 += uint64(len(tx.Outputs)-1) * 2 * gasSendTxPerAccount / stypes.BatchSendTxOutputFeeDivisor
+	}
+	gas := new(big.Int).SetUint64(gasUint64)
+	effectiveGasPrice := new(big.Int).Div(fee.NoNil().TFuelWei, gas)
+	return effectiveGasPrice
+}
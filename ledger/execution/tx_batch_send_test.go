@@ -0,0 +1,194 @@
+package execution
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/result"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/ledger/types"
+
+	stypes "github.com/thetatoken/thetasubchain/ledger/types"
+)
+
+// newTestBatchSendTx creates a BatchSendTx paying the amount to each of the recipients from
+// et.accIn, with the minimum fee.
+func newTestBatchSendTx(et *execTest, amount types.Coins, recipients ...common.Address) *stypes.BatchSendTx {
+	blockHeight := et.state().Delivered().Height() + 1
+	fee := types.Coins{
+		ThetaWei: big.NewInt(0),
+		TFuelWei: stypes.GetBatchSendTxMinimumTransactionFeeTFuelWei(uint64(len(recipients)), blockHeight),
+	}
+	tx := &stypes.BatchSendTx{
+		Fee: fee,
+		Input: types.TxInput{
+			Address:  et.accIn.Account.Address,
+			Coins:    fee,
+			Sequence: 1,
+		},
+	}
+	for _, recipient := range recipients {
+		tx.Outputs = append(tx.Outputs, types.TxOutput{Address: recipient, Coins: amount})
+		tx.Input.Coins = tx.Input.Coins.Plus(amount)
+	}
+	return tx
+}
+
+func signBatchSendTx(et *execTest, tx *stypes.BatchSendTx, signer types.PrivAccount) {
+	sig, err := signer.PrivKey.Sign(tx.SignBytes(et.chainID))
+	if err != nil {
+		panic(err)
+	}
+	tx.Input.Signature = sig
+}
+
+func TestBatchSendTx(t *testing.T) {
+	amount := types.NewCoins(0, 1000)
+	recipients := []common.Address{
+		types.MakeAcc("recipient1").Account.Address,
+		types.MakeAcc("recipient2").Account.Address,
+		types.MakeAcc("recipient3").Account.Address,
+	}
+	contract := types.MakeAcc("contract").Account.Address
+
+	tests := []struct {
+		name  string
+		build func(et *execTest) *stypes.BatchSendTx
+		valid bool
+		code  result.ErrorCode // the expected error code, not checked for generic errors
+	}{
+		{"single recipient", func(et *execTest) *stypes.BatchSendTx {
+			tx := newTestBatchSendTx(et, amount, recipients[0])
+			signBatchSendTx(et, tx, et.accIn)
+			return tx
+		}, true, result.CodeOK},
+		{"multiple recipients", func(et *execTest) *stypes.BatchSendTx {
+			tx := newTestBatchSendTx(et, amount, recipients...)
+			signBatchSendTx(et, tx, et.accIn)
+			return tx
+		}, true, result.CodeOK},
+		{"existing recipient", func(et *execTest) *stypes.BatchSendTx {
+			tx := newTestBatchSendTx(et, amount, recipients[0], et.accOut.Account.Address)
+			signBatchSendTx(et, tx, et.accIn)
+			return tx
+		}, true, result.CodeOK},
+		{"fee above the minimum", func(et *execTest) *stypes.BatchSendTx {
+			tx := newTestBatchSendTx(et, amount, recipients...)
+			tx.Fee = tx.Fee.Plus(types.NewCoins(0, 1))
+			tx.Input.Coins = tx.Input.Coins.Plus(types.NewCoins(0, 1))
+			signBatchSendTx(et, tx, et.accIn)
+			return tx
+		}, true, result.CodeOK},
+		{"insufficient fee", func(et *execTest) *stypes.BatchSendTx {
+			tx := newTestBatchSendTx(et, amount, recipients...)
+			tx.Fee = tx.Fee.Minus(types.NewCoins(0, 1))
+			tx.Input.Coins = tx.Input.Coins.Minus(types.NewCoins(0, 1))
+			signBatchSendTx(et, tx, et.accIn)
+			return tx
+		}, false, result.CodeInvalidFee},
+		{"input above outputs plus fee", func(et *execTest) *stypes.BatchSendTx {
+			tx := newTestBatchSendTx(et, amount, recipients...)
+			tx.Input.Coins = tx.Input.Coins.Plus(types.NewCoins(0, 1))
+			signBatchSendTx(et, tx, et.accIn)
+			return tx
+		}, false, result.CodeOK},
+		{"input below outputs plus fee", func(et *execTest) *stypes.BatchSendTx {
+			tx := newTestBatchSendTx(et, amount, recipients...)
+			tx.Outputs[2].Coins = tx.Outputs[2].Coins.Plus(types.NewCoins(0, 1))
+			signBatchSendTx(et, tx, et.accIn)
+			return tx
+		}, false, result.CodeOK},
+		{"duplicated recipients", func(et *execTest) *stypes.BatchSendTx {
+			tx := newTestBatchSendTx(et, amount, recipients[0], recipients[1], recipients[0])
+			signBatchSendTx(et, tx, et.accIn)
+			return tx
+		}, false, result.CodeOK},
+		{"input equal to an output", func(et *execTest) *stypes.BatchSendTx {
+			tx := newTestBatchSendTx(et, amount, recipients[0], et.accIn.Account.Address)
+			signBatchSendTx(et, tx, et.accIn)
+			return tx
+		}, false, result.CodeOK},
+		{"smart contract recipient", func(et *execTest) *stypes.BatchSendTx {
+			tx := newTestBatchSendTx(et, amount, recipients[0], contract)
+			signBatchSendTx(et, tx, et.accIn)
+			return tx
+		}, false, result.CodeOK},
+		{"no output", func(et *execTest) *stypes.BatchSendTx {
+			tx := newTestBatchSendTx(et, amount)
+			signBatchSendTx(et, tx, et.accIn)
+			return tx
+		}, false, result.CodeOK},
+		{"too many outputs", func(et *execTest) *stypes.BatchSendTx {
+			many := []common.Address{}
+			for i := 0; i <= stypes.MaxBatchSendTxOutputs; i++ {
+				many = append(many, types.MakeAcc(fmt.Sprintf("recipient%v", i)).Account.Address)
+			}
+			tx := newTestBatchSendTx(et, types.NewCoins(0, 1), many...)
+			signBatchSendTx(et, tx, et.accIn)
+			return tx
+		}, false, result.CodeOK},
+		{"native theta", func(et *execTest) *stypes.BatchSendTx {
+			tx := newTestBatchSendTx(et, types.NewCoins(1, 1000), recipients...)
+			signBatchSendTx(et, tx, et.accIn)
+			return tx
+		}, false, result.CodeOK},
+		{"signed by another account", func(et *execTest) *stypes.BatchSendTx {
+			tx := newTestBatchSendTx(et, amount, recipients...)
+			signBatchSendTx(et, tx, et.accOut)
+			return tx
+		}, false, result.CodeInvalidSignature},
+		{"wrong sequence", func(et *execTest) *stypes.BatchSendTx {
+			tx := newTestBatchSendTx(et, amount, recipients...)
+			tx.Input.Sequence = 2
+			signBatchSendTx(et, tx, et.accIn)
+			return tx
+		}, false, result.CodeInvalidSequence},
+	}
+
+	for _, test := range tests {
+		et := NewExecTest()
+		et.acc2State(et.accIn, et.accOut)
+		et.state().Delivered().SetAccount(contract, &types.Account{
+			Address:  contract,
+			Balance:  types.NewCoins(0, 0),
+			CodeHash: crypto.Keccak256Hash(common.Bytes("contract code")),
+		})
+		et.state().Commit()
+
+		tx := test.build(et)
+		inBalance := et.state().Delivered().GetAccount(et.accIn.Account.Address).Balance
+		outBalances := []types.Coins{}
+		for _, output := range tx.Outputs {
+			balance := types.NewCoins(0, 0)
+			if account := et.state().Delivered().GetAccount(output.Address); account != nil {
+				balance = account.Balance
+			}
+			outBalances = append(outBalances, balance)
+		}
+
+		_, res := et.executor.ExecuteTx(tx)
+		if test.valid {
+			if !assert.True(t, res.IsOK(), "%v: %v", test.name, res.Message) {
+				continue
+			}
+			// The input pays the outputs and the fee
+			assert.True(t, inBalance.Minus(tx.Input.Coins).IsEqual(
+				et.state().Delivered().GetAccount(et.accIn.Account.Address).Balance), test.name)
+			assert.True(t, tx.Input.Coins.IsEqual(sumOutputs(tx.Outputs).Plus(tx.Fee)), test.name)
+			for i, output := range tx.Outputs {
+				assert.True(t, outBalances[i].Plus(output.Coins).IsEqual(
+					et.state().Delivered().GetAccount(output.Address).Balance), test.name)
+			}
+			assert.Equal(t, uint64(1), et.state().Delivered().GetAccount(et.accIn.Account.Address).Sequence, test.name)
+		} else {
+			assert.True(t, res.IsError(), test.name)
+			if test.code != result.CodeOK {
+				assert.Equal(t, test.code, res.Code, "%v: %v", test.name, res.Message)
+			}
+			assert.True(t, inBalance.IsEqual(et.state().Delivered().GetAccount(et.accIn.Account.Address).Balance), test.name)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package execution
+
+import (
+	"math/big"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/result"
+	"github.com/thetatoken/theta/ledger/types"
+
+	score "github.com/thetatoken/thetasubchain/core"
+	slst "github.com/thetatoken/thetasubchain/ledger/state"
+	stypes "github.com/thetatoken/thetasubchain/ledger/types"
+)
+
+var _ TxExecutor = (*MultisigSendTxExecutor)(nil)
+
+// ------------------------------- Multisig Send Transaction -----------------------------------
+
+// MultisigSendTxExecutor implements the TxExecutor interface
+type MultisigSendTxExecutor struct {
+	state *slst.LedgerState
+}
+
+// NewMultisigSendTxExecutor creates a new instance of MultisigSendTxExecutor
+func NewMultisigSendTxExecutor(state *slst.LedgerState) *MultisigSendTxExecutor {
+	return &MultisigSendTxExecutor{
+		state: state,
+	}
+}
+
+func (exec *MultisigSendTxExecutor) sanityCheck(chainID string, view *slst.StoreView, viewSel score.ViewSelector, transaction types.Tx) result.Result {
+	tx := transaction.(*stypes.MultisigSendTx)
+
+	if len(tx.Multisigs) == 0 {
+		return result.Error("Invalid multisigSendTx, no multisig account")
+	}
+
+	// Each multisig needs to authorize exactly one input, so that no unverified signatures are carried along
+	seen := make(map[common.Address]bool)
+	for _, multisig := range tx.Multisigs {
+		if err := multisig.Account.Validate(); err != nil {
+			return result.Error("Invalid multisig account: %v", err)
+		}
+		address := multisig.Account.Address()
+		if seen[address] {
+			return result.Error("Duplicated multisig account: %v", address)
+		}
+		seen[address] = true
+
+		found := false
+		for _, input := range tx.Inputs {
+			if input.Address == address {
+				if input.Signature != nil {
+					return result.Error("Multisig input %v must not carry a signature", address).
+						WithErrorCode(result.CodeInvalidSignature)
+				}
+				found = true
+				break
+			}
+		}
+		if !found {
+			return result.Error("Multisig account %v does not match any input", address)
+		}
+	}
+
+	extraFeeUnits := exec.numExtraSignatures(tx)
+	return sanityCheckForTransfer(view, tx.Fee, tx.Inputs, tx.Outputs, tx.SignBytes(chainID), tx.Multisigs, extraFeeUnits)
+}
+
+func (exec *MultisigSendTxExecutor) process(chainID string, view *slst.StoreView, viewSel score.ViewSelector, transaction types.Tx) (common.Hash, result.Result) {
+	tx := transaction.(*stypes.MultisigSendTx)
+
+	res := processTransfer(view, tx.Inputs, tx.Outputs)
+	if res.IsError() {
+		return common.Hash{}, res
+	}
+
+	txHash := types.TxID(chainID, tx)
+	return txHash, result.OK
+}
+
+func (exec *MultisigSendTxExecutor) getTxInfo(transaction types.Tx) *score.TxInfo {
+	tx := transaction.(*stypes.MultisigSendTx)
+	return &score.TxInfo{
+		Address:           tx.Inputs[0].Address,
+		Sequence:          tx.Inputs[0].Sequence,
+		EffectiveGasPrice: exec.calculateEffectiveGasPrice(transaction),
+	}
+}
+
+func (exec *MultisigSendTxExecutor) calculateEffectiveGasPrice(transaction types.Tx) *big.Int {
+	tx := transaction.(*stypes.MultisigSendTx)
+	fee := tx.Fee
+	numAccountsAffected := uint64(len(tx.Inputs)+len(tx.Outputs)) + exec.numExtraSignatures(tx)
+
+	gasSendTxPerAccount := getRegularTxGas(exec.state) / 2
+	gasUint64 := gasSendTxPerAccount * numAccountsAffected
+	if gasUint64 < 2*gasSendTxPerAccount {
+		gasUint64 = 2 * gasSendTxPerAccount // to prevent spamming with invalid transactions, e.g. empty inputs/outputs
+	}
+	gas := new(big.Int).SetUint64(gasUint64)
+	effectiveGasPrice := new(big.Int).Div(fee.TFuelWei, gas)
+	return effectiveGasPrice
+}
+
+// numExtraSignatures returns the number of multisig signatures beyond the one signature per input
+// a SendTx carries, which are charged as additional accounts affected.
+func (exec *MultisigSendTxExecutor) numExtraSignatures(tx *stypes.MultisigSendTx) uint64 {
+	extra := uint64(0)
+	for _, multisig := range tx.Multisigs {
+		if len(multisig.Signatures) > 1 {
+			extra += uint64(len(multisig.Signatures) - 1)
+		}
+	}
+	return extra
+}
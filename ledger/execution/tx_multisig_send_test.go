@@ -0,0 +1,299 @@
+package execution
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/result"
+	"github.com/thetatoken/theta/ledger/types"
+
+	stypes "github.com/thetatoken/thetasubchain/ledger/types"
+)
+
+type multisigTest struct {
+	*execTest
+	signers  []types.PrivAccount
+	account  *stypes.MultisigAccount
+	address  common.Address
+	amount   types.Coins
+	fee      types.Coins
+	stranger types.PrivAccount
+}
+
+// newMultisigTest funds a 2-of-3 multisig account, and the regular accounts of the execTest.
+func newMultisigTest(t *testing.T) *multisigTest {
+	et := NewExecTest()
+	mt := &multisigTest{
+		execTest: et,
+		signers:  []types.PrivAccount{types.MakeAcc("alice"), types.MakeAcc("bob"), types.MakeAcc("carol")},
+		amount:   types.NewCoins(0, 1000),
+		stranger: types.MakeAcc("mallory"),
+	}
+	pubKeys := []common.Bytes{}
+	for _, signer := range mt.signers {
+		pubKeys = append(pubKeys, signer.PrivKey.PublicKey().ToBytes())
+	}
+	account, err := stypes.NewMultisigAccount(2, pubKeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mt.account = account
+	mt.address = account.Address()
+
+	blockHeight := et.state().Delivered().Height() + 1
+	mt.fee = types.Coins{
+		ThetaWei: big.NewInt(0),
+		TFuelWei: types.GetSendTxMinimumTransactionFeeTFuelWei(types.MaxAccountsAffectedPerTx, blockHeight),
+	}
+
+	balance := types.Coins{
+		ThetaWei: big.NewInt(0),
+		TFuelWei: new(big.Int).Mul(mt.fee.TFuelWei, big.NewInt(10)),
+	}
+	et.state().Delivered().SetAccount(mt.address, &types.Account{
+		Address: mt.address,
+		Balance: balance,
+	})
+	et.acc2State(et.accIn, et.accOut)
+	return mt
+}
+
+// newTx creates a tx sending mt.amount from the multisig account to et.accOut.
+func (mt *multisigTest) newTx() *stypes.MultisigSendTx {
+	return &stypes.MultisigSendTx{
+		Fee: mt.fee,
+		Inputs: []types.TxInput{{
+			Address:  mt.address,
+			Coins:    mt.amount.Plus(mt.fee),
+			Sequence: 1,
+		}},
+		Outputs: []types.TxOutput{{
+			Address: mt.accOut.Account.Address,
+			Coins:   mt.amount,
+		}},
+		Multisigs: []stypes.MultisigAuth{{Account: *mt.account}},
+	}
+}
+
+func (mt *multisigTest) sign(tx *stypes.MultisigSendTx, signers ...types.PrivAccount) {
+	signBytes := tx.SignBytes(mt.chainID)
+	for _, signer := range signers {
+		sig, err := signer.PrivKey.Sign(signBytes)
+		if err != nil {
+			panic(err)
+		}
+		tx.SetSignature(signer.PrivKey.PublicKey().Address(), sig)
+	}
+}
+
+func TestMultisigSendTx(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(mt *multisigTest) *stypes.MultisigSendTx
+		valid bool
+		code  result.ErrorCode // the expected error code, not checked for generic errors
+	}{
+		{"threshold met", func(mt *multisigTest) *stypes.MultisigSendTx {
+			tx := mt.newTx()
+			mt.sign(tx, mt.signers[0], mt.signers[2])
+			return tx
+		}, true, result.CodeOK},
+		{"below threshold", func(mt *multisigTest) *stypes.MultisigSendTx {
+			tx := mt.newTx()
+			mt.sign(tx, mt.signers[1])
+			return tx
+		}, false, result.CodeInvalidSignature},
+		{"duplicated index", func(mt *multisigTest) *stypes.MultisigSendTx {
+			tx := mt.newTx()
+			mt.sign(tx, mt.signers[0])
+			tx.Multisigs[0].Signatures = append(tx.Multisigs[0].Signatures, tx.Multisigs[0].Signatures[0])
+			return tx
+		}, false, result.CodeInvalidSignature},
+		{"out of range index", func(mt *multisigTest) *stypes.MultisigSendTx {
+			tx := mt.newTx()
+			mt.sign(tx, mt.signers[0], mt.signers[1])
+			tx.Multisigs[0].Signatures[1].Index = uint64(len(mt.signers))
+			return tx
+		}, false, result.CodeInvalidSignature},
+		{"wrong key", func(mt *multisigTest) *stypes.MultisigSendTx {
+			tx := mt.newTx()
+			mt.sign(tx, mt.signers[0])
+			sig, err := mt.stranger.PrivKey.Sign(tx.SignBytes(mt.chainID))
+			if err != nil {
+				panic(err)
+			}
+			index := uint64(mt.account.IndexOf(mt.signers[1].PrivKey.PublicKey().Address()))
+			tx.Multisigs[0].Signatures = append(tx.Multisigs[0].Signatures, stypes.MultisigSignature{Index: index, Signature: sig})
+			return tx
+		}, false, result.CodeInvalidSignature},
+		{"multisig input with a signature", func(mt *multisigTest) *stypes.MultisigSendTx {
+			tx := mt.newTx()
+			mt.sign(tx, mt.signers[0], mt.signers[1])
+			sig, err := mt.signers[0].PrivKey.Sign(tx.SignBytes(mt.chainID))
+			if err != nil {
+				panic(err)
+			}
+			tx.Inputs[0].Signature = sig
+			return tx
+		}, false, result.CodeInvalidSignature},
+		{"multisig matching no input", func(mt *multisigTest) *stypes.MultisigSendTx {
+			tx := mt.newTx()
+			other, err := stypes.NewMultisigAccount(1, []common.Bytes{mt.stranger.PrivKey.PublicKey().ToBytes()})
+			if err != nil {
+				panic(err)
+			}
+			tx.Multisigs = append(tx.Multisigs, stypes.MultisigAuth{Account: *other})
+			mt.sign(tx, mt.signers[0], mt.signers[1], mt.stranger)
+			return tx
+		}, false, result.CodeOK},
+		{"no multisig", func(mt *multisigTest) *stypes.MultisigSendTx {
+			tx := mt.newTx()
+			tx.Multisigs = nil
+			return tx
+		}, false, result.CodeOK},
+		{"mixed regular and multisig inputs", func(mt *multisigTest) *stypes.MultisigSendTx {
+			tx := mt.newTx()
+			tx.Inputs = append(tx.Inputs, types.TxInput{
+				Address:  mt.accIn.Account.Address,
+				Coins:    mt.amount,
+				Sequence: 1,
+			})
+			tx.Outputs[0].Coins = mt.amount.Plus(mt.amount)
+			mt.sign(tx, mt.signers[0], mt.signers[1], mt.accIn)
+			return tx
+		}, true, result.CodeOK},
+		{"mixed inputs with a wrong regular signature", func(mt *multisigTest) *stypes.MultisigSendTx {
+			tx := mt.newTx()
+			tx.Inputs = append(tx.Inputs, types.TxInput{
+				Address:  mt.accIn.Account.Address,
+				Coins:    mt.amount,
+				Sequence: 1,
+			})
+			tx.Outputs[0].Coins = mt.amount.Plus(mt.amount)
+			mt.sign(tx, mt.signers[0], mt.signers[1])
+			sig, err := mt.stranger.PrivKey.Sign(tx.SignBytes(mt.chainID))
+			if err != nil {
+				panic(err)
+			}
+			tx.Inputs[1].Signature = sig
+			return tx
+		}, false, result.CodeInvalidSignature},
+		{"input total mismatch", func(mt *multisigTest) *stypes.MultisigSendTx {
+			tx := mt.newTx()
+			tx.Outputs[0].Coins = mt.amount.Plus(types.NewCoins(0, 1))
+			mt.sign(tx, mt.signers[0], mt.signers[1])
+			return tx
+		}, false, result.CodeOK},
+	}
+
+	for _, test := range tests {
+		mt := newMultisigTest(t)
+		tx := test.build(mt)
+
+		multisigBalance := mt.state().Delivered().GetAccount(mt.address).Balance
+		outBalance := mt.state().Delivered().GetAccount(mt.accOut.Account.Address).Balance
+
+		_, res := mt.executor.ExecuteTx(tx)
+		if test.valid {
+			if !assert.True(t, res.IsOK(), "%v: %v", test.name, res.Message) {
+				continue
+			}
+			assert.True(t, multisigBalance.Minus(tx.Inputs[0].Coins).IsEqual(
+				mt.state().Delivered().GetAccount(mt.address).Balance), test.name)
+			assert.True(t, outBalance.Plus(tx.Outputs[0].Coins).IsEqual(
+				mt.state().Delivered().GetAccount(mt.accOut.Account.Address).Balance), test.name)
+			assert.Equal(t, uint64(1), mt.state().Delivered().GetAccount(mt.address).Sequence, test.name)
+		} else {
+			assert.True(t, res.IsError(), test.name)
+			if test.code != result.CodeOK {
+				assert.Equal(t, test.code, res.Code, "%v: %v", test.name, res.Message)
+			}
+			assert.True(t, multisigBalance.IsEqual(mt.state().Delivered().GetAccount(mt.address).Balance), test.name)
+		}
+	}
+}
+
+func TestSendTxSanityCheckForTransfer(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(et *execTest) *types.SendTx
+		valid bool
+		code  result.ErrorCode // the expected error code, not checked for generic errors
+	}{
+		{"valid", func(et *execTest) *types.SendTx {
+			return newTestSendTx(et, types.NewCoins(0, 1000), true)
+		}, true, result.CodeOK},
+		{"signed by another account", func(et *execTest) *types.SendTx {
+			tx := newTestSendTx(et, types.NewCoins(0, 1000), false)
+			et.signSendTx(tx, et.accOut)
+			return tx
+		}, false, result.CodeInvalidSignature},
+		{"wrong sequence", func(et *execTest) *types.SendTx {
+			tx := newTestSendTx(et, types.NewCoins(0, 1000), false)
+			tx.Inputs[0].Sequence = 2
+			et.signSendTx(tx, et.accIn)
+			return tx
+		}, false, result.CodeInvalidSequence},
+		{"insufficient fee", func(et *execTest) *types.SendTx {
+			tx := newTestSendTx(et, types.NewCoins(0, 1000), false)
+			tx.Fee = types.NewCoins(0, 1)
+			tx.Inputs[0].Coins = types.NewCoins(0, 1001)
+			et.signSendTx(tx, et.accIn)
+			return tx
+		}, false, result.CodeInvalidFee},
+		{"native theta", func(et *execTest) *types.SendTx {
+			tx := newTestSendTx(et, types.NewCoins(1, 1000), false)
+			et.signSendTx(tx, et.accIn)
+			return tx
+		}, false, result.CodeDoNotSupportNativeThetaInSubchain},
+		{"no output", func(et *execTest) *types.SendTx {
+			tx := newTestSendTx(et, types.NewCoins(0, 1000), false)
+			tx.Outputs = []types.TxOutput{}
+			et.signSendTx(tx, et.accIn)
+			return tx
+		}, false, result.CodeOK},
+	}
+
+	for _, test := range tests {
+		et := NewExecTest()
+		et.acc2State(et.accIn, et.accOut)
+		tx := test.build(et)
+
+		_, res := et.executor.ScreenTx(tx)
+		if test.valid {
+			assert.True(t, res.IsOK(), "%v: %v", test.name, res.Message)
+		} else {
+			assert.True(t, res.IsError(), test.name)
+			if test.code != result.CodeOK {
+				assert.Equal(t, test.code, res.Code, "%v: %v", test.name, res.Message)
+			}
+		}
+	}
+}
+
+// newTestSendTx creates a SendTx transferring the coins from et.accIn to et.accOut.
+func newTestSendTx(et *execTest, coins types.Coins, signed bool) *types.SendTx {
+	blockHeight := et.state().Screened().Height() + 1
+	fee := types.Coins{
+		ThetaWei: big.NewInt(0),
+		TFuelWei: types.GetSendTxMinimumTransactionFeeTFuelWei(2, blockHeight),
+	}
+	tx := &types.SendTx{
+		Fee: fee,
+		Inputs: []types.TxInput{{
+			Address:  et.accIn.Account.Address,
+			Coins:    coins.Plus(fee),
+			Sequence: 1,
+		}},
+		Outputs: []types.TxOutput{{
+			Address: et.accOut.Account.Address,
+			Coins:   coins,
+		}},
+	}
+	if signed {
+		et.signSendTx(tx, et.accIn)
+	}
+	return tx
+}
@@ -10,6 +10,7 @@ import (
 
 	score "github.com/thetatoken/thetasubchain/core"
 	slst "github.com/thetatoken/thetasubchain/ledger/state"
+	stypes "github.com/thetatoken/thetasubchain/ledger/types"
 )
 
 var _ TxExecutor = (*SendTxExecutor)(nil)
@@ -30,34 +31,53 @@ func NewSendTxExecutor(state *slst.LedgerState) *SendTxExecutor {
 
 func (exec *SendTxExecutor) sanityCheck(chainID string, view *slst.StoreView, viewSel score.ViewSelector, transaction types.Tx) result.Result {
 	tx := transaction.(*types.SendTx)
+	return sanityCheckForTransfer(view, tx.Fee, tx.Inputs, tx.Outputs, tx.SignBytes(chainID), nil, 0)
+}
+
+func (exec *SendTxExecutor) process(chainID string, view *slst.StoreView, viewSel score.ViewSelector, transaction types.Tx) (common.Hash, result.Result) {
+	tx := transaction.(*types.SendTx)
+
+	res := processTransfer(view, tx.Inputs, tx.Outputs)
+	if res.IsError() {
+		return common.Hash{}, res
+	}
+
+	txHash := types.TxID(chainID, tx)
+	return txHash, result.OK
+}
 
+// sanityCheckForTransfer checks the inputs, outputs and fee of a transfer. The inputs owned by multisig
+// accounts are authorized by the multisigs. The extra fee units, e.g. the additional signatures to verify,
+// are charged on top of the number of accounts affected.
+func sanityCheckForTransfer(view *slst.StoreView, fee types.Coins, inputs []types.TxInput, outputs []types.TxOutput,
+	signBytes []byte, multisigs []stypes.MultisigAuth, extraFeeUnits uint64) result.Result {
 	// Validate inputs and outputs, basic
-	res := validateInputsBasic(tx.Inputs)
+	res := validateInputsBasic(inputs)
 	if res.IsError() {
 		return res
 	}
-	res = validateOutputsBasic(tx.Outputs)
+	res = validateOutputsBasic(outputs)
 	if res.IsError() {
 		return res
 	}
 
-	if len(tx.Inputs) == 0 || len(tx.Outputs) == 0 {
+	if len(inputs) == 0 || len(outputs) == 0 {
 		return result.Error("Invalid sendTx, Inputs and/or Outputs are empty")
 	}
 
-	numAccountsAffected := uint64(len(tx.Inputs) + len(tx.Outputs))
+	numAccountsAffected := uint64(len(inputs) + len(outputs))
 	if numAccountsAffected > types.MaxAccountsAffectedPerTx {
 		return result.Error("Trasaction modifying too many accounts. At most %v accounts are allowed per transaction",
 			types.MaxAccountsAffectedPerTx)
 	}
 
 	// Get inputs
-	accounts, res := getInputs(view, tx.Inputs)
+	accounts, res := getInputs(view, inputs)
 	if res.IsError() {
 		return res
 	}
 
-	for _, input := range tx.Inputs {
+	for _, input := range inputs {
 		coins := input.Coins.NoNil()
 		if coins.ThetaWei.Cmp(types.Zero) != 0 { // subchains do not support native THETA
 			return result.Error("Subchain does not support native THETA").
@@ -66,7 +86,7 @@ func (exec *SendTxExecutor) sanityCheck(chainID string, view *slst.StoreView, vi
 	}
 
 	// Get or make outputs.
-	accounts, res = getOrMakeOutputs(view, accounts, tx.Outputs)
+	accounts, res = getOrMakeOutputs(view, accounts, outputs)
 	if res.IsError() {
 		return res
 	}
@@ -82,20 +102,19 @@ func (exec *SendTxExecutor) sanityCheck(chainID string, view *slst.StoreView, vi
 	}
 
 	// Validate inputs and outputs, advanced
-	signBytes := tx.SignBytes(chainID)
-	inTotal, res := validateInputsAdvanced(accounts, signBytes, tx.Inputs, blockHeight)
+	inTotal, res := validateInputsAdvanced(accounts, signBytes, inputs, multisigs, blockHeight)
 	if res.IsError() {
 		return res
 	}
 
-	if minTxFee, success := sanityCheckForSendTxFee(tx.Fee, numAccountsAffected, blockHeight); !success {
+	if minTxFee, success := sanityCheckForSendTxFee(fee, numAccountsAffected+extraFeeUnits, 0, blockHeight); !success {
 		return result.Error("Insufficient fee. Transaction fee needs to be at least %v TFuelWei",
 			minTxFee).WithErrorCode(result.CodeInvalidFee)
 	}
 
-	outTotal := sumOutputs(tx.Outputs)
+	outTotal := sumOutputs(outputs)
 	outPlusFees := outTotal
-	outPlusFees = outTotal.Plus(tx.Fee)
+	outPlusFees = outTotal.Plus(fee)
 	if !inTotal.IsEqual(outPlusFees) {
 		return result.Error("Input total (%v) != output total + fees (%v)", inTotal, outPlusFees)
 	}
@@ -103,24 +122,21 @@ func (exec *SendTxExecutor) sanityCheck(chainID string, view *slst.StoreView, vi
 	return result.OK
 }
 
-func (exec *SendTxExecutor) process(chainID string, view *slst.StoreView, viewSel score.ViewSelector, transaction types.Tx) (common.Hash, result.Result) {
-	tx := transaction.(*types.SendTx)
-
-	accounts, res := getInputs(view, tx.Inputs)
+// processTransfer moves the coins from the inputs to the outputs.
+func processTransfer(view *slst.StoreView, inputs []types.TxInput, outputs []types.TxOutput) result.Result {
+	accounts, res := getInputs(view, inputs)
 	if res.IsError() {
-		return common.Hash{}, res
+		return res
 	}
 
-	accounts, res = getOrMakeOutputs(view, accounts, tx.Outputs)
+	accounts, res = getOrMakeOutputs(view, accounts, outputs)
 	if res.IsError() {
-		return common.Hash{}, res
+		return res
 	}
 
-	adjustByInputs(view, accounts, tx.Inputs)
-	adjustByOutputs(view, accounts, tx.Outputs)
-
-	txHash := types.TxID(chainID, tx)
-	return txHash, result.OK
+	adjustByInputs(view, accounts, inputs)
+	adjustByOutputs(view, accounts, outputs)
+	return result.OK
 }
 
 func (exec *SendTxExecutor) getTxInfo(transaction types.Tx) *score.TxInfo {
@@ -238,6 +238,84 @@ func (ledger *Ledger) ScreenTx(rawTx common.Bytes) (txInfo *score.TxInfo, res re
 	return txInfo, res
 }
 
+// ResetScreenedState resets the screened view to the delivered view, discarding all the txs screened since
+// the last block. It is called when the mempool drops pending txs, e.g. by evicting them, whose effects on the
+// senders, the recipients and the contract storage must be rolled back. The caller must hold the mempool lock,
+// and screen the remaining pending txs again.
+func (ledger *Ledger) ResetScreenedState() {
+	ledger.mu.Lock()
+	defer ledger.mu.Unlock()
+
+	res := ledger.state.ResetScreened()
+	if res.IsError() {
+		logger.Warnf("Failed to reset the screened state: %v", res.Message)
+	}
+}
+
+// ScreenReplacementTx screens the given transaction which replaces a pending transaction with
+// the same sender and sequence
+func (ledger *Ledger) ScreenReplacementTx(rawTx common.Bytes) (txInfo *score.TxInfo, res result.Result) {
+	txInfo, accSeq, res := ledger.screenTxAtSequence(rawTx, true)
+	if res.IsError() {
+		return nil, res
+	}
+	if accSeq < txInfo.Sequence {
+		return nil, result.Error("No pending tx with sequence %v to replace (acc.seq=%v)",
+			txInfo.Sequence, accSeq).WithErrorCode(result.CodeInvalidSequence)
+	}
+	return txInfo, res
+}
+
+// ScreenFutureTx screens the given transaction whose sequence is ahead of the next
+// expected sequence of the sender
+func (ledger *Ledger) ScreenFutureTx(rawTx common.Bytes) (txInfo *score.TxInfo, res result.Result) {
+	txInfo, accSeq, res := ledger.screenTxAtSequence(rawTx, false)
+	if res.IsError() {
+		return nil, res
+	}
+	if accSeq+1 >= txInfo.Sequence {
+		return nil, result.Error("Sequence %v is not ahead of the expected sequence %v",
+			txInfo.Sequence, accSeq+1).WithErrorCode(result.CodeInvalidSequence)
+	}
+	return txInfo, res
+}
+
+// screenTxAtSequence screens the tx at its sequence. If replacing is set, the pending tx it replaces
+// is looked up in the mempool, whose lock the caller must hold.
+func (ledger *Ledger) screenTxAtSequence(rawTx common.Bytes, replacing bool) (txInfo *score.TxInfo, accSeq uint64, res result.Result) {
+	var tx types.Tx
+	tx, err := stypes.TxFromBytes(rawTx)
+	if err != nil {
+		return nil, 0, result.Error("Error decoding tx: %v", err)
+	}
+
+	if ledger.shouldSkipCheckTx(tx) {
+		return nil, 0, result.Error("Unauthorized transaction, should skip").
+			WithErrorCode(result.CodeUnauthorizedTx)
+	}
+
+	ledger.mu.RLock()
+	defer ledger.mu.RUnlock()
+
+	txInfo, res = ledger.executor.GetTxInfo(tx)
+	if res.IsError() {
+		return nil, 0, res
+	}
+
+	var replacedTx types.Tx
+	if replacing && ledger.mempool != nil {
+		if replacedRawTx := ledger.mempool.GetPendingTxUnsafe(txInfo.Address, txInfo.Sequence); replacedRawTx != nil {
+			replacedTx, err = stypes.TxFromBytes(replacedRawTx)
+			if err != nil {
+				return nil, 0, result.Error("Error decoding the replaced tx: %v", err)
+			}
+		}
+	}
+
+	accSeq, res = ledger.executor.ScreenTxAtSequence(tx, txInfo, replacedTx)
+	return txInfo, accSeq, res
+}
+
 // ProposeBlockTxs collects and executes a list of transactions, which will be used to assemble the next blockl
 // It also clears these transactions from the mempool.
 func (ledger *Ledger) ProposeBlockTxs(block *score.Block, validatorMajorityInTheSameDynasty bool) (stateRootHash common.Hash, blockRawTxs []common.Bytes, res result.Result) {
@@ -372,6 +450,8 @@ func (ledger *Ledger) ApplyBlockTxs(block *score.Block) result.Result {
 			hex.EncodeToString(expectedStateRoot[:]))
 	}
 
+	ledger.recordStateDiff(block, view)
+
 	start = time.Now()
 	ledger.state.Commit() // commit to persistent storage
 	commitTime := time.Since(start)
@@ -393,6 +473,42 @@ func (ledger *Ledger) ApplyBlockTxs(block *score.Block) result.Result {
 	return result.OKWith(result.Info{"hasValidatorUpdate": hasValidatorUpdate})
 }
 
+// recordStateDiff records the accounts and the storage slots modified by the block for the state
+// history index, if enabled. Must be called before the view is committed.
+func (ledger *Ledger) recordStateDiff(block *score.Block, view *slst.StoreView) {
+	touchedAccounts := view.PopTouchedAccounts()
+	if !ledger.chain.StateHistoryEnabled() {
+		return
+	}
+
+	diff := &sbc.StateDiff{
+		Accounts: []sbc.AccountDiff{},
+		Storage:  []sbc.StorageDiff{},
+	}
+	for _, touched := range touchedAccounts {
+		var accBytes common.Bytes
+		if acc := view.GetAccount(touched.Address); acc != nil {
+			var err error
+			accBytes, err = types.ToBytes(acc)
+			if err != nil {
+				logger.Panicf("Failed to encode account %v: %v", touched.Address.Hex(), err)
+			}
+		}
+		diff.Accounts = append(diff.Accounts, sbc.AccountDiff{
+			Address: touched.Address,
+			Account: accBytes,
+		})
+		for _, key := range touched.StorageKeys {
+			diff.Storage = append(diff.Storage, sbc.StorageDiff{
+				Address: touched.Address,
+				Key:     key,
+				Value:   view.GetState(touched.Address, key),
+			})
+		}
+	}
+	ledger.chain.AddStateDiff(block.Hash(), diff)
+}
+
 // ApplyBlockTxsForChainCorrection applies all block's txs and re-calculate root hash
 func (ledger *Ledger) ApplyBlockTxsForChainCorrection(block *score.Block) (common.Hash, result.Result) {
 	ledger.mempool.Lock()
@@ -805,8 +921,7 @@ func (ledger *Ledger) addSubchainValidatorSetUpdateTx(view *slst.StoreView, prop
 // signTransaction signs the given transaction
 func (ledger *Ledger) signTransaction(tx types.Tx) (*crypto.Signature, error) {
 	chainID := ledger.state.GetChainID()
-	signBytes := tx.SignBytes(chainID)
-	signature, err := ledger.consensus.PrivateKey().Sign(signBytes)
+	signature, err := ledger.consensus.Signer().SignTx(chainID, tx)
 	if err != nil {
 		return nil, err
 	}
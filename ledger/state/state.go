@@ -77,6 +77,16 @@ func (s *LedgerState) ResetState(block *score.Block) result.Result {
 	return result.OK
 }
 
+// ResetScreened resets the screened view to a copy of the delivered view.
+func (s *LedgerState) ResetScreened() result.Result {
+	var err error
+	s.screened, err = s.delivered.Copy()
+	if err != nil {
+		return result.Error(fmt.Sprintf("Failed to copy to the screened view: %v", err))
+	}
+	return result.OK
+}
+
 // Finalize updates the finalized view.
 func (s *LedgerState) Finalize(height uint64, stateRootHash common.Hash) result.Result {
 	storeview := NewStoreView(height, stateRootHash, s.db)
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"math/big"
+	"sort"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/thetatoken/theta/common"
@@ -28,9 +29,16 @@ type StoreView struct {
 	coinbaseTransactinProcessed             bool
 	subchainValidatorSetTransactinProcessed bool
 	slashIntents                            []types.SlashIntent
-	refund                                  uint64                 // Gas refund during smart contract execution
-	logs                                    []*types.Log           // Temporary store of events during smart contract execution
-	balanceChanges                          []*types.BalanceChange // Temporary store of balance changes during smart contract execution
+	refund                                  uint64                                  // Gas refund during smart contract execution
+	logs                                    []*types.Log                            // Temporary store of events during smart contract execution
+	balanceChanges                          []*types.BalanceChange                  // Temporary store of balance changes during smart contract execution
+	touchedAccounts                         map[common.Address]map[common.Hash]bool // Accounts and storage slots modified since the last PopTouchedAccounts() call
+}
+
+// TouchedAccount is an account modified by the transactions, along with its modified storage slots.
+type TouchedAccount struct {
+	Address     common.Address
+	StorageKeys []common.Hash
 }
 
 // NewStoreView creates an instance of the StoreView
@@ -198,6 +206,7 @@ func (sv *StoreView) setAccount(addr common.Address, acc *types.Account, updateR
 			acc, err.Error())
 	}
 	sv.Set(AccountKey(addr), accBytes)
+	sv.touchAccount(addr)
 
 	if !updateRefCountForAccountStateTree {
 		return
@@ -217,6 +226,7 @@ func (sv *StoreView) setAccount(addr common.Address, acc *types.Account, updateR
 // DeleteAccount deletes an account.
 func (sv *StoreView) DeleteAccount(addr common.Address) {
 	sv.Delete(AccountKey(addr))
+	sv.touchAccount(addr)
 }
 
 // GetDynasty gets the dynasty associated with the view
@@ -407,6 +417,40 @@ func (sv *StoreView) PopBalanceChanges() []*types.BalanceChange {
 	return ret
 }
 
+func (sv *StoreView) touchAccount(addr common.Address) {
+	if sv.touchedAccounts == nil {
+		sv.touchedAccounts = make(map[common.Address]map[common.Hash]bool)
+	}
+	if sv.touchedAccounts[addr] == nil {
+		sv.touchedAccounts[addr] = make(map[common.Hash]bool)
+	}
+}
+
+// PopTouchedAccounts returns the accounts and the storage slots modified since the last call, sorted
+// by address and storage key. The list may include the changes reverted afterwards, e.g. by a failed
+// smart contract call, so the callers should read the resulting values from the view.
+func (sv *StoreView) PopTouchedAccounts() []TouchedAccount {
+	ret := []TouchedAccount{}
+	for addr, keys := range sv.touchedAccounts {
+		touched := TouchedAccount{
+			Address:     addr,
+			StorageKeys: []common.Hash{},
+		}
+		for key := range keys {
+			touched.StorageKeys = append(touched.StorageKeys, key)
+		}
+		sort.Slice(touched.StorageKeys, func(i, j int) bool {
+			return bytes.Compare(touched.StorageKeys[i][:], touched.StorageKeys[j][:]) < 0
+		})
+		ret = append(ret, touched)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return bytes.Compare(ret[i].Address[:], ret[j].Address[:]) < 0
+	})
+	sv.touchedAccounts = nil
+	return ret
+}
+
 //
 // ---------- Implement svm.StateDB interface -----------
 //
@@ -627,6 +671,9 @@ func (sv *StoreView) GetState(addr common.Address, key common.Hash) common.Hash
 }
 
 func (sv *StoreView) SetState(addr common.Address, key, val common.Hash) {
+	sv.touchAccount(addr)
+	sv.touchedAccounts[addr][key] = true
+
 	account := sv.GetAccount(addr)
 	if account == nil {
 		account = types.NewAccount(addr)
@@ -0,0 +1,104 @@
+package state
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/ledger/types"
+	"github.com/thetatoken/theta/store/database"
+	"github.com/thetatoken/theta/store/trie"
+)
+
+// StateTrieVerifier makes sure every node of state tries, including the storage tries of all the
+// accounts, can be loaded from the database. It remembers the nodes already verified, so the
+// subtries shared between consecutive state roots are only walked once. A node is only remembered
+// once the whole state trie it was reached from checks out, so a missing node is reported again for
+// every state root that reaches it.
+type StateTrieVerifier struct {
+	db       database.Database
+	verified map[common.Hash]struct{}
+
+	NumNodes uint64 // number of distinct trie nodes verified so far
+}
+
+// NewStateTrieVerifier creates a new instance of StateTrieVerifier.
+func NewStateTrieVerifier(db database.Database) *StateTrieVerifier {
+	return &StateTrieVerifier{
+		db:       db,
+		verified: make(map[common.Hash]struct{}),
+	}
+}
+
+// Verify walks the state trie with the given root, and returns an error for the first node found missing.
+func (v *StateTrieVerifier) Verify(root common.Hash) error {
+	visited := make(map[common.Hash]struct{}) // the nodes walked for this root, verified only if all of them check out
+	storageRoots := []common.Hash{}
+	err := v.walkTrie(root, visited, func(key, value []byte) error {
+		if !bytes.HasPrefix(key, []byte("ls/a/")) {
+			return nil
+		}
+		account := &types.Account{}
+		if err := types.FromBytes(value, account); err != nil {
+			return fmt.Errorf("failed to decode account %x: %v", key, err)
+		}
+		if account.Root != (common.Hash{}) {
+			storageRoots = append(storageRoots, account.Root)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, storageRoot := range storageRoots {
+		if err := v.walkTrie(storageRoot, visited, nil); err != nil {
+			return fmt.Errorf("storage trie %v: %v", storageRoot.Hex(), err)
+		}
+	}
+
+	// The subtries of the state trie nodes include the storage tries of their accounts, so
+	// the nodes can only be marked as verified after all the storage tries check out
+	for hash := range visited {
+		v.verified[hash] = struct{}{}
+	}
+	v.NumNodes += uint64(len(visited))
+	return nil
+}
+
+func (v *StateTrieVerifier) walkTrie(root common.Hash, visited map[common.Hash]struct{}, onLeaf func(key, value []byte) error) error {
+	if v.isWalked(root, visited) {
+		return nil
+	}
+
+	tr, err := trie.New(root, trie.NewDatabase(v.db))
+	if err != nil {
+		return err
+	}
+	it := tr.NodeIterator(nil)
+	descend := true
+	for it.Next(descend) {
+		descend = true
+		if hash := it.Hash(); hash != (common.Hash{}) {
+			if v.isWalked(hash, visited) {
+				descend = false // the whole subtrie has been verified, or is being walked for this root
+				continue
+			}
+			visited[hash] = struct{}{}
+		}
+		if it.Leaf() && onLeaf != nil {
+			if err := onLeaf(it.LeafKey(), it.LeafBlob()); err != nil {
+				return err
+			}
+		}
+	}
+	return it.Error()
+}
+
+func (v *StateTrieVerifier) isWalked(hash common.Hash, visited map[common.Hash]struct{}) bool {
+	if _, ok := v.verified[hash]; ok {
+		return true
+	}
+	_, ok := visited[hash]
+	return ok
+}
@@ -0,0 +1,43 @@
+package state
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/ledger/types"
+	"github.com/thetatoken/theta/store/database/backend"
+)
+
+func TestStateTrieVerifierReportsMissingNodeForEveryRoot(t *testing.T) {
+	assert := assert.New(t)
+
+	db := backend.NewMemDatabase()
+	contractAddr := common.HexToAddress("0x5C3159dDD2fe0F9862bC7b7D60C1875fa8F81337")
+	addr := common.HexToAddress("0x2E833968E5bB786Ae419c4d13189fB081Cc43bab")
+
+	sv := NewStoreView(0, common.Hash{}, db)
+	sv.SetState(contractAddr, common.BigToHash(big.NewInt(1)), common.BigToHash(big.NewInt(42)))
+	root1 := sv.Save()
+
+	// The second state root shares the contract account, and its storage trie, with the first one
+	sv.SetAccount(addr, &types.Account{
+		Address: addr,
+		Balance: types.Coins{ThetaWei: big.NewInt(0), TFuelWei: big.NewInt(100)},
+	})
+	root2 := sv.Save()
+
+	v := NewStateTrieVerifier(db)
+	assert.Nil(v.Verify(root1))
+	assert.Nil(v.Verify(root2))
+	assert.True(v.NumNodes > 0)
+
+	storageRoot := sv.GetAccount(contractAddr).Root
+	assert.Nil(db.Delete(storageRoot[:]))
+
+	v = NewStateTrieVerifier(db)
+	assert.NotNil(v.Verify(root1))
+	assert.NotNil(v.Verify(root2))
+	assert.Equal(uint64(0), v.NumNodes)
+}
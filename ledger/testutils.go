@@ -26,6 +26,7 @@ import (
 	slst "github.com/thetatoken/thetasubchain/ledger/state"
 	stypes "github.com/thetatoken/thetasubchain/ledger/types"
 	smp "github.com/thetatoken/thetasubchain/mempool"
+	ssigner "github.com/thetatoken/thetasubchain/signer"
 )
 
 type mockSnapshot struct {
@@ -57,7 +58,7 @@ func newExecSim(chainID string, db database.Database, snapshot mockSnapshot, val
 	dispatcher := dp.NewDispatcher(messenger, nil)
 
 	valMgr := sconsensus.NewFixedValidatorManager()
-	consensus := sconsensus.NewConsensusEngine(valPrivAcc.PrivKey, store, chain, dispatcher, valMgr, nil)
+	consensus := sconsensus.NewConsensusEngine(ssigner.NewLocalSigner(valPrivAcc.PrivKey, nil), store, chain, dispatcher, valMgr, nil)
 	valMgr.SetConsensusEngine(consensus)
 
 	mempool := smp.CreateMempool(dispatcher, consensus)
@@ -141,7 +142,7 @@ func newTestLedger() (chainID string, ledger *Ledger, mempool *smp.Mempool) {
 }
 
 func newTesetValidatorManager(consensus score.ConsensusEngine) score.ValidatorManager {
-	proposerAddressStr := consensus.PrivateKey().PublicKey().Address().String()
+	proposerAddressStr := consensus.Signer().Address().String()
 	propser := score.NewValidator(proposerAddressStr, new(big.Int).SetUint64(999))
 
 	_, val2PubKey, err := crypto.TEST_GenerateKeyPairWithSeed("val2")
@@ -204,20 +205,18 @@ func newRawCoinbaseTx(chainID string, ledger *Ledger, sequence int) common.Bytes
 		outputs = append(outputs, output)
 	}
 
-	proposerSk := ledger.consensus.PrivateKey()
-	proposerPk := proposerSk.PublicKey()
+	proposerSigner := ledger.consensus.Signer()
 	coinbaseTx := &types.CoinbaseTx{
-		Proposer:    types.TxInput{Address: proposerPk.Address(), Sequence: uint64(sequence)},
+		Proposer:    types.TxInput{Address: proposerSigner.Address(), Sequence: uint64(sequence)},
 		Outputs:     outputs,
 		BlockHeight: 2,
 	}
 
-	signBytes := coinbaseTx.SignBytes(chainID)
-	sig, err := proposerSk.Sign(signBytes)
+	sig, err := proposerSigner.SignTx(chainID, coinbaseTx)
 	if err != nil {
 		panic("Failed to sign the coinbase transaction")
 	}
-	if !coinbaseTx.SetSignature(proposerPk.Address(), sig) {
+	if !coinbaseTx.SetSignature(proposerSigner.Address(), sig) {
 		panic("Failed to set signature for the coinbase transaction")
 	}
 
@@ -0,0 +1,94 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/ledger/types"
+)
+
+const (
+	TxBatchSend types.TxType = 204
+)
+
+// MaxBatchSendTxOutputs is the maximum number of recipients of a BatchSendTx, so that the input
+// and the outputs do not modify more accounts than any other transaction is allowed to
+const MaxBatchSendTxOutputs = types.MaxAccountsAffectedPerTx - 1
+
+// BatchSendTxOutputFeeDivisor determines the fee of each output of a BatchSendTx beyond the first one, which
+// is 1/BatchSendTxOutputFeeDivisor of the minimum fee of a SendTx with one input and one output. The extra
+// outputs are cheaper than in a SendTx since they carry no signature and share the sequence of the input.
+const BatchSendTxOutputFeeDivisor = 4
+
+// GetBatchSendTxOutputFeeTFuelWei returns the minimum fee of each output beyond the first one.
+func GetBatchSendTxOutputFeeTFuelWei(blockHeight uint64) *big.Int {
+	sendTxFee := types.GetSendTxMinimumTransactionFeeTFuelWei(2, blockHeight)
+	return new(big.Int).Div(sendTxFee, big.NewInt(BatchSendTxOutputFeeDivisor))
+}
+
+// GetBatchSendTxMinimumTransactionFeeTFuelWei returns the minimum fee of a BatchSendTx with the given number of outputs.
+func GetBatchSendTxMinimumTransactionFeeTFuelWei(numOutputs uint64, blockHeight uint64) *big.Int {
+	fee := new(big.Int).Set(types.GetSendTxMinimumTransactionFeeTFuelWei(2, blockHeight))
+	if numOutputs > 1 {
+		outputFee := GetBatchSendTxOutputFeeTFuelWei(blockHeight)
+		fee.Add(fee, new(big.Int).Mul(outputFee, new(big.Int).SetUint64(numOutputs-1)))
+	}
+	return fee
+}
+
+//---------------------------------BatchSendTx--------------------------------------------
+
+// BatchSendTx pays multiple recipients from a single input with a single signature.
+type BatchSendTx struct {
+	Fee     types.Coins
+	Input   types.TxInput
+	Outputs []types.TxOutput
+}
+
+type BatchSendTxJSON struct {
+	Fee     types.Coins      `json:"fee"`
+	Input   types.TxInput    `json:"input"`
+	Outputs []types.TxOutput `json:"outputs"`
+}
+
+func (tx BatchSendTx) MarshalJSON() ([]byte, error) {
+	return json.Marshal(BatchSendTxJSON(tx))
+}
+
+func (tx *BatchSendTx) UnmarshalJSON(data []byte) error {
+	var a BatchSendTxJSON
+	if err := json.Unmarshal(data, &a); err != nil {
+		return err
+	}
+	*tx = BatchSendTx(a)
+	return nil
+}
+
+func (_ *BatchSendTx) AssertIsTx() {}
+
+func (tx *BatchSendTx) SignBytes(chainID string) []byte {
+	signBytes := encodeToBytes(chainID)
+	sig := tx.Input.Signature
+	tx.Input.Signature = nil
+	txBytes, _ := TxToBytes(tx)
+	signBytes = append(signBytes, txBytes...)
+	signBytes = addPrefixForSignBytes(signBytes)
+
+	tx.Input.Signature = sig
+	return signBytes
+}
+
+func (tx *BatchSendTx) SetSignature(addr common.Address, sig *crypto.Signature) bool {
+	if tx.Input.Address == addr {
+		tx.Input.Signature = sig
+		return true
+	}
+	return false
+}
+
+func (tx *BatchSendTx) String() string {
+	return fmt.Sprintf("BatchSendTx{%v -> %v outputs}", tx.Input, len(tx.Outputs))
+}
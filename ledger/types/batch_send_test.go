@@ -0,0 +1,37 @@
+package types
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/ledger/types"
+)
+
+func TestGetBatchSendTxMinimumTransactionFeeTFuelWei(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, blockHeight := range []uint64{1, common.HeightJune2021FeeAdjustment} {
+		sendTxFee := types.GetSendTxMinimumTransactionFeeTFuelWei(2, blockHeight)
+		outputFee := GetBatchSendTxOutputFeeTFuelWei(blockHeight)
+		assert.Equal(new(big.Int).Div(sendTxFee, big.NewInt(BatchSendTxOutputFeeDivisor)), outputFee)
+
+		// A single output costs as much as a SendTx with one input and one output
+		assert.Equal(sendTxFee, GetBatchSendTxMinimumTransactionFeeTFuelWei(0, blockHeight))
+		assert.Equal(sendTxFee, GetBatchSendTxMinimumTransactionFeeTFuelWei(1, blockHeight))
+
+		for _, numOutputs := range []uint64{2, 10, MaxBatchSendTxOutputs} {
+			expected := new(big.Int).Mul(outputFee, new(big.Int).SetUint64(numOutputs-1))
+			expected.Add(expected, sendTxFee)
+			assert.Equal(expected, GetBatchSendTxMinimumTransactionFeeTFuelWei(numOutputs, blockHeight), "outputs: %v", numOutputs)
+		}
+
+		// The batch is cheaper than the equivalent SendTx
+		numOutputs := uint64(10)
+		assert.True(GetBatchSendTxMinimumTransactionFeeTFuelWei(numOutputs, blockHeight).Cmp(
+			types.GetSendTxMinimumTransactionFeeTFuelWei(numOutputs+1, blockHeight)) <= 0)
+	}
+
+	assert.Equal(types.MaxAccountsAffectedPerTx, MaxBatchSendTxOutputs+1)
+}
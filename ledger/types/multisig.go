@@ -0,0 +1,335 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/ledger/types"
+	"github.com/thetatoken/theta/rlp"
+)
+
+const (
+	TxMultisigSend types.TxType = 203
+)
+
+// MaxMultisigPubKeys is the maximum number of public keys of a multisig account
+const MaxMultisigPubKeys = 16
+
+var multisigAddressPrefix = []byte("multisig")
+
+//---------------------------------MultisigAccount--------------------------------------------
+
+// MultisigAccount is an m-of-n account. Its address is derived from the threshold and the set
+// of public keys, hence it is not necessary to register the account on chain before using it.
+type MultisigAccount struct {
+	Threshold uint64
+	PubKeys   []common.Bytes // sorted in ascending byte order
+}
+
+type MultisigAccountJSON struct {
+	Threshold common.JSONUint64 `json:"threshold"`
+	PubKeys   []common.Bytes    `json:"pub_keys"`
+	Address   common.Address    `json:"address"`
+}
+
+// NewMultisigAccount creates a multisig account, the public keys are sorted so that the same
+// set of keys always results in the same address.
+func NewMultisigAccount(threshold uint64, pubKeys []common.Bytes) (*MultisigAccount, error) {
+	sorted := make([]common.Bytes, len(pubKeys))
+	copy(sorted, pubKeys)
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i], sorted[j]) < 0
+	})
+	account := &MultisigAccount{
+		Threshold: threshold,
+		PubKeys:   sorted,
+	}
+	if err := account.Validate(); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// Validate checks the threshold and the public keys of the account.
+func (ma *MultisigAccount) Validate() error {
+	if len(ma.PubKeys) == 0 || len(ma.PubKeys) > MaxMultisigPubKeys {
+		return fmt.Errorf("a multisig account needs 1 to %v public keys, got %v", MaxMultisigPubKeys, len(ma.PubKeys))
+	}
+	if ma.Threshold == 0 || ma.Threshold > uint64(len(ma.PubKeys)) {
+		return fmt.Errorf("invalid threshold %v for %v public keys", ma.Threshold, len(ma.PubKeys))
+	}
+	for i, pubKey := range ma.PubKeys {
+		if _, err := crypto.PublicKeyFromBytes(pubKey); err != nil {
+			return fmt.Errorf("invalid public key %v: %v", pubKey.String(), err)
+		}
+		if i > 0 && bytes.Compare(ma.PubKeys[i-1], pubKey) >= 0 {
+			return errors.New("the public keys need to be sorted and distinct")
+		}
+	}
+	return nil
+}
+
+// Address returns the address of the multisig account.
+func (ma *MultisigAccount) Address() common.Address {
+	encoded, err := rlp.EncodeToBytes(ma)
+	if err != nil {
+		panic(err)
+	}
+	hash := crypto.Keccak256(multisigAddressPrefix, encoded)
+	return common.BytesToAddress(hash[12:])
+}
+
+// SignerAddresses returns the addresses of the public keys, in the same order as the public keys.
+func (ma *MultisigAccount) SignerAddresses() []common.Address {
+	addresses := make([]common.Address, len(ma.PubKeys))
+	for i, pubKey := range ma.PubKeys {
+		pk, err := crypto.PublicKeyFromBytes(pubKey)
+		if err != nil {
+			continue
+		}
+		addresses[i] = pk.Address()
+	}
+	return addresses
+}
+
+// IndexOf returns the index of the signer address, or -1 if it is not a signer of the account.
+func (ma *MultisigAccount) IndexOf(signer common.Address) int {
+	for i, address := range ma.SignerAddresses() {
+		if address == signer {
+			return i
+		}
+	}
+	return -1
+}
+
+func (ma MultisigAccount) MarshalJSON() ([]byte, error) {
+	return json.Marshal(MultisigAccountJSON{
+		Threshold: common.JSONUint64(ma.Threshold),
+		PubKeys:   ma.PubKeys,
+		Address:   ma.Address(),
+	})
+}
+
+func (ma *MultisigAccount) UnmarshalJSON(data []byte) error {
+	var a MultisigAccountJSON
+	if err := json.Unmarshal(data, &a); err != nil {
+		return err
+	}
+	ma.Threshold = uint64(a.Threshold)
+	ma.PubKeys = a.PubKeys
+	return nil
+}
+
+//---------------------------------MultisigSendTx--------------------------------------------
+
+// MultisigSignature is the signature of the signer with the given index in the public keys of a multisig account.
+type MultisigSignature struct {
+	Index     uint64
+	Signature *crypto.Signature
+}
+
+type MultisigSignatureJSON struct {
+	Index     common.JSONUint64 `json:"index"`
+	Signature *crypto.Signature `json:"signature"`
+}
+
+func (ms MultisigSignature) MarshalJSON() ([]byte, error) {
+	return json.Marshal(MultisigSignatureJSON{
+		Index:     common.JSONUint64(ms.Index),
+		Signature: ms.Signature,
+	})
+}
+
+func (ms *MultisigSignature) UnmarshalJSON(data []byte) error {
+	var s MultisigSignatureJSON
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	ms.Index = uint64(s.Index)
+	ms.Signature = s.Signature
+	return nil
+}
+
+// MultisigAuth authorizes the spending of a multisig account input.
+type MultisigAuth struct {
+	Account    MultisigAccount
+	Signatures []MultisigSignature
+}
+
+type MultisigAuthJSON struct {
+	Account    MultisigAccount     `json:"account"`
+	Signatures []MultisigSignature `json:"signatures"`
+}
+
+func (ma MultisigAuth) MarshalJSON() ([]byte, error) {
+	return json.Marshal(MultisigAuthJSON(ma))
+}
+
+func (ma *MultisigAuth) UnmarshalJSON(data []byte) error {
+	var a MultisigAuthJSON
+	if err := json.Unmarshal(data, &a); err != nil {
+		return err
+	}
+	*ma = MultisigAuth(a)
+	return nil
+}
+
+// Verify checks that at least threshold distinct signers of the account signed the sign bytes.
+func (ma *MultisigAuth) Verify(signBytes common.Bytes) error {
+	if err := ma.Account.Validate(); err != nil {
+		return err
+	}
+	if uint64(len(ma.Signatures)) > uint64(len(ma.Account.PubKeys)) {
+		return fmt.Errorf("too many signatures: %v", len(ma.Signatures))
+	}
+	signers := ma.Account.SignerAddresses()
+	signed := make(map[uint64]bool)
+	for _, sig := range ma.Signatures {
+		if sig.Index >= uint64(len(signers)) {
+			return fmt.Errorf("invalid signer index %v", sig.Index)
+		}
+		if signed[sig.Index] {
+			return fmt.Errorf("duplicated signature of signer %v", sig.Index)
+		}
+		if sig.Signature == nil || !sig.Signature.Verify(signBytes, signers[sig.Index]) {
+			return fmt.Errorf("invalid signature of signer %v", signers[sig.Index].Hex())
+		}
+		signed[sig.Index] = true
+	}
+	if uint64(len(signed)) < ma.Account.Threshold {
+		return fmt.Errorf("insufficient signatures: got %v, need %v", len(signed), ma.Account.Threshold)
+	}
+	return nil
+}
+
+// AddSignature adds or replaces the signature of the signer.
+func (ma *MultisigAuth) AddSignature(signer common.Address, sig *crypto.Signature) bool {
+	index := ma.Account.IndexOf(signer)
+	if index < 0 {
+		return false
+	}
+	for i := range ma.Signatures {
+		if ma.Signatures[i].Index == uint64(index) {
+			ma.Signatures[i].Signature = sig
+			return true
+		}
+	}
+	ma.Signatures = append(ma.Signatures, MultisigSignature{
+		Index:     uint64(index),
+		Signature: sig,
+	})
+	sort.Slice(ma.Signatures, func(i, j int) bool {
+		return ma.Signatures[i].Index < ma.Signatures[j].Index
+	})
+	return true
+}
+
+// MultisigSendTx is a SendTx whose inputs may be multisig accounts. The inputs owned by a multisig
+// account carry no signature themselves, they are authorized by the MultisigAuth with the same address.
+// Regular inputs are signed as in a SendTx.
+type MultisigSendTx struct {
+	Fee       types.Coins
+	Inputs    []types.TxInput
+	Outputs   []types.TxOutput
+	Multisigs []MultisigAuth
+}
+
+type MultisigSendTxJSON struct {
+	Fee       types.Coins      `json:"fee"`
+	Inputs    []types.TxInput  `json:"inputs"`
+	Outputs   []types.TxOutput `json:"outputs"`
+	Multisigs []MultisigAuth   `json:"multisigs"`
+}
+
+func (tx MultisigSendTx) MarshalJSON() ([]byte, error) {
+	return json.Marshal(MultisigSendTxJSON(tx))
+}
+
+func (tx *MultisigSendTx) UnmarshalJSON(data []byte) error {
+	var a MultisigSendTxJSON
+	if err := json.Unmarshal(data, &a); err != nil {
+		return err
+	}
+	*tx = MultisigSendTx(a)
+	return nil
+}
+
+func (_ *MultisigSendTx) AssertIsTx() {}
+
+// SignBytes returns the bytes signed by both the regular input owners and the multisig signers.
+// All signatures are excluded, while the multisig accounts are included.
+func (tx *MultisigSendTx) SignBytes(chainID string) []byte {
+	signBytes := encodeToBytes(chainID)
+	inputs := tx.Inputs
+	multisigs := tx.Multisigs
+
+	tx.Inputs = make([]types.TxInput, len(inputs))
+	for i, input := range inputs {
+		tx.Inputs[i] = input
+		tx.Inputs[i].Signature = nil
+	}
+	tx.Multisigs = make([]MultisigAuth, len(multisigs))
+	for i, multisig := range multisigs {
+		tx.Multisigs[i] = MultisigAuth{
+			Account:    multisig.Account,
+			Signatures: []MultisigSignature{},
+		}
+	}
+	txBytes, _ := TxToBytes(tx)
+	signBytes = append(signBytes, txBytes...)
+	signBytes = addPrefixForSignBytes(signBytes)
+
+	tx.Inputs = inputs
+	tx.Multisigs = multisigs
+	return signBytes
+}
+
+// SetSignature sets the signature of a regular input, or adds the signature of a multisig signer.
+func (tx *MultisigSendTx) SetSignature(addr common.Address, sig *crypto.Signature) bool {
+	set := false
+	for i, input := range tx.Inputs {
+		if input.Address == addr {
+			tx.Inputs[i].Signature = sig
+			return true
+		}
+	}
+	for i := range tx.Multisigs {
+		if tx.Multisigs[i].AddSignature(addr, sig) {
+			set = true
+		}
+	}
+	return set
+}
+
+// MultisigOf returns the multisig authorization of the address, or nil if the address is not a multisig account.
+func (tx *MultisigSendTx) MultisigOf(address common.Address) *MultisigAuth {
+	for i := range tx.Multisigs {
+		if tx.Multisigs[i].Account.Address() == address {
+			return &tx.Multisigs[i]
+		}
+	}
+	return nil
+}
+
+// NumSignatures returns the total number of signatures to verify.
+func (tx *MultisigSendTx) NumSignatures() uint64 {
+	num := uint64(0)
+	for _, input := range tx.Inputs {
+		if input.Signature != nil {
+			num++
+		}
+	}
+	for _, multisig := range tx.Multisigs {
+		num += uint64(len(multisig.Signatures))
+	}
+	return num
+}
+
+func (tx *MultisigSendTx) String() string {
+	return fmt.Sprintf("MultisigSendTx{%v -> %v, multisigs: %v}", tx.Inputs, tx.Outputs, len(tx.Multisigs))
+}
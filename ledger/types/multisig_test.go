@@ -0,0 +1,120 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/ledger/types"
+)
+
+func TestMultisigAccountAddress(t *testing.T) {
+	assert := assert.New(t)
+
+	signers := []types.PrivAccount{types.MakeAcc("alice"), types.MakeAcc("bob"), types.MakeAcc("carol")}
+	pubKeys := []common.Bytes{}
+	for _, signer := range signers {
+		pubKeys = append(pubKeys, signer.PrivKey.PublicKey().ToBytes())
+	}
+	reversed := []common.Bytes{pubKeys[2], pubKeys[1], pubKeys[0]}
+
+	ma1, err := NewMultisigAccount(2, pubKeys)
+	assert.Nil(err)
+	ma2, err := NewMultisigAccount(2, reversed)
+	assert.Nil(err)
+	assert.Equal(ma1.Address(), ma2.Address())
+
+	ma3, err := NewMultisigAccount(3, pubKeys)
+	assert.Nil(err)
+	assert.NotEqual(ma1.Address(), ma3.Address())
+
+	_, err = NewMultisigAccount(0, pubKeys)
+	assert.NotNil(err)
+	_, err = NewMultisigAccount(4, pubKeys)
+	assert.NotNil(err)
+	_, err = NewMultisigAccount(1, []common.Bytes{pubKeys[0], pubKeys[0]})
+	assert.NotNil(err)
+}
+
+func TestMultisigAuthVerify(t *testing.T) {
+	signers := []types.PrivAccount{types.MakeAcc("alice"), types.MakeAcc("bob"), types.MakeAcc("carol")}
+	pubKeys := []common.Bytes{}
+	for _, signer := range signers {
+		pubKeys = append(pubKeys, signer.PrivKey.PublicKey().ToBytes())
+	}
+	account, err := NewMultisigAccount(2, pubKeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signBytes := common.Bytes("multisig sign bytes")
+	sign := func(signer types.PrivAccount) *crypto.Signature {
+		sig, err := signer.PrivKey.Sign(signBytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sig
+	}
+	sigOf := func(signer types.PrivAccount) MultisigSignature {
+		return MultisigSignature{
+			Index:     uint64(account.IndexOf(signer.PrivKey.PublicKey().Address())),
+			Signature: sign(signer),
+		}
+	}
+	stranger := types.MakeAcc("mallory")
+
+	tests := []struct {
+		name       string
+		signatures []MultisigSignature
+		valid      bool
+	}{
+		{"threshold met", []MultisigSignature{sigOf(signers[0]), sigOf(signers[1])}, true},
+		{"all signers", []MultisigSignature{sigOf(signers[0]), sigOf(signers[1]), sigOf(signers[2])}, true},
+		{"below threshold", []MultisigSignature{sigOf(signers[2])}, false},
+		{"no signature", []MultisigSignature{}, false},
+		{"duplicated index", []MultisigSignature{sigOf(signers[0]), sigOf(signers[0])}, false},
+		{"out of range index", []MultisigSignature{sigOf(signers[0]), {Index: 3, Signature: sign(signers[1])}}, false},
+		{"wrong key", []MultisigSignature{sigOf(signers[0]), {Index: sigOf(signers[1]).Index, Signature: sign(stranger)}}, false},
+		{"nil signature", []MultisigSignature{sigOf(signers[0]), {Index: sigOf(signers[1]).Index}}, false},
+		{"too many signatures", []MultisigSignature{sigOf(signers[0]), sigOf(signers[1]), sigOf(signers[2]), sigOf(signers[2])}, false},
+	}
+
+	for _, test := range tests {
+		auth := MultisigAuth{Account: *account, Signatures: test.signatures}
+		err := auth.Verify(signBytes)
+		if test.valid {
+			assert.Nil(t, err, test.name)
+		} else {
+			assert.NotNil(t, err, test.name)
+		}
+	}
+
+	// The signatures over different sign bytes are rejected
+	auth := MultisigAuth{Account: *account, Signatures: []MultisigSignature{sigOf(signers[0]), sigOf(signers[1])}}
+	assert.NotNil(t, auth.Verify(common.Bytes("other sign bytes")))
+}
+
+func TestMultisigAuthAddSignature(t *testing.T) {
+	assert := assert.New(t)
+
+	signers := []types.PrivAccount{types.MakeAcc("alice"), types.MakeAcc("bob")}
+	account, err := NewMultisigAccount(2, []common.Bytes{
+		signers[0].PrivKey.PublicKey().ToBytes(),
+		signers[1].PrivKey.PublicKey().ToBytes(),
+	})
+	assert.Nil(err)
+
+	auth := MultisigAuth{Account: *account}
+	signBytes := common.Bytes("multisig sign bytes")
+	for i := len(signers) - 1; i >= 0; i-- {
+		sig, err := signers[i].PrivKey.Sign(signBytes)
+		assert.Nil(err)
+		assert.True(auth.AddSignature(signers[i].PrivKey.PublicKey().Address(), sig))
+	}
+	assert.False(auth.AddSignature(types.MakeAcc("mallory").PrivKey.PublicKey().Address(), nil))
+
+	assert.Equal(2, len(auth.Signatures))
+	assert.True(auth.Signatures[0].Index < auth.Signatures[1].Index)
+	assert.Nil(auth.Verify(signBytes))
+}
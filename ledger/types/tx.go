@@ -140,6 +140,10 @@ func TxToBytes(t types.Tx) ([]byte, error) {
 		txType = types.TxSmartContract
 	case *SubchainValidatorSetUpdateTx:
 		txType = TxSubchainValidatorSetUpdate
+	case *MultisigSendTx:
+		txType = TxMultisigSend
+	case *BatchSendTx:
+		txType = TxBatchSend
 	default:
 		return nil, errors.New("unsupported message type")
 	}
@@ -178,6 +182,14 @@ func TxFromBytes(raw []byte) (types.Tx, error) {
 		data := &SubchainValidatorSetUpdateTx{}
 		err = s.Decode(data)
 		return data, err
+	} else if txType == TxMultisigSend {
+		data := &MultisigSendTx{}
+		err = s.Decode(data)
+		return data, err
+	} else if txType == TxBatchSend {
+		data := &BatchSendTx{}
+		err = s.Decode(data)
+		return data, err
 	} else {
 		return nil, fmt.Errorf("Unknown TX type: %v", txType)
 	}
@@ -5,19 +5,23 @@ import (
 	"encoding/hex"
 	"errors"
 	"math/big"
+	"sort"
 	"sync"
 	"time"
 
 	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
 
 	"github.com/thetatoken/theta/common"
-	"github.com/thetatoken/theta/common/clist"
 	"github.com/thetatoken/theta/common/math"
 	"github.com/thetatoken/theta/common/pqueue"
 	"github.com/thetatoken/theta/common/result"
+	"github.com/thetatoken/theta/crypto"
 	dp "github.com/thetatoken/theta/dispatcher"
+	scom "github.com/thetatoken/thetasubchain/common"
 	sconsensus "github.com/thetatoken/thetasubchain/consensus"
 	score "github.com/thetatoken/thetasubchain/core"
+	stypes "github.com/thetatoken/thetasubchain/ledger/types"
 )
 
 var logger *log.Entry = log.WithFields(log.Fields{"prefix": "mempool"})
@@ -30,9 +34,25 @@ func (m MempoolError) Error() string {
 
 const DuplicateTxError = MempoolError("Transaction already seen")
 const FastsyncSkipTxError = MempoolError("Skip tx during fastsync")
+const MempoolFullError = MempoolError("Mempool is full, please submit your transaction again later")
+const AccountQuotaExceededError = MempoolError("Too many pending transactions from the sender, please submit your transaction again later")
+const ReplacementUnderpricedError = MempoolError("Replacement transaction gas price is too low")
+const UnderpricedTxError = MempoolError("Mempool is full and the transaction gas price is too low to replace pending transactions")
+
+// InvalidTxError is returned for the txs which can never become valid, i.e. the undecodable txs and the
+// txs with invalid signatures. The txs failing the screening for the current state of the sender account,
+// e.g. with a stale sequence or an insufficient balance, are relayed by honest peers too.
+type InvalidTxError string
+
+func (e InvalidTxError) Error() string {
+	return string(e)
+}
 
 const MaxMempoolTxCount int = 25600
 
+const journalReplayCheckInterval = 1 * time.Second
+const defaultJournalRotateInterval = 1 * time.Hour
+
 //
 // mempoolTransaction implements the pqueue.Element interface
 //
@@ -105,6 +125,50 @@ func (mtg *mempoolTransactionGroup) IsEmpty() bool {
 	return mtg.txs.IsEmpty()
 }
 
+// FindTx returns the pending tx with the given sequence, or nil if not found.
+func (mtg *mempoolTransactionGroup) FindTx(sequence uint64) *mempoolTransaction {
+	for _, elem := range *mtg.txs.ElementList() {
+		mptx := elem.(*mempoolTransaction)
+		if mptx.txInfo.Sequence == sequence {
+			return mptx
+		}
+	}
+	return nil
+}
+
+// ReplaceTx replaces the given pending tx with a new tx of the same sequence.
+func (mtg *mempoolTransactionGroup) ReplaceTx(mptx *mempoolTransaction, rawTx common.Bytes, txInfo *score.TxInfo) {
+	mtg.txs.Remove(mptx.GetIndex())
+	mtg.AddTx(rawTx, txInfo)
+}
+
+// Size returns the number of transactions in the group.
+func (mtg *mempoolTransactionGroup) Size() int {
+	return len(*mtg.txs.ElementList())
+}
+
+// SortedTxs returns the transactions of the group in ascending sequence order.
+func (mtg *mempoolTransactionGroup) SortedTxs() []*mempoolTransaction {
+	mptxs := []*mempoolTransaction{}
+	for _, elem := range *mtg.txs.ElementList() {
+		mptxs = append(mptxs, elem.(*mempoolTransaction))
+	}
+	sort.Slice(mptxs, func(i, j int) bool {
+		return mptxs[i].txInfo.Sequence < mptxs[j].txInfo.Sequence
+	})
+	return mptxs
+}
+
+// SortedRawTxs returns the raw transactions of the group in ascending sequence order.
+func (mtg *mempoolTransactionGroup) SortedRawTxs() []common.Bytes {
+	mptxs := mtg.SortedTxs()
+	rawTxs := make([]common.Bytes, 0, len(mptxs))
+	for _, mptx := range mptxs {
+		rawTxs = append(rawTxs, mptx.rawTransaction)
+	}
+	return rawTxs
+}
+
 // RemoveTxs removes matching Txs from transaction group. Returns number of Txs removed.
 func (mtg *mempoolTransactionGroup) RemoveTxs(committedRawTxMap map[string]bool) (numRemoved int) {
 	elementList := mtg.txs.ElementList()
@@ -133,6 +197,23 @@ func createMempoolTransactionGroup(rawTx common.Bytes, txInfo *score.TxInfo) *me
 	return txGroup
 }
 
+// MempoolStats contains the mempool size and the counters of the txs
+// rejected or dropped by the mempool.
+type MempoolStats struct {
+	Size                    int
+	NumAccounts             int
+	MaxTxCount              int
+	MaxTxsPerAccount        int
+	NumEvicted              uint64
+	NumExpired              uint64
+	NumRejectedFull         uint64
+	NumRejectedAccountQuota uint64
+	NumRejectedUnderpriced  uint64
+	NumReplaced             uint64
+	NumQueued               int
+	NumPromoted             uint64
+}
+
 //
 // Mempool manages the transactions submitted by the clients
 // or relayed from peers
@@ -144,12 +225,21 @@ type Mempool struct {
 	ledger     score.Ledger
 	dispatcher *dp.Dispatcher
 
-	newTxs           *clist.CList          // new transactions, to be gossiped to other nodes
 	candidateTxs     *pqueue.PriorityQueue // candidate transactions for new block assembly, ordered by the transaction fee (high to low)
 	txBookeepper     transactionBookkeeper
 	addressToTxGroup map[common.Address]*mempoolTransactionGroup
+	txQueue          transactionQueue // future-sequence transactions
 	size             int
 
+	journal               *transactionJournal // nil if the journal is disabled
+	journalRotateInterval time.Duration
+	reapedTxs             map[string]common.Bytes // map: tx hash -> reaped tx not yet finalized, kept in the journal
+
+	maxTxCount       int
+	maxTxsPerAccount int
+	priceBump        int64 // min gas price increase in percent for same-sequence replacement
+	stats            MempoolStats
+
 	// Life cycle
 	wg      *sync.WaitGroup
 	quit    chan struct{}
@@ -160,14 +250,24 @@ type Mempool struct {
 
 // CreateMempool creates an instance of Mempool
 func CreateMempool(dispatcher *dp.Dispatcher, engine *sconsensus.ConsensusEngine) *Mempool {
+	maxTxCount := viper.GetInt(scom.CfgMempoolMaxTxCount)
+	if maxTxCount <= 0 {
+		maxTxCount = MaxMempoolTxCount
+	}
+	maxTxLife := time.Duration(viper.GetInt(scom.CfgMempoolTxLifetimeSecs)) * time.Second
+
 	return &Mempool{
 		mutex:            &sync.Mutex{},
 		consensus:        engine,
 		dispatcher:       dispatcher,
-		newTxs:           clist.New(),
 		candidateTxs:     pqueue.CreatePriorityQueue(),
 		addressToTxGroup: make(map[common.Address]*mempoolTransactionGroup),
-		txBookeepper:     createTransactionBookkeeper(defaultMaxNumTxs),
+		txBookeepper:     createTransactionBookkeeper(defaultMaxNumTxs, maxTxLife),
+		txQueue: createTransactionQueue(viper.GetInt(scom.CfgMempoolMaxQueuedTxCount), viper.GetInt(scom.CfgMempoolMaxQueuedTxsPerAccount),
+			time.Duration(viper.GetInt(scom.CfgMempoolQueuedTxLifetimeSecs))*time.Second),
+		maxTxCount:       maxTxCount,
+		maxTxsPerAccount: viper.GetInt(scom.CfgMempoolMaxTxsPerAccount),
+		priceBump:        viper.GetInt64(scom.CfgMempoolReplacementPriceBump),
 		wg:               &sync.WaitGroup{},
 	}
 }
@@ -177,22 +277,46 @@ func (mp *Mempool) SetLedger(ledger score.Ledger) {
 	mp.ledger = ledger
 }
 
+// SetJournalPath enables journaling the accepted transactions to the given file, so that
+// they can be replayed after the node restarts. The journal is opened right away, so the
+// txs accepted before the replay are journaled as well.
+func (mp *Mempool) SetJournalPath(journalPath string) error {
+	journal := createTransactionJournal(journalPath)
+	if err := journal.open(); err != nil {
+		return err
+	}
+
+	mp.journal = journal
+	mp.reapedTxs = make(map[string]common.Bytes)
+	mp.journalRotateInterval = time.Duration(viper.GetInt(scom.CfgMempoolJournalRotateIntervalSecs)) * time.Second
+	if mp.journalRotateInterval <= 0 {
+		mp.journalRotateInterval = defaultJournalRotateInterval
+	}
+	return nil
+}
+
 // InsertTransaction inserts the incoming transaction to mempool (submitted by the clients or relayed from peers)
 func (mp *Mempool) InsertTransaction(rawTx common.Bytes) error {
 	mp.mutex.Lock()
 	defer mp.mutex.Unlock()
 
-	if mp.txBookeepper.hasSeen(rawTx) {
+	err := mp.insertTransactionUnsafe(rawTx)
+	if err == nil && mp.journal != nil {
+		if jerr := mp.journal.insert(rawTx); jerr != nil {
+			logger.Warnf("Failed to journal tx, tx.hash: 0x%v, err: %v", getTransactionHash(rawTx), jerr)
+		}
+	}
+	return err
+}
+
+// insertTransactionUnsafe is the non-locking version of InsertTransaction, which does not journal the tx
+func (mp *Mempool) insertTransactionUnsafe(rawTx common.Bytes) error {
+	if mp.txBookeepper.hasSeen(rawTx) || mp.txQueue.has(getTransactionHash(rawTx)) {
 		logger.Debugf("Transaction already seen: %v, hash: 0x%v",
 			hex.EncodeToString(rawTx), getTransactionHash(rawTx))
 		return DuplicateTxError
 	}
 
-	// if mp.size >= MaxMempoolTxCount {
-	// 	logger.Debugf("Mempool is full")
-	// 	return errors.New("mempool is full, please submit your transaction again later")
-	// }
-
 	var txInfo *score.TxInfo
 	var checkTxRes result.Result
 
@@ -200,10 +324,31 @@ func (mp *Mempool) InsertTransaction(rawTx common.Bytes) error {
 	if mp.consensus.HasSynced() {
 		txInfo, checkTxRes = mp.ledger.ScreenTx(rawTx)
 		if !checkTxRes.IsOK() {
+			if checkTxRes.Code == result.CodeInvalidSequence {
+				// The tx might be replacing a pending tx with the same sequence
+				if replaced, err := mp.replaceTxUnsafe(rawTx); replaced || err != nil {
+					return err
+				}
+				// Or its sequence might be ahead of the next expected sequence
+				if queued, err := mp.queueTxUnsafe(rawTx); queued || err != nil {
+					return err
+				}
+			}
 			logger.Debugf("Transaction screening failed, tx: %v, error: %v", hex.EncodeToString(rawTx), checkTxRes.Message)
+			if checkTxRes.Code == result.CodeInvalidSignature {
+				return InvalidTxError(checkTxRes.Message)
+			}
+			if _, err := stypes.TxFromBytes(rawTx); err != nil {
+				return InvalidTxError(checkTxRes.Message)
+			}
 			return errors.New(checkTxRes.Message)
 		}
 
+		if err := mp.reserveCapacityUnsafe(rawTx, txInfo); err != nil {
+			logger.Debugf("Transaction rejected, tx.hash: 0x%v, error: %v", getTransactionHash(rawTx), err)
+			return err
+		}
+
 		// only record the transactions that passed the screening. This is because that
 		// an invalid transaction could becoume valid later on. For example, assume expected
 		// sequence for an account is 6. The account accidentally submits txA (seq = 7), got rejected.
@@ -211,18 +356,13 @@ func (mp *Mempool) InsertTransaction(rawTx common.Bytes) error {
 		// should not be rejected even though it has been submitted earlier.
 		mp.txBookeepper.record(rawTx)
 
-		txGroup, ok := mp.addressToTxGroup[txInfo.Address]
-		if ok {
-			txGroup.AddTx(rawTx, txInfo)
-			mp.candidateTxs.Remove(txGroup.index) // Need to re-insert txGroup into queue since its priority could change.
-		} else {
-			txGroup = createMempoolTransactionGroup(rawTx, txInfo)
-			mp.addressToTxGroup[txInfo.Address] = txGroup
-		}
-		mp.candidateTxs.Push(txGroup)
-		logger.Debugf("rawTx: %v, txInfo: %v", hex.EncodeToString(rawTx), txInfo)
-		logger.Infof("Insert tx, tx.hash: 0x%v", getTransactionHash(rawTx))
-		mp.size++
+		mp.addCandidateTxUnsafe(rawTx, txInfo)
+
+		// The tx might have closed the sequence gap of the queued txs from the same account
+		mp.promoteQueuedTxsUnsafe(txInfo.Address, func(rawTx common.Bytes) result.Result {
+			_, res := mp.ledger.ScreenTx(rawTx)
+			return res
+		})
 
 		return nil
 	}
@@ -230,15 +370,221 @@ func (mp *Mempool) InsertTransaction(rawTx common.Bytes) error {
 	return FastsyncSkipTxError
 }
 
+// addCandidateTxUnsafe inserts a screened tx into the candidate pool.
+func (mp *Mempool) addCandidateTxUnsafe(rawTx common.Bytes, txInfo *score.TxInfo) {
+	txGroup, ok := mp.addressToTxGroup[txInfo.Address]
+	if ok {
+		txGroup.AddTx(rawTx, txInfo)
+		mp.candidateTxs.Remove(txGroup.index) // Need to re-insert txGroup into queue since its priority could change.
+	} else {
+		txGroup = createMempoolTransactionGroup(rawTx, txInfo)
+		mp.addressToTxGroup[txInfo.Address] = txGroup
+	}
+	mp.candidateTxs.Push(txGroup)
+	logger.Debugf("rawTx: %v, txInfo: %v", hex.EncodeToString(rawTx), txInfo)
+	logger.Infof("Insert tx, tx.hash: 0x%v", getTransactionHash(rawTx))
+	mp.size++
+}
+
+// queueTxUnsafe queues a tx whose sequence is ahead of the next expected sequence of
+// the sender. It returns false without an error if the tx is not a future-sequence tx.
+func (mp *Mempool) queueTxUnsafe(rawTx common.Bytes) (bool, error) {
+	txInfo, res := mp.ledger.ScreenFutureTx(rawTx)
+	if !res.IsOK() {
+		return false, nil
+	}
+
+	mp.stats.NumExpired += uint64(mp.txQueue.removeOutdated())
+
+	if queuedTx := mp.txQueue.get(txInfo.Address, txInfo.Sequence); queuedTx != nil {
+		if mp.isReplacementUnderpriced(queuedTx.txInfo.EffectiveGasPrice, txInfo.EffectiveGasPrice) {
+			mp.stats.NumRejectedUnderpriced++
+			return false, ReplacementUnderpricedError
+		}
+		mp.txQueue.remove(queuedTx)
+		mp.stats.NumReplaced++
+	} else {
+		if mp.txQueue.maxNumTxsPerAccount > 0 && mp.txQueue.accountSize(txInfo.Address) >= mp.txQueue.maxNumTxsPerAccount {
+			mp.stats.NumRejectedAccountQuota++
+			return false, AccountQuotaExceededError
+		}
+		if mp.txQueue.size() >= mp.txQueue.maxNumTxs {
+			mp.stats.NumRejectedFull++
+			return false, MempoolFullError
+		}
+	}
+
+	mp.txQueue.add(rawTx, txInfo)
+	logger.Infof("Queue tx, tx.hash: 0x%v, sequence: %v", getTransactionHash(rawTx), txInfo.Sequence)
+
+	return true, nil
+}
+
+// promoteQueuedTxsUnsafe moves the queued txs of the address whose sequence gap has
+// closed into the candidate pool.
+func (mp *Mempool) promoteQueuedTxsUnsafe(address common.Address, screenTx func(rawTx common.Bytes) result.Result) {
+	for _, queuedTx := range mp.txQueue.sortedTxs(address) {
+		if queuedTx.isOutdated(mp.txQueue.maxTxLife) {
+			mp.txQueue.remove(queuedTx)
+			mp.stats.NumExpired++
+			continue
+		}
+
+		if mp.size >= mp.maxTxCount || mp.isAccountQuotaReachedUnsafe(address) {
+			return // leave the txs queued until the candidate pool and the account quota have room
+		}
+		res := screenTx(queuedTx.rawTransaction)
+		if !res.IsOK() {
+			if res.Code != result.CodeInvalidSequence {
+				// The tx is no longer valid, e.g. insufficient fund
+				mp.txQueue.remove(queuedTx)
+			}
+			continue // the tx is stale, or the gap has not closed yet
+		}
+
+		mp.txQueue.remove(queuedTx)
+		mp.txBookeepper.remove(queuedTx.rawTransaction) // reset the tx life
+		mp.txBookeepper.record(queuedTx.rawTransaction)
+		mp.addCandidateTxUnsafe(queuedTx.rawTransaction, queuedTx.txInfo)
+		mp.stats.NumPromoted++
+	}
+}
+
+// promoteAllQueuedTxsUnsafe tries to promote the queued txs of all the accounts.
+func (mp *Mempool) promoteAllQueuedTxsUnsafe() {
+	mp.stats.NumExpired += uint64(mp.txQueue.removeOutdated())
+	for _, address := range mp.txQueue.addresses() {
+		mp.promoteQueuedTxsUnsafe(address, mp.ledger.ScreenTxUnsafe)
+	}
+}
+
 // Start needs to be called when the Mempool starts
 func (mp *Mempool) Start(ctx context.Context) error {
 	c, cancel := context.WithCancel(ctx)
 	mp.ctx = c
 	mp.cancel = cancel
 
+	if mp.journal != nil {
+		mp.wg.Add(1)
+		go mp.journalLoop()
+	}
+
 	return nil
 }
 
+// journalLoop replays the journal once the node has synced, and then periodically
+// rotates the journal until the mempool stops
+func (mp *Mempool) journalLoop() {
+	defer mp.wg.Done()
+
+	// The journaled txs are re-screened against the latest ledger state, hence
+	// wait until the node catches up with the network
+	checkTicker := time.NewTicker(journalReplayCheckInterval)
+	for !mp.consensus.HasSynced() {
+		select {
+		case <-mp.ctx.Done():
+			checkTicker.Stop()
+			return // leave the journal untouched, it has not been replayed yet
+		case <-checkTicker.C:
+		}
+	}
+	checkTicker.Stop()
+
+	mp.replayJournal()
+
+	rotateTicker := time.NewTicker(mp.journalRotateInterval)
+	defer rotateTicker.Stop()
+	for {
+		select {
+		case <-mp.ctx.Done():
+			mp.mutex.Lock()
+			mp.rotateJournalUnsafe()
+			if err := mp.journal.close(); err != nil {
+				logger.Warnf("Failed to close the mempool journal: %v", err)
+			}
+			mp.mutex.Unlock()
+			return
+		case <-rotateTicker.C:
+			mp.mutex.Lock()
+			mp.rotateJournalUnsafe()
+			mp.mutex.Unlock()
+		}
+	}
+}
+
+// replayJournal re-inserts the journaled txs into the mempool. The txs that have been
+// included in finalized blocks, or fail the screening, are dropped.
+func (mp *Mempool) replayJournal() {
+	rawTxs, err := mp.journal.load()
+	if err != nil {
+		logger.Warnf("Failed to load the mempool journal: %v", err)
+	}
+
+	mp.mutex.Lock()
+	defer mp.mutex.Unlock()
+
+	numReplayed, numDropped := 0, 0
+	replayedTxs := []common.Bytes{}
+	for _, rawTx := range rawTxs {
+		if mp.isFinalizedTx(rawTx) {
+			numDropped++
+			continue
+		}
+		if err := mp.insertTransactionUnsafe(rawTx); err != nil {
+			logger.Debugf("Dropped journaled tx, tx.hash: 0x%v, err: %v", getTransactionHash(rawTx), err)
+			numDropped++
+			continue
+		}
+		replayedTxs = append(replayedTxs, rawTx)
+		numReplayed++
+	}
+
+	// Only gossip the txs which have not been evicted by the txs replayed after them
+	for _, rawTx := range replayedTxs {
+		if status, ok := mp.txBookeepper.getStatus(getTransactionHash(rawTx)); ok && status != TxStatusAbandoned {
+			mp.BroadcastTxUnsafe(rawTx)
+		}
+	}
+
+	// Compact the journal to the txs that are actually in the mempool
+	mp.rotateJournalUnsafe()
+
+	logger.Infof("Replayed mempool journal, replayed: %v, dropped: %v", numReplayed, numDropped)
+}
+
+// isFinalizedTx returns whether the tx has been included in a finalized block.
+func (mp *Mempool) isFinalizedTx(rawTx common.Bytes) bool {
+	_, block, found := mp.consensus.Chain().FindTxByHash(crypto.Keccak256Hash(rawTx))
+	return found && block.Status.IsFinalized()
+}
+
+// rotateJournalUnsafe rewrites the journal with the txs currently in the mempool, and
+// the reaped txs that have not been finalized yet.
+func (mp *Mempool) rotateJournalUnsafe() {
+	rawTxs := []common.Bytes{}
+	for txHash, rawTx := range mp.reapedTxs {
+		if _, exists := mp.txBookeepper.getStatus(txHash); !exists || mp.isFinalizedTx(rawTx) {
+			delete(mp.reapedTxs, txHash)
+			continue
+		}
+		rawTxs = append(rawTxs, rawTx)
+	}
+	for _, txgElem := range *mp.candidateTxs.ElementList() {
+		rawTxs = append(rawTxs, txgElem.(*mempoolTransactionGroup).SortedRawTxs()...)
+	}
+	for _, address := range mp.txQueue.addresses() {
+		for _, queuedTx := range mp.txQueue.sortedTxs(address) {
+			rawTxs = append(rawTxs, queuedTx.rawTransaction)
+		}
+	}
+
+	if err := mp.journal.rotate(rawTxs); err != nil {
+		logger.Warnf("Failed to rotate the mempool journal: %v", err)
+		return
+	}
+	logger.Debugf("Rotated mempool journal, num txs: %v", len(rawTxs))
+}
+
 // Stop needs to be called when the Mempool stops
 func (mp *Mempool) Stop() {
 	mp.cancel()
@@ -289,12 +635,14 @@ func (mp *Mempool) ReapUnsafe(maxNumTxs int) []common.Bytes {
 	}
 
 	txs := make([]common.Bytes, 0, maxNumTxs)
+	numPopped := 0
 	for i := 0; i < maxNumTxs; i++ {
 		if mp.candidateTxs.IsEmpty() {
 			break
 		}
 		txGroup := mp.candidateTxs.Pop().(*mempoolTransactionGroup)
 		rawTx, txInfo := txGroup.PopTx()
+		numPopped++
 
 		// Check for outdated txs
 		txHash := getTransactionHash(rawTx)
@@ -302,6 +650,11 @@ func (mp *Mempool) ReapUnsafe(maxNumTxs int) []common.Bytes {
 		if exists {
 			// Only add back Txs that has not been removed from bookkeeper due to timeout
 			txs = append(txs, rawTx)
+			if mp.reapedTxs != nil {
+				mp.reapedTxs[txHash] = rawTx // keep the tx journaled until it is finalized
+			}
+		} else {
+			mp.stats.NumExpired++
 		}
 
 		if txGroup.IsEmpty() {
@@ -314,7 +667,7 @@ func (mp *Mempool) ReapUnsafe(maxNumTxs int) []common.Bytes {
 			hex.EncodeToString(rawTx), txInfo)
 	}
 
-	mp.size -= len(txs)
+	mp.size -= numPopped
 
 	return txs
 }
@@ -337,40 +690,66 @@ func (mp *Mempool) UpdateUnsafe(committedRawTxs []common.Bytes) {
 
 	// Remove Txs that have become obsolete.
 	start = time.Now()
-	count := 0
-	invalidTxs := []common.Bytes{}
-	txGroups := mp.candidateTxs.ElementList()
-	for _, txGroupEl := range *txGroups {
-		txGroup := txGroupEl.(*mempoolTransactionGroup)
-		txs := txGroup.txs.ElementList()
-		for _, txEl := range *txs {
-			count++
+	count, invalidTxs := mp.rescreenCandidateTxsUnsafe()
+	screenTxTime := time.Since(start)
 
-			mempoolTx := txEl.(*mempoolTransaction)
+	start = time.Now()
+	mp.removeTxs(invalidTxs)
+	removeInvalidTxTime := time.Since(start)
 
+	mp.promoteAllQueuedTxsUnsafe()
+
+	logger.Debugf("UpdateUnsafe: %d tx screened in %v, removeCommittedTxTime = %v, removed %d obsolete Txs in %v: %v,", count, screenTxTime, removeCommittedTxTime, len(invalidTxs), removeInvalidTxTime, invalidTxs)
+}
+
+// rescreenCandidateTxsUnsafe screens the candidate txs again after the screened view has been reset to the
+// delivered view, and returns the number of txs screened and the txs which have expired or are no longer
+// valid. The txs of each account are screened in ascending sequence order, and the accounts are revisited
+// until no more tx passes, so that a tx spending the funds credited by a tx of another account is kept
+// regardless of the order of the accounts. The caller needs to remove the returned txs.
+func (mp *Mempool) rescreenCandidateTxsUnsafe() (int, []common.Bytes) {
+	invalidTxs := []common.Bytes{}
+	pendingTxs := [][]*mempoolTransaction{}
+	for _, txGroupEl := range *mp.candidateTxs.ElementList() {
+		txGroup := txGroupEl.(*mempoolTransactionGroup)
+		txs := []*mempoolTransaction{}
+		for _, mempoolTx := range txGroup.SortedTxs() {
 			// Check for outdated txs
 			txHash := getTransactionHash(mempoolTx.rawTransaction)
-			_, exists := mp.txBookeepper.getStatus(txHash)
-			if !exists {
+			if _, exists := mp.txBookeepper.getStatus(txHash); !exists {
 				// Tx has been removed from bookkeeper due to timeout
 				invalidTxs = append(invalidTxs, mempoolTx.rawTransaction)
+				mp.stats.NumExpired++
 				continue
 			}
+			txs = append(txs, mempoolTx)
+		}
+		pendingTxs = append(pendingTxs, txs)
+	}
 
-			checkTxRes := mp.ledger.ScreenTxUnsafe(mempoolTx.rawTransaction)
-			if !checkTxRes.IsOK() {
-				invalidTxs = append(invalidTxs, mempoolTx.rawTransaction)
-				mp.txBookeepper.markAbandoned(mempoolTx.rawTransaction)
+	count := 0
+	for progress := true; progress; {
+		progress = false
+		for i := range pendingTxs {
+			for len(pendingTxs[i]) > 0 {
+				count++
+				checkTxRes := mp.ledger.ScreenTxUnsafe(pendingTxs[i][0].rawTransaction)
+				if !checkTxRes.IsOK() {
+					break // retry in the next round, the tx might depend on the txs of the other accounts
+				}
+				pendingTxs[i] = pendingTxs[i][1:]
+				progress = true
 			}
 		}
 	}
-	screenTxTime := time.Since(start)
-
-	start = time.Now()
-	mp.removeTxs(invalidTxs)
-	removeInvalidTxTime := time.Since(start)
 
-	logger.Debugf("UpdateUnsafe: %d tx screened in %v, removeCommittedTxTime = %v, removed %d obsolete Txs in %v: %v,", count, screenTxTime, removeCommittedTxTime, len(invalidTxs), removeInvalidTxTime, invalidTxs)
+	for _, txs := range pendingTxs {
+		for _, mempoolTx := range txs {
+			invalidTxs = append(invalidTxs, mempoolTx.rawTransaction)
+			mp.txBookeepper.markAbandoned(mempoolTx.rawTransaction)
+		}
+	}
+	return count, invalidTxs
 }
 
 func (mp *Mempool) removeTxs(committedRawTxs []common.Bytes) {
@@ -398,6 +777,175 @@ func (mp *Mempool) removeTxs(committedRawTxs []common.Bytes) {
 	}
 }
 
+// replaceTxUnsafe replaces the pending tx with the same sender and sequence if the
+// new tx pays a gas price higher by at least the configured percentage. It returns
+// false without an error if there is no pending tx to replace.
+func (mp *Mempool) replaceTxUnsafe(rawTx common.Bytes) (bool, error) {
+	txInfo, res := mp.ledger.ScreenReplacementTx(rawTx)
+	if !res.IsOK() {
+		return false, nil
+	}
+
+	txGroup, ok := mp.addressToTxGroup[txInfo.Address]
+	if !ok {
+		return false, nil
+	}
+	pendingTx := txGroup.FindTx(txInfo.Sequence)
+	if pendingTx == nil {
+		return false, nil
+	}
+
+	oldPrice := pendingTx.txInfo.EffectiveGasPrice
+	newPrice := txInfo.EffectiveGasPrice
+	if mp.isReplacementUnderpriced(oldPrice, newPrice) {
+		mp.stats.NumRejectedUnderpriced++
+		logger.Debugf("Replacement tx underpriced, tx.hash: 0x%v, gas price: %v, pending gas price: %v, required bump: %v%%",
+			getTransactionHash(rawTx), newPrice, oldPrice, mp.priceBump)
+		return false, ReplacementUnderpricedError
+	}
+
+	mp.txBookeepper.markAbandoned(pendingTx.rawTransaction)
+	mp.txBookeepper.record(rawTx)
+
+	mp.candidateTxs.Remove(txGroup.index) // Need to re-insert txGroup into queue since its priority could change.
+	txGroup.ReplaceTx(pendingTx, rawTx, txInfo)
+	mp.candidateTxs.Push(txGroup)
+	mp.stats.NumReplaced++
+
+	logger.Infof("Replace tx, tx.hash: 0x%v, replaced tx.hash: 0x%v, sequence: %v",
+		getTransactionHash(rawTx), getTransactionHash(pendingTx.rawTransaction), txInfo.Sequence)
+
+	return true, nil
+}
+
+// isReplacementUnderpriced returns whether the new gas price fails to exceed the old one by the configured
+// percentage, i.e. unless newPrice * 100 >= oldPrice * (100 + priceBump), and newPrice > oldPrice.
+func (mp *Mempool) isReplacementUnderpriced(oldPrice, newPrice *big.Int) bool {
+	minPrice := new(big.Int).Mul(oldPrice, big.NewInt(100+mp.priceBump))
+	return newPrice.Cmp(oldPrice) <= 0 || new(big.Int).Mul(newPrice, big.NewInt(100)).Cmp(minPrice) < 0
+}
+
+// isAccountQuotaReachedUnsafe returns whether the address already has the maximum number
+// of candidate transactions allowed per account.
+func (mp *Mempool) isAccountQuotaReachedUnsafe(address common.Address) bool {
+	if mp.maxTxsPerAccount <= 0 {
+		return false
+	}
+	txGroup, ok := mp.addressToTxGroup[address]
+	return ok && txGroup.Size() >= mp.maxTxsPerAccount
+}
+
+// reserveCapacityUnsafe checks the global and per-account limits before a screened tx
+// is inserted. When the mempool is full, it evicts the transaction group with the
+// lowest EffectiveGasPrice, provided that the incoming tx pays a higher price.
+func (mp *Mempool) reserveCapacityUnsafe(rawTx common.Bytes, txInfo *score.TxInfo) error {
+	if mp.isAccountQuotaReachedUnsafe(txInfo.Address) {
+		mp.stats.NumRejectedAccountQuota++
+		return AccountQuotaExceededError
+	}
+
+	if mp.size < mp.maxTxCount {
+		return nil
+	}
+
+	// Drop the expired txs first, they might free up enough space
+	mp.removeExpiredTxsUnsafe()
+
+	evicted := false
+	for mp.size >= mp.maxTxCount {
+		var lowest *mempoolTransactionGroup
+		for _, elem := range *mp.candidateTxs.ElementList() {
+			txGroup := elem.(*mempoolTransactionGroup)
+			if txGroup.address == txInfo.Address {
+				continue // never evict the predecessors of the incoming tx
+			}
+			if lowest == nil || txGroup.Priority().Cmp(lowest.Priority()) < 0 {
+				lowest = txGroup
+			}
+		}
+		if lowest == nil {
+			mp.stats.NumRejectedFull++
+			return MempoolFullError
+		}
+		if txInfo.EffectiveGasPrice == nil || txInfo.EffectiveGasPrice.Cmp(lowest.Priority()) <= 0 {
+			mp.stats.NumRejectedUnderpriced++
+			return UnderpricedTxError
+		}
+		mp.evictTxGroupUnsafe(lowest)
+		evicted = true
+	}
+
+	if evicted {
+		// The screened view has applied the evicted txs, including their credits to other accounts and
+		// their contract storage writes. Rebuild it from the delivered view with the remaining txs, which
+		// drops the txs depending on the evicted ones, e.g. spending the funds they credited.
+		mp.ledger.ResetScreenedState()
+		_, invalidTxs := mp.rescreenCandidateTxsUnsafe()
+		mp.removeTxs(invalidTxs)
+
+		checkTxRes := mp.ledger.ScreenTxUnsafe(rawTx)
+		if !checkTxRes.IsOK() {
+			return errors.New(checkTxRes.Message)
+		}
+	}
+
+	return nil
+}
+
+// evictTxGroupUnsafe removes all the txs of the group from the mempool. The caller needs to
+// rebuild the screened view, which has applied the evicted txs.
+func (mp *Mempool) evictTxGroupUnsafe(txGroup *mempoolTransactionGroup) {
+	mp.candidateTxs.Remove(txGroup.GetIndex())
+	delete(mp.addressToTxGroup, txGroup.address)
+
+	numEvicted := 0
+	for !txGroup.IsEmpty() {
+		rawTx, _ := txGroup.PopTx()
+		mp.txBookeepper.markAbandoned(rawTx)
+		numEvicted++
+	}
+	mp.size -= numEvicted
+	mp.stats.NumEvicted += uint64(numEvicted)
+
+	logger.Debugf("Evicted %v txs of %v from the full mempool", numEvicted, txGroup.address.Hex())
+}
+
+// removeExpiredTxsUnsafe removes the txs that have stayed in the mempool for
+// longer than the tx lifetime.
+func (mp *Mempool) removeExpiredTxsUnsafe() {
+	expiredTxs := []common.Bytes{}
+	for _, txGroupEl := range *mp.candidateTxs.ElementList() {
+		txGroup := txGroupEl.(*mempoolTransactionGroup)
+		for _, txEl := range *txGroup.txs.ElementList() {
+			mempoolTx := txEl.(*mempoolTransaction)
+			txHash := getTransactionHash(mempoolTx.rawTransaction)
+			if _, exists := mp.txBookeepper.getStatus(txHash); !exists {
+				expiredTxs = append(expiredTxs, mempoolTx.rawTransaction)
+			}
+		}
+	}
+	if len(expiredTxs) == 0 {
+		return
+	}
+	mp.removeTxs(expiredTxs)
+	mp.stats.NumExpired += uint64(len(expiredTxs))
+}
+
+// GetStats returns the mempool size, limits and the counters of the rejected,
+// evicted and expired txs.
+func (mp *Mempool) GetStats() MempoolStats {
+	mp.mutex.Lock()
+	defer mp.mutex.Unlock()
+
+	stats := mp.stats
+	stats.Size = mp.size
+	stats.NumQueued = mp.txQueue.size()
+	stats.NumAccounts = len(mp.addressToTxGroup)
+	stats.MaxTxCount = mp.maxTxCount
+	stats.MaxTxsPerAccount = mp.maxTxsPerAccount
+	return stats
+}
+
 func (mp *Mempool) GetTransactionStatus(hash string) (TxStatus, bool) {
 	return mp.txBookeepper.getStatus(hash)
 }
@@ -423,12 +971,70 @@ func (mp *Mempool) GetCandidateTransactionHashes() []string {
 	return txHashes
 }
 
+// GetPendingSequence returns the highest sequence among the candidate transactions of
+// the address. The second return value is false if the address has no candidate transaction.
+func (mp *Mempool) GetPendingSequence(address common.Address) (uint64, bool) {
+	mp.mutex.Lock()
+	defer mp.mutex.Unlock()
+
+	txGroup, ok := mp.addressToTxGroup[address]
+	if !ok || txGroup.IsEmpty() {
+		return 0, false
+	}
+	maxSequence := uint64(0)
+	for _, elem := range *txGroup.txs.ElementList() {
+		if seq := elem.(*mempoolTransaction).txInfo.Sequence; seq > maxSequence {
+			maxSequence = seq
+		}
+	}
+	return maxSequence, true
+}
+
+// GetPendingTxUnsafe returns the pending tx of the address with the given sequence, or nil if there is none.
+// The caller must hold the mempool lock.
+func (mp *Mempool) GetPendingTxUnsafe(address common.Address, sequence uint64) common.Bytes {
+	txGroup, ok := mp.addressToTxGroup[address]
+	if !ok {
+		return nil
+	}
+	pendingTx := txGroup.FindTx(sequence)
+	if pendingTx == nil {
+		return nil
+	}
+	return pendingTx.rawTransaction
+}
+
+// GetCandidateGasPrices returns the effective gas prices of the candidate transactions.
+func (mp *Mempool) GetCandidateGasPrices() []*big.Int {
+	mp.mutex.Lock()
+	defer mp.mutex.Unlock()
+
+	gasPrices := []*big.Int{}
+	for _, txgElem := range *mp.candidateTxs.ElementList() {
+		txg := txgElem.(*mempoolTransactionGroup)
+		for _, txElem := range *txg.txs.ElementList() {
+			gasPrices = append(gasPrices, txElem.(*mempoolTransaction).txInfo.EffectiveGasPrice)
+		}
+	}
+	return gasPrices
+}
+
+// GetQueuedTransactionHashes returns the hashes of the transactions waiting for their
+// sequence gap to close
+func (mp *Mempool) GetQueuedTransactionHashes() []string {
+	mp.mutex.Lock()
+	defer mp.mutex.Unlock()
+
+	return mp.txQueue.txHashes()
+}
+
 // Flush removes all transactions from the Mempool and the transactionBookkeeper
 func (mp *Mempool) Flush() {
 	mp.mutex.Lock()
 	defer mp.mutex.Unlock()
 
 	mp.txBookeepper.reset()
+	mp.txQueue.reset()
 
 	for !mp.candidateTxs.IsEmpty() {
 		mp.candidateTxs.Pop()
@@ -10,6 +10,7 @@ import (
 	dp "github.com/thetatoken/theta/dispatcher"
 	"github.com/thetatoken/theta/p2p/types"
 	"github.com/thetatoken/theta/rlp"
+	srep "github.com/thetatoken/thetasubchain/reputation"
 )
 
 //
@@ -17,7 +18,8 @@ import (
 // ChannelIDTransaction channel
 //
 type MempoolMessageHandler struct {
-	mempool *Mempool
+	mempool    *Mempool
+	reputation *srep.Manager
 }
 
 // CreateMempoolMessageHandler create an instance of the MempoolMessageHandler
@@ -27,6 +29,11 @@ func CreateMempoolMessageHandler(mempool *Mempool) *MempoolMessageHandler {
 	}
 }
 
+// SetReputationManager sets the manager used to penalize peers gossiping invalid transactions
+func (mmh *MempoolMessageHandler) SetReputationManager(reputation *srep.Manager) {
+	mmh.reputation = reputation
+}
+
 // GetChannelIDs implements the p2p.MessageHandler interface
 func (mmh *MempoolMessageHandler) GetChannelIDs() []common.ChannelIDEnum {
 	return []common.ChannelIDEnum{
@@ -50,7 +57,10 @@ func Fuzz(data []byte) int {
 // ParseMessage implements the p2p.MessageHandler interface
 func (mmh *MempoolMessageHandler) ParseMessage(peerID string, channelID common.ChannelIDEnum, rawMessageBytes common.Bytes) (types.Message, error) {
 	var dataResponse dp.DataResponse
-	rlp.DecodeBytes(rawMessageBytes, &dataResponse)
+	if err := rlp.DecodeBytes(rawMessageBytes, &dataResponse); err != nil {
+		mmh.reputation.Report(peerID, srep.OffenceUndecodableTx)
+		return types.Message{}, err
+	}
 
 	rawTx := dataResponse.Payload
 	message := types.Message{
@@ -66,6 +76,9 @@ func (mmh *MempoolMessageHandler) HandleMessage(message types.Message) error {
 	if message.ChannelID != common.ChannelIDTransaction {
 		return fmt.Errorf("Invalid channel for MempoolMessageHandler: %v", message.ChannelID)
 	}
+	if mmh.reputation.IsBanned(message.PeerID) {
+		return nil
+	}
 	rawTx := message.Content.(common.Bytes)
 	logger.Debugf("Received gossiped transaction: %v", hex.EncodeToString(rawTx))
 
@@ -74,6 +87,11 @@ func (mmh *MempoolMessageHandler) HandleMessage(message types.Message) error {
 		return nil
 	}
 	if err != nil {
+		if _, ok := err.(InvalidTxError); ok {
+			// Only the txs which can never be valid are the fault of the peer, the txs failing the
+			// sequence or balance screening, or rejected for the mempool capacity, are not
+			mmh.reputation.Report(message.PeerID, srep.OffenceInvalidTx)
+		}
 		return err
 	}
 
@@ -0,0 +1,188 @@
+package mempool
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/result"
+
+	scom "github.com/thetatoken/thetasubchain/common"
+	score "github.com/thetatoken/thetasubchain/core"
+)
+
+var (
+	alice = common.HexToAddress("0x0000000000000000000000000000000000000a11")
+	bob   = common.HexToAddress("0x0000000000000000000000000000000000000b0b")
+	carol = common.HexToAddress("0x0000000000000000000000000000000000000ca0")
+	dave  = common.HexToAddress("0x0000000000000000000000000000000000000da7")
+)
+
+type testAccount struct {
+	balance  int64
+	sequence uint64
+}
+
+// testLedger screens the transfers encoded as "from/to/amount/sequence/gasPrice" against the
+// balances and the sequences of the accounts, the same way the ledger does with its screened view.
+type testLedger struct {
+	score.Ledger
+
+	delivered map[common.Address]testAccount
+	screened  map[common.Address]testAccount
+}
+
+func newTestLedger(balances map[common.Address]int64) *testLedger {
+	l := &testLedger{delivered: make(map[common.Address]testAccount)}
+	for address, balance := range balances {
+		l.delivered[address] = testAccount{balance: balance}
+	}
+	l.ResetScreenedState()
+	return l
+}
+
+func newTestTransfer(from, to common.Address, amount int64, sequence uint64, gasPrice int64) common.Bytes {
+	return common.Bytes(fmt.Sprintf("%v/%v/%v/%v/%v", from.Hex(), to.Hex(), amount, sequence, gasPrice))
+}
+
+func parseTestTransfer(rawTx common.Bytes) (from, to common.Address, amount int64, txInfo *score.TxInfo) {
+	fields := strings.Split(string(rawTx), "/")
+	amount, _ = strconv.ParseInt(fields[2], 10, 64)
+	sequence, _ := strconv.ParseUint(fields[3], 10, 64)
+	gasPrice, _ := strconv.ParseInt(fields[4], 10, 64)
+	from = common.HexToAddress(fields[0])
+	txInfo = &score.TxInfo{
+		Address:           from,
+		Sequence:          sequence,
+		EffectiveGasPrice: big.NewInt(gasPrice),
+	}
+	return from, common.HexToAddress(fields[1]), amount, txInfo
+}
+
+func (l *testLedger) ScreenTxUnsafe(rawTx common.Bytes) result.Result {
+	from, to, amount, txInfo := parseTestTransfer(rawTx)
+	sender := l.screened[from]
+	if txInfo.Sequence != sender.sequence+1 {
+		return result.Error("Invalid sequence").WithErrorCode(result.CodeInvalidSequence)
+	}
+	if sender.balance < amount {
+		return result.Error("Insufficient fund")
+	}
+	sender.balance -= amount
+	sender.sequence++
+	l.screened[from] = sender
+	receiver := l.screened[to]
+	receiver.balance += amount
+	l.screened[to] = receiver
+	return result.OK
+}
+
+func (l *testLedger) ScreenTx(rawTx common.Bytes) (*score.TxInfo, result.Result) {
+	_, _, _, txInfo := parseTestTransfer(rawTx)
+	return txInfo, l.ScreenTxUnsafe(rawTx)
+}
+
+func (l *testLedger) ScreenFutureTx(rawTx common.Bytes) (*score.TxInfo, result.Result) {
+	_, _, _, txInfo := parseTestTransfer(rawTx)
+	if txInfo.Sequence <= l.screened[txInfo.Address].sequence+1 {
+		return nil, result.Error("Sequence is not ahead").WithErrorCode(result.CodeInvalidSequence)
+	}
+	return txInfo, result.OK
+}
+
+func (l *testLedger) ResetScreenedState() {
+	l.screened = make(map[common.Address]testAccount)
+	for address, account := range l.delivered {
+		l.screened[address] = account
+	}
+}
+
+func newTestMempool(ledger score.Ledger, maxTxCount int) *Mempool {
+	mp := CreateMempool(nil, nil)
+	mp.SetLedger(ledger)
+	mp.maxTxCount = maxTxCount
+	return mp
+}
+
+// insertScreenedTx inserts the tx the same way insertTransactionUnsafe() does once the node has synced.
+func insertScreenedTx(mp *Mempool, rawTx common.Bytes) error {
+	txInfo, res := mp.ledger.ScreenTx(rawTx)
+	if !res.IsOK() {
+		return errors.New(res.Message)
+	}
+	if err := mp.reserveCapacityUnsafe(rawTx, txInfo); err != nil {
+		return err
+	}
+	mp.txBookeepper.record(rawTx)
+	mp.addCandidateTxUnsafe(rawTx, txInfo)
+	return nil
+}
+
+func TestEvictionDropsTxsSpendingEvictedCredits(t *testing.T) {
+	assert := assert.New(t)
+
+	ledger := newTestLedger(map[common.Address]int64{alice: 100, carol: 100})
+	mp := newTestMempool(ledger, 2)
+
+	aliceTx := newTestTransfer(alice, bob, 50, 1, 1)
+	bobTx := newTestTransfer(bob, dave, 40, 1, 2) // spends the funds credited by aliceTx
+	carolTx := newTestTransfer(carol, dave, 10, 1, 3)
+	assert.Nil(insertScreenedTx(mp, aliceTx))
+	assert.Nil(insertScreenedTx(mp, bobTx))
+
+	// The mempool is full, carolTx evicts aliceTx, which pays the lowest gas price
+	assert.Nil(insertScreenedTx(mp, carolTx))
+	assert.Equal(1, mp.Size())
+	assert.Equal(uint64(1), mp.GetStats().NumEvicted)
+	assert.Equal([]string{"0x" + getTransactionHash(carolTx)}, mp.GetCandidateTransactionHashes())
+
+	status, _ := mp.GetTransactionStatus(getTransactionHash(aliceTx))
+	assert.Equal(TxStatusAbandoned, status)
+	status, _ = mp.GetTransactionStatus(getTransactionHash(bobTx))
+	assert.Equal(TxStatusAbandoned, status)
+
+	// The screened view no longer has the evicted credit, and the evicted tx of alice can be screened again
+	assert.Equal(int64(0), ledger.screened[bob].balance)
+	assert.Equal(int64(90), ledger.screened[carol].balance)
+	assert.Equal(int64(10), ledger.screened[dave].balance)
+	assert.True(ledger.ScreenTxUnsafe(newTestTransfer(alice, bob, 50, 1, 4)).IsOK())
+}
+
+func TestRescreenKeepsTxsSpendingCreditsOfOtherAccounts(t *testing.T) {
+	assert := assert.New(t)
+
+	ledger := newTestLedger(map[common.Address]int64{alice: 100})
+	mp := newTestMempool(ledger, 10)
+
+	aliceTx := newTestTransfer(alice, bob, 50, 1, 1)
+	bobTx := newTestTransfer(bob, dave, 40, 1, 2) // rescreened first, its group pays a higher gas price
+	assert.Nil(insertScreenedTx(mp, aliceTx))
+	assert.Nil(insertScreenedTx(mp, bobTx))
+
+	ledger.ResetScreenedState()
+	_, invalidTxs := mp.rescreenCandidateTxsUnsafe()
+	assert.Empty(invalidTxs)
+	assert.Equal(int64(10), ledger.screened[bob].balance)
+	assert.Equal(int64(40), ledger.screened[dave].balance)
+}
+
+func TestQueueTxWithDefaultMaxQueuedTxCount(t *testing.T) {
+	assert := assert.New(t)
+
+	viper.Set(scom.CfgMempoolMaxQueuedTxCount, 0)
+	defer viper.Set(scom.CfgMempoolMaxQueuedTxCount, 4096)
+
+	ledger := newTestLedger(map[common.Address]int64{alice: 100})
+	mp := newTestMempool(ledger, 10)
+
+	queued, err := mp.queueTxUnsafe(newTestTransfer(alice, bob, 50, 2, 1))
+	assert.Nil(err)
+	assert.True(queued)
+	assert.Equal(1, mp.GetStats().NumQueued)
+}
@@ -12,7 +12,7 @@ import (
 
 const defaultMaxNumTxs = uint(200000)
 
-const maxTxLife = 1 * time.Minute
+const defaultMaxTxLife = 1 * time.Minute
 
 //
 // transactionBookkeeper keeps tracks of recently seen transactions
@@ -24,6 +24,7 @@ type transactionBookkeeper struct {
 	txList list.List            // FIFO list of transaction hashes
 
 	maxNumTxs uint
+	maxTxLife time.Duration
 }
 
 type TxRecord struct {
@@ -32,7 +33,7 @@ type TxRecord struct {
 	CreatedAt time.Time
 }
 
-func (r *TxRecord) IsOutdated() bool {
+func (r *TxRecord) IsOutdated(maxTxLife time.Duration) bool {
 	return time.Since(r.CreatedAt) > maxTxLife
 }
 
@@ -43,11 +44,15 @@ const (
 	TxStatusAbandoned
 )
 
-func createTransactionBookkeeper(maxNumTxs uint) transactionBookkeeper {
+func createTransactionBookkeeper(maxNumTxs uint, maxTxLife time.Duration) transactionBookkeeper {
+	if maxTxLife <= 0 {
+		maxTxLife = defaultMaxTxLife
+	}
 	return transactionBookkeeper{
 		mutex:     &sync.Mutex{},
 		txMap:     make(map[string]*TxRecord),
 		maxNumTxs: maxNumTxs,
+		maxTxLife: maxTxLife,
 	}
 }
 
@@ -94,7 +99,7 @@ func (tb *transactionBookkeeper) removeOutdatedTxsUnsafe() {
 			return
 		}
 		txRecord := el.Value.(*TxRecord)
-		if !txRecord.IsOutdated() {
+		if !txRecord.IsOutdated(tb.maxTxLife) {
 			return
 		}
 
@@ -0,0 +1,130 @@
+package mempool
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/rlp"
+)
+
+const maxJournalEntrySize = 8 * 1024 * 1024
+
+var errJournalNotOpen = errors.New("mempool journal not open")
+
+//
+// transactionJournal is an append-only log of the raw transactions accepted by the
+// mempool, so that the pending transactions survive node restarts
+//
+type transactionJournal struct {
+	path   string
+	writer io.WriteCloser
+}
+
+func createTransactionJournal(path string) *transactionJournal {
+	return &transactionJournal{
+		path: path,
+	}
+}
+
+// open makes the journal ready for inserts. The existing entries are kept for the
+// replay, and a corrupted tail is dropped so that the new entries remain readable.
+func (tj *transactionJournal) open() error {
+	rawTxs, err := tj.load()
+	if err != nil {
+		return err
+	}
+	return tj.rotate(rawTxs)
+}
+
+// load reads all the raw transactions stored in the journal. A truncated or corrupted
+// tail, e.g. caused by a crash in the middle of a write, is skipped.
+func (tj *transactionJournal) load() ([]common.Bytes, error) {
+	file, err := os.Open(tj.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	rawTxs := []common.Bytes{}
+	stream := rlp.NewStream(file, maxJournalEntrySize)
+	for {
+		var rawTx common.Bytes
+		if err = stream.Decode(&rawTx); err != nil {
+			if err != io.EOF {
+				logger.Warnf("Mempool journal corrupted after %v transactions, err: %v", len(rawTxs), err)
+			}
+			break
+		}
+		rawTxs = append(rawTxs, rawTx)
+	}
+
+	return rawTxs, nil
+}
+
+// insert appends a raw transaction to the journal.
+func (tj *transactionJournal) insert(rawTx common.Bytes) error {
+	if tj.writer == nil {
+		return errJournalNotOpen
+	}
+	return rlp.Encode(tj.writer, rawTx)
+}
+
+// rotate replaces the journal with the given raw transactions, which drops the
+// entries of the transactions no longer in the mempool.
+func (tj *transactionJournal) rotate(rawTxs []common.Bytes) error {
+	if tj.writer != nil {
+		if err := tj.writer.Close(); err != nil {
+			return err
+		}
+		tj.writer = nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(tj.path), 0700); err != nil {
+		return err
+	}
+
+	tmpPath := tj.path + ".new"
+	replacement, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	for _, rawTx := range rawTxs {
+		if err = rlp.Encode(replacement, rawTx); err != nil {
+			replacement.Close()
+			return err
+		}
+	}
+	if err = replacement.Sync(); err != nil {
+		replacement.Close()
+		return err
+	}
+	replacement.Close()
+
+	if err = os.Rename(tmpPath, tj.path); err != nil {
+		return err
+	}
+
+	writer, err := os.OpenFile(tj.path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	tj.writer = writer
+
+	return nil
+}
+
+// close flushes and closes the journal.
+func (tj *transactionJournal) close() error {
+	var err error
+	if tj.writer != nil {
+		err = tj.writer.Close()
+		tj.writer = nil
+	}
+	return err
+}
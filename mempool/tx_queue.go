@@ -0,0 +1,153 @@
+package mempool
+
+import (
+	"sort"
+	"time"
+
+	"github.com/thetatoken/theta/common"
+	score "github.com/thetatoken/thetasubchain/core"
+)
+
+const defaultMaxQueuedTxCount = 4096
+const defaultMaxQueuedTxLife = 10 * time.Minute
+
+//
+// queuedTransaction is a transaction whose sequence is ahead of the next expected sequence
+// of its sender. It waits in the queue until the preceding transactions arrive.
+//
+type queuedTransaction struct {
+	rawTransaction common.Bytes
+	txInfo         *score.TxInfo
+	hash           string
+	queuedAt       time.Time
+}
+
+func (qtx *queuedTransaction) isOutdated(maxTxLife time.Duration) bool {
+	return time.Since(qtx.queuedAt) > maxTxLife
+}
+
+//
+// transactionQueue holds the future-sequence transactions of each account
+//
+type transactionQueue struct {
+	txs    map[common.Address]map[uint64]*queuedTransaction // map: address -> sequence -> tx
+	hashes map[string]*queuedTransaction                    // map: transaction hash -> tx
+
+	maxNumTxs           int
+	maxNumTxsPerAccount int
+	maxTxLife           time.Duration
+}
+
+func createTransactionQueue(maxNumTxs, maxNumTxsPerAccount int, maxTxLife time.Duration) transactionQueue {
+	if maxNumTxs <= 0 {
+		maxNumTxs = defaultMaxQueuedTxCount
+	}
+	if maxTxLife <= 0 {
+		maxTxLife = defaultMaxQueuedTxLife
+	}
+	return transactionQueue{
+		txs:                 make(map[common.Address]map[uint64]*queuedTransaction),
+		hashes:              make(map[string]*queuedTransaction),
+		maxNumTxs:           maxNumTxs,
+		maxNumTxsPerAccount: maxNumTxsPerAccount,
+		maxTxLife:           maxTxLife,
+	}
+}
+
+func (tq *transactionQueue) reset() {
+	tq.txs = make(map[common.Address]map[uint64]*queuedTransaction)
+	tq.hashes = make(map[string]*queuedTransaction)
+}
+
+func (tq *transactionQueue) size() int {
+	return len(tq.hashes)
+}
+
+func (tq *transactionQueue) accountSize(address common.Address) int {
+	return len(tq.txs[address])
+}
+
+func (tq *transactionQueue) has(txhash string) bool {
+	_, exists := tq.hashes[txhash]
+	return exists
+}
+
+func (tq *transactionQueue) get(address common.Address, sequence uint64) *queuedTransaction {
+	accountTxs, ok := tq.txs[address]
+	if !ok {
+		return nil
+	}
+	return accountTxs[sequence]
+}
+
+func (tq *transactionQueue) add(rawTx common.Bytes, txInfo *score.TxInfo) {
+	qtx := &queuedTransaction{
+		rawTransaction: rawTx,
+		txInfo:         txInfo,
+		hash:           getTransactionHash(rawTx),
+		queuedAt:       time.Now(),
+	}
+	accountTxs, ok := tq.txs[txInfo.Address]
+	if !ok {
+		accountTxs = make(map[uint64]*queuedTransaction)
+		tq.txs[txInfo.Address] = accountTxs
+	}
+	accountTxs[txInfo.Sequence] = qtx
+	tq.hashes[qtx.hash] = qtx
+}
+
+func (tq *transactionQueue) remove(qtx *queuedTransaction) {
+	delete(tq.hashes, qtx.hash)
+	accountTxs, ok := tq.txs[qtx.txInfo.Address]
+	if !ok {
+		return
+	}
+	delete(accountTxs, qtx.txInfo.Sequence)
+	if len(accountTxs) == 0 {
+		delete(tq.txs, qtx.txInfo.Address)
+	}
+}
+
+// addresses returns the addresses that have queued transactions.
+func (tq *transactionQueue) addresses() []common.Address {
+	addresses := make([]common.Address, 0, len(tq.txs))
+	for address := range tq.txs {
+		addresses = append(addresses, address)
+	}
+	return addresses
+}
+
+// sortedTxs returns the queued transactions of the address in ascending sequence order.
+func (tq *transactionQueue) sortedTxs(address common.Address) []*queuedTransaction {
+	accountTxs := tq.txs[address]
+	qtxs := make([]*queuedTransaction, 0, len(accountTxs))
+	for _, qtx := range accountTxs {
+		qtxs = append(qtxs, qtx)
+	}
+	sort.Slice(qtxs, func(i, j int) bool {
+		return qtxs[i].txInfo.Sequence < qtxs[j].txInfo.Sequence
+	})
+	return qtxs
+}
+
+// removeOutdated removes the transactions that have been queued for too long, and
+// returns the number of transactions removed.
+func (tq *transactionQueue) removeOutdated() int {
+	numRemoved := 0
+	for _, qtx := range tq.hashes {
+		if qtx.isOutdated(tq.maxTxLife) {
+			tq.remove(qtx)
+			numRemoved++
+		}
+	}
+	return numRemoved
+}
+
+// txHashes returns the hashes of all the queued transactions.
+func (tq *transactionQueue) txHashes() []string {
+	txHashes := make([]string, 0, len(tq.hashes))
+	for txhash := range tq.hashes {
+		txHashes = append(txHashes, "0x"+txhash)
+	}
+	return txHashes
+}
@@ -0,0 +1,145 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// MetricType is the Prometheus type of a metric.
+type MetricType string
+
+const (
+	MetricTypeGauge   MetricType = "gauge"
+	MetricTypeCounter MetricType = "counter"
+)
+
+// Label is a name/value pair which distinguishes the samples of a metric.
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Sample is a value of a metric.
+type Sample struct {
+	Labels []Label
+	Value  float64
+}
+
+// Metric is a named metric with one or more samples.
+type Metric struct {
+	Name    string
+	Help    string
+	Type    MetricType
+	Samples []Sample
+}
+
+// Gauge creates a gauge metric with a single sample.
+func Gauge(name, help string, value float64) Metric {
+	return Metric{Name: name, Help: help, Type: MetricTypeGauge, Samples: []Sample{{Value: value}}}
+}
+
+// Counter creates a counter metric with a single sample.
+func Counter(name, help string, value float64) Metric {
+	return Metric{Name: name, Help: help, Type: MetricTypeCounter, Samples: []Sample{{Value: value}}}
+}
+
+// Collector provides the current values of a group of metrics.
+type Collector interface {
+	CollectMetrics() []Metric
+}
+
+// CollectorFunc adapts a function to the Collector interface.
+type CollectorFunc func() []Metric
+
+// CollectMetrics implements the Collector interface.
+func (f CollectorFunc) CollectMetrics() []Metric {
+	return f()
+}
+
+// Registry gathers the metrics of the registered collectors.
+type Registry struct {
+	mu         *sync.Mutex
+	namespace  string
+	collectors []Collector
+}
+
+// NewRegistry creates a Registry which prefixes all metric names with the namespace.
+func NewRegistry(namespace string) *Registry {
+	return &Registry{
+		mu:        &sync.Mutex{},
+		namespace: namespace,
+	}
+}
+
+// Register adds a collector to the registry.
+func (r *Registry) Register(c Collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, c)
+}
+
+// Gather returns the metrics of all the collectors sorted by name.
+func (r *Registry) Gather() []Metric {
+	r.mu.Lock()
+	collectors := make([]Collector, len(r.collectors))
+	copy(collectors, r.collectors)
+	r.mu.Unlock()
+
+	metrics := []Metric{}
+	for _, c := range collectors {
+		metrics = append(metrics, c.CollectMetrics()...)
+	}
+	for i := range metrics {
+		if r.namespace != "" {
+			metrics[i].Name = r.namespace + "_" + metrics[i].Name
+		}
+	}
+	sort.SliceStable(metrics, func(i, j int) bool {
+		return metrics[i].Name < metrics[j].Name
+	})
+	return metrics
+}
+
+// WriteText writes the metrics in the Prometheus text exposition format.
+func (r *Registry) WriteText(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, m := range r.Gather() {
+		if m.Help != "" {
+			fmt.Fprintf(bw, "# HELP %s %s\n", m.Name, escapeHelp(m.Help))
+		}
+		fmt.Fprintf(bw, "# TYPE %s %s\n", m.Name, m.Type)
+		for _, s := range m.Samples {
+			bw.WriteString(m.Name)
+			if len(s.Labels) > 0 {
+				bw.WriteByte('{')
+				for i, l := range s.Labels {
+					if i > 0 {
+						bw.WriteByte(',')
+					}
+					fmt.Fprintf(bw, "%s=\"%s\"", l.Name, escapeLabelValue(l.Value))
+				}
+				bw.WriteByte('}')
+			}
+			bw.WriteByte(' ')
+			bw.WriteString(strconv.FormatFloat(s.Value, 'g', -1, 64))
+			bw.WriteByte('\n')
+		}
+	}
+	return bw.Flush()
+}
+
+var helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func escapeLabelValue(s string) string {
+	return labelValueEscaper.Replace(s)
+}
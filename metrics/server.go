@@ -0,0 +1,88 @@
+package metrics
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	scom "github.com/thetatoken/thetasubchain/common"
+)
+
+var logger *log.Entry = log.WithFields(log.Fields{"prefix": "metrics"})
+
+// Server exposes the metrics of a Registry at the /metrics HTTP endpoint, to be scraped by Prometheus.
+type Server struct {
+	registry *Registry
+	server   *http.Server
+
+	// Life cycle
+	wg     *sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewServer creates a new metrics Server.
+func NewServer(registry *Registry) *Server {
+	s := &Server{
+		registry: registry,
+		wg:       &sync.WaitGroup{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", s.serveMetrics)
+	s.server = &http.Server{
+		Handler: mux,
+	}
+	return s
+}
+
+// Start starts serving the metrics.
+func (s *Server) Start(ctx context.Context) {
+	c, cancel := context.WithCancel(ctx)
+	s.ctx = c
+	s.cancel = cancel
+
+	address := viper.GetString(scom.CfgMetricsAddress)
+	port := viper.GetString(scom.CfgMetricsPort)
+	l, err := net.Listen("tcp", address+":"+port)
+	if err != nil {
+		logger.WithFields(log.Fields{"error": err}).Error("Failed to create metrics listener")
+		return
+	}
+	logger.WithFields(log.Fields{"address": address, "port": port}).Info("Metrics server started")
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		if err := s.server.Serve(l); err != nil && err != http.ErrServerClosed {
+			logger.Warnf("Metrics server stopped: %v", err)
+		}
+	}()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		<-s.ctx.Done()
+		s.server.Close()
+	}()
+}
+
+// Stop notifies the server to stop without blocking.
+func (s *Server) Stop() {
+	s.cancel()
+}
+
+// Wait blocks until the server stops.
+func (s *Server) Wait() {
+	s.wg.Wait()
+}
+
+func (s *Server) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := s.registry.WriteText(w); err != nil {
+		logger.Debugf("Failed to write metrics: %v", err)
+	}
+}
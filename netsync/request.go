@@ -26,7 +26,7 @@ const Expiration = 300 * time.Second
 const MinInventoryRequestInterval = 6 * time.Second
 const MaxInventoryRequestInterval = 6 * time.Second
 
-const FastsyncRequestQuota = 8 // Max number of outstanding block requests
+const FastsyncRequestQuota = 8 // Max number of outstanding block requests by hash
 const GossipRequestQuotaPerSecond = 10
 const MaxNumPeersToSendRequests = 4
 const RefreshCounterLimit = 4
@@ -131,6 +131,7 @@ type RequestManager struct {
 	ifDownloadByHeader      bool
 
 	dumpBlockCache *lru.Cache
+	scheduler      *DownloadScheduler
 
 	endHashCache      []common.Bytes
 	blockRequestCache []common.Bytes
@@ -177,6 +178,9 @@ func NewRequestManager(syncMgr *SyncManager) *RequestManager {
 		logger = logger.WithFields(log.Fields{"id": rm.syncMgr.consensus.ID()})
 	}
 	rm.logger = logger
+	rm.scheduler = NewDownloadScheduler(logger, func(peerID string) bool {
+		return rm.dispatcher.PeerExists(peerID) && !rm.syncMgr.reputation.IsBanned(peerID)
+	})
 
 	return rm
 }
@@ -407,12 +411,15 @@ func (rm *RequestManager) downloadBlockFromHash() {
 
 //download block from header
 func (rm *RequestManager) downloadBlockFromHeader() {
+	// Release the windows that timed out so that their blocks can be re-assigned.
+	rm.scheduler.Expire()
+
 	addBack := HeaderHeap{}
 	elToRemove := []*list.Element{}
-	peerMap := make(map[string][]string)
-	var blockBuffer []string
-	var ok bool
-	for rm.pendingBlocksWithHeader.Len() > 0 && rm.fastsyncQuota > 0 {
+	candidates := []*PendingBlock{}
+	// The outstanding blocks are bounded by the windows the scheduler can keep in flight
+	quota := rm.scheduler.MaxInflightBlocks()
+	for rm.pendingBlocksWithHeader.Len() > 0 && quota > 0 {
 		pendingBlock := heap.Pop(rm.pendingBlocksWithHeader).(*PendingBlock)
 
 		// Remove expired header from queue
@@ -447,54 +454,32 @@ func (rm *RequestManager) downloadBlockFromHeader() {
 			}).Debug("Skip block with no peer")
 			continue
 		}
-		if pendingBlock.status == RequestWaitingBodyResp && !pendingBlock.HasTimedOut() {
-			rm.fastsyncQuota--
+		if pendingBlock.status == RequestWaitingBodyResp && !rm.scheduler.IsScheduled(pendingBlock.hash) {
+			// The block was requested but is no longer tracked by any window
+			pendingBlock.status = RequestToSendBodyReq
+		}
+		if pendingBlock.status == RequestWaitingBodyResp {
+			quota--
 			continue
 		}
-		if pendingBlock.status == RequestToSendBodyReq ||
-			(pendingBlock.status == RequestWaitingBodyResp && pendingBlock.HasTimedOut()) {
-
-			peersWithBlock := util.Shuffle(pendingBlock.peers)
-			var randomPeerID string
-			for i := 0; i < len(peersWithBlock); i++ {
-				if rm.dispatcher.PeerExists(peersWithBlock[i]) { // the peer may have been purged
-					randomPeerID = peersWithBlock[i]
-					break
-				}
-
-				rm.logger.WithFields(log.Fields{
-					"pendingBlock": pendingBlock.hash.String(),
-					"peer":         peersWithBlock[i],
-				}).Debug("Skipped peer that may have been purged")
-
-			}
-			if len(randomPeerID) == 0 {
-				rm.logger.WithFields(log.Fields{
-					"pendingBlock": pendingBlock.hash.String(),
-				}).Debug("All peers skipped")
-				continue
-			}
-
-			if blockBuffer, ok = peerMap[randomPeerID]; !ok {
-				blockBuffer = []string{}
-			}
-			blockBuffer := append(blockBuffer, pendingBlock.hash.String())
-			if len(blockBuffer) == MaxBlocksPerRequest {
-				rm.sendBlocksRequest(randomPeerID, blockBuffer)
-				blockBuffer = []string{}
-			}
-			peerMap[randomPeerID] = blockBuffer
-			pendingBlock.UpdateTimestamp()
-			pendingBlock.status = RequestWaitingBodyResp
-			rm.fastsyncQuota--
+		if pendingBlock.status == RequestToSendBodyReq {
+			candidates = append(candidates, pendingBlock)
+			quota--
 		}
 	}
-	// send block requests for every peer in map
-	for k, v := range peerMap {
-		if len(v) > 0 {
-			rm.sendBlocksRequest(k, v)
+
+	// Assign the blocks to peers in windows, and send block requests for every peer
+	assignments := rm.scheduler.Schedule(candidates)
+	for peerID, hashes := range assignments {
+		for start := 0; start < len(hashes); start += MaxBlocksPerRequest {
+			end := start + MaxBlocksPerRequest
+			if end > len(hashes) {
+				end = len(hashes)
+			}
+			rm.sendBlocksRequest(peerID, hashes[start:end])
 		}
 	}
+
 	for _, header := range addBack {
 		heap.Push(rm.pendingBlocksWithHeader, header)
 	}
@@ -525,6 +510,9 @@ func (rm *RequestManager) getInventory(req dispatcher.InventoryRequest) {
 		if !rm.dispatcher.PeerExists(pid) { // the peer may have been purged
 			rm.logger.Debugf("Removing disconnected peer from active list: %v", pid)
 			delete(rm.activePeers, pid)
+		} else if rm.syncMgr.reputation.IsBanned(pid) {
+			rm.logger.Debugf("Removing banned peer from active list: %v", pid)
+			delete(rm.activePeers, pid)
 		} else {
 			rm.activePeers[pid]--
 		}
@@ -557,7 +545,7 @@ func (rm *RequestManager) getInventory(req dispatcher.InventoryRequest) {
 		targetSize += 2
 	}
 	if len(peersToRequest) < targetSize { // resample
-		allPeers := rm.syncMgr.dispatcher.Peers(true) // skip edge nodes
+		allPeers := rm.syncMgr.reputation.FilterBanned(rm.syncMgr.dispatcher.Peers(true)) // skip edge nodes
 		samples := util.Sample(allPeers, targetSize)
 		for _, sample := range samples {
 			duplicate := false
@@ -608,6 +596,7 @@ func (rm *RequestManager) removeEl(el *list.Element) {
 	delete(rm.pendingBlocksByHash, hash)
 
 	rm.pendingBlocks.Remove(el)
+	rm.scheduler.RemoveBlock(pendingBlock.hash)
 }
 
 func (rm *RequestManager) AddHash(x common.Hash, peerIDs []string, fromGossip bool) {
@@ -697,11 +686,15 @@ func (rm *RequestManager) AddHeader(header *score.BlockHeader, peerIDs []string)
 	}
 }
 
-// AddBlock process an incoming block.
-func (rm *RequestManager) AddBlock(block *score.Block) {
+// AddBlock process an incoming block received from the given peer.
+func (rm *RequestManager) AddBlock(peerID string, block *score.Block) {
 	rm.mu.Lock()
 	defer rm.mu.Unlock()
 
+	// A block not requested from the peer, e.g. a duplicate delivery or a gossiped block, is not an offence.
+	// The peers are only penalized for the invalid blocks, see ReportInvalidBlock.
+	rm.scheduler.OnBlockReceived(peerID, block.Hash())
+
 	eb, err := rm.chain.AddBlock(block)
 	if err != nil {
 		log.Debugf("failed to add block, err=%v", err)
@@ -711,8 +704,7 @@ func (rm *RequestManager) AddBlock(block *score.Block) {
 	hash := block.Hash().String()
 
 	if pendingBlockEl, ok := rm.pendingBlocksByHash[hash]; ok {
-		rm.pendingBlocks.Remove(pendingBlockEl)
-		delete(rm.pendingBlocksByHash, hash)
+		rm.removeEl(pendingBlockEl)
 	}
 
 	select {
@@ -721,6 +713,14 @@ func (rm *RequestManager) AddBlock(block *score.Block) {
 	}
 }
 
+// ReportInvalidBlock excludes the peer from the downloads for a while if it was assigned the height of the
+// block failing validation, i.e. it returned a block mismatching the requested header.
+func (rm *RequestManager) ReportInvalidBlock(peerID string, block *score.Block) {
+	if rm.scheduler.IsAssigned(peerID, block.Height) {
+		rm.scheduler.PenalizePeer(peerID, "mismatched block")
+	}
+}
+
 func (rm *RequestManager) passReadyBlocks() {
 	defer rm.wg.Done()
 
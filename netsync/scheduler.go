@@ -0,0 +1,430 @@
+package netsync
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/util"
+	scom "github.com/thetatoken/thetasubchain/common"
+)
+
+const DefaultPeerThroughput = 4.0 // blocks per second assumed for peers without measurements
+const ThroughputSmoothingFactor = 0.3
+const MaxWindowTimeout = 3 * RequestTimeout
+const PeerPenaltyDuration = 60 * time.Second
+const MaxPeerPenaltyDuration = 30 * time.Minute
+
+// downloadWindow is a range of consecutive pending blocks assigned to a single peer.
+type downloadWindow struct {
+	id         uint64
+	peer       string
+	blocks     []*PendingBlock
+	remaining  map[string]bool
+	minHeight  uint64
+	maxHeight  uint64
+	assignedAt time.Time
+	deadline   time.Time
+}
+
+// peerDownloadStats keeps the measured performance of a peer.
+type peerDownloadStats struct {
+	throughput     float64 // exponential moving average, in blocks per second
+	inflight       int
+	delivered      uint64
+	timeouts       uint64
+	invalid        uint64
+	penalizedUntil time.Time
+}
+
+func (ps *peerDownloadStats) isPenalized() bool {
+	return time.Now().Before(ps.penalizedUntil)
+}
+
+// DownloadScheduler splits the pending blocks into windows and assigns them
+// to multiple peers concurrently based on their measured throughput.
+type DownloadScheduler struct {
+	logger *log.Entry
+
+	mu *sync.Mutex
+
+	windowSize         int
+	maxWindowsPerPeer  int
+	maxInflightWindows int
+
+	nextID       uint64
+	windows      map[uint64]*downloadWindow
+	windowByHash map[string]*downloadWindow
+	peers        map[string]*peerDownloadStats
+
+	peerExists func(peerID string) bool
+}
+
+func NewDownloadScheduler(logger *log.Entry, peerExists func(peerID string) bool) *DownloadScheduler {
+	windowSize := viper.GetInt(scom.CfgSyncDownloadWindowSize)
+	if windowSize <= 0 {
+		windowSize = MaxBlocksPerRequest
+	}
+	maxWindowsPerPeer := viper.GetInt(scom.CfgSyncMaxWindowsPerPeer)
+	if maxWindowsPerPeer <= 0 {
+		maxWindowsPerPeer = 1
+	}
+	maxInflightWindows := viper.GetInt(scom.CfgSyncMaxInflightWindows)
+	if maxInflightWindows <= 0 {
+		maxInflightWindows = 1
+	}
+
+	return &DownloadScheduler{
+		logger:             logger,
+		mu:                 &sync.Mutex{},
+		windowSize:         windowSize,
+		maxWindowsPerPeer:  maxWindowsPerPeer,
+		maxInflightWindows: maxInflightWindows,
+		windows:            make(map[uint64]*downloadWindow),
+		windowByHash:       make(map[string]*downloadWindow),
+		peers:              make(map[string]*peerDownloadStats),
+		peerExists:         peerExists,
+	}
+}
+
+func (ds *DownloadScheduler) getPeerStats(peerID string) *peerDownloadStats {
+	ps, ok := ds.peers[peerID]
+	if !ok {
+		ps = &peerDownloadStats{throughput: DefaultPeerThroughput}
+		ds.peers[peerID] = ps
+	}
+	return ps
+}
+
+// MaxInflightBlocks returns the max number of blocks that can be outstanding in the download windows.
+func (ds *DownloadScheduler) MaxInflightBlocks() int {
+	return ds.windowSize * ds.maxInflightWindows
+}
+
+// IsScheduled returns whether the block is part of an outstanding download window.
+func (ds *DownloadScheduler) IsScheduled(hash common.Hash) bool {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	_, ok := ds.windowByHash[hash.Hex()]
+	return ok
+}
+
+// Expire releases the windows that have timed out or whose peer has
+// disconnected. The blocks in the released windows become requestable again.
+func (ds *DownloadScheduler) Expire() {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	now := time.Now()
+	for _, w := range ds.windows {
+		if !ds.peerExists(w.peer) {
+			ds.logger.WithFields(log.Fields{
+				"peer":      w.peer,
+				"minHeight": w.minHeight,
+				"maxHeight": w.maxHeight,
+			}).Debug("Releasing download window of disconnected peer")
+			delete(ds.peers, w.peer)
+			ds.releaseWindow(w)
+			continue
+		}
+		if now.After(w.deadline) {
+			ps := ds.getPeerStats(w.peer)
+			ps.timeouts++
+			ps.throughput = ps.throughput / 2
+			ds.logger.WithFields(log.Fields{
+				"peer":       w.peer,
+				"minHeight":  w.minHeight,
+				"maxHeight":  w.maxHeight,
+				"remaining":  len(w.remaining),
+				"throughput": ps.throughput,
+			}).Debug("Download window timed out")
+			ds.releaseWindow(w)
+		}
+	}
+
+	for pid := range ds.peers {
+		if !ds.peerExists(pid) {
+			delete(ds.peers, pid)
+		}
+	}
+}
+
+// releaseWindow puts the undelivered blocks of the window back into the
+// to-request state. Must be called with ds.mu held.
+func (ds *DownloadScheduler) releaseWindow(w *downloadWindow) {
+	for _, pb := range w.blocks {
+		if w.remaining[pb.hash.Hex()] {
+			pb.status = RequestToSendBodyReq
+		}
+		delete(ds.windowByHash, pb.hash.Hex())
+	}
+	if ps, ok := ds.peers[w.peer]; ok && ps.inflight > 0 {
+		ps.inflight--
+	}
+	delete(ds.windows, w.id)
+}
+
+// Schedule groups the given blocks (which must be in the to-request state)
+// into windows and assigns each window to a peer. It returns the hashes to
+// request from each peer.
+func (ds *DownloadScheduler) Schedule(candidates []*PendingBlock) map[string][]string {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	assignments := make(map[string][]string)
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].header.Height < candidates[j].header.Height
+	})
+
+	i := 0
+	for i < len(candidates) && len(ds.windows) < ds.maxInflightWindows {
+		if _, ok := ds.windowByHash[candidates[i].hash.Hex()]; ok {
+			i++
+			continue
+		}
+
+		// Extend the window as long as the blocks share at least one available peer.
+		blocks := []*PendingBlock{candidates[i]}
+		peers := ds.availablePeers(candidates[i].peers)
+		j := i + 1
+		for ; j < len(candidates) && len(blocks) < ds.windowSize && len(peers) > 0; j++ {
+			if _, ok := ds.windowByHash[candidates[j].hash.Hex()]; ok {
+				break
+			}
+			shared := intersect(peers, candidates[j].peers)
+			if len(shared) == 0 {
+				break
+			}
+			blocks = append(blocks, candidates[j])
+			peers = shared
+		}
+		i = j
+
+		peerID := ds.selectPeer(peers)
+		if len(peerID) == 0 {
+			ds.logger.WithFields(log.Fields{
+				"block":  blocks[0].hash.Hex(),
+				"height": blocks[0].header.Height,
+			}).Debug("No peer available for download window")
+			continue
+		}
+
+		w := ds.newWindow(peerID, blocks)
+		for _, pb := range blocks {
+			pb.UpdateTimestamp()
+			pb.status = RequestWaitingBodyResp
+			assignments[peerID] = append(assignments[peerID], pb.hash.String())
+		}
+
+		ds.logger.WithFields(log.Fields{
+			"peer":      peerID,
+			"minHeight": w.minHeight,
+			"maxHeight": w.maxHeight,
+			"deadline":  w.deadline,
+		}).Debug("Assigned download window")
+	}
+
+	return assignments
+}
+
+func (ds *DownloadScheduler) newWindow(peerID string, blocks []*PendingBlock) *downloadWindow {
+	ds.nextID++
+	w := &downloadWindow{
+		id:         ds.nextID,
+		peer:       peerID,
+		blocks:     blocks,
+		remaining:  make(map[string]bool),
+		minHeight:  blocks[0].header.Height,
+		maxHeight:  blocks[len(blocks)-1].header.Height,
+		assignedAt: time.Now(),
+	}
+	for _, pb := range blocks {
+		w.remaining[pb.hash.Hex()] = true
+		ds.windowByHash[pb.hash.Hex()] = w
+	}
+
+	ps := ds.getPeerStats(peerID)
+	ps.inflight++
+
+	// Allow the peer the expected transfer time on top of the round trip.
+	timeout := RequestTimeout + time.Duration(float64(len(blocks))/ps.throughput*float64(time.Second))
+	if timeout > MaxWindowTimeout {
+		timeout = MaxWindowTimeout
+	}
+	w.deadline = w.assignedAt.Add(timeout)
+
+	ds.windows[w.id] = w
+	return w
+}
+
+// availablePeers filters out the peers that are disconnected, penalized or
+// already at their window limit. Must be called with ds.mu held.
+func (ds *DownloadScheduler) availablePeers(peerIDs []string) []string {
+	ret := []string{}
+	for _, pid := range peerIDs {
+		if !ds.peerExists(pid) {
+			continue
+		}
+		if ps, ok := ds.peers[pid]; ok {
+			if ps.isPenalized() || ps.inflight >= ds.maxWindowsPerPeer {
+				continue
+			}
+		}
+		ret = append(ret, pid)
+	}
+	return ret
+}
+
+// selectPeer returns the peer with the highest measured throughput. Peers
+// without measurements are assumed to have DefaultPeerThroughput so that
+// they get a chance to be measured.
+func (ds *DownloadScheduler) selectPeer(peerIDs []string) string {
+	selected := ""
+	best := -1.0
+	for _, pid := range util.Shuffle(peerIDs) {
+		ps := ds.getPeerStats(pid)
+		if ps.inflight >= ds.maxWindowsPerPeer || ps.isPenalized() {
+			continue
+		}
+		// Prefer idle peers among those with similar throughput.
+		score := ps.throughput / float64(ps.inflight+1)
+		if score > best {
+			best = score
+			selected = pid
+		}
+	}
+	return selected
+}
+
+// OnBlockReceived records the delivery of a block. Blocks which are not part of
+// any window, e.g. gossiped or duplicate blocks, are ignored.
+func (ds *DownloadScheduler) OnBlockReceived(peerID string, hash common.Hash) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	w, ok := ds.windowByHash[hash.Hex()]
+	if !ok {
+		return
+	}
+
+	delete(ds.windowByHash, hash.Hex())
+	delete(w.remaining, hash.Hex())
+	if w.peer == peerID {
+		ds.getPeerStats(peerID).delivered++
+	}
+
+	if len(w.remaining) == 0 {
+		ds.completeWindow(w)
+	}
+}
+
+// IsAssigned returns whether the block at the given height is requested from
+// the peer in one of its outstanding windows.
+func (ds *DownloadScheduler) IsAssigned(peerID string, height uint64) bool {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	for _, w := range ds.windows {
+		if w.peer != peerID {
+			continue
+		}
+		for _, pb := range w.blocks {
+			if pb.header.Height == height && w.remaining[pb.hash.Hex()] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// completeWindow updates the throughput estimate of the peer. Must be called
+// with ds.mu held.
+func (ds *DownloadScheduler) completeWindow(w *downloadWindow) {
+	elapsed := time.Since(w.assignedAt).Seconds()
+	if elapsed < 0.001 {
+		elapsed = 0.001
+	}
+	ps := ds.getPeerStats(w.peer)
+	measured := float64(len(w.blocks)) / elapsed
+	ps.throughput = (1-ThroughputSmoothingFactor)*ps.throughput + ThroughputSmoothingFactor*measured
+	if ps.inflight > 0 {
+		ps.inflight--
+	}
+	delete(ds.windows, w.id)
+
+	ds.logger.WithFields(log.Fields{
+		"peer":       w.peer,
+		"minHeight":  w.minHeight,
+		"maxHeight":  w.maxHeight,
+		"elapsed":    elapsed,
+		"throughput": ps.throughput,
+	}).Debug("Download window completed")
+}
+
+// RemoveBlock drops a block from its window, e.g. when it expired or was
+// received through gossip.
+func (ds *DownloadScheduler) RemoveBlock(hash common.Hash) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	w, ok := ds.windowByHash[hash.Hex()]
+	if !ok {
+		return
+	}
+	delete(ds.windowByHash, hash.Hex())
+	delete(w.remaining, hash.Hex())
+	if len(w.remaining) == 0 {
+		if ps, ok := ds.peers[w.peer]; ok && ps.inflight > 0 {
+			ps.inflight--
+		}
+		delete(ds.windows, w.id)
+	}
+}
+
+// PenalizePeer excludes a peer that returned blocks mismatching the requested
+// headers from downloads for a while. The penalty grows with repeated offences, and
+// the windows assigned to the peer are re-assigned.
+func (ds *DownloadScheduler) PenalizePeer(peerID string, reason string) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	ps := ds.getPeerStats(peerID)
+	ps.invalid++
+	penalty := PeerPenaltyDuration * time.Duration(ps.invalid)
+	if penalty > MaxPeerPenaltyDuration {
+		penalty = MaxPeerPenaltyDuration
+	}
+	ps.penalizedUntil = time.Now().Add(penalty)
+	ps.throughput = DefaultPeerThroughput / 2
+
+	ds.logger.WithFields(log.Fields{
+		"peer":    peerID,
+		"reason":  reason,
+		"offence": ps.invalid,
+		"penalty": penalty,
+	}).Info("Penalized peer for block download")
+
+	for _, w := range ds.windows {
+		if w.peer == peerID {
+			ds.releaseWindow(w)
+		}
+	}
+}
+
+func intersect(a []string, b []string) []string {
+	ret := []string{}
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				ret = append(ret, x)
+				break
+			}
+		}
+	}
+	return ret
+}
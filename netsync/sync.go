@@ -5,6 +5,7 @@ import (
 	"reflect"
 	"strings"
 	"sync"
+	"sync/atomic"
 
 	lru "github.com/hashicorp/golang-lru"
 	log "github.com/sirupsen/logrus"
@@ -18,6 +19,7 @@ import (
 	"github.com/thetatoken/theta/rlp"
 	sbc "github.com/thetatoken/thetasubchain/blockchain"
 	score "github.com/thetatoken/thetasubchain/core"
+	srep "github.com/thetatoken/thetasubchain/reputation"
 )
 
 const voteCacheLimit = 512
@@ -46,6 +48,7 @@ type SyncManager struct {
 	consumer   MessageConsumer
 	dispatcher *dispatcher.Dispatcher
 	requestMgr *RequestManager
+	reputation *srep.Manager
 
 	wg       *sync.WaitGroup
 	ctx      context.Context
@@ -58,6 +61,8 @@ type SyncManager struct {
 	logger *log.Entry
 
 	voteCache *lru.Cache // Cache for votes
+
+	highestKnownHeight uint64 // the highest valid block height received from the peers
 }
 
 func NewSyncManager(chain *sbc.Chain, cons score.ConsensusEngine, networkOld p2p.Network, network p2pl.Network, disp *dispatcher.Dispatcher, consumer MessageConsumer) *SyncManager {
@@ -94,6 +99,11 @@ func NewSyncManager(chain *sbc.Chain, cons score.ConsensusEngine, networkOld p2p
 	return sm
 }
 
+// SetReputationManager sets the manager used to score and ban misbehaving peers.
+func (sm *SyncManager) SetReputationManager(reputation *srep.Manager) {
+	sm.reputation = reputation
+}
+
 func (sm *SyncManager) Start(ctx context.Context) {
 	c, cancel := context.WithCancel(ctx)
 	sm.ctx = c
@@ -163,6 +173,14 @@ func (sm *SyncManager) HandleMessage(msg p2ptypes.Message) (err error) {
 }
 
 func (sm *SyncManager) processMessage(message p2ptypes.Message) {
+	if sm.reputation.IsBanned(message.PeerID) {
+		sm.logger.WithFields(log.Fields{
+			"peer":      message.PeerID,
+			"channelID": message.ChannelID,
+		}).Debug("Ignoring message from banned peer")
+		return
+	}
+
 	inboundAllowed := true
 	// If whitelist is set, only process message from peers in the whitelist.
 	if len(sm.whitelist) > 0 {
@@ -541,6 +559,7 @@ func (m *SyncManager) handleDataResponse(peerID string, data *dispatcher.DataRes
 					"error":     err,
 					"peerID":    peerID,
 				}).Warn("Failed to decode DataResponse payload")
+				m.reputation.Report(peerID, srep.OffenceUndecodableMessage)
 				return
 			}
 			for _, block = range blocks.BlockArray {
@@ -550,7 +569,7 @@ func (m *SyncManager) handleDataResponse(peerID string, data *dispatcher.DataRes
 					"block.Height": block.Height,
 					"peer":         peerID,
 				}).Debug("Received block")
-				m.handleBlock(block)
+				m.handleBlock(peerID, block)
 				if block.Height > maxReceivedHeight {
 					maxReceivedHeight = block.Height
 				}
@@ -562,7 +581,7 @@ func (m *SyncManager) handleDataResponse(peerID string, data *dispatcher.DataRes
 				"block.Height": block.Height,
 				"peer":         peerID,
 			}).Debug("Received block")
-			m.handleBlock(block)
+			m.handleBlock(peerID, block)
 			maxReceivedHeight = block.Height
 		}
 	case common.ChannelIDVote:
@@ -575,6 +594,7 @@ func (m *SyncManager) handleDataResponse(peerID string, data *dispatcher.DataRes
 				"error":     err,
 				"peerID":    peerID,
 			}).Warn("Failed to decode DataResponse payload")
+			m.reputation.Report(peerID, srep.OffenceUndecodableMessage)
 			return
 		}
 		m.logger.WithFields(log.Fields{
@@ -583,6 +603,7 @@ func (m *SyncManager) handleDataResponse(peerID string, data *dispatcher.DataRes
 			"vote.Epoch": vote.Epoch,
 			"peer":       peerID,
 		}).Debug("Received vote")
+		m.reputation.TrackOrigin(vote.Hash(), peerID)
 		m.handleVote(vote)
 	case common.ChannelIDProposal:
 		proposal := &score.Proposal{}
@@ -594,13 +615,14 @@ func (m *SyncManager) handleDataResponse(peerID string, data *dispatcher.DataRes
 				"error":     err,
 				"peerID":    peerID,
 			}).Warn("Failed to decode DataResponse payload")
+			m.reputation.Report(peerID, srep.OffenceUndecodableMessage)
 			return
 		}
 		m.logger.WithFields(log.Fields{
 			"proposal": proposal,
 			"peer":     peerID,
 		}).Debug("Received proposal")
-		m.handleProposal(proposal)
+		m.handleProposal(peerID, proposal)
 	case common.ChannelIDHeader:
 		headers := &Headers{}
 		err := rlp.DecodeBytes(data.Payload, headers)
@@ -611,6 +633,7 @@ func (m *SyncManager) handleDataResponse(peerID string, data *dispatcher.DataRes
 				"error":     err,
 				"peerID":    peerID,
 			}).Debug("Failed to decode HeaderResponse payload")
+			m.reputation.Report(peerID, srep.OffenceUndecodableMessage)
 			return
 		}
 		for _, header := range headers.HeaderArray {
@@ -629,13 +652,14 @@ func (m *SyncManager) handleDataResponse(peerID string, data *dispatcher.DataRes
 	}
 }
 
-func (sm *SyncManager) handleProposal(p *score.Proposal) {
+func (sm *SyncManager) handleProposal(peerID string, p *score.Proposal) {
 	if p.Votes != nil {
 		for _, vote := range p.Votes.Votes() {
+			sm.reputation.TrackOrigin(vote.Hash(), peerID)
 			sm.handleVote(vote)
 		}
 	}
-	sm.handleBlock(p.Block)
+	sm.handleBlock(peerID, p.Block)
 }
 
 func (sm *SyncManager) handleHeader(header *score.BlockHeader, peerID []string) {
@@ -664,7 +688,7 @@ func (sm *SyncManager) handleHeader(header *score.BlockHeader, peerID []string)
 	}
 }
 
-func (sm *SyncManager) handleBlock(block *score.Block) {
+func (sm *SyncManager) handleBlock(peerID string, block *score.Block) {
 	if eb, err := sm.chain.FindBlock(block.Hash()); err == nil && !eb.Status.IsPending() {
 		sm.logger.WithFields(log.Fields{
 			"block hash":   block.Hash().String(),
@@ -679,6 +703,7 @@ func (sm *SyncManager) handleBlock(block *score.Block) {
 				"block hash":   block.Hash().String(),
 				"block height": block.Height,
 			}).Debug("hardcoded block")
+			sm.reportInvalidBlock(peerID, block)
 			return
 		}
 	} else if res := block.Validate(sm.chain.ChainID); res.IsError() {
@@ -686,10 +711,13 @@ func (sm *SyncManager) handleBlock(block *score.Block) {
 			"block hash":   block.Hash().String(),
 			"block height": block.Height,
 		}).Debug("chain ID is invalid")
+		sm.reportInvalidBlock(peerID, block)
 		return
 	}
 
-	sm.requestMgr.AddBlock(block)
+	sm.reputation.TrackOrigin(block.Hash(), peerID)
+	sm.updateHighestKnownHeight(block.Height)
+	sm.requestMgr.AddBlock(peerID, block)
 
 	p2pOpt := common.P2POptEnum(viper.GetInt(common.CfgP2POpt))
 	if sm.requestMgr.IsGossipBlock(block.Hash()) && p2pOpt != common.P2POptLibp2p {
@@ -717,6 +745,26 @@ func (sm *SyncManager) handleBlock(block *score.Block) {
 	}
 }
 
+func (sm *SyncManager) updateHighestKnownHeight(height uint64) {
+	for {
+		current := atomic.LoadUint64(&sm.highestKnownHeight)
+		if height <= current || atomic.CompareAndSwapUint64(&sm.highestKnownHeight, current, height) {
+			return
+		}
+	}
+}
+
+// GetHighestKnownHeight returns the highest block height received from the peers, which
+// together with the local finalized height tells how far the node lags behind the network.
+func (sm *SyncManager) GetHighestKnownHeight() uint64 {
+	return atomic.LoadUint64(&sm.highestKnownHeight)
+}
+
+func (sm *SyncManager) reportInvalidBlock(peerID string, block *score.Block) {
+	sm.requestMgr.ReportInvalidBlock(peerID, block)
+	sm.reputation.Report(peerID, srep.OffenceInvalidBlock)
+}
+
 func (sm *SyncManager) handleVote(vote score.Vote) {
 	votes := sm.chain.FindVotesByHash(vote.Block).Votes()
 	for _, v := range votes {
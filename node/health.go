@@ -0,0 +1,46 @@
+package node
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/viper"
+	scom "github.com/thetatoken/thetasubchain/common"
+	"github.com/thetatoken/thetasubchain/interchain/orchestrator"
+)
+
+// connectivityChecker is implemented by the witnesses which can probe the chains they watch.
+type connectivityChecker interface {
+	CheckConnectivity(ctx context.Context) error
+}
+
+// registerHealthChecks adds the readiness checks of the inter-chain components.
+func (n *Node) registerHealthChecks() {
+	if n.RPC == nil {
+		return
+	}
+
+	if checker, ok := n.MainchainWitness.(connectivityChecker); ok {
+		timeout := time.Duration(viper.GetInt(scom.CfgHealthWitnessTimeoutSecs)) * time.Second
+		n.RPC.Health.AddReadinessCheck("witness", func() error {
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			return checker.CheckConnectivity(ctx)
+		})
+	}
+
+	if oc, ok := n.Orchestrator.(*orchestrator.Orchestrator); ok {
+		maxBacklog := uint64(viper.GetInt(scom.CfgHealthMaxOrchestratorBacklog))
+		n.RPC.Health.AddReadinessCheck("orchestrator", func() error {
+			backlog := uint64(0)
+			for _, stats := range oc.GetPipelineStats() {
+				backlog += uint64(stats.PendingEvents)
+			}
+			if backlog > maxBacklog {
+				return fmt.Errorf("%v inter-chain events pending, max allowed: %v", backlog, maxBacklog)
+			}
+			return nil
+		})
+	}
+}
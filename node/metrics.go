@@ -0,0 +1,129 @@
+package node
+
+import (
+	"strconv"
+
+	"github.com/thetatoken/thetasubchain/interchain/orchestrator"
+	smetrics "github.com/thetatoken/thetasubchain/metrics"
+	srollingdb "github.com/thetatoken/thetasubchain/store/rollingdb"
+)
+
+const metricsNamespace = "thetasubchain"
+
+// scanHeightReporter is implemented by the witnesses which report how far they have
+// scanned the chains for inter-chain message events.
+type scanHeightReporter interface {
+	GetScanHeights() map[string]uint64
+}
+
+// newMetricsRegistry creates a registry with the metrics of the node components.
+func (n *Node) newMetricsRegistry() *smetrics.Registry {
+	registry := smetrics.NewRegistry(metricsNamespace)
+	registry.Register(smetrics.CollectorFunc(n.collectConsensusMetrics))
+	registry.Register(smetrics.CollectorFunc(n.collectMempoolMetrics))
+	registry.Register(smetrics.CollectorFunc(n.collectSyncMetrics))
+	if n.RollingDB != nil {
+		registry.Register(smetrics.CollectorFunc(n.collectRollingDBMetrics))
+	}
+	registry.Register(smetrics.CollectorFunc(n.collectInterchainMetrics))
+	return registry
+}
+
+func (n *Node) collectConsensusMetrics() []smetrics.Metric {
+	cm := n.Consensus.GetMetrics()
+	return []smetrics.Metric{
+		smetrics.Gauge("consensus_epoch", "Current consensus epoch", float64(n.Consensus.GetEpoch())),
+		smetrics.Gauge("consensus_finalized_height", "Height of the last finalized block", float64(n.Consensus.GetLastFinalizedBlock().Height)),
+		smetrics.Counter("consensus_proposals_missed_total", "Number of epochs that timed out before a block was processed", float64(cm.ProposalsMissed)),
+		smetrics.Counter("consensus_votes_total", "Number of votes cast by this node", float64(cm.NumVotes)),
+		smetrics.Counter("consensus_vote_latency_seconds_sum", "Total time between entering an epoch and casting the vote", cm.VoteLatencySecsSum),
+		smetrics.Gauge("consensus_last_vote_latency_seconds", "Time between entering the epoch and casting the last vote", cm.LastVoteLatencySecs),
+	}
+}
+
+func (n *Node) collectMempoolMetrics() []smetrics.Metric {
+	stats := n.Mempool.GetStats()
+	return []smetrics.Metric{
+		smetrics.Gauge("mempool_size", "Number of transactions ready to be included in a block", float64(stats.Size)),
+		smetrics.Gauge("mempool_queued", "Number of transactions waiting for the preceding sequences", float64(stats.NumQueued)),
+		smetrics.Gauge("mempool_accounts", "Number of accounts with pending transactions", float64(stats.NumAccounts)),
+		smetrics.Counter("mempool_evicted_total", "Number of transactions evicted to make room for higher priced ones", float64(stats.NumEvicted)),
+		smetrics.Counter("mempool_expired_total", "Number of transactions expired in the mempool", float64(stats.NumExpired)),
+	}
+}
+
+func (n *Node) collectSyncMetrics() []smetrics.Metric {
+	lfbHeight := n.Consensus.GetLastFinalizedBlock().Height
+	highestKnownHeight := n.SyncManager.GetHighestKnownHeight()
+	lag := uint64(0)
+	if highestKnownHeight > lfbHeight {
+		lag = highestKnownHeight - lfbHeight
+	}
+	synced := 0.0
+	if n.Consensus.HasSynced() {
+		synced = 1.0
+	}
+	return []smetrics.Metric{
+		smetrics.Gauge("sync_highest_known_height", "Highest block height received from the peers", float64(highestKnownHeight)),
+		smetrics.Gauge("sync_lag_blocks", "Number of blocks the last finalized block lags behind the highest known block", float64(lag)),
+		smetrics.Gauge("sync_synced", "Whether the node has caught up with the network", synced),
+		smetrics.Gauge("p2p_peers", "Number of connected peers", float64(len(n.Dispatcher.Peers(true)))),
+	}
+}
+
+func (n *Node) collectRollingDBMetrics() []smetrics.Metric {
+	status := n.RollingDB.GetStatus()
+	layers := append([]srollingdb.LayerInfo{status.RootLayer, status.ActiveLayer}, status.Layers...)
+	size := int64(0)
+	for _, layer := range layers {
+		size += layer.Size
+	}
+	return []smetrics.Metric{
+		smetrics.Gauge("rollingdb_layers", "Number of rolling DB layers, including the root and the active layers", float64(len(layers))),
+		smetrics.Gauge("rollingdb_size_bytes", "Total size of the rolling DB layers", float64(size)),
+	}
+}
+
+func (n *Node) collectInterchainMetrics() []smetrics.Metric {
+	metrics := []smetrics.Metric{}
+
+	if reporter, ok := n.MainchainWitness.(scanHeightReporter); ok {
+		scanHeights := smetrics.Metric{
+			Name: "witness_scan_height",
+			Help: "Last block height scanned for inter-chain message events",
+			Type: smetrics.MetricTypeGauge,
+		}
+		for chainID, height := range reporter.GetScanHeights() {
+			scanHeights.Samples = append(scanHeights.Samples, smetrics.Sample{
+				Labels: []smetrics.Label{{Name: "chain_id", Value: chainID}},
+				Value:  float64(height),
+			})
+		}
+		metrics = append(metrics, scanHeights)
+	}
+
+	if oc, ok := n.Orchestrator.(*orchestrator.Orchestrator); ok {
+		pendingEvents := smetrics.Metric{
+			Name: "orchestrator_pending_events",
+			Help: "Number of witnessed inter-chain events not yet processed by the target chain",
+			Type: smetrics.MetricTypeGauge,
+		}
+		submissionFailures := smetrics.Metric{
+			Name: "orchestrator_submission_failures_total",
+			Help: "Number of failed attempts to submit the target chain transactions",
+			Type: smetrics.MetricTypeCounter,
+		}
+		for _, stats := range oc.GetPipelineStats() {
+			labels := []smetrics.Label{
+				{Name: "source_chain_id", Value: stats.SourceChainID},
+				{Name: "target_chain_id", Value: stats.TargetChainID},
+				{Name: "event_type", Value: strconv.FormatUint(uint64(stats.EventType), 10)},
+			}
+			pendingEvents.Samples = append(pendingEvents.Samples, smetrics.Sample{Labels: labels, Value: float64(stats.PendingEvents)})
+			submissionFailures.Samples = append(submissionFailures.Samples, smetrics.Sample{Labels: labels, Value: float64(stats.SubmissionFailures)})
+		}
+		metrics = append(metrics, pendingEvents, submissionFailures)
+	}
+
+	return metrics
+}
@@ -4,12 +4,12 @@ import (
 	"context"
 	"log"
 	"math/big"
+	"path"
 	"reflect"
 	"sync"
 
 	"github.com/spf13/viper"
 	"github.com/thetatoken/theta/common"
-	"github.com/thetatoken/theta/crypto"
 	dp "github.com/thetatoken/theta/dispatcher"
 	"github.com/thetatoken/theta/p2p"
 	"github.com/thetatoken/theta/p2pl"
@@ -27,7 +27,9 @@ import (
 
 	sld "github.com/thetatoken/thetasubchain/ledger"
 	smp "github.com/thetatoken/thetasubchain/mempool"
+	smetrics "github.com/thetatoken/thetasubchain/metrics"
 	snsync "github.com/thetatoken/thetasubchain/netsync"
+	srep "github.com/thetatoken/thetasubchain/reputation"
 	srpc "github.com/thetatoken/thetasubchain/rpc"
 	ssnst "github.com/thetatoken/thetasubchain/snapshot"
 	srollingdb "github.com/thetatoken/thetasubchain/store/rollingdb"
@@ -42,7 +44,10 @@ type Node struct {
 	Dispatcher           *dp.Dispatcher
 	Ledger               score.Ledger
 	Mempool              *smp.Mempool
+	Reputation           *srep.Manager
 	RPC                  *srpc.ThetaRPCServer
+	Metrics              *smetrics.Server
+	RollingDB            *srollingdb.RollingDB
 	InterChainEventCache *siu.InterChainEventCache
 	MainchainWitness     witness.ChainWitness
 	Orchestrator         orchestrator.ChainOrchestrator
@@ -60,57 +65,80 @@ type Node struct {
 type Params struct {
 	ChainID             string
 	GasPriceLimit       *big.Int
-	PrivateKey          *crypto.PrivateKey
+	Signer              score.Signer
 	Root                *score.Block
 	NetworkOld          p2p.Network
 	Network             p2pl.Network
 	DB                  database.Database
 	RollingDB           *srollingdb.RollingDB
+	DataPath            string
 	SnapshotPath        string
 	ChainImportDirPath  string
 	ChainCorrectionPath string
+
+	// Optional, the mainchain witness and the orchestrator are created from the config if not provided,
+	// e.g. the devnet provides a simulated mainchain witness instead.
+	MainchainWitness witness.ChainWitness
+	Orchestrator     orchestrator.ChainOrchestrator
 }
 
 func NewNode(params *Params) *Node {
 	store := kvstore.NewKVStore(params.DB)
 	chain := sbc.NewChain(params.ChainID, store, params.Root)
+	chain.SetAccountHistoryEnabled(viper.GetBool(scom.CfgStorageAccountHistoryEnabled))
+	chain.SetStateHistoryEnabled(viper.GetBool(scom.CfgStorageArchiveEnabled))
 	params.RollingDB.SetChain(chain)
 
 	validatorManager := sconsensus.NewRotatingValidatorManager()
 	dispatcher := dp.NewDispatcher(params.NetworkOld, params.Network)
 
-	interChainEventCache := siu.NewInterChainEventCache(params.DB)
-
-	// // For testing...
-	// metachainWitness := witness.NewSimulatedMetachainWitness(
-	// 	"privatenet",
-	// 	params.ChainID,
-	// 	interChainEventCache,
-	// 	0)
-	metachainWitness := witness.NewMetachainWitness(
-		params.DB,
-		viper.GetInt(scom.CfgSubchainUpdateIntervalInMilliseconds),
-		interChainEventCache)
-	orchestrator := orchestrator.NewOrchestrator(
-		params.DB,
-		viper.GetInt(scom.CfgSubchainUpdateIntervalInMilliseconds),
-		interChainEventCache,
-		metachainWitness,
-		params.PrivateKey,
-	)
-
-	consensus := sconsensus.NewConsensusEngine(params.PrivateKey, store, chain, dispatcher, validatorManager, metachainWitness)
+	var interChainEventCache *siu.InterChainEventCache
+	var metachainWitness witness.ChainWitness
+	if params.MainchainWitness != nil {
+		metachainWitness = params.MainchainWitness
+		interChainEventCache = metachainWitness.GetInterChainEventCache()
+	} else {
+		interChainEventCache = siu.NewInterChainEventCache(params.DB)
+		metachainWitness = witness.NewMetachainWitness(
+			params.DB,
+			viper.GetInt(scom.CfgSubchainUpdateIntervalInMilliseconds),
+			interChainEventCache)
+	}
+	var chainOrchestrator orchestrator.ChainOrchestrator
+	if params.Orchestrator != nil {
+		chainOrchestrator = params.Orchestrator
+	} else {
+		chainOrchestrator = orchestrator.NewOrchestrator(
+			params.DB,
+			viper.GetInt(scom.CfgSubchainUpdateIntervalInMilliseconds),
+			interChainEventCache,
+			metachainWitness,
+			params.Signer,
+		)
+	}
+
+	consensus := sconsensus.NewConsensusEngine(params.Signer, store, chain, dispatcher, validatorManager, metachainWitness)
 	// reporter := srp.NewReporter(dispatcher, consensus, chain)
 
 	syncMgr := snsync.NewSyncManager(chain, consensus, params.NetworkOld, params.Network, dispatcher, consensus)
 	mempool := smp.CreateMempool(dispatcher, consensus)
 	ledger := sld.NewLedger(params.ChainID, params.RollingDB, params.RollingDB, chain, consensus, validatorManager, mempool, metachainWitness)
 
+	reputation := srep.NewManager(dispatcher, params.NetworkOld, params.Network)
+
 	validatorManager.SetConsensusEngine(consensus)
 	consensus.SetLedger(ledger)
+	consensus.SetReputationManager(reputation)
+	syncMgr.SetReputationManager(reputation)
 	mempool.SetLedger(ledger)
+	if viper.GetBool(scom.CfgMempoolJournalEnabled) && params.DataPath != "" {
+		if err := mempool.SetJournalPath(path.Join(params.DataPath, "mempool", "journal")); err != nil {
+			log.Fatalf("Failed to open the mempool journal: %v", err)
+		}
+	}
 
 	txMsgHandler := smp.CreateMempoolMessageHandler(mempool)
+	txMsgHandler.SetReputationManager(reputation)
 
 	if !reflect.ValueOf(params.Network).IsNil() {
 		params.Network.RegisterMessageHandler(txMsgHandler)
@@ -138,7 +166,7 @@ func NewNode(params *Params) *Node {
 		}
 	}
 	metachainWitness.SetSubchainTokenBanks(ledger)
-	orchestrator.SetLedgerAndSubchainTokenBanks(ledger)
+	chainOrchestrator.SetLedgerAndSubchainTokenBanks(ledger)
 	node := &Node{
 		Store:                store,
 		Chain:                chain,
@@ -148,14 +176,20 @@ func NewNode(params *Params) *Node {
 		Dispatcher:           dispatcher,
 		Ledger:               ledger,
 		Mempool:              mempool,
+		Reputation:           reputation,
 		InterChainEventCache: interChainEventCache,
 		MainchainWitness:     metachainWitness,
-		Orchestrator:         orchestrator,
+		Orchestrator:         chainOrchestrator,
+		RollingDB:            params.RollingDB,
 		// reporter:             reporter,
 	}
 
 	if viper.GetBool(common.CfgRPCEnabled) {
-		node.RPC = srpc.NewThetaRPCServer(mempool, ledger, dispatcher, chain, consensus)
+		node.RPC = srpc.NewThetaRPCServer(mempool, ledger, dispatcher, chain, consensus, params.RollingDB, reputation)
+		node.registerHealthChecks()
+	}
+	if viper.GetBool(scom.CfgMetricsEnabled) {
+		node.Metrics = smetrics.NewServer(node.newMetricsRegistry())
 	}
 	return node
 }
@@ -174,9 +208,12 @@ func (n *Node) Start(ctx context.Context) {
 	n.MainchainWitness.Start(n.ctx)
 	n.Orchestrator.Start(n.ctx)
 
-	if viper.GetBool(common.CfgRPCEnabled) {
+	if n.RPC != nil {
 		n.RPC.Start(n.ctx)
 	}
+	if n.Metrics != nil {
+		n.Metrics.Start(n.ctx)
+	}
 }
 
 // Stop notifies all sub components to stop without blocking.
@@ -188,9 +225,13 @@ func (n *Node) Stop() {
 func (n *Node) Wait() {
 	n.Consensus.Wait()
 	n.SyncManager.Wait()
+	n.Mempool.Wait()
 	n.MainchainWitness.Wait()
 
 	if n.RPC != nil {
 		n.RPC.Wait()
 	}
+	if n.Metrics != nil {
+		n.Metrics.Wait()
+	}
 }
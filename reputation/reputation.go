@@ -0,0 +1,311 @@
+package reputation
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/dispatcher"
+	scom "github.com/thetatoken/thetasubchain/common"
+)
+
+var logger *log.Entry = log.WithFields(log.Fields{"prefix": "reputation"})
+
+const originCacheLimit = 4096
+const scoreRecoveryInterval = 1 * time.Minute // the score of a peer recovers by one point per interval, up to zero
+
+// Offence describes a kind of peer misbehaviour and the score it costs.
+type Offence struct {
+	Name    string
+	Penalty int
+}
+
+var (
+	OffenceUndecodableMessage = Offence{Name: "undecodable message", Penalty: 20}
+	OffenceInvalidBlock       = Offence{Name: "invalid block", Penalty: 50}
+	OffenceInvalidVote        = Offence{Name: "invalid vote", Penalty: 25}
+	OffenceInvalidTx          = Offence{Name: "invalid transaction", Penalty: 2}
+	OffenceUndecodableTx      = Offence{Name: "undecodable transaction", Penalty: 10}
+)
+
+// PeerScore is the reputation of a peer.
+type PeerScore struct {
+	PeerID        string    `json:"peer_id"`
+	Score         int       `json:"score"`
+	NumOffences   uint64    `json:"num_offences"`
+	LastOffence   string    `json:"last_offence"`
+	LastOffenceAt time.Time `json:"last_offence_at"`
+}
+
+// Ban records a banned peer.
+type Ban struct {
+	PeerID    string    `json:"peer_id"`
+	Reason    string    `json:"reason"`
+	BannedAt  time.Time `json:"banned_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// PeerDisconnector is implemented by the networks which can actively drop a peer. If none of the networks
+// of the node implements it, the messages from the peers below the disconnect threshold are ignored instead.
+type PeerDisconnector interface {
+	DisconnectPeer(peerID string)
+}
+
+type peerRecord struct {
+	score         int
+	numOffences   uint64
+	lastOffence   string
+	lastOffenceAt time.Time
+	lastUpdate    time.Time
+}
+
+// Manager keeps a reputation score for each peer. Message handlers report
+// offences, and peers whose score drops below the thresholds are
+// disconnected (or ignored) and temporarily banned.
+type Manager struct {
+	mu *sync.Mutex
+
+	enabled             bool
+	disconnectThreshold int
+	banThreshold        int
+	banDuration         time.Duration
+
+	dispatcher    *dispatcher.Dispatcher
+	disconnectors []PeerDisconnector
+
+	peers map[string]*peerRecord
+	bans  map[string]*Ban
+
+	origins *lru.Cache // message hash -> ID of the peer that relayed it
+}
+
+// NewManager creates a new reputation Manager instance.
+func NewManager(disp *dispatcher.Dispatcher, networks ...interface{}) *Manager {
+	origins, err := lru.New(originCacheLimit)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	m := &Manager{
+		mu:                  &sync.Mutex{},
+		enabled:             viper.GetBool(scom.CfgReputationEnabled),
+		disconnectThreshold: viper.GetInt(scom.CfgReputationDisconnectThreshold),
+		banThreshold:        viper.GetInt(scom.CfgReputationBanThreshold),
+		banDuration:         time.Duration(viper.GetInt(scom.CfgReputationBanDurationSecs)) * time.Second,
+		dispatcher:          disp,
+		peers:               make(map[string]*peerRecord),
+		bans:                make(map[string]*Ban),
+		origins:             origins,
+	}
+
+	for _, network := range networks {
+		if network == nil || reflect.ValueOf(network).IsNil() {
+			continue
+		}
+		if d, ok := network.(PeerDisconnector); ok {
+			m.disconnectors = append(m.disconnectors, d)
+		}
+	}
+	if m.enabled && len(m.disconnectors) == 0 {
+		logger.Info("Network does not support disconnecting peers, disconnects and bans are enforced by ignoring messages")
+	}
+
+	return m
+}
+
+// TrackOrigin remembers which peer relayed the message with the given hash, so that
+// offences detected later on (e.g. by the consensus engine) can be attributed to it.
+func (m *Manager) TrackOrigin(hash common.Hash, peerID string) {
+	if m == nil || len(peerID) == 0 {
+		return
+	}
+	m.origins.Add(hash, peerID)
+}
+
+// ReportOrigin reports an offence against the peer that relayed the message with the given hash.
+func (m *Manager) ReportOrigin(hash common.Hash, offence Offence) {
+	if m == nil {
+		return
+	}
+	if peerID, ok := m.origins.Get(hash); ok {
+		m.Report(peerID.(string), offence)
+	}
+}
+
+// Report lowers the score of the peer, and disconnects or bans the peer if the
+// score drops below the thresholds.
+func (m *Manager) Report(peerID string, offence Offence) {
+	if m == nil || !m.enabled || len(peerID) == 0 {
+		return
+	}
+
+	m.mu.Lock()
+	record := m.getRecord(peerID)
+	record.score -= offence.Penalty
+	record.numOffences++
+	record.lastOffence = offence.Name
+	record.lastOffenceAt = time.Now()
+	score := record.score
+
+	shouldBan := score <= m.banThreshold
+	shouldDisconnect := shouldBan || score <= m.disconnectThreshold
+	if shouldBan {
+		now := time.Now()
+		m.bans[peerID] = &Ban{
+			PeerID:    peerID,
+			Reason:    offence.Name,
+			BannedAt:  now,
+			ExpiresAt: now.Add(m.banDuration),
+		}
+	}
+	m.mu.Unlock()
+
+	logger.WithFields(log.Fields{
+		"peer":    peerID,
+		"offence": offence.Name,
+		"score":   score,
+	}).Debug("Peer misbehaved")
+
+	if shouldBan {
+		logger.WithFields(log.Fields{
+			"peer":     peerID,
+			"offence":  offence.Name,
+			"score":    score,
+			"duration": m.banDuration,
+		}).Warn("Banning peer")
+	}
+	if shouldDisconnect {
+		m.disconnect(peerID)
+	}
+}
+
+// IsBanned returns whether messages from the peer should be ignored.
+func (m *Manager) IsBanned(peerID string) bool {
+	if m == nil || !m.enabled {
+		return false
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ban, ok := m.bans[peerID]
+	if !ok {
+		// Without a network to drop the peer, ignore it until its score recovers
+		if _, tracked := m.peers[peerID]; tracked && len(m.disconnectors) == 0 {
+			return m.getRecord(peerID).score <= m.disconnectThreshold
+		}
+		return false
+	}
+	if time.Now().After(ban.ExpiresAt) {
+		delete(m.bans, peerID)
+		// Give the peer a fresh start after the ban expires
+		delete(m.peers, peerID)
+		return false
+	}
+	return true
+}
+
+// FilterBanned removes the banned peers from the given list.
+func (m *Manager) FilterBanned(peerIDs []string) []string {
+	if m == nil || !m.enabled {
+		return peerIDs
+	}
+	ret := []string{}
+	for _, pid := range peerIDs {
+		if !m.IsBanned(pid) {
+			ret = append(ret, pid)
+		}
+	}
+	return ret
+}
+
+// Unban lifts the ban of the peer and resets its score.
+func (m *Manager) Unban(peerID string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, ok := m.bans[peerID]
+	delete(m.bans, peerID)
+	delete(m.peers, peerID)
+	return ok
+}
+
+// GetPeerScores returns the scores of all the peers that have misbehaved,
+// lowest score first.
+func (m *Manager) GetPeerScores() []PeerScore {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ret := []PeerScore{}
+	for pid := range m.peers {
+		record := m.getRecord(pid)
+		ret = append(ret, PeerScore{
+			PeerID:        pid,
+			Score:         record.score,
+			NumOffences:   record.numOffences,
+			LastOffence:   record.lastOffence,
+			LastOffenceAt: record.lastOffenceAt,
+		})
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Score < ret[j].Score
+	})
+	return ret
+}
+
+// GetBans returns the current bans.
+func (m *Manager) GetBans() []Ban {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	ret := []Ban{}
+	for pid, ban := range m.bans {
+		if now.After(ban.ExpiresAt) {
+			delete(m.bans, pid)
+			delete(m.peers, pid)
+			continue
+		}
+		ret = append(ret, *ban)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].BannedAt.Before(ret[j].BannedAt)
+	})
+	return ret
+}
+
+// getRecord returns the record of the peer with the score recovered for the
+// time elapsed since the last update. Must be called with m.mu held.
+func (m *Manager) getRecord(peerID string) *peerRecord {
+	now := time.Now()
+	record, ok := m.peers[peerID]
+	if !ok {
+		record = &peerRecord{lastUpdate: now}
+		m.peers[peerID] = record
+		return record
+	}
+
+	recovered := int(now.Sub(record.lastUpdate) / scoreRecoveryInterval)
+	if recovered > 0 {
+		record.score += recovered
+		if record.score > 0 {
+			record.score = 0
+		}
+		record.lastUpdate = record.lastUpdate.Add(time.Duration(recovered) * scoreRecoveryInterval)
+	}
+	return record
+}
+
+func (m *Manager) disconnect(peerID string) {
+	if !m.dispatcher.PeerExists(peerID) {
+		return
+	}
+	for _, d := range m.disconnectors {
+		d.DisconnectPeer(peerID)
+	}
+}
@@ -0,0 +1,149 @@
+package rpc
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/ledger/types"
+	"github.com/thetatoken/theta/store/database/backend"
+	"github.com/thetatoken/theta/store/kvstore"
+
+	sbc "github.com/thetatoken/thetasubchain/blockchain"
+	scom "github.com/thetatoken/thetasubchain/common"
+	score "github.com/thetatoken/thetasubchain/core"
+	slst "github.com/thetatoken/thetasubchain/ledger/state"
+)
+
+func TestFinalizedStoreViewAtHeightInArchiveMode(t *testing.T) {
+	assert := assert.New(t)
+
+	// Archive mode disables rolling and pruning, as the start command does
+	viper.Set(scom.CfgStorageArchiveEnabled, true)
+	viper.Set(common.CfgStorageRollingEnabled, false)
+	viper.Set(common.CfgStorageStatePruningEnabled, false)
+	defer viper.Set(scom.CfgStorageArchiveEnabled, false)
+
+	chainID := "archive_test"
+	addr := common.HexToAddress("0x2E833968E5bB786Ae419c4d13189fB081Cc43bab")
+	idleAddr := common.HexToAddress("0x70f587259738cB626A1720Af7038B8DcDb6a42a0") // only set in the genesis state
+	contractAddr := common.HexToAddress("0x5C3159dDD2fe0F9862bC7b7D60C1875fa8F81337")
+	slot := common.BigToHash(big.NewInt(1))
+	db := backend.NewMemDatabase()
+
+	// The balance of the account is 100 * (height + 1) TFuelWei at each height, and the
+	// contract storage slot holds the height at the even heights
+	sv := slst.NewStoreView(0, common.Hash{}, db)
+	sv.SetAccount(idleAddr, &types.Account{
+		Address: idleAddr,
+		Balance: types.Coins{ThetaWei: big.NewInt(0), TFuelWei: big.NewInt(42)},
+	})
+	stateHashAt := func(height uint64) common.Hash {
+		sv.SetAccount(addr, &types.Account{
+			Address: addr,
+			Balance: types.Coins{ThetaWei: big.NewInt(0), TFuelWei: big.NewInt(int64(100 * (height + 1)))},
+		})
+		if height%2 == 0 {
+			sv.SetState(contractAddr, slot, common.BigToHash(new(big.Int).SetUint64(height)))
+		}
+		return sv.Save()
+	}
+	// Records the state modified by the block the same way as the ledger does
+	stateDiff := func() *sbc.StateDiff {
+		diff := &sbc.StateDiff{}
+		for _, touched := range sv.PopTouchedAccounts() {
+			accBytes, err := types.ToBytes(sv.GetAccount(touched.Address))
+			assert.Nil(err)
+			diff.Accounts = append(diff.Accounts, sbc.AccountDiff{Address: touched.Address, Account: accBytes})
+			for _, key := range touched.StorageKeys {
+				diff.Storage = append(diff.Storage, sbc.StorageDiff{Address: touched.Address, Key: key, Value: sv.GetState(touched.Address, key)})
+			}
+		}
+		return diff
+	}
+
+	root := score.NewBlock()
+	root.ChainID = chainID
+	root.Height = 0
+	root.StateHash = stateHashAt(0)
+	root.Timestamp = big.NewInt(0)
+	sv.PopTouchedAccounts()
+	chain := sbc.NewChain(chainID, kvstore.NewKVStore(db), root)
+	chain.SetStateHistoryEnabled(true)
+
+	// The state diff of the block at height 6 is not recorded, the history is rebased on its state
+	parent := root
+	for height := uint64(1); height <= 10; height++ {
+		block := score.NewBlock()
+		block.ChainID = chainID
+		block.Epoch = height
+		block.Height = height
+		block.Parent = parent.Hash()
+		block.StateHash = stateHashAt(height)
+		block.Timestamp = big.NewInt(int64(height))
+		_, err := chain.AddBlock(block)
+		assert.Nil(err)
+		diff := stateDiff()
+		if height != 6 {
+			chain.AddStateDiff(block.Hash(), diff)
+		}
+		assert.Nil(chain.FinalizePreviousBlocks(block.Hash()))
+		parent = block
+	}
+
+	for height := uint64(0); height <= 10; height++ {
+		view, block, err := finalizedStoreViewAtHeight(chain, db, height)
+		assert.Nil(err)
+		if assert.NotNil(view, "height %v", height) {
+			assert.Equal(height, block.Height)
+			account := view.GetAccount(addr)
+			assert.Equal(int64(100*(height+1)), account.Balance.TFuelWei.Int64(), "height %v", height)
+		}
+
+		account, found, err := accountAtHeight(chain, db, addr, height)
+		assert.Nil(err)
+		assert.True(found)
+		if assert.NotNil(account, "height %v", height) {
+			assert.Equal(int64(100*(height+1)), account.Balance.TFuelWei.Int64(), "height %v", height)
+		}
+
+		account, found, err = accountAtHeight(chain, db, idleAddr, height)
+		assert.Nil(err)
+		assert.True(found)
+		if assert.NotNil(account, "height %v", height) {
+			assert.Equal(int64(42), account.Balance.TFuelWei.Int64(), "height %v", height)
+		}
+
+		value, found, err := storageAtHeight(chain, db, contractAddr, slot, height)
+		assert.Nil(err)
+		assert.True(found)
+		assert.Equal(common.BigToHash(new(big.Int).SetUint64(height-height%2)), value, "height %v", height)
+
+		// The accounts modified after the genesis state are served from the history, except at the
+		// height the history got rebased on
+		_, trieHeight, ok := chain.FindAccountStateAtHeight(addr, height)
+		assert.Equal(height != 0 && height != 6, ok, "height %v", height)
+		if !ok {
+			assert.Equal(height, trieHeight)
+		}
+		_, trieHeight, ok = chain.FindAccountStateAtHeight(idleAddr, height)
+		assert.False(ok)
+		if height < 6 {
+			assert.Equal(uint64(0), trieHeight)
+		} else {
+			assert.Equal(uint64(6), trieHeight)
+		}
+	}
+
+	view, block, err := finalizedStoreViewAtHeight(chain, db, 11)
+	assert.Nil(err)
+	assert.Nil(view)
+	assert.Nil(block)
+
+	account, found, err := accountAtHeight(chain, db, addr, 11)
+	assert.Nil(err)
+	assert.False(found)
+	assert.Nil(account)
+}
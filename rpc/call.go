@@ -7,6 +7,7 @@ import (
 	"github.com/thetatoken/theta/common"
 	"github.com/thetatoken/theta/ledger/types"
 
+	score "github.com/thetatoken/thetasubchain/core"
 	sldst "github.com/thetatoken/thetasubchain/ledger/state"
 	stypes "github.com/thetatoken/thetasubchain/ledger/types"
 	svm "github.com/thetatoken/thetasubchain/ledger/vm"
@@ -15,7 +16,8 @@ import (
 // ------------------------------- CallSmartContract -----------------------------------
 
 type CallSmartContractArgs struct {
-	SctxBytes string `json:"sctx_bytes"`
+	SctxBytes string            `json:"sctx_bytes"`
+	Height    common.JSONUint64 `json:"height"` // execute against the finalized state at the given height, 0 for the latest state
 }
 
 type CallSmartContractResult struct {
@@ -30,9 +32,25 @@ type CallSmartContractResult struct {
 // without actually spending gas.
 func (t *ThetaRPCService) CallSmartContract(args *CallSmartContractArgs, result *CallSmartContractResult) (err error) {
 	var ledgerState *sldst.StoreView
-	ledgerState, err = t.ledger.GetDeliveredSnapshot()
-	if err != nil {
-		return err
+	var parentBlockInfo *svm.BlockInfo
+	height := uint64(args.Height)
+	if height == 0 {
+		ledgerState, err = t.ledger.GetDeliveredSnapshot()
+		if err != nil {
+			return err
+		}
+		pb := t.ledger.State().ParentBlock()
+		parentBlockInfo = svm.NewBlockInfo(pb.Height, pb.Timestamp, pb.ChainID)
+	} else {
+		var block *score.ExtendedBlock
+		ledgerState, block, err = t.getFinalizedStoreViewAtHeight(height)
+		if err != nil {
+			return err
+		}
+		if ledgerState == nil {
+			return fmt.Errorf("No finalized block found at height %v", height)
+		}
+		parentBlockInfo = svm.NewBlockInfo(block.Height, block.Timestamp, block.ChainID)
 	}
 
 	blockHeight := ledgerState.Height() + 1 // the view points to the parent of the current block
@@ -54,8 +72,6 @@ func (t *ThetaRPCService) CallSmartContract(args *CallSmartContractArgs, result
 		return fmt.Errorf("Failed to parse SmartContractTx: %v", args.SctxBytes)
 	}
 
-	pb := t.ledger.State().ParentBlock()
-	parentBlockInfo := svm.NewBlockInfo(pb.Height, pb.Timestamp, pb.ChainID)
 	vmRet, contractAddr, gasUsed, vmErr := svm.Execute(parentBlockInfo, sctx, ledgerState)
 	ledgerState.Save()
 
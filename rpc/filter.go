@@ -0,0 +1,109 @@
+package rpc
+
+import (
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/ledger/types"
+
+	score "github.com/thetatoken/thetasubchain/core"
+)
+
+// LogEntry is a log emitted by a smart contract tx, along with its position in the chain
+type LogEntry struct {
+	Address     common.Address    `json:"address"`
+	Topics      []common.Hash     `json:"topics"`
+	Data        common.Bytes      `json:"data"`
+	TxHash      common.Hash       `json:"transaction_hash"`
+	LogIndex    common.JSONUint64 `json:"log_index"`
+	BlockHash   common.Hash       `json:"block_hash"`
+	BlockHeight common.JSONUint64 `json:"block_height"`
+}
+
+func newLogEntry(log *types.Log, txHash common.Hash, logIndex int, blockHash common.Hash, blockHeight uint64) *LogEntry {
+	return &LogEntry{
+		Address:     log.Address,
+		Topics:      log.Topics,
+		Data:        log.Data,
+		TxHash:      txHash,
+		LogIndex:    common.JSONUint64(logIndex),
+		BlockHash:   blockHash,
+		BlockHeight: common.JSONUint64(blockHeight),
+	}
+}
+
+// LogFilter matches the logs emitted by any of the addresses, with topics matching the
+// topic list position by position. An empty address list or topic position matches anything,
+// and the hashes at the same position are alternatives.
+type LogFilter struct {
+	Addresses []common.Address
+	Topics    [][]common.Hash
+}
+
+// Matches returns whether the log passes the filter.
+func (f *LogFilter) Matches(log *types.Log) bool {
+	if len(f.Addresses) > 0 {
+		matched := false
+		for _, address := range f.Addresses {
+			if address == log.Address {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	if len(f.Topics) > len(log.Topics) {
+		return false
+	}
+	for i, alternatives := range f.Topics {
+		if len(alternatives) == 0 {
+			continue
+		}
+		matched := false
+		for _, topic := range alternatives {
+			if topic == log.Topics[i] {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// MayMatch returns whether a block with the given logs bloom may contain matching logs.
+// False positives are possible, but a false result means the block can be skipped.
+func (f *LogFilter) MayMatch(bloom score.Bloom) bool {
+	if len(f.Addresses) > 0 {
+		matched := false
+		for _, address := range f.Addresses {
+			if score.BloomLookup(bloom, address) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	for _, alternatives := range f.Topics {
+		if len(alternatives) == 0 {
+			continue
+		}
+		matched := false
+		for _, topic := range alternatives {
+			if score.BloomLookup(bloom, topic) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
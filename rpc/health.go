@@ -0,0 +1,152 @@
+package rpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+	scom "github.com/thetatoken/thetasubchain/common"
+)
+
+const (
+	HealthStatusOK        = "ok"
+	HealthStatusUnhealthy = "unhealthy"
+)
+
+// HealthCheck returns a non-nil error describing the problem if the component is unhealthy.
+type HealthCheck func() error
+
+type namedHealthCheck struct {
+	name  string
+	check HealthCheck
+}
+
+type HealthCheckResult struct {
+	Healthy bool   `json:"healthy"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+type HealthResult struct {
+	Status string                       `json:"status"`
+	Checks map[string]HealthCheckResult `json:"checks"`
+}
+
+//
+// HealthChecker serves the liveness (/healthz) and readiness (/readyz) probes. A node is live
+// as long as its finalized height keeps advancing, and ready when it also has caught up with the
+// network and the registered readiness checks, e.g. the inter-chain components, pass.
+//
+type HealthChecker struct {
+	mu *sync.Mutex
+
+	service *ThetaRPCService
+
+	livenessChecks  []namedHealthCheck
+	readinessChecks []namedHealthCheck
+
+	finalizationTimeout time.Duration
+	lastFinalizedHeight uint64
+	lastProgressTime    time.Time
+}
+
+func NewHealthChecker(service *ThetaRPCService) *HealthChecker {
+	hc := &HealthChecker{
+		mu:                  &sync.Mutex{},
+		service:             service,
+		finalizationTimeout: time.Duration(viper.GetInt(scom.CfgHealthFinalizationTimeoutSecs)) * time.Second,
+		lastProgressTime:    time.Now(),
+	}
+
+	hc.AddLivenessCheck("consensus", hc.checkFinalizationProgress)
+	hc.AddReadinessCheck("consensus", hc.checkFinalizationProgress)
+	hc.AddReadinessCheck("sync", hc.checkSynced)
+
+	return hc
+}
+
+// AddLivenessCheck adds a check to the liveness probe.
+func (hc *HealthChecker) AddLivenessCheck(name string, check HealthCheck) {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+	hc.livenessChecks = append(hc.livenessChecks, namedHealthCheck{name: name, check: check})
+}
+
+// AddReadinessCheck adds a check to the readiness probe.
+func (hc *HealthChecker) AddReadinessCheck(name string, check HealthCheck) {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+	hc.readinessChecks = append(hc.readinessChecks, namedHealthCheck{name: name, check: check})
+}
+
+// ServeLiveness handles the /healthz requests.
+func (hc *HealthChecker) ServeLiveness(w http.ResponseWriter, r *http.Request) {
+	hc.mu.Lock()
+	checks := append([]namedHealthCheck{}, hc.livenessChecks...)
+	hc.mu.Unlock()
+	hc.serve(w, checks)
+}
+
+// ServeReadiness handles the /readyz requests.
+func (hc *HealthChecker) ServeReadiness(w http.ResponseWriter, r *http.Request) {
+	hc.mu.Lock()
+	checks := append([]namedHealthCheck{}, hc.readinessChecks...)
+	hc.mu.Unlock()
+	hc.serve(w, checks)
+}
+
+func (hc *HealthChecker) serve(w http.ResponseWriter, checks []namedHealthCheck) {
+	sort.SliceStable(checks, func(i, j int) bool {
+		return checks[i].name < checks[j].name
+	})
+
+	result := HealthResult{
+		Status: HealthStatusOK,
+		Checks: make(map[string]HealthCheckResult),
+	}
+	for _, c := range checks {
+		if err := c.check(); err != nil {
+			result.Status = HealthStatusUnhealthy
+			result.Checks[c.name] = HealthCheckResult{Healthy: false, Reason: err.Error()}
+		} else {
+			result.Checks[c.name] = HealthCheckResult{Healthy: true}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if result.Status != HealthStatusOK {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	json.NewEncoder(w).Encode(result)
+}
+
+// checkFinalizationProgress fails if the finalized height has not advanced within the timeout.
+func (hc *HealthChecker) checkFinalizationProgress() error {
+	height := hc.service.consensus.GetLastFinalizedBlock().Height
+
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+
+	now := time.Now()
+	if height != hc.lastFinalizedHeight {
+		hc.lastFinalizedHeight = height
+		hc.lastProgressTime = now
+		return nil
+	}
+	if hc.finalizationTimeout > 0 && now.Sub(hc.lastProgressTime) > hc.finalizationTimeout {
+		return fmt.Errorf("finalized height %v has not advanced for %v", height, now.Sub(hc.lastProgressTime).Round(time.Second))
+	}
+	return nil
+}
+
+func (hc *HealthChecker) checkSynced() error {
+	if !hc.service.consensus.HasSynced() {
+		return fmt.Errorf("node is still syncing, finalized height: %v", hc.service.consensus.GetLastFinalizedBlock().Height)
+	}
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"math/big"
 	"math/rand"
+	"sort"
 	"time"
 
 	"github.com/spf13/viper"
@@ -13,14 +14,18 @@ import (
 	"github.com/thetatoken/theta/common"
 	"github.com/thetatoken/theta/crypto"
 	"github.com/thetatoken/theta/ledger/types"
+	"github.com/thetatoken/theta/store/database"
 
 	sbc "github.com/thetatoken/thetasubchain/blockchain"
+	scom "github.com/thetatoken/thetasubchain/common"
 	"github.com/thetatoken/thetasubchain/core"
 	score "github.com/thetatoken/thetasubchain/core"
 	"github.com/thetatoken/thetasubchain/ledger/state"
 	slst "github.com/thetatoken/thetasubchain/ledger/state"
 	stypes "github.com/thetatoken/thetasubchain/ledger/types"
 	smp "github.com/thetatoken/thetasubchain/mempool"
+	srep "github.com/thetatoken/thetasubchain/reputation"
+	srollingdb "github.com/thetatoken/thetasubchain/store/rollingdb"
 	sversion "github.com/thetatoken/thetasubchain/version"
 )
 
@@ -83,34 +88,18 @@ func (t *ThetaRPCService) GetAccount(args *GetAccountArgs, result *GetAccountRes
 
 		result.Account = account
 	} else {
-		blocks := t.chain.FindBlocksByHeight(height)
-		if len(blocks) == 0 {
-			result.Account = nil
-			return nil
-		}
-
-		deliveredView, err := t.ledger.GetDeliveredSnapshot()
+		account, found, err := t.getAccountAtHeight(address, height)
 		if err != nil {
 			return err
 		}
-		db := deliveredView.GetDB()
-
-		for _, b := range blocks {
-			if b.Status.IsFinalized() {
-				stateRoot := b.StateHash
-				ledgerState := slst.NewStoreView(height, stateRoot, db)
-				if ledgerState == nil { // might have been pruned
-					return fmt.Errorf("the account details for height %v is not available, it might have been pruned", height)
-				}
-				account := ledgerState.GetAccount(address)
-				if account == nil {
-					return fmt.Errorf("Account with address %v is not found", address.Hex())
-				}
-				result.Account = account
-				break
-			}
+		if !found {
+			result.Account = nil
+			return nil
 		}
-
+		if account == nil {
+			return fmt.Errorf("Account with address %v is not found", address.Hex())
+		}
+		result.Account = account
 	}
 
 	return nil
@@ -205,12 +194,169 @@ type GetPendingTransactionsArgs struct {
 }
 
 type GetPendingTransactionsResult struct {
-	TxHashes []string `json:"tx_hashes"`
+	TxHashes       []string `json:"tx_hashes"`        // txs ready to be included in a block
+	QueuedTxHashes []string `json:"queued_tx_hashes"` // txs waiting for the preceding sequences
 }
 
 func (t *ThetaRPCService) GetPendingTransactions(args *GetPendingTransactionsArgs, result *GetPendingTransactionsResult) (err error) {
 	pendingTxHashes := t.mempool.GetCandidateTransactionHashes()
 	result.TxHashes = pendingTxHashes
+	result.QueuedTxHashes = t.mempool.GetQueuedTransactionHashes()
+	return nil
+}
+
+// ------------------------------ GetMempoolStats -----------------------------------
+
+type GetMempoolStatsArgs struct {
+}
+
+type GetMempoolStatsResult struct {
+	Size                    common.JSONUint64 `json:"size"`
+	NumAccounts             common.JSONUint64 `json:"num_accounts"`
+	MaxTxCount              common.JSONUint64 `json:"max_tx_count"`
+	MaxTxsPerAccount        common.JSONUint64 `json:"max_txs_per_account"`
+	NumEvicted              common.JSONUint64 `json:"num_evicted"`
+	NumExpired              common.JSONUint64 `json:"num_expired"`
+	NumRejectedFull         common.JSONUint64 `json:"num_rejected_full"`
+	NumRejectedAccountQuota common.JSONUint64 `json:"num_rejected_account_quota"`
+	NumRejectedUnderpriced  common.JSONUint64 `json:"num_rejected_underpriced"`
+	NumReplaced             common.JSONUint64 `json:"num_replaced"`
+	NumQueued               common.JSONUint64 `json:"num_queued"`
+	NumPromoted             common.JSONUint64 `json:"num_promoted"`
+}
+
+func (t *ThetaRPCService) GetMempoolStats(args *GetMempoolStatsArgs, result *GetMempoolStatsResult) (err error) {
+	stats := t.mempool.GetStats()
+	result.Size = common.JSONUint64(stats.Size)
+	result.NumAccounts = common.JSONUint64(stats.NumAccounts)
+	result.MaxTxCount = common.JSONUint64(stats.MaxTxCount)
+	result.MaxTxsPerAccount = common.JSONUint64(stats.MaxTxsPerAccount)
+	result.NumEvicted = common.JSONUint64(stats.NumEvicted)
+	result.NumExpired = common.JSONUint64(stats.NumExpired)
+	result.NumRejectedFull = common.JSONUint64(stats.NumRejectedFull)
+	result.NumRejectedAccountQuota = common.JSONUint64(stats.NumRejectedAccountQuota)
+	result.NumRejectedUnderpriced = common.JSONUint64(stats.NumRejectedUnderpriced)
+	result.NumReplaced = common.JSONUint64(stats.NumReplaced)
+	result.NumQueued = common.JSONUint64(stats.NumQueued)
+	result.NumPromoted = common.JSONUint64(stats.NumPromoted)
+	return nil
+}
+
+// ------------------------------ GetNextSequence -----------------------------------
+
+type GetNextSequenceArgs struct {
+	Address string `json:"address"`
+}
+
+type GetNextSequenceResult struct {
+	Address           string            `json:"address"`
+	Sequence          common.JSONUint64 `json:"sequence"`           // sequence to use for the next transaction
+	CommittedSequence common.JSONUint64 `json:"committed_sequence"` // sequence of the last transaction in the committed blocks
+	NumPendingTxs     common.JSONUint64 `json:"num_pending_txs"`    // txs in the mempool ahead of the next transaction
+}
+
+// GetNextSequence returns the sequence for the next transaction of the account, taking
+// the committed blocks and the candidate transactions in the mempool into account.
+func (t *ThetaRPCService) GetNextSequence(args *GetNextSequenceArgs, result *GetNextSequenceResult) (err error) {
+	if args.Address == "" {
+		return errors.New("Address must be specified")
+	}
+	address := common.HexToAddress(args.Address)
+
+	deliveredView, err := t.ledger.GetDeliveredSnapshot()
+	if err != nil {
+		return err
+	}
+	committedSequence := uint64(0)
+	if account := deliveredView.GetAccount(address); account != nil {
+		committedSequence = account.Sequence
+	}
+
+	// The screened view includes the txs accepted by the mempool since the last commit
+	nextSequence := committedSequence + 1
+	screenedView, err := t.ledger.GetScreenedSnapshot()
+	if err != nil {
+		return err
+	}
+	if account := screenedView.GetAccount(address); account != nil && account.Sequence >= nextSequence {
+		nextSequence = account.Sequence + 1
+	}
+	if pendingSequence, ok := t.mempool.GetPendingSequence(address); ok && pendingSequence >= nextSequence {
+		nextSequence = pendingSequence + 1
+	}
+
+	result.Address = args.Address
+	result.Sequence = common.JSONUint64(nextSequence)
+	result.CommittedSequence = common.JSONUint64(committedSequence)
+	result.NumPendingTxs = common.JSONUint64(nextSequence - committedSequence - 1)
+	return nil
+}
+
+// ------------------------------ GetSuggestedFee -----------------------------------
+
+type GetSuggestedFeeArgs struct {
+	NumAccounts     uint64 `json:"num_accounts"`      // number of inputs and outputs of the send tx, 2 if not specified
+	NumBatchOutputs uint64 `json:"num_batch_outputs"` // number of outputs of the batch send tx, if any
+}
+
+type GetSuggestedFeeResult struct {
+	GasPrice         *big.Int `json:"gas_price"`           // gas price for smart contract txs
+	SendTxFee        *big.Int `json:"send_tx_fee"`         // fee for send txs
+	MinimumGasPrice  *big.Int `json:"minimum_gas_price"`   // gas price below which txs are rejected
+	MinimumSendTxFee *big.Int `json:"minimum_send_tx_fee"` // fee below which send txs are rejected
+	BatchSendTxFee   *big.Int `json:"batch_send_tx_fee"`   // fee for the batch send tx, if num_batch_outputs is specified
+}
+
+// GetSuggestedFee suggests the gas price and the send tx fee. When the mempool is at least
+// half full, the suggestion is the median effective gas price of the candidate transactions,
+// so that new transactions are not the first to be evicted. Otherwise the minimums suffice.
+func (t *ThetaRPCService) GetSuggestedFee(args *GetSuggestedFeeArgs, result *GetSuggestedFeeResult) (err error) {
+	numAccounts := args.NumAccounts
+	if numAccounts < 2 {
+		numAccounts = 2
+	}
+	height := t.consensus.GetLastFinalizedBlock().Height
+
+	minGasPrice := scom.GetMinimumGasPrice()
+	gasPrice := new(big.Int).Set(minGasPrice)
+	stats := t.mempool.GetStats()
+	if stats.MaxTxCount > 0 && stats.Size*2 >= stats.MaxTxCount {
+		candidatePrices := t.mempool.GetCandidateGasPrices()
+		if len(candidatePrices) > 0 {
+			sort.Slice(candidatePrices, func(i, j int) bool {
+				return candidatePrices[i].Cmp(candidatePrices[j]) < 0
+			})
+			if median := candidatePrices[len(candidatePrices)/2]; median.Cmp(gasPrice) > 0 {
+				gasPrice.Set(median)
+			}
+		}
+	}
+
+	gasPerAccount := uint64(types.GasRegularTxJune2021 / 2)
+	if height < common.HeightJune2021FeeAdjustment {
+		gasPerAccount = uint64(types.GasRegularTx / 2)
+	}
+	minSendTxFee := types.GetSendTxMinimumTransactionFeeTFuelWei(numAccounts, height)
+	sendTxFee := new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(gasPerAccount*numAccounts))
+	if sendTxFee.Cmp(minSendTxFee) < 0 {
+		sendTxFee.Set(minSendTxFee)
+	}
+
+	if args.NumBatchOutputs > 0 {
+		batchGas := 2 * gasPerAccount
+		batchGas += (args.NumBatchOutputs - 1) * 2 * gasPerAccount / stypes.BatchSendTxOutputFeeDivisor
+		minBatchSendTxFee := stypes.GetBatchSendTxMinimumTransactionFeeTFuelWei(args.NumBatchOutputs, height)
+		batchSendTxFee := new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(batchGas))
+		if batchSendTxFee.Cmp(minBatchSendTxFee) < 0 {
+			batchSendTxFee.Set(minBatchSendTxFee)
+		}
+		result.BatchSendTxFee = batchSendTxFee
+	}
+
+	result.GasPrice = gasPrice
+	result.SendTxFee = sendTxFee
+	result.MinimumGasPrice = minGasPrice
+	result.MinimumSendTxFee = minSendTxFee
 	return nil
 }
 
@@ -279,6 +425,8 @@ const (
 
 	TxSubchainValidatorSetUpdate = byte(201)
 	TxInterChainMessage          = byte(202)
+	TxMultisigSend               = byte(203)
+	TxBatchSend                  = byte(204)
 )
 
 func (t *ThetaRPCService) GetBlock(args *GetBlockArgs, result *GetBlockResult) (err error) {
@@ -575,6 +723,24 @@ func (t *ThetaRPCService) GetPeers(args *GetPeersArgs, result *GetPeersResult) (
 	return
 }
 
+// ------------------------------ GetPeerScores -----------------------------------
+
+type GetPeerScoresArgs struct{}
+
+type GetPeerScoresResult struct {
+	Scores []srep.PeerScore `json:"scores"`
+	Bans   []srep.Ban       `json:"bans"`
+}
+
+func (t *ThetaRPCService) GetPeerScores(args *GetPeerScoresArgs, result *GetPeerScoresResult) (err error) {
+	if t.reputation == nil {
+		return errors.New("Peer reputation is not available")
+	}
+	result.Scores = t.reputation.GetPeerScores()
+	result.Bans = t.reputation.GetBans()
+	return nil
+}
+
 // ------------------------------ GetValidatorSet -----------------------------------
 
 type GetValidatorSetByHeightArgs struct {
@@ -654,6 +820,8 @@ func (t *ThetaRPCService) GetTokenBankContractAddress(args *GetTokenBankContract
 		contractAddr = deliveredView.GetTNT20TokenBankContractAddress()
 	case core.CrossChainTokenTypeTNT721:
 		contractAddr = deliveredView.GetTNT721TokenBankContractAddress()
+	case core.CrossChainTokenTypeTNT1155:
+		contractAddr = deliveredView.GetTNT1155TokenBankContractAddress()
 	default:
 		return fmt.Errorf("unknown token type: %v", args.TokenType)
 	}
@@ -696,31 +864,16 @@ func (t *ThetaRPCService) GetCode(args *GetCodeArgs, result *GetCodeResult) (err
 		codeBytes := ledgerState.GetCode(address)
 		result.Code = hex.EncodeToString(codeBytes)
 	} else {
-		blocks := t.chain.FindBlocksByHeight(height)
-		if len(blocks) == 0 {
-			result.Code = ""
-			return nil
-		}
-
-		deliveredView, err := t.ledger.GetDeliveredSnapshot()
+		ledgerState, _, err := t.getFinalizedStoreViewAtHeight(height)
 		if err != nil {
 			return err
 		}
-		db := deliveredView.GetDB()
-
-		for _, b := range blocks {
-			if b.Status.IsFinalized() {
-				stateRoot := b.StateHash
-				ledgerState := slst.NewStoreView(height, stateRoot, db)
-				if ledgerState == nil { // might have been pruned
-					return fmt.Errorf("the account details for height %v is not available, it might have been pruned", height)
-				}
-				codeBytes := ledgerState.GetCode(address)
-				result.Code = hex.EncodeToString(codeBytes)
-				break
-			}
+		if ledgerState == nil {
+			result.Code = ""
+			return nil
 		}
-
+		codeBytes := ledgerState.GetCode(address)
+		result.Code = hex.EncodeToString(codeBytes)
 	}
 
 	return nil
@@ -755,28 +908,75 @@ func (t *ThetaRPCService) GetStorageAt(args *GetStorageAtArgs, result *GetStorag
 		value := ledgerState.GetState(address, key)
 		result.Value = hex.EncodeToString(value.Bytes())
 	} else {
-		blocks := t.chain.FindBlocksByHeight(height)
-		if len(blocks) == 0 {
+		value, found, err := t.getStorageAtHeight(address, key, height)
+		if err != nil {
+			return err
+		}
+		if !found {
 			result.Value = ""
 			return nil
 		}
+		result.Value = hex.EncodeToString(value.Bytes())
+	}
 
-		deliveredView, err := t.ledger.GetDeliveredSnapshot()
-		if err != nil {
-			return err
+	return nil
+}
+
+// ------------------------------ GetLogs -----------------------------------
+
+const maxGetLogsBlockRange = 5000
+
+type GetLogsArgs struct {
+	FromBlock common.JSONUint64 `json:"from_block"`
+	ToBlock   common.JSONUint64 `json:"to_block"` // defaults to the last finalized block
+	Addresses []common.Address  `json:"addresses"`
+	Topics    [][]common.Hash   `json:"topics"`
+}
+
+type GetLogsResult struct {
+	Logs []*LogEntry `json:"logs"`
+}
+
+func (t *ThetaRPCService) GetLogs(args *GetLogsArgs, result *GetLogsResult) (err error) {
+	fromHeight := uint64(args.FromBlock)
+	toHeight := uint64(args.ToBlock)
+	lfbHeight := t.consensus.GetLastFinalizedBlock().Height
+	if toHeight == 0 || toHeight > lfbHeight {
+		toHeight = lfbHeight
+	}
+	if fromHeight > toHeight {
+		return errors.New("Starting block must be less than ending block")
+	}
+	if toHeight-fromHeight >= maxGetLogsBlockRange {
+		return fmt.Errorf("Can't query logs for more than %v blocks at a time", maxGetLogsBlockRange)
+	}
+
+	filter := &LogFilter{
+		Addresses: args.Addresses,
+		Topics:    args.Topics,
+	}
+
+	result.Logs = []*LogEntry{}
+	for height := fromHeight; height <= toHeight; height++ {
+		if bloom, found := t.chain.FindLogsBloom(height); found && !filter.MayMatch(bloom) {
+			continue
+		}
+		block, found := t.chain.FindFinalizedBlockByHeight(height)
+		if !found {
+			continue
 		}
-		db := deliveredView.GetDB()
-
-		for _, b := range blocks {
-			if b.Status.IsFinalized() {
-				stateRoot := b.StateHash
-				ledgerState := slst.NewStoreView(height, stateRoot, db)
-				if ledgerState == nil { // might have been pruned
-					return fmt.Errorf("the account details for height %v is not available, it might have been pruned", height)
+
+		blockHash := block.Hash()
+		for _, rawTx := range block.Txs {
+			txHash := crypto.Keccak256Hash(rawTx)
+			receipt, found := t.chain.FindTxReceiptByHash(blockHash, txHash)
+			if !found {
+				continue
+			}
+			for idx, log := range receipt.Logs {
+				if filter.Matches(log) {
+					result.Logs = append(result.Logs, newLogEntry(log, txHash, idx, blockHash, block.Height))
 				}
-				value := ledgerState.GetState(address, key)
-				result.Value = hex.EncodeToString(value.Bytes())
-				break
 			}
 		}
 	}
@@ -784,8 +984,172 @@ func (t *ThetaRPCService) GetStorageAt(args *GetStorageAtArgs, result *GetStorag
 	return nil
 }
 
+// ------------------------------ GetAccountHistory -----------------------------------
+
+const maxAccountHistoryPageSize = 100
+
+type GetAccountHistoryArgs struct {
+	Address  string            `json:"address"`
+	Page     common.JSONUint64 `json:"page"` // starting from 0
+	PageSize common.JSONUint64 `json:"page_size"`
+}
+
+type AccountTx struct {
+	TxHash      common.Hash       `json:"hash"`
+	BlockHeight common.JSONUint64 `json:"block_height"`
+	Direction   sbc.TxDirection   `json:"direction"`
+}
+
+type GetAccountHistoryResult struct {
+	Total common.JSONUint64 `json:"total"`
+	Txs   []AccountTx       `json:"transactions"`
+}
+
+func (t *ThetaRPCService) GetAccountHistory(args *GetAccountHistoryArgs, result *GetAccountHistoryResult) (err error) {
+	if args.Address == "" {
+		return errors.New("Address must be specified")
+	}
+	if !t.chain.AccountHistoryEnabled() {
+		return errors.New("Account history index is not enabled on this node")
+	}
+	address := common.HexToAddress(args.Address)
+
+	pageSize := uint64(args.PageSize)
+	if pageSize == 0 || pageSize > maxAccountHistoryPageSize {
+		pageSize = maxAccountHistoryPageSize
+	}
+
+	entries, total := t.chain.FindAccountTxs(address, uint64(args.Page)*pageSize, pageSize)
+	result.Total = common.JSONUint64(total)
+	result.Txs = []AccountTx{}
+	for _, entry := range entries {
+		result.Txs = append(result.Txs, AccountTx{
+			TxHash:      entry.TxHash,
+			BlockHeight: common.JSONUint64(entry.BlockHeight),
+			Direction:   entry.Direction,
+		})
+	}
+
+	return nil
+}
+
+// ------------------------------ GetRollingDBStatus -----------------------------------
+
+type GetRollingDBStatusArgs struct{}
+
+type GetRollingDBStatusResult struct {
+	*srollingdb.Status
+}
+
+func (t *ThetaRPCService) GetRollingDBStatus(args *GetRollingDBStatusArgs, result *GetRollingDBStatusResult) (err error) {
+	if t.rollingDB == nil {
+		return errors.New("Rolling DB is not available")
+	}
+	result.Status = t.rollingDB.GetStatus()
+	return nil
+}
+
 // ------------------------------ Utils ------------------------------
 
+// getFinalizedStoreViewAtHeight returns the state of the finalized block at the given height. It returns
+// a nil view if no block at that height has been finalized yet. Unless the node runs in archive mode,
+// the state of old blocks may no longer be available.
+func (t *ThetaRPCService) getFinalizedStoreViewAtHeight(height uint64) (*slst.StoreView, *score.ExtendedBlock, error) {
+	deliveredView, err := t.ledger.GetDeliveredSnapshot()
+	if err != nil {
+		return nil, nil, err
+	}
+	return finalizedStoreViewAtHeight(t.chain, deliveredView.GetDB(), height)
+}
+
+// getAccountAtHeight returns the account at the given finalized height, see accountAtHeight().
+func (t *ThetaRPCService) getAccountAtHeight(address common.Address, height uint64) (*types.Account, bool, error) {
+	deliveredView, err := t.ledger.GetDeliveredSnapshot()
+	if err != nil {
+		return nil, false, err
+	}
+	return accountAtHeight(t.chain, deliveredView.GetDB(), address, height)
+}
+
+// getStorageAtHeight returns the value of the storage slot at the given finalized height, see storageAtHeight().
+func (t *ThetaRPCService) getStorageAtHeight(address common.Address, key common.Hash, height uint64) (common.Hash, bool, error) {
+	deliveredView, err := t.ledger.GetDeliveredSnapshot()
+	if err != nil {
+		return common.Hash{}, false, err
+	}
+	return storageAtHeight(t.chain, deliveredView.GetDB(), address, key, height)
+}
+
+// finalizedStoreViewAtHeight resolves the finalized block at the given height with the finalized block
+// index, and opens the state trie at its state root. The code and the smart contract calls are served
+// from the state trie, which archive mode retains for every height.
+func finalizedStoreViewAtHeight(chain *sbc.Chain, db database.Database, height uint64) (*slst.StoreView, *score.ExtendedBlock, error) {
+	block, found := chain.FindFinalizedBlockByHeight(height)
+	if !found {
+		return nil, nil, nil
+	}
+
+	ledgerState := slst.NewStoreView(height, block.StateHash, db)
+	if ledgerState == nil { // might have been pruned
+		return nil, nil, fmt.Errorf("the state for height %v is not available, it might have been pruned", height)
+	}
+	return ledgerState, block, nil
+}
+
+// accountAtHeight returns the account at the given finalized height, nil if it did not exist. In archive
+// mode the account is looked up in the state history, which indexes the accounts modified by each block
+// by address and height. The state trie is only read for what the history does not cover. It returns
+// false if no block at that height has been finalized yet.
+func accountAtHeight(chain *sbc.Chain, db database.Database, address common.Address, height uint64) (*types.Account, bool, error) {
+	if _, found := chain.FindFinalizedBlockByHeight(height); !found {
+		return nil, false, nil
+	}
+
+	accBytes, trieHeight, ok := chain.FindAccountStateAtHeight(address, height)
+	if ok {
+		if len(accBytes) == 0 {
+			return nil, true, nil
+		}
+		account := &types.Account{}
+		err := types.FromBytes(accBytes, account)
+		if err != nil {
+			return nil, true, fmt.Errorf("failed to decode account %v at height %v: %v", address.Hex(), height, err)
+		}
+		return account, true, nil
+	}
+
+	ledgerState, _, err := finalizedStoreViewAtHeight(chain, db, trieHeight)
+	if err != nil {
+		return nil, true, err
+	}
+	if ledgerState == nil {
+		return nil, true, fmt.Errorf("no finalized block found at height %v", trieHeight)
+	}
+	return ledgerState.GetAccount(address), true, nil
+}
+
+// storageAtHeight returns the value of the storage slot at the given finalized height, in the same way
+// as accountAtHeight().
+func storageAtHeight(chain *sbc.Chain, db database.Database, address common.Address, key common.Hash, height uint64) (common.Hash, bool, error) {
+	if _, found := chain.FindFinalizedBlockByHeight(height); !found {
+		return common.Hash{}, false, nil
+	}
+
+	value, trieHeight, ok := chain.FindStorageStateAtHeight(address, key, height)
+	if ok {
+		return value, true, nil
+	}
+
+	ledgerState, _, err := finalizedStoreViewAtHeight(chain, db, trieHeight)
+	if err != nil {
+		return common.Hash{}, true, err
+	}
+	if ledgerState == nil {
+		return common.Hash{}, true, fmt.Errorf("no finalized block found at height %v", trieHeight)
+	}
+	return ledgerState.GetState(address, key), true, nil
+}
+
 func (t *ThetaRPCService) gatherTxs(block *score.ExtendedBlock, txs *[]interface{}, includeEthTxHashes bool) error {
 	// Parse and fulfill Txs.
 	//var tx types.Tx
@@ -863,6 +1227,10 @@ func getTxType(tx types.Tx) byte {
 		t = TxTypeStakeRewardDistributionTx
 	case *stypes.SubchainValidatorSetUpdateTx:
 		t = TxSubchainValidatorSetUpdate
+	case *stypes.MultisigSendTx:
+		t = TxMultisigSend
+	case *stypes.BatchSendTx:
+		t = TxBatchSend
 	}
 
 	return t
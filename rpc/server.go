@@ -28,6 +28,8 @@ import (
 	sconsensus "github.com/thetatoken/thetasubchain/consensus"
 	sld "github.com/thetatoken/thetasubchain/ledger"
 	smp "github.com/thetatoken/thetasubchain/mempool"
+	srep "github.com/thetatoken/thetasubchain/reputation"
+	srollingdb "github.com/thetatoken/thetasubchain/store/rollingdb"
 )
 
 var logger *log.Entry
@@ -38,6 +40,11 @@ type ThetaRPCService struct {
 	dispatcher *dispatcher.Dispatcher
 	chain      *sbc.Chain
 	consensus  *sconsensus.ConsensusEngine
+	rollingDB  *srollingdb.RollingDB
+	reputation *srep.Manager
+
+	handler       *rpc.Server
+	subscriptions *SubscriptionManager
 
 	// Life cycle
 	wg      *sync.WaitGroup
@@ -51,14 +58,15 @@ type ThetaRPCServer struct {
 	*ThetaRPCService
 
 	server   *http.Server
-	handler  *rpc.Server
 	router   *mux.Router
 	listener net.Listener
+
+	Health *HealthChecker
 }
 
 // NewThetaRPCServer creates a new instance of ThetaRPCServer.
 func NewThetaRPCServer(mempool *smp.Mempool, ledger *sld.Ledger, dispatcher *dispatcher.Dispatcher,
-	chain *sbc.Chain, consensus *sconsensus.ConsensusEngine) *ThetaRPCServer {
+	chain *sbc.Chain, consensus *sconsensus.ConsensusEngine, rollingDB *srollingdb.RollingDB, reputation *srep.Manager) *ThetaRPCServer {
 	t := &ThetaRPCServer{
 		ThetaRPCService: &ThetaRPCService{
 			wg: &sync.WaitGroup{},
@@ -70,6 +78,9 @@ func NewThetaRPCServer(mempool *smp.Mempool, ledger *sld.Ledger, dispatcher *dis
 	t.dispatcher = dispatcher
 	t.chain = chain
 	t.consensus = consensus
+	t.rollingDB = rollingDB
+	t.reputation = reputation
+	t.subscriptions = NewSubscriptionManager(t.ThetaRPCService)
 
 	s := rpc.NewServer()
 	s.RegisterName("theta", t.ThetaRPCService)
@@ -79,9 +90,11 @@ func NewThetaRPCServer(mempool *smp.Mempool, ledger *sld.Ledger, dispatcher *dis
 	t.router = mux.NewRouter()
 	t.router.Handle("/", &defaultHTTPHandler{})
 	t.router.Handle("/rpc", corsMiddleware(TimeoutHandler(jsonrpc2.HTTPHandler(s), viper.GetDuration(scom.CfgRPCTimeoutSecs)*time.Second, "")))
-	t.router.Handle("/ws", websocket.Handler(func(ws *websocket.Conn) {
-		s.ServeCodec(jsonrpc2.NewServerCodec(ws, s))
-	}))
+	t.router.Handle("/ws", websocket.Handler(t.subscriptions.ServeWebsocket))
+
+	t.Health = NewHealthChecker(t.ThetaRPCService)
+	t.router.HandleFunc("/healthz", t.Health.ServeLiveness)
+	t.router.HandleFunc("/readyz", t.Health.ServeReadiness)
 
 	t.server = &http.Server{
 		Handler: t.router,
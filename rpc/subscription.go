@@ -0,0 +1,526 @@
+package rpc
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/rpc/lib/rpc-codec/jsonrpc2"
+	"golang.org/x/net/websocket"
+
+	scom "github.com/thetatoken/thetasubchain/common"
+	score "github.com/thetatoken/thetasubchain/core"
+	smp "github.com/thetatoken/thetasubchain/mempool"
+)
+
+const (
+	SubscriptionTypeNewBlocks           = "new_blocks"
+	SubscriptionTypeTxStatus            = "tx_status"
+	SubscriptionTypeLogs                = "logs"
+	SubscriptionTypeValidatorSetChanges = "validator_set_changes"
+)
+
+const (
+	subscribeMethod    = "theta.Subscribe"
+	unsubscribeMethod  = "theta.Unsubscribe"
+	notificationMethod = "theta.Subscription"
+
+	maxSubscriptionsPerConnection = 64
+	notificationBufferSize        = 256
+
+	jsonrpcInvalidParamsCode = -32602
+)
+
+// TxStatusIncluded indicates the tx has been included in a block which is not finalized yet
+const TxStatusIncluded = "included"
+
+// TxStatusExpired is the last notification of a tx_status subscription which ended before the tx was finalized
+const TxStatusExpired = "expired"
+
+// SubscribeArgs specifies the events to subscribe to. TxHash is required by the tx_status
+// subscriptions, and Addresses and Topics optionally filter the logs subscriptions.
+type SubscribeArgs struct {
+	Type      string           `json:"type"`
+	TxHash    string           `json:"tx_hash"`
+	Addresses []common.Address `json:"addresses"`
+	Topics    [][]common.Hash  `json:"topics"`
+}
+
+type UnsubscribeArgs struct {
+	SubscriptionID string `json:"subscription_id"`
+}
+
+type SubscriptionNotification struct {
+	SubscriptionID string      `json:"subscription"`
+	Result         interface{} `json:"result"`
+}
+
+type TxStatusNotification struct {
+	TxHash      common.Hash       `json:"hash"`
+	Status      TxStatus          `json:"status"`
+	BlockHash   common.Hash       `json:"block_hash"`
+	BlockHeight common.JSONUint64 `json:"block_height"`
+}
+
+type ValidatorSetChangeNotification struct {
+	BlockHash    common.Hash       `json:"block_hash"`
+	BlockHeight  common.JSONUint64 `json:"block_height"`
+	ValidatorSet ValidatorSet      `json:"validator_set"`
+}
+
+type subscription struct {
+	id   string
+	kind string
+	conn *wsConnection
+
+	txHash       common.Hash
+	lastTxStatus TxStatus
+	created      time.Time
+	logFilter    *LogFilter
+}
+
+//
+// wsConnection is a websocket connection which receives subscription notifications
+//
+type wsConnection struct {
+	ws            *websocket.Conn
+	notifications chan interface{}
+	quit          chan struct{}
+	closeOnce     *sync.Once
+}
+
+func newWSConnection(ws *websocket.Conn) *wsConnection {
+	return &wsConnection{
+		ws:            ws,
+		notifications: make(chan interface{}, notificationBufferSize),
+		quit:          make(chan struct{}),
+		closeOnce:     &sync.Once{},
+	}
+}
+
+// notify queues the notification without blocking. A client which does not keep up
+// with its notifications is disconnected.
+func (c *wsConnection) notify(notification interface{}) {
+	select {
+	case c.notifications <- notification:
+	case <-c.quit:
+	default:
+		logger.Warnf("Websocket client %v is too slow to receive notifications, disconnecting", c.ws.Request().RemoteAddr)
+		c.close()
+	}
+}
+
+func (c *wsConnection) writeLoop() {
+	for {
+		select {
+		case <-c.quit:
+			return
+		case notification := <-c.notifications:
+			if err := websocket.JSON.Send(c.ws, notification); err != nil {
+				c.close()
+				return
+			}
+		}
+	}
+}
+
+func (c *wsConnection) close() {
+	c.closeOnce.Do(func() {
+		close(c.quit)
+		c.ws.Close()
+	})
+}
+
+// wsPipe feeds the websocket messages not handled by the SubscriptionManager to the
+// JSON-RPC codec, while the responses are written to the websocket directly
+type wsPipe struct {
+	reader *io.PipeReader
+	ws     *websocket.Conn
+}
+
+func (p *wsPipe) Read(b []byte) (int, error) {
+	return p.reader.Read(b)
+}
+
+func (p *wsPipe) Write(b []byte) (int, error) {
+	return p.ws.Write(b)
+}
+
+func (p *wsPipe) Close() error {
+	p.reader.Close()
+	return p.ws.Close()
+}
+
+//
+// SubscriptionManager pushes the chain events to the websocket clients
+//
+type SubscriptionManager struct {
+	mu *sync.Mutex
+
+	service          *ThetaRPCService
+	subscriptions    map[string]*subscription
+	lastValidatorSet *score.ValidatorSet
+	txStatusLifetime time.Duration
+}
+
+func NewSubscriptionManager(service *ThetaRPCService) *SubscriptionManager {
+	txStatusLifetime := time.Duration(viper.GetInt(scom.CfgRPCTxStatusSubscriptionLifetimeSecs)) * time.Second
+	if txStatusLifetime <= 0 {
+		txStatusLifetime = txTimeout
+	}
+	return &SubscriptionManager{
+		mu:               &sync.Mutex{},
+		service:          service,
+		subscriptions:    make(map[string]*subscription),
+		txStatusLifetime: txStatusLifetime,
+	}
+}
+
+// ServeWebsocket serves the JSON-RPC requests of a websocket connection, and handles
+// the subscription requests in place since they are bound to the connection.
+func (sm *SubscriptionManager) ServeWebsocket(ws *websocket.Conn) {
+	conn := newWSConnection(ws)
+	go conn.writeLoop()
+
+	pr, pw := io.Pipe()
+	codecDone := make(chan struct{})
+	go func() {
+		handler := sm.service.handler
+		handler.ServeCodec(jsonrpc2.NewServerCodec(&wsPipe{reader: pr, ws: ws}, handler))
+		close(codecDone)
+	}()
+
+	for {
+		var msg []byte
+		if err := websocket.Message.Receive(ws, &msg); err != nil {
+			break
+		}
+		if sm.handleRequest(conn, msg) {
+			continue
+		}
+		if _, err := pw.Write(msg); err != nil {
+			break
+		}
+	}
+
+	pw.Close()
+	<-codecDone
+	sm.removeConnection(conn)
+	conn.close()
+}
+
+type wsRequest struct {
+	ID     *json.RawMessage `json:"id"`
+	Method string           `json:"method"`
+	Params json.RawMessage  `json:"params"`
+}
+
+type wsError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type wsResponse struct {
+	Version string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Result  interface{}      `json:"result,omitempty"`
+	Error   *wsError         `json:"error,omitempty"`
+}
+
+type wsNotification struct {
+	Version string                   `json:"jsonrpc"`
+	Method  string                   `json:"method"`
+	Params  SubscriptionNotification `json:"params"`
+}
+
+// handleRequest handles the subscription requests, and returns false for the other requests.
+func (sm *SubscriptionManager) handleRequest(conn *wsConnection, msg []byte) bool {
+	req := &wsRequest{}
+	if err := json.Unmarshal(msg, req); err != nil {
+		return false // e.g. batch requests, leave them to the codec
+	}
+	if req.Method != subscribeMethod && req.Method != unsubscribeMethod {
+		return false
+	}
+
+	var result interface{}
+	var err error
+	if req.Method == subscribeMethod {
+		args := &SubscribeArgs{}
+		if err = unmarshalParams(req.Params, args); err == nil {
+			result, err = sm.subscribe(conn, args)
+		}
+	} else {
+		args := &UnsubscribeArgs{}
+		if err = unmarshalParams(req.Params, args); err == nil {
+			result, err = sm.unsubscribe(conn, args)
+		}
+	}
+
+	if req.ID == nil {
+		return true // notification, no response expected
+	}
+	resp := wsResponse{Version: "2.0", ID: req.ID, Result: result}
+	if err != nil {
+		resp.Result = nil
+		resp.Error = &wsError{Code: jsonrpcInvalidParamsCode, Message: err.Error()}
+	}
+	if err := websocket.JSON.Send(conn.ws, resp); err != nil {
+		conn.close()
+	}
+	return true
+}
+
+// unmarshalParams accepts both the by-name params, and the positional params with a single element
+func unmarshalParams(params json.RawMessage, args interface{}) error {
+	if len(params) == 0 {
+		return errors.New("Missing params")
+	}
+	if params[0] == '[' {
+		list := []json.RawMessage{}
+		if err := json.Unmarshal(params, &list); err != nil {
+			return err
+		}
+		if len(list) != 1 {
+			return errors.New("Expected exactly one param")
+		}
+		params = list[0]
+	}
+	return json.Unmarshal(params, args)
+}
+
+func (sm *SubscriptionManager) subscribe(conn *wsConnection, args *SubscribeArgs) (string, error) {
+	sub := &subscription{
+		id:   newSubscriptionID(),
+		kind: args.Type,
+		conn: conn,
+	}
+
+	switch args.Type {
+	case SubscriptionTypeNewBlocks, SubscriptionTypeValidatorSetChanges:
+	case SubscriptionTypeTxStatus:
+		if args.TxHash == "" {
+			return "", errors.New("Transanction hash must be specified")
+		}
+		sub.txHash = common.HexToHash(args.TxHash)
+		sub.created = time.Now()
+	case SubscriptionTypeLogs:
+		sub.logFilter = &LogFilter{
+			Addresses: args.Addresses,
+			Topics:    args.Topics,
+		}
+	default:
+		return "", fmt.Errorf("Unknown subscription type: %v", args.Type)
+	}
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	numSubs := 0
+	for _, s := range sm.subscriptions {
+		if s.conn == conn {
+			numSubs++
+		}
+	}
+	if numSubs >= maxSubscriptionsPerConnection {
+		return "", fmt.Errorf("Too many subscriptions, max: %v", maxSubscriptionsPerConnection)
+	}
+	sm.subscriptions[sub.id] = sub
+
+	logger.Debugf("New subscription, id: %v, type: %v", sub.id, sub.kind)
+
+	return sub.id, nil
+}
+
+func (sm *SubscriptionManager) unsubscribe(conn *wsConnection, args *UnsubscribeArgs) (bool, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	sub, ok := sm.subscriptions[args.SubscriptionID]
+	if !ok || sub.conn != conn {
+		return false, nil
+	}
+	delete(sm.subscriptions, sub.id)
+	return true, nil
+}
+
+func (sm *SubscriptionManager) removeConnection(conn *wsConnection) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	for id, sub := range sm.subscriptions {
+		if sub.conn == conn {
+			delete(sm.subscriptions, id)
+		}
+	}
+}
+
+func (sm *SubscriptionManager) getSubscriptions(kind string) []*subscription {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	subs := []*subscription{}
+	for _, sub := range sm.subscriptions {
+		if sub.kind == kind {
+			subs = append(subs, sub)
+		}
+	}
+	return subs
+}
+
+func (sm *SubscriptionManager) notify(sub *subscription, result interface{}) {
+	sub.conn.notify(wsNotification{
+		Version: "2.0",
+		Method:  notificationMethod,
+		Params: SubscriptionNotification{
+			SubscriptionID: sub.id,
+			Result:         result,
+		},
+	})
+}
+
+// OnFinalizedBlock notifies the subscribers of the new blocks, logs and validator set changes.
+func (sm *SubscriptionManager) OnFinalizedBlock(block *score.Block) {
+	sm.notifyNewBlock(block)
+	sm.notifyLogs(block)
+	sm.notifyValidatorSetChange(block)
+}
+
+func (sm *SubscriptionManager) notifyNewBlock(block *score.Block) {
+	subs := sm.getSubscriptions(SubscriptionTypeNewBlocks)
+	if len(subs) == 0 {
+		return
+	}
+
+	result := &GetBlockResult{}
+	if err := sm.service.GetBlock(&GetBlockArgs{Hash: block.Hash()}, result); err != nil || result.GetBlockResultInner == nil {
+		logger.Warnf("Failed to load finalized block %v for subscriptions: %v", block.Hash().Hex(), err)
+		return
+	}
+	for _, sub := range subs {
+		sm.notify(sub, result.GetBlockResultInner)
+	}
+}
+
+func (sm *SubscriptionManager) notifyLogs(block *score.Block) {
+	subs := sm.getSubscriptions(SubscriptionTypeLogs)
+	if len(subs) == 0 {
+		return
+	}
+
+	blockHash := block.Hash()
+	for _, rawTx := range block.Txs {
+		txHash := crypto.Keccak256Hash(rawTx)
+		receipt, found := sm.service.chain.FindTxReceiptByHash(blockHash, txHash)
+		if !found {
+			continue
+		}
+		for idx, log := range receipt.Logs {
+			var entry *LogEntry
+			for _, sub := range subs {
+				if !sub.logFilter.Matches(log) {
+					continue
+				}
+				if entry == nil {
+					entry = newLogEntry(log, txHash, idx, blockHash, block.Height)
+				}
+				sm.notify(sub, entry)
+			}
+		}
+	}
+}
+
+func (sm *SubscriptionManager) notifyValidatorSetChange(block *score.Block) {
+	blockHash := block.Hash()
+	vs := sm.service.consensus.GetValidatorManager().GetValidatorSet(blockHash)
+	if vs == nil {
+		return
+	}
+
+	sm.mu.Lock()
+	lastValidatorSet := sm.lastValidatorSet
+	sm.lastValidatorSet = vs
+	sm.mu.Unlock()
+
+	if lastValidatorSet == nil || lastValidatorSet.Equals(vs) {
+		return
+	}
+
+	notification := &ValidatorSetChangeNotification{
+		BlockHash:    blockHash,
+		BlockHeight:  common.JSONUint64(block.Height),
+		ValidatorSet: ValidatorSet{Dynasty: vs.Dynasty()},
+	}
+	notification.ValidatorSet.Validators = append(notification.ValidatorSet.Validators, vs.Validators()...)
+
+	for _, sub := range sm.getSubscriptions(SubscriptionTypeValidatorSetChanges) {
+		sm.notify(sub, notification)
+	}
+}
+
+// PollTxStatuses notifies the subscribers of the txs whose status has changed. The
+// subscription ends once the tx is finalized or abandoned, or with an expired notification
+// after the configured lifetime.
+func (sm *SubscriptionManager) PollTxStatuses() {
+	for _, sub := range sm.getSubscriptions(SubscriptionTypeTxStatus) {
+		notification := sm.getTxStatus(sub.txHash)
+		if notification.Status != TxStatusNotFound && notification.Status != sub.lastTxStatus {
+			sub.lastTxStatus = notification.Status
+			sm.notify(sub, notification)
+		}
+
+		done := notification.Status == TxStatusFinalized || notification.Status == TxStatusAbandoned
+		if !done && time.Since(sub.created) >= sm.txStatusLifetime {
+			sm.notify(sub, &TxStatusNotification{TxHash: sub.txHash, Status: TxStatusExpired})
+			done = true
+		}
+		if done {
+			sm.mu.Lock()
+			delete(sm.subscriptions, sub.id)
+			sm.mu.Unlock()
+		}
+	}
+}
+
+func (sm *SubscriptionManager) getTxStatus(txHash common.Hash) *TxStatusNotification {
+	notification := &TxStatusNotification{TxHash: txHash}
+
+	_, block, found := sm.service.chain.FindTxByHash(txHash)
+	if !found {
+		txStatus, exists := sm.service.mempool.GetTransactionStatus(strings.TrimPrefix(txHash.Hex(), "0x"))
+		if !exists {
+			notification.Status = TxStatusNotFound
+		} else if txStatus == smp.TxStatusAbandoned {
+			notification.Status = TxStatusAbandoned
+		} else {
+			notification.Status = TxStatusPending
+		}
+		return notification
+	}
+
+	notification.BlockHash = block.Hash()
+	notification.BlockHeight = common.JSONUint64(block.Height)
+	if block.Status.IsFinalized() {
+		notification.Status = TxStatusFinalized
+	} else {
+		notification.Status = TxStatusIncluded
+	}
+	return notification
+}
+
+func newSubscriptionID() string {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		logger.Panic(err)
+	}
+	return "0x" + hex.EncodeToString(id)
+}
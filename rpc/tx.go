@@ -114,11 +114,14 @@ func (t *ThetaRPCService) txCallback() {
 				}
 			}
 
+			t.subscriptions.OnFinalizedBlock(block)
+
 			logger.Infof("Done processing finalized block, height=%v", block.Height)
 		case <-timer.C:
 			logger.Debugf("txCallbackManager.Trim()")
 
 			txCallbackManager.Trim()
+			t.subscriptions.PollTxStatuses()
 
 			logger.Debugf("Done txCallbackManager.Trim()")
 		}
@@ -0,0 +1,85 @@
+package signer
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+)
+
+const (
+	authHeader        = "Authorization"
+	authScheme        = "Bearer "
+	authTokenNumBytes = 32
+)
+
+// LoadAuthToken reads the token authenticating the node to the signer from the file. If the file
+// does not exist and create is true, a random token is generated and written to the file.
+func LoadAuthToken(filePath string, create bool) (string, error) {
+	raw, err := ioutil.ReadFile(filePath)
+	if os.IsNotExist(err) && create {
+		token := make([]byte, authTokenNumBytes)
+		if _, err := rand.Read(token); err != nil {
+			return "", err
+		}
+		encoded := hex.EncodeToString(token)
+		if err := os.MkdirAll(path.Dir(filePath), 0700); err != nil {
+			return "", err
+		}
+		if err := writeFileSync(filePath, []byte(encoded+"\n")); err != nil {
+			return "", err
+		}
+		logger.Infof("Generated the signer auth token at %v", filePath)
+		return encoded, nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	token := strings.TrimSpace(string(raw))
+	if token == "" {
+		return "", fmt.Errorf("the auth token file %v is empty", filePath)
+	}
+	return token, nil
+}
+
+// authenticate rejects the requests without the auth token of the server, if it has one.
+func (s *Server) authenticate(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.authToken != "" {
+			token := strings.TrimPrefix(r.Header.Get(authHeader), authScheme)
+			if subtle.ConstantTimeCompare([]byte(token), []byte(s.authToken)) != 1 {
+				logger.Warnf("Rejected unauthenticated request from %v", r.RemoteAddr)
+				writeJSON(w, http.StatusUnauthorized, signResult{Error: "invalid auth token"})
+				return
+			}
+		}
+		handler(w, r)
+	}
+}
+
+// checkListenAddress only allows TCP listeners on a loopback address, with an auth token. A signer
+// reachable from other hosts would sign for anyone who can connect to it.
+func checkListenAddress(network, address, authToken string) error {
+	if network != "tcp" {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return fmt.Errorf("refused to listen on %v, TCP listeners are limited to loopback addresses, please use a unix socket", address)
+	}
+	if authToken == "" {
+		return fmt.Errorf("an auth token is required to listen on %v", address)
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package signer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+
+	"github.com/thetatoken/theta/common"
+)
+
+// ErrDoubleSign is returned when signing would produce a vote or a proposal conflicting with one signed earlier.
+var ErrDoubleSign = errors.New("refused to double sign")
+
+// signState records the last vote and proposal signed with the key.
+type signState struct {
+	VoteHeight        uint64      `json:"vote_height"`
+	VoteEpoch         uint64      `json:"vote_epoch"`
+	VoteBlock         common.Hash `json:"vote_block"`
+	ProposalHeight    uint64      `json:"proposal_height"`
+	ProposalEpoch     uint64      `json:"proposal_epoch"`
+	ProposalSignBytes common.Hash `json:"proposal_sign_bytes"` // hash of the sign bytes of the proposed header
+}
+
+// Guard protects a validator key from double signing. A vote conflicts with the last vote if it is
+// for a lower height, or for a different block at the same height. A proposal conflicts with the last
+// proposal if it is for an earlier epoch, or is a different block in the same epoch. Re-signing the
+// same vote or proposal is allowed, e.g. the consensus engine repeats its last vote in later epochs.
+type Guard struct {
+	mu       *sync.Mutex
+	filePath string
+	state    signState
+}
+
+// NewGuard creates a guard which persists its state to the given file, so the protection survives
+// restarts. The state is kept in memory only if the file path is empty.
+func NewGuard(filePath string) (*Guard, error) {
+	g := &Guard{
+		mu:       &sync.Mutex{},
+		filePath: filePath,
+	}
+	if filePath == "" {
+		return g, nil
+	}
+
+	raw, err := ioutil.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return g, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, &g.state); err != nil {
+		return nil, fmt.Errorf("failed to parse the sign state %v: %v", filePath, err)
+	}
+	return g, nil
+}
+
+// CheckVote checks the vote against the last signed vote and records it if it does not conflict.
+func (g *Guard) CheckVote(height uint64, epoch uint64, block common.Hash) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	last := g.state
+	if last.VoteHeight != 0 || !last.VoteBlock.IsEmpty() {
+		if height < last.VoteHeight {
+			return fmt.Errorf("%w: vote height %v is lower than the last signed vote height %v", ErrDoubleSign, height, last.VoteHeight)
+		}
+		if height == last.VoteHeight && block != last.VoteBlock {
+			return fmt.Errorf("%w: already signed a vote for block %v at height %v, refused block %v",
+				ErrDoubleSign, last.VoteBlock.Hex(), height, block.Hex())
+		}
+	}
+
+	g.state.VoteHeight = height
+	g.state.VoteEpoch = epoch
+	g.state.VoteBlock = block
+	return g.save(last)
+}
+
+// CheckProposal checks the proposal against the last signed proposal and records it if it does not conflict.
+func (g *Guard) CheckProposal(height uint64, epoch uint64, signBytesHash common.Hash) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	last := g.state
+	if !last.ProposalSignBytes.IsEmpty() {
+		if epoch < last.ProposalEpoch {
+			return fmt.Errorf("%w: proposal epoch %v is earlier than the last signed proposal epoch %v", ErrDoubleSign, epoch, last.ProposalEpoch)
+		}
+		if epoch == last.ProposalEpoch && signBytesHash != last.ProposalSignBytes {
+			return fmt.Errorf("%w: already signed a different proposal at height %v in epoch %v",
+				ErrDoubleSign, last.ProposalHeight, epoch)
+		}
+	}
+
+	g.state.ProposalHeight = height
+	g.state.ProposalEpoch = epoch
+	g.state.ProposalSignBytes = signBytesHash
+	return g.save(last)
+}
+
+// save persists the state, and reverts to the previous state if it fails. The state is written before
+// the signature is released, so a crash never leaves a signature which is not recorded.
+func (g *Guard) save(previous signState) error {
+	if g.filePath == "" {
+		return nil
+	}
+
+	raw, err := json.MarshalIndent(g.state, "", "  ")
+	if err == nil {
+		err = os.MkdirAll(path.Dir(g.filePath), 0700)
+	}
+	if err == nil {
+		err = writeFileSync(g.filePath, raw)
+	}
+	if err != nil {
+		g.state = previous
+		return fmt.Errorf("failed to persist the sign state: %v", err)
+	}
+	return nil
+}
+
+// writeFileSync atomically replaces the file with the data. The data is flushed to the disk before
+// the rename, and the directory is flushed after it, so the new content survives a power loss.
+func writeFileSync(filePath string, data []byte) error {
+	tmpPath := filePath + ".tmp"
+	file, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err = file.Write(data); err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := os.Rename(tmpPath, filePath); err != nil {
+		return err
+	}
+
+	dir, err := os.Open(path.Dir(filePath))
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
@@ -0,0 +1,102 @@
+package signer
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/ledger/types"
+	"github.com/thetatoken/theta/rlp"
+
+	score "github.com/thetatoken/thetasubchain/core"
+	ethtypes "github.com/thetatoken/thetasubchain/eth/core/types"
+)
+
+var _ score.Signer = (*LocalSigner)(nil)
+
+// LocalSigner signs with a private key held in the process.
+type LocalSigner struct {
+	privKey *crypto.PrivateKey
+	address common.Address
+	guard   *Guard
+}
+
+// NewLocalSigner creates a signer for the private key. The guard is optional, without it the signer
+// relies on the consensus engine alone to avoid double signing.
+func NewLocalSigner(privKey *crypto.PrivateKey, guard *Guard) *LocalSigner {
+	return &LocalSigner{
+		privKey: privKey,
+		address: privKey.PublicKey().Address(),
+		guard:   guard,
+	}
+}
+
+// Address returns the address of the key.
+func (ls *LocalSigner) Address() common.Address {
+	return ls.address
+}
+
+// SignVote signs the vote.
+func (ls *LocalSigner) SignVote(vote *score.Vote) error {
+	if vote.ID != ls.address {
+		return fmt.Errorf("vote of %v cannot be signed with the key of %v", vote.ID.Hex(), ls.address.Hex())
+	}
+	if ls.guard != nil {
+		if err := ls.guard.CheckVote(vote.Height, vote.Epoch, vote.Block); err != nil {
+			return err
+		}
+	}
+	sig, err := ls.privKey.Sign(vote.SignBytes())
+	if err != nil {
+		return err
+	}
+	vote.SetSignature(sig)
+	return nil
+}
+
+// SignProposal signs the header of the proposed block.
+func (ls *LocalSigner) SignProposal(block *score.Block) error {
+	sig, err := ls.signHeader(block.SignBytes())
+	if err != nil {
+		return err
+	}
+	block.SetSignature(sig)
+	return nil
+}
+
+// signHeader checks the header sign bytes against the guard and signs them.
+func (ls *LocalSigner) signHeader(signBytes common.Bytes) (*crypto.Signature, error) {
+	header := &score.BlockHeader{}
+	if err := rlp.DecodeBytes(signBytes, header); err != nil {
+		return nil, fmt.Errorf("failed to decode the block header: %v", err)
+	}
+	if header.Proposer != ls.address {
+		return nil, fmt.Errorf("block proposed by %v cannot be signed with the key of %v", header.Proposer.Hex(), ls.address.Hex())
+	}
+	if ls.guard != nil {
+		if err := ls.guard.CheckProposal(header.Height, header.Epoch, crypto.Keccak256Hash(signBytes)); err != nil {
+			return nil, err
+		}
+	}
+	return ls.privKey.Sign(signBytes)
+}
+
+// SignTx signs a native transaction.
+func (ls *LocalSigner) SignTx(chainID string, tx types.Tx) (*crypto.Signature, error) {
+	return ls.privKey.Sign(tx.SignBytes(chainID))
+}
+
+// SignEthTx signs an Ethereum transaction.
+func (ls *LocalSigner) SignEthTx(chainID *big.Int, tx *ethtypes.Transaction) (*ethtypes.Transaction, error) {
+	signer := ethtypes.LatestSignerForChainID(chainID)
+	sig, err := ls.signEthTxHash(signer, tx)
+	if err != nil {
+		return nil, err
+	}
+	return tx.WithSignature(signer, sig)
+}
+
+func (ls *LocalSigner) signEthTxHash(signer ethtypes.Signer, tx *ethtypes.Transaction) ([]byte, error) {
+	return crypto.Sign(signer.Hash(tx).Bytes(), crypto.PrivKeyToECDSA(ls.privKey))
+}
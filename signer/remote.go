@@ -0,0 +1,198 @@
+package signer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/ledger/types"
+
+	score "github.com/thetatoken/thetasubchain/core"
+	ethtypes "github.com/thetatoken/thetasubchain/eth/core/types"
+	stypes "github.com/thetatoken/thetasubchain/ledger/types"
+)
+
+var _ score.Signer = (*RemoteSigner)(nil)
+
+// RemoteSigner delegates signing to an external signing service, e.g. the thetasubsigner daemon, so
+// the validator key never enters the node process. Double-sign protection is enforced by the service.
+type RemoteSigner struct {
+	url       string
+	authToken string
+	client    *http.Client
+	address   common.Address
+}
+
+// NewRemoteSigner connects to the signing service at the given address, either "unix://<socket path>"
+// or "<host>:<port>", and fetches the address of its key. The auth token is sent with each request
+// if it is not empty.
+func NewRemoteSigner(address string, authToken string, timeout time.Duration) (*RemoteSigner, error) {
+	network, dialAddress := parseAddress(address)
+	dialer := &net.Dialer{Timeout: timeout}
+	rs := &RemoteSigner{
+		authToken: authToken,
+		client: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					return dialer.DialContext(ctx, network, dialAddress)
+				},
+			},
+		},
+	}
+	if network == "unix" {
+		rs.url = "http://signer" // the host is ignored by the dialer
+	} else {
+		rs.url = "http://" + dialAddress
+	}
+
+	result := addressResult{}
+	req, err := http.NewRequest(http.MethodGet, rs.url+pathAddress, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := rs.do(req, &result); err != nil {
+		return nil, fmt.Errorf("failed to get the address from the remote signer %v: %v", address, err)
+	}
+	rs.address = result.Address
+	return rs, nil
+}
+
+// Address returns the address of the key held by the signing service.
+func (rs *RemoteSigner) Address() common.Address {
+	return rs.address
+}
+
+// SignVote signs the vote.
+func (rs *RemoteSigner) SignVote(vote *score.Vote) error {
+	sig, err := rs.sign(pathSignVote, signVoteArgs{
+		Block:           vote.Block,
+		Height:          vote.Height,
+		MainchainHeight: vote.MainchainHeight,
+		Epoch:           vote.Epoch,
+		ID:              vote.ID,
+	})
+	if err != nil {
+		return err
+	}
+	signature, err := crypto.SignatureFromBytes(sig)
+	if err != nil {
+		return err
+	}
+	if !signature.Verify(vote.SignBytes(), rs.address) {
+		return fmt.Errorf("invalid vote signature from the remote signer")
+	}
+	vote.SetSignature(signature)
+	return nil
+}
+
+// SignProposal signs the header of the proposed block.
+func (rs *RemoteSigner) SignProposal(block *score.Block) error {
+	signBytes := block.SignBytes()
+	sig, err := rs.sign(pathSignProposal, signBytesArgs{Data: signBytes})
+	if err != nil {
+		return err
+	}
+	signature, err := crypto.SignatureFromBytes(sig)
+	if err != nil {
+		return err
+	}
+	if !signature.Verify(signBytes, rs.address) {
+		return fmt.Errorf("invalid block signature from the remote signer")
+	}
+	block.SetSignature(signature)
+	return nil
+}
+
+// SignTx signs a native transaction.
+func (rs *RemoteSigner) SignTx(chainID string, tx types.Tx) (*crypto.Signature, error) {
+	raw, err := stypes.TxToBytes(tx)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := rs.sign(pathSignTx, signBytesArgs{ChainID: chainID, Data: raw})
+	if err != nil {
+		return nil, err
+	}
+	signature, err := crypto.SignatureFromBytes(sig)
+	if err != nil {
+		return nil, err
+	}
+	if !signature.Verify(tx.SignBytes(chainID), rs.address) {
+		return nil, fmt.Errorf("invalid tx signature from the remote signer")
+	}
+	return signature, nil
+}
+
+// SignEthTx signs an Ethereum transaction.
+func (rs *RemoteSigner) SignEthTx(chainID *big.Int, tx *ethtypes.Transaction) (*ethtypes.Transaction, error) {
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	sig, err := rs.sign(pathSignEthTx, signBytesArgs{ChainID: chainID.String(), Data: raw})
+	if err != nil {
+		return nil, err
+	}
+	signer := ethtypes.LatestSignerForChainID(chainID)
+	signedTx, err := tx.WithSignature(signer, sig)
+	if err != nil {
+		return nil, err
+	}
+	sender, err := ethtypes.Sender(signer, signedTx)
+	if err != nil {
+		return nil, err
+	}
+	if sender != rs.address {
+		return nil, fmt.Errorf("invalid eth tx signature from the remote signer, signed by %v", sender.Hex())
+	}
+	return signedTx, nil
+}
+
+func (rs *RemoteSigner) sign(path string, args interface{}) (common.Bytes, error) {
+	body, err := json.Marshal(args)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, rs.url+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	result := signResult{}
+	if err := rs.do(req, &result); err != nil {
+		return nil, err
+	}
+	return result.Signature, nil
+}
+
+func (rs *RemoteSigner) do(req *http.Request, result interface{}) error {
+	if rs.authToken != "" {
+		req.Header.Set(authHeader, authScheme+rs.authToken)
+	}
+	resp, err := rs.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		failure := signResult{}
+		if err := json.NewDecoder(resp.Body).Decode(&failure); err != nil || failure.Error == "" {
+			return fmt.Errorf("remote signer returned %v", resp.Status)
+		}
+		if failure.DoubleSign {
+			return fmt.Errorf("%w: %v", ErrDoubleSign, failure.Error)
+		}
+		return fmt.Errorf("remote signer: %v", failure.Error)
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
@@ -0,0 +1,62 @@
+package signer
+
+import (
+	"math/big"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/ledger/types"
+
+	ethtypes "github.com/thetatoken/thetasubchain/eth/core/types"
+)
+
+func TestRemoteSignerVerifiesTxSignatures(t *testing.T) {
+	assert := assert.New(t)
+
+	privKey, _, err := crypto.TEST_GenerateKeyPairWithSeed("remote_signer")
+	assert.Nil(err)
+	server := NewServer(NewLocalSigner(privKey, nil), "", "")
+	httpServer := httptest.NewServer(server.server.Handler)
+	defer httpServer.Close()
+
+	rs, err := NewRemoteSigner(strings.TrimPrefix(httpServer.URL, "http://"), "", time.Second)
+	assert.Nil(err)
+	assert.Equal(privKey.PublicKey().Address(), rs.Address())
+
+	chainID := "test_chain_id"
+	receiver := common.HexToAddress("0x2E833968E5bB786Ae419c4d13189fB081Cc43bab")
+	tx := &types.SendTx{
+		Fee:     types.NewCoins(0, 1),
+		Inputs:  []types.TxInput{{Address: rs.Address(), Coins: types.NewCoins(0, 2), Sequence: 1}},
+		Outputs: []types.TxOutput{{Address: receiver, Coins: types.NewCoins(0, 1)}},
+	}
+	sig, err := rs.SignTx(chainID, tx)
+	assert.Nil(err)
+	assert.True(sig.Verify(tx.SignBytes(chainID), rs.Address()))
+
+	ethChainID := big.NewInt(360777)
+	ethTx := ethtypes.NewTx(&ethtypes.LegacyTx{
+		Nonce:    1,
+		To:       &receiver,
+		Value:    big.NewInt(1),
+		Gas:      21000,
+		GasPrice: big.NewInt(1),
+	})
+	signedEthTx, err := rs.SignEthTx(ethChainID, ethTx)
+	assert.Nil(err)
+	sender, err := ethtypes.Sender(ethtypes.LatestSignerForChainID(ethChainID), signedEthTx)
+	assert.Nil(err)
+	assert.Equal(rs.Address(), sender)
+
+	// The signatures made with a key other than the one reported by the signing service are rejected
+	rs.address = receiver
+	_, err = rs.SignTx(chainID, tx)
+	assert.NotNil(err)
+	_, err = rs.SignEthTx(ethChainID, ethTx)
+	assert.NotNil(err)
+}
@@ -0,0 +1,270 @@
+package signer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/thetatoken/theta/common"
+
+	score "github.com/thetatoken/thetasubchain/core"
+	ethtypes "github.com/thetatoken/thetasubchain/eth/core/types"
+	stypes "github.com/thetatoken/thetasubchain/ledger/types"
+)
+
+var logger *log.Entry = log.WithFields(log.Fields{"prefix": "signer"})
+
+const (
+	pathAddress      = "/address"
+	pathSignVote     = "/sign/vote"
+	pathSignProposal = "/sign/proposal"
+	pathSignTx       = "/sign/tx"
+	pathSignEthTx    = "/sign/eth_tx"
+
+	maxRequestBodySize = 4 * 1024 * 1024
+)
+
+type addressResult struct {
+	Address common.Address `json:"address"`
+}
+
+type signVoteArgs struct {
+	Block           common.Hash    `json:"block"`
+	Height          uint64         `json:"height"`
+	MainchainHeight uint64         `json:"mainchain_height"`
+	Epoch           uint64         `json:"epoch"`
+	ID              common.Address `json:"id"`
+}
+
+// signBytesArgs carries the sign bytes of a block header, or an encoded transaction.
+type signBytesArgs struct {
+	ChainID string       `json:"chain_id,omitempty"`
+	Data    common.Bytes `json:"data"`
+}
+
+type signResult struct {
+	Signature  common.Bytes `json:"signature,omitempty"`
+	Error      string       `json:"error,omitempty"`
+	DoubleSign bool         `json:"double_sign,omitempty"`
+}
+
+// Server serves the signing requests of a RemoteSigner with a local key. All the requests go
+// through the double-sign guard of the local signer, and need to carry the auth token if the
+// server has one.
+type Server struct {
+	signer    *LocalSigner
+	address   string
+	authToken string
+	server    *http.Server
+
+	// Life cycle
+	wg     *sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewServer creates a signing server listening on the given address, either "unix://<socket path>"
+// or "<host>:<port>". TCP listeners need to be on a loopback address, and need an auth token.
+func NewServer(signer *LocalSigner, address string, authToken string) *Server {
+	s := &Server{
+		signer:    signer,
+		address:   address,
+		authToken: authToken,
+		wg:        &sync.WaitGroup{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(pathAddress, s.authenticate(s.serveAddress))
+	mux.HandleFunc(pathSignVote, s.authenticate(s.serveSignVote))
+	mux.HandleFunc(pathSignProposal, s.authenticate(s.serveSignProposal))
+	mux.HandleFunc(pathSignTx, s.authenticate(s.serveSignTx))
+	mux.HandleFunc(pathSignEthTx, s.authenticate(s.serveSignEthTx))
+	s.server = &http.Server{
+		Handler: mux,
+	}
+	return s
+}
+
+// Start starts serving the signing requests.
+func (s *Server) Start(ctx context.Context) error {
+	c, cancel := context.WithCancel(ctx)
+	s.ctx = c
+	s.cancel = cancel
+
+	network, address := parseAddress(s.address)
+	if err := checkListenAddress(network, address, s.authToken); err != nil {
+		return err
+	}
+	if network == "unix" {
+		os.Remove(address) // remove the stale socket left by an unclean shutdown
+	}
+	l, err := net.Listen(network, address)
+	if err != nil {
+		return err
+	}
+	if network == "unix" {
+		if err := os.Chmod(address, 0600); err != nil {
+			l.Close()
+			return err
+		}
+	}
+	logger.WithFields(log.Fields{"address": s.address, "signer": s.signer.Address().Hex()}).Info("Signer server started")
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		if err := s.server.Serve(l); err != nil && err != http.ErrServerClosed {
+			logger.Warnf("Signer server stopped: %v", err)
+		}
+	}()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		<-s.ctx.Done()
+		s.server.Close()
+	}()
+	return nil
+}
+
+// Stop notifies the server to stop without blocking.
+func (s *Server) Stop() {
+	s.cancel()
+}
+
+// Wait blocks until the server stops.
+func (s *Server) Wait() {
+	s.wg.Wait()
+}
+
+func (s *Server) serveAddress(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, addressResult{Address: s.signer.Address()})
+}
+
+func (s *Server) serveSignVote(w http.ResponseWriter, r *http.Request) {
+	args := signVoteArgs{}
+	if !readArgs(w, r, &args) {
+		return
+	}
+	vote := score.Vote{
+		Block:           args.Block,
+		Height:          args.Height,
+		MainchainHeight: args.MainchainHeight,
+		Epoch:           args.Epoch,
+		ID:              args.ID,
+	}
+	err := s.signer.SignVote(&vote)
+	if err != nil {
+		writeSignResult(w, nil, err)
+		return
+	}
+	logger.WithFields(log.Fields{"block": args.Block.Hex(), "height": args.Height, "epoch": args.Epoch}).Debug("Signed vote")
+	writeSignResult(w, vote.Signature.ToBytes(), nil)
+}
+
+func (s *Server) serveSignProposal(w http.ResponseWriter, r *http.Request) {
+	args := signBytesArgs{}
+	if !readArgs(w, r, &args) {
+		return
+	}
+	sig, err := s.signer.signHeader(args.Data)
+	if err != nil {
+		writeSignResult(w, nil, err)
+		return
+	}
+	logger.Debug("Signed proposal")
+	writeSignResult(w, sig.ToBytes(), nil)
+}
+
+func (s *Server) serveSignTx(w http.ResponseWriter, r *http.Request) {
+	args := signBytesArgs{}
+	if !readArgs(w, r, &args) {
+		return
+	}
+	tx, err := stypes.TxFromBytes(args.Data)
+	if err != nil {
+		writeSignResult(w, nil, fmt.Errorf("failed to decode the transaction: %v", err))
+		return
+	}
+	sig, err := s.signer.SignTx(args.ChainID, tx)
+	if err != nil {
+		writeSignResult(w, nil, err)
+		return
+	}
+	logger.WithFields(log.Fields{"tx": tx}).Debug("Signed transaction")
+	writeSignResult(w, sig.ToBytes(), nil)
+}
+
+func (s *Server) serveSignEthTx(w http.ResponseWriter, r *http.Request) {
+	args := signBytesArgs{}
+	if !readArgs(w, r, &args) {
+		return
+	}
+	chainID, ok := new(big.Int).SetString(args.ChainID, 10)
+	if !ok {
+		writeSignResult(w, nil, fmt.Errorf("invalid chain ID: %v", args.ChainID))
+		return
+	}
+	tx := &ethtypes.Transaction{}
+	if err := tx.UnmarshalBinary(args.Data); err != nil {
+		writeSignResult(w, nil, fmt.Errorf("failed to decode the transaction: %v", err))
+		return
+	}
+	sig, err := s.signer.signEthTxHash(ethtypes.LatestSignerForChainID(chainID), tx)
+	if err != nil {
+		writeSignResult(w, nil, err)
+		return
+	}
+	logger.WithFields(log.Fields{"chainID": chainID, "to": tx.To(), "nonce": tx.Nonce()}).Debug("Signed ETH transaction")
+	writeSignResult(w, sig, nil)
+}
+
+func readArgs(w http.ResponseWriter, r *http.Request, args interface{}) bool {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, signResult{Error: "POST required"})
+		return false
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodySize)).Decode(args); err != nil {
+		writeJSON(w, http.StatusBadRequest, signResult{Error: err.Error()})
+		return false
+	}
+	return true
+}
+
+func writeSignResult(w http.ResponseWriter, sig common.Bytes, err error) {
+	if err == nil {
+		writeJSON(w, http.StatusOK, signResult{Signature: sig})
+		return
+	}
+	if errors.Is(err, ErrDoubleSign) {
+		logger.WithFields(log.Fields{"error": err}).Warn("Refused to double sign")
+		writeJSON(w, http.StatusForbidden, signResult{Error: err.Error(), DoubleSign: true})
+		return
+	}
+	logger.WithFields(log.Fields{"error": err}).Warn("Failed to sign")
+	writeJSON(w, http.StatusBadRequest, signResult{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, result interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		logger.Debugf("Failed to write response: %v", err)
+	}
+}
+
+// parseAddress splits the signer address into the network and the address to listen on or dial.
+func parseAddress(address string) (string, string) {
+	if strings.HasPrefix(address, "unix://") {
+		return "unix", strings.TrimPrefix(address, "unix://")
+	}
+	return "tcp", strings.TrimPrefix(address, "tcp://")
+}
@@ -0,0 +1,31 @@
+package signer
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/thetatoken/theta/common"
+
+	score "github.com/thetatoken/thetasubchain/core"
+	"github.com/thetatoken/thetasubchain/eth/abi/bind"
+	ethtypes "github.com/thetatoken/thetasubchain/eth/core/types"
+)
+
+// NewTransactor creates the transact options which sign the contract transactions with the signer,
+// the counterpart of bind.NewKeyedTransactorWithChainID for keys that may live outside the process.
+func NewTransactor(signer score.Signer, chainID *big.Int) (*bind.TransactOpts, error) {
+	if chainID == nil {
+		return nil, bind.ErrNoChainID
+	}
+	keyAddr := signer.Address()
+	return &bind.TransactOpts{
+		From: keyAddr,
+		Signer: func(address common.Address, tx *ethtypes.Transaction) (*ethtypes.Transaction, error) {
+			if address != keyAddr {
+				return nil, bind.ErrNotAuthorized
+			}
+			return signer.SignEthTx(chainID, tx)
+		},
+		Context: context.Background(),
+	}, nil
+}
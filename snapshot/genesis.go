@@ -0,0 +1,245 @@
+package snapshot
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/ledger/types"
+	"github.com/thetatoken/theta/store/database"
+	"github.com/thetatoken/theta/store/database/backend"
+
+	scom "github.com/thetatoken/thetasubchain/common"
+	score "github.com/thetatoken/thetasubchain/core"
+	"github.com/thetatoken/thetasubchain/eth/abi"
+	"github.com/thetatoken/thetasubchain/interchain/contracts/predeployed"
+	slst "github.com/thetatoken/thetasubchain/ledger/state"
+	svm "github.com/thetatoken/thetasubchain/ledger/vm"
+)
+
+// GenerateGenesisSnapshot generates the genesis state of a subchain with the initial validator set, and the
+// chain registrar and token bank contracts predeployed.
+func GenerateGenesisSnapshot(mainchainID, subchainID string, validatorSet *score.ValidatorSet,
+	admin common.Address, fallbackReceiver common.Address) (database.Database, *slst.StoreView, *score.SnapshotMetadata, error) {
+
+	metadata := &score.SnapshotMetadata{}
+	genesisHeight := score.GenesisBlockHeight
+
+	db := backend.NewMemDatabase()
+	sv := slst.NewStoreView(0, common.Hash{}, db)
+
+	setInitialValidatorSet(subchainID, validatorSet, genesisHeight, sv)
+	if err := deployInitialSmartContracts(mainchainID, subchainID, admin, fallbackReceiver, sv); err != nil {
+		return nil, nil, nil, err
+	}
+
+	stateHash := sv.Hash()
+
+	genesisBlock := score.NewBlock()
+	genesisBlock.ChainID = subchainID
+	genesisBlock.Height = genesisHeight
+	genesisBlock.Epoch = genesisBlock.Height
+	genesisBlock.Parent = common.Hash{}
+	genesisBlock.StateHash = stateHash
+	genesisBlock.Timestamp = big.NewInt(time.Now().Unix())
+
+	metadata.TailTrio = score.SnapshotBlockTrio{
+		First:  score.SnapshotFirstBlock{},
+		Second: score.SnapshotSecondBlock{Header: genesisBlock.BlockHeader},
+		Third:  score.SnapshotThirdBlock{},
+	}
+
+	return db, sv, metadata, nil
+}
+
+// WriteGenesisSnapshot writes the genesis snapshot to file system.
+func WriteGenesisSnapshot(db database.Database, sv *slst.StoreView, metadata *score.SnapshotMetadata, genesisSnapshotFilePath string) error {
+	file, err := os.Create(genesisSnapshotFilePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	writer := bufio.NewWriter(file)
+	err = score.WriteMetadata(writer, metadata)
+	if err != nil {
+		return err
+	}
+	writeStoreView(sv, true, writer, db)
+	return writer.Flush()
+}
+
+func setInitialValidatorSet(subchainID string, validatorSet *score.ValidatorSet, genesisHeight uint64, sv *slst.StoreView) {
+	for _, v := range validatorSet.Validators() {
+		setInitialBalance(sv, v.Address, common.Big0) // need to create accounts with zero balances for the inital validators
+	}
+	subchainIDInt := scom.MapChainID(subchainID)
+	sv.UpdateValidatorSet(subchainIDInt, validatorSet)
+
+	hl := &types.HeightList{}
+	hl.Append(genesisHeight)
+	sv.UpdateValidatorSetUpdateTxHeightList(hl)
+	sv.Save()
+}
+
+func setInitialBalance(sv *slst.StoreView, address common.Address, tfuelBalance *big.Int) {
+	acc := &types.Account{
+		Address:  address,
+		Root:     common.Hash{},
+		CodeHash: types.EmptyCodeHash,
+		Balance: types.Coins{
+			ThetaWei: big.NewInt(0),
+			TFuelWei: tfuelBalance,
+		},
+	}
+	sv.SetAccount(acc.Address, acc)
+}
+
+func deployInitialSmartContracts(mainchainID, subchainID string, admin common.Address, fallbackReceiver common.Address, sv *slst.StoreView) error {
+	mainchainIDInt := scom.MapChainID(mainchainID)
+	deployer := common.Address{}
+
+	//
+	// Deploy the ChainRegistrar contract
+	//
+
+	sequence := 0
+	numMainchainBlockPerDynastyBigInt := big.NewInt(scom.NumMainchainBlocksPerDynasty)
+	dec18, _ := big.NewInt(0).SetString("1000000000000000000", 10)
+	initialCrossChainFee := big.NewInt(0).Mul(big.NewInt(10), dec18)
+	chainRegistrarContractAddr, err := deploySmartContract(subchainID, sv, addConstructorArgumentForChainRegistrarBytecode(predeployed.ChainRegistrarContractBytecode, numMainchainBlockPerDynastyBigInt, initialCrossChainFee, admin, fallbackReceiver),
+		deployer, sequence, slst.ChainRegistrarContractAddressKey())
+	if err != nil {
+		return fmt.Errorf("failed to deploy the chain registrar smart contract (sequence = %v): %v", sequence, err)
+	}
+
+	tokenBanks := []struct {
+		name     string
+		bytecode string
+		key      common.Bytes
+	}{
+		{"TFuel", predeployed.TFuelTokenBankContractBytecode, slst.TFuelTokenBankContractAddressKey()},
+		{"TNT20", predeployed.TNT20TokenBankContractBytecode, slst.TNT20TokenBankContractAddressKey()},
+		{"TNT721", predeployed.TNT721TokenBankContractBytecode, slst.TNT721TokenBankContractAddressKey()},
+		{"TNT1155", predeployed.TNT1155TokenBankContractBytecode, slst.TNT1155TokenBankContractAddressKey()},
+	}
+	for _, tb := range tokenBanks {
+		sequence += 1
+		_, err = deploySmartContract(subchainID, sv, addConstructorArgumentForTokenBankBytecode(tb.bytecode, mainchainIDInt, chainRegistrarContractAddr), deployer, sequence, tb.key)
+		if err != nil {
+			return fmt.Errorf("failed to deploy the %v token bank smart contract (sequence = %v): %v", tb.name, sequence, err)
+		}
+	}
+	return nil
+}
+
+// Reference: https://docs.blockscout.com/for-users/abi-encoded-constructor-arguments
+func addConstructorArgumentForChainRegistrarBytecode(contractBytecode string, numBlocksPerDynasty, crossChainFee *big.Int, admin common.Address, fallbackReceiver common.Address) string {
+	rawABI := `[
+		{
+		"inputs": [
+			{
+			"internalType": "uint256",
+			"name": "numBlocksPerDynasty_",
+			"type": "uint256"
+			},
+			{
+				"internalType": "uint256",
+				"name": "crossChainFee_",
+				"type": "uint256"
+			},
+			{
+				"internalType": "uint256",
+				"name": "admin_",
+				"type": "address"
+			},
+			{
+				"internalType": "uint256",
+				"name": "fallbackReceiver_",
+				"type": "address"
+			}
+		],
+		"stateMutability": "nonpayable",
+		"type": "constructor"
+		}
+    ]`
+	parsed, err := abi.JSON(strings.NewReader(rawABI))
+	if err != nil {
+		panic(err)
+	}
+	encodedConstructorArgument, err := parsed.Pack("", numBlocksPerDynasty, crossChainFee, admin, fallbackReceiver)
+	if err != nil {
+		panic(err)
+	}
+	encodedConstructorArgumentString := hex.EncodeToString(encodedConstructorArgument)
+	ff := contractBytecode + encodedConstructorArgumentString
+	return ff
+}
+
+// Reference: https://docs.blockscout.com/for-users/abi-encoded-constructor-arguments
+func addConstructorArgumentForTokenBankBytecode(contractBytecode string, mainchainIDInt *big.Int, chainRegistrarContractAddr common.Address) string {
+	rawABI := `[
+		{
+		"inputs": [
+			{
+			"internalType": "uint256",
+			"name": "mainchainID_",
+			"type": "uint256"
+			},
+			{
+			"internalType": "contract ChainRegistrar",
+			"name": "chainRegistrar_",
+			"type": "address"
+			}
+		],
+		"stateMutability": "nonpayable",
+		"type": "constructor"
+		}
+    ]`
+	parsed, err := abi.JSON(strings.NewReader(rawABI))
+	if err != nil {
+		panic(err)
+	}
+	encodedConstructorArgument, err := parsed.Pack("", mainchainIDInt, chainRegistrarContractAddr)
+	if err != nil {
+		panic(err)
+	}
+	encodedConstructorArgumentString := hex.EncodeToString(encodedConstructorArgument)
+	ff := contractBytecode + encodedConstructorArgumentString
+	return ff
+}
+
+func deploySmartContract(subchainID string, sv *slst.StoreView, contractBytecodeStr string, deployer common.Address, sequence int, contractAddressKey common.Bytes) (common.Address, error) {
+	dummyGasLimit := uint64(10000000)
+	dummyGasPrice := big.NewInt(1)
+
+	// Token Bank contract
+	contractBytecode, err := hex.DecodeString(contractBytecodeStr)
+	if err != nil {
+		return common.Address{}, err
+	}
+	deploySCTx := types.SmartContractTx{
+		From:     types.NewTxInput(deployer, types.NewCoins(0, 0), sequence),
+		To:       types.TxOutput{Address: common.Address{}}, // deploy contract
+		GasLimit: dummyGasLimit,
+		GasPrice: dummyGasPrice,
+		Data:     contractBytecode,
+	}
+	parentBlockInfo := svm.NewBlockInfo(0, big.NewInt(0), subchainID)
+	_, contractAddr, _, evmErr := svm.Execute(parentBlockInfo, &deploySCTx, sv)
+	if evmErr != nil {
+		return common.Address{}, evmErr
+	}
+
+	tbcaBytes, err := types.ToBytes(contractAddr)
+	if err != nil {
+		return common.Address{}, err
+	}
+	sv.Set(contractAddressKey, tbcaBytes)
+
+	return contractAddr, nil
+}
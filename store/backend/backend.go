@@ -0,0 +1,140 @@
+package backend
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/spf13/viper"
+	"github.com/thetatoken/theta/common/util"
+	"github.com/thetatoken/theta/store/database"
+	tbackend "github.com/thetatoken/theta/store/database/backend"
+	scom "github.com/thetatoken/thetasubchain/common"
+)
+
+var logger = util.GetLoggerForModule("backend")
+
+// Supported storage backends
+const (
+	LevelDB  = "leveldb"
+	BadgerDB = "badgerdb"
+	MemDB    = "memdb" // in-memory database, nothing is persisted. Only intended for tests
+)
+
+// Type returns the storage backend selected by the config.
+func Type() string {
+	return strings.ToLower(viper.GetString(scom.CfgStorageBackend))
+}
+
+// IsSupported returns true if the given backend type is supported.
+func IsSupported(backendType string) bool {
+	switch backendType {
+	case LevelDB, BadgerDB, MemDB:
+		return true
+	default:
+		return false
+	}
+}
+
+// MainDBPath returns the path to the main database under the given data folder.
+func MainDBPath(dataPath string) string {
+	return path.Join(dataPath, "db", "main")
+}
+
+// RefDBPath returns the path to the reference count database under the given data folder.
+func RefDBPath(dataPath string) string {
+	return path.Join(dataPath, "db", "ref")
+}
+
+// backendMarkerPath returns the path to the file recording which backend the data folder was created with.
+func backendMarkerPath(dataPath string) string {
+	return path.Join(dataPath, "db", "backend")
+}
+
+// DetectType returns the backend the data folder was created with.
+func DetectType(dataPath string) string {
+	raw, err := ioutil.ReadFile(backendMarkerPath(dataPath))
+	if err != nil {
+		return LevelDB
+	}
+	return strings.TrimSpace(string(raw))
+}
+
+// CheckDataDir makes sure the data folder is not opened with a backend different from the one it was
+// created with, and records the backend for new data folders. Data folders created before the backend
+// became configurable are LevelDB folders.
+func CheckDataDir(backendType, dataPath string) error {
+	if backendType == MemDB {
+		return nil
+	}
+
+	markerPath := backendMarkerPath(dataPath)
+	raw, err := ioutil.ReadFile(markerPath)
+	if err == nil {
+		existing := strings.TrimSpace(string(raw))
+		if existing != backendType {
+			return fmt.Errorf("data folder %v was created with the %v backend, but the %v backend is configured, please migrate the data folder first",
+				dataPath, existing, backendType)
+		}
+		return nil
+	}
+
+	if _, err := os.Stat(MainDBPath(dataPath)); err == nil && backendType != LevelDB {
+		return fmt.Errorf("data folder %v was created with the %v backend, but the %v backend is configured, please migrate the data folder first",
+			dataPath, LevelDB, backendType)
+	}
+
+	if err := os.MkdirAll(path.Join(dataPath, "db"), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(markerPath, []byte(backendType), 0600)
+}
+
+// NewDatabase opens the main node database under the given data folder, using the backend
+// selected by the config.
+func NewDatabase(dataPath string) (database.Database, error) {
+	return NewDatabaseWithType(Type(), dataPath)
+}
+
+// NewDatabaseWithType opens the main node database under the given data folder, using the given backend.
+func NewDatabaseWithType(backendType, dataPath string) (database.Database, error) {
+	mainDBPath := MainDBPath(dataPath)
+	refDBPath := RefDBPath(dataPath)
+
+	logger.Infof("Opening %v database, main: %v, ref: %v", backendType, mainDBPath, refDBPath)
+
+	switch backendType {
+	case LevelDB:
+		return tbackend.NewLDBDatabase(mainDBPath, refDBPath,
+			viper.GetInt(scom.CfgStorageLevelDBCacheSize),
+			viper.GetInt(scom.CfgStorageLevelDBHandles))
+	case BadgerDB:
+		return tbackend.NewBadgerDatabase(mainDBPath)
+	case MemDB:
+		return tbackend.NewMemDatabase(), nil
+	default:
+		return nil, fmt.Errorf("unsupported storage backend: %v", backendType)
+	}
+}
+
+// NewLayerDatabase opens a database stored in a single folder (e.g. a rolling DB layer), using the
+// backend selected by the config.
+func NewLayerDatabase(dbPath string) (database.Database, error) {
+	return NewLayerDatabaseWithType(Type(), dbPath)
+}
+
+// NewLayerDatabaseWithType opens a database stored in a single folder, using the given backend.
+func NewLayerDatabaseWithType(backendType, dbPath string) (database.Database, error) {
+	switch backendType {
+	case LevelDB:
+		return NewRawDB(dbPath)
+	case BadgerDB:
+		return tbackend.NewBadgerDatabase(dbPath)
+	case MemDB:
+		return tbackend.NewMemDatabase(), nil
+	default:
+		return nil, fmt.Errorf("unsupported storage backend: %v", backendType)
+	}
+}
@@ -1,4 +1,4 @@
-package rollingdb
+package backend
 
 import (
 	"time"
@@ -0,0 +1,179 @@
+package rollingdb
+
+import (
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/rlp"
+)
+
+// compactionProgressKey is the key in the root DB under which the ongoing compaction task is
+// recorded, so that the task can be resumed if the node restarts in the middle of the copy.
+var compactionProgressKey = []byte("/rollingdb/compaction")
+
+type compactionProgress struct {
+	SourceLayer uint64
+	TargetLayer uint64
+	Height      uint64
+	StateRoot   common.Hash
+}
+
+// CompactionStatus describes the ongoing, or the last finished compaction.
+type CompactionStatus struct {
+	Running      bool        `json:"running"`
+	Resumed      bool        `json:"resumed"`
+	SourceLayer  int         `json:"source_layer"`
+	TargetLayer  int         `json:"target_layer"`
+	Height       uint64      `json:"height"`
+	StateRoot    common.Hash `json:"state_root"`
+	NodesCopied  uint64      `json:"nodes_copied"`
+	NodesSkipped uint64      `json:"nodes_skipped"`
+	BytesCopied  uint64      `json:"bytes_copied"`
+	StartTime    time.Time   `json:"start_time"`
+	EndTime      time.Time   `json:"end_time"`
+	Error        string      `json:"error,omitempty"`
+}
+
+// LayerInfo describes a DB layer.
+type LayerInfo struct {
+	Name       int           `json:"name"`
+	Height     uint64        `json:"height"`
+	StateRoots []common.Hash `json:"state_roots"`
+	Size       int64         `json:"size"`
+}
+
+// Status summarizes the layers of the rolling DB and the compaction progress.
+type Status struct {
+	ArchiveMode bool              `json:"archive_mode"`
+	RootLayer   LayerInfo         `json:"root_layer"`
+	Layers      []LayerInfo       `json:"layers"` // ordered from old to new
+	ActiveLayer LayerInfo         `json:"active_layer"`
+	Compaction  *CompactionStatus `json:"compaction"`
+}
+
+// GetStatus returns the current status of the rolling DB. It does not block reads or writes.
+func (rdb *RollingDB) GetStatus() *Status {
+	rdb.mu.RLock()
+	rootLayer := rdb.rootLayer
+	activeLayer := rdb.activeLayer
+	layers := make([]*DBLayer, len(rdb.layers))
+	copy(layers, rdb.layers)
+	rdb.mu.RUnlock()
+
+	status := &Status{
+		ArchiveMode: isArchiveMode(),
+		RootLayer:   rootLayer.info(),
+		Layers:      []LayerInfo{},
+	}
+	for _, layer := range layers {
+		status.Layers = append(status.Layers, layer.info())
+	}
+	if activeLayer != rootLayer {
+		status.ActiveLayer = activeLayer.info()
+	}
+
+	rdb.statusMu.Lock()
+	defer rdb.statusMu.Unlock()
+	if rdb.compaction != nil {
+		c := *rdb.compaction
+		c.NodesCopied = atomic.LoadUint64(&rdb.compaction.NodesCopied)
+		c.NodesSkipped = atomic.LoadUint64(&rdb.compaction.NodesSkipped)
+		c.BytesCopied = atomic.LoadUint64(&rdb.compaction.BytesCopied)
+		status.Compaction = &c
+	}
+
+	return status
+}
+
+func (rdb *RollingDB) loadCompactionProgress() *compactionProgress {
+	raw, err := rdb.root.Get(compactionProgressKey)
+	if err != nil {
+		return nil
+	}
+	progress := &compactionProgress{}
+	if err := rlp.DecodeBytes(raw, progress); err != nil {
+		logger.Errorf("Failed to decode compaction progress: %v", err)
+		return nil
+	}
+	return progress
+}
+
+func (rdb *RollingDB) saveCompactionProgress(progress *compactionProgress) {
+	raw, err := rlp.EncodeToBytes(progress)
+	if err != nil {
+		logger.Panicf("Failed to encode compaction progress: %v", err)
+	}
+	if err := rdb.root.Put(compactionProgressKey, raw); err != nil {
+		logger.Panicf("Failed to save compaction progress: %v", err)
+	}
+}
+
+func (rdb *RollingDB) clearCompactionProgress() {
+	if err := rdb.root.Delete(compactionProgressKey); err != nil {
+		logger.Warnf("Failed to clear compaction progress: %v", err)
+	}
+}
+
+func (rdb *RollingDB) setCompactionStatus(status *CompactionStatus) {
+	rdb.statusMu.Lock()
+	defer rdb.statusMu.Unlock()
+	rdb.compaction = status
+}
+
+func (rdb *RollingDB) finishCompactionStatus(status *CompactionStatus, err string) {
+	rdb.statusMu.Lock()
+	defer rdb.statusMu.Unlock()
+	status.Running = false
+	status.EndTime = time.Now()
+	status.Error = err
+}
+
+// findLayer returns the layer with the given name, or nil if it no longer exists.
+func (rdb *RollingDB) findLayer(name int) *DBLayer {
+	rdb.mu.RLock()
+	defer rdb.mu.RUnlock()
+
+	if rdb.activeLayer.name == name {
+		return rdb.activeLayer
+	}
+	for _, layer := range rdb.layers {
+		if layer.name == name {
+			return layer
+		}
+	}
+	return nil
+}
+
+func (l *DBLayer) info() LayerInfo {
+	info := LayerInfo{
+		Name: l.name,
+		Size: l.size(),
+	}
+	if l.tag != nil {
+		info.Height = l.tag.Height
+		info.StateRoots = l.tag.StateRoots
+	}
+	return info
+}
+
+// size returns the disk usage of the layer in bytes. For the root layer, the rolling layers
+// stored underneath its folder are excluded.
+func (l *DBLayer) size() int64 {
+	var size int64
+	filepath.Walk(l.dbPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() && l.name == 0 && info.Name() == "rolling" {
+			return filepath.SkipDir
+		}
+		if !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
@@ -2,19 +2,45 @@ package rollingdb
 
 import (
 	"bytes"
-	"log"
+	"sync/atomic"
+	"time"
 
 	"github.com/thetatoken/theta/common"
 	"github.com/thetatoken/theta/ledger/types"
 	"github.com/thetatoken/theta/store/database"
-	slst "github.com/thetatoken/thetasubchain/ledger/state"
 	"github.com/thetatoken/theta/store/trie"
+	slst "github.com/thetatoken/thetasubchain/ledger/state"
 )
 
-func copyState(source database.Database, target database.Batch, root common.Hash) {
-	copyTrie(source, target, root)
+// stateCopier copies state tries from the rolling DB into a target layer. Nodes already present in
+// the target are skipped, so an interrupted copy can simply be restarted. The copy speed is capped
+// at maxNodesPerSecond (no limit if zero).
+type stateCopier struct {
+	source            database.Database
+	target            *DBLayer
+	batch             database.Batch
+	maxNodesPerSecond int
+	status            *CompactionStatus
+
+	start        time.Time
+	nodesVisited uint64
+}
+
+func newStateCopier(source database.Database, target *DBLayer, maxNodesPerSecond int, status *CompactionStatus) *stateCopier {
+	return &stateCopier{
+		source:            source,
+		target:            target,
+		batch:             target.db.NewBatch(),
+		maxNodesPerSecond: maxNodesPerSecond,
+		status:            status,
+		start:             time.Now(),
+	}
+}
+
+func (c *stateCopier) copyState(root common.Hash) {
+	c.copyTrie(root)
 
-	sv := slst.NewStoreView(0, root, source)
+	sv := slst.NewStoreView(0, root, c.source)
 
 	sv.GetStore().Traverse(nil, func(k, v common.Bytes) bool {
 		if bytes.HasPrefix(k, []byte("ls/a")) {
@@ -25,41 +51,64 @@ func copyState(source database.Database, target database.Batch, root common.Hash
 				panic(err)
 			}
 			if account.Root != (common.Hash{}) {
-				copyTrie(source, target, account.Root)
+				c.copyTrie(account.Root)
 			}
 		}
 		return true
 	})
 }
 
-func copyTrie(source database.Database, target database.Batch, root common.Hash) {
-	tr, err := trie.New(root, trie.NewDatabase(source))
+func (c *stateCopier) copyTrie(root common.Hash) {
+	tr, err := trie.New(root, trie.NewDatabase(c.source))
 	if err != nil {
 		logger.Panic(err)
 	}
 	it := tr.NodeIterator(nil)
 	for it.Next(true) {
-		if it.Hash() != (common.Hash{}) {
-			hash := it.Hash()
-			val, err := source.Get(hash.Bytes())
-			if err != nil {
-				log.Panic(err)
-			}
-			err = target.Put(hash.Bytes(), val)
-			if err != nil {
-				logger.Panic(err)
-			}
+		if it.Hash() == (common.Hash{}) {
+			continue
+		}
+		c.nodesVisited++
 
-			if target.ValueSize() > database.IdealBatchSize {
-				if err := target.Write(); err != nil {
-					logger.Panicf("Failed to copy trie: %v", err)
-				}
-				target.Reset()
-			}
+		hash := it.Hash()
+		if exists, err := c.target.db.Has(hash.Bytes()); err == nil && exists {
+			atomic.AddUint64(&c.status.NodesSkipped, 1)
+			continue
+		}
+
+		val, err := c.source.Get(hash.Bytes())
+		if err != nil {
+			logger.Panic(err)
+		}
+		err = c.batch.Put(hash.Bytes(), val)
+		if err != nil {
+			logger.Panic(err)
+		}
+		atomic.AddUint64(&c.status.NodesCopied, 1)
+		atomic.AddUint64(&c.status.BytesCopied, uint64(len(val)))
 
+		if c.batch.ValueSize() > database.IdealBatchSize {
+			c.flush()
+			c.throttle()
 		}
 	}
-	if err := target.Write(); err != nil {
+	c.flush()
+}
+
+func (c *stateCopier) flush() {
+	if err := c.batch.Write(); err != nil {
 		logger.Panicf("Failed to copy trie: %v", err)
 	}
+	c.batch.Reset()
+}
+
+// throttle sleeps long enough to keep the copy speed under maxNodesPerSecond.
+func (c *stateCopier) throttle() {
+	if c.maxNodesPerSecond <= 0 {
+		return
+	}
+	expected := time.Duration(float64(c.nodesVisited) / float64(c.maxNodesPerSecond) * float64(time.Second))
+	if elapsed := time.Since(c.start); elapsed < expected {
+		time.Sleep(expected - elapsed)
+	}
 }
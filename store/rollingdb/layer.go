@@ -9,6 +9,7 @@ import (
 	"github.com/thetatoken/theta/common"
 	"github.com/thetatoken/theta/rlp"
 	"github.com/thetatoken/theta/store/database"
+	sbackend "github.com/thetatoken/thetasubchain/store/backend"
 )
 
 var layerTagKey = []byte("/layertag")
@@ -27,7 +28,7 @@ type DBLayer struct {
 
 func NewDBLayer(rollingPath string, name int) *DBLayer {
 	dbPath := path.Join(rollingPath, fmt.Sprintf("%d", name))
-	db, err := NewRawDB(dbPath)
+	db, err := sbackend.NewLayerDatabase(dbPath)
 	if err != nil {
 		logger.Panicf("Failed to create roll db layer, %v", err)
 	}
@@ -14,6 +14,7 @@ import (
 	"github.com/thetatoken/theta/common/util"
 	"github.com/thetatoken/theta/store/database"
 	sbc "github.com/thetatoken/thetasubchain/blockchain"
+	scom "github.com/thetatoken/thetasubchain/common"
 )
 
 var logger = util.GetLoggerForModule("rollingdb")
@@ -31,6 +32,9 @@ type RollingDB struct {
 	activeLayer *DBLayer
 
 	compactC chan struct{}
+
+	statusMu   sync.Mutex
+	compaction *CompactionStatus // the ongoing, or the last finished compaction
 }
 
 func NewRollingDB(parentPath string, root database.Database) *RollingDB {
@@ -49,7 +53,7 @@ func NewRollingDB(parentPath string, root database.Database) *RollingDB {
 		rootLayer:  rootLayer,
 		compactC:   make(chan struct{}, 1),
 	}
-	activeLayer, layers := rdb.loadLayers(rollingPath)
+	activeLayer, layers := rdb.loadLayers(rollingPath, true)
 	rdb.activeLayer = activeLayer
 	rdb.layers = layers
 
@@ -58,11 +62,35 @@ func NewRollingDB(parentPath string, root database.Database) *RollingDB {
 
 }
 
+// OpenRollingDBForRead opens the existing layers of the rolling DB without creating the rolling folder or
+// a new active layer, for the tools which inspect the data folder without changing it.
+func OpenRollingDBForRead(parentPath string, root database.Database) *RollingDB {
+	rootLayer := &DBLayer{
+		dbPath: path.Join(parentPath, "db"),
+		db:     root,
+		name:   0,
+	}
+
+	rdb := &RollingDB{
+		parentPath: parentPath,
+		root:       root,
+		rootLayer:  rootLayer,
+		compactC:   make(chan struct{}, 1),
+	}
+	rollingPath := path.Join(parentPath, "db", "rolling")
+	if _, err := os.Stat(rollingPath); err != nil {
+		rdb.activeLayer = rootLayer
+		return rdb
+	}
+	rdb.activeLayer, rdb.layers = rdb.loadLayers(rollingPath, false)
+	return rdb
+}
+
 func (rdb *RollingDB) SetChain(chain *sbc.Chain) {
 	rdb.chain = chain
 }
 
-func (rdb *RollingDB) loadLayers(rollingPath string) (*DBLayer, []*DBLayer) {
+func (rdb *RollingDB) loadLayers(rollingPath string, createLayer bool) (*DBLayer, []*DBLayer) {
 	files, err := ioutil.ReadDir(rollingPath)
 	if err != nil {
 		logger.Panicf("Failed to load layers", err)
@@ -80,12 +108,17 @@ func (rdb *RollingDB) loadLayers(rollingPath string) (*DBLayer, []*DBLayer) {
 	}
 
 	if len(names) == 0 {
-		if !viper.GetBool(common.CfgStorageRollingEnabled) {
+		if !createLayer || !viper.GetBool(common.CfgStorageRollingEnabled) || isArchiveMode() {
 			return rdb.rootLayer, nil
 		}
 		return NewDBLayer(rollingPath, 1), nil
 	}
 
+	if isArchiveMode() {
+		// The layers are kept readable, but states compacted away before archive mode was enabled cannot be recovered
+		logger.Warnf("Archive mode enabled on a rolling DB with %v layers, historical states prior to the oldest layer are not available", len(names))
+	}
+
 	sort.Sort(sort.IntSlice(names))
 	activeLayer := NewDBLayer(rollingPath, names[len(names)-1])
 	layers := []*DBLayer{}
@@ -97,7 +130,7 @@ func (rdb *RollingDB) loadLayers(rollingPath string) (*DBLayer, []*DBLayer) {
 }
 
 func (rdb *RollingDB) Tag(height uint64, stateRoot common.Hash) {
-	if !viper.GetBool(common.CfgStorageRollingEnabled) {
+	if !viper.GetBool(common.CfgStorageRollingEnabled) || isArchiveMode() {
 		return
 	}
 
@@ -108,7 +141,7 @@ func (rdb *RollingDB) Tag(height uint64, stateRoot common.Hash) {
 		rdb.addLayer()
 	}
 
-	if isCompactionHeight(height) {
+	if isCompactionHeight(height) || rdb.hasPendingCompaction() {
 		go rdb.compact(height)
 	}
 }
@@ -126,7 +159,7 @@ func (rdb *RollingDB) addLayer() {
 }
 
 func (rdb *RollingDB) compact(height uint64) {
-	if !viper.GetBool(common.CfgStorageStatePruningEnabled) {
+	if !viper.GetBool(common.CfgStorageStatePruningEnabled) || isArchiveMode() {
 		return
 	}
 
@@ -143,75 +176,142 @@ func (rdb *RollingDB) compact(height uint64) {
 			<-rdb.compactC
 		}()
 
-		logger.Debugf("Number of layers: %v", len(rdb.layers))
-		if len(rdb.layers) == 0 {
-			logger.Infof("No rolling DB layer found, skip compaction")
+		progress := rdb.loadCompactionProgress()
+		resumed := progress != nil
+		if !resumed {
+			progress = rdb.findCompactionTask(height)
+			if progress == nil {
+				return
+			}
+			rdb.saveCompactionProgress(progress)
+		}
+
+		sourceLayer := rdb.findLayer(int(progress.SourceLayer))
+		targetLayer := rdb.findLayer(int(progress.TargetLayer))
+		if sourceLayer == nil || targetLayer == nil {
+			logger.Warnf("Compaction canceled, layer no longer exists: source=%v, target=%v", progress.SourceLayer, progress.TargetLayer)
+			rdb.clearCompactionProgress()
 			return
 		}
 
-		// Copying state from source to target
-		targetLayer := rdb.activeLayer
-		var sourceLayer *DBLayer
-
-		// Look for layers to cut off
-		minimumNumBlocksToRetain := uint64(viper.GetInt(common.CfgStorageStatePruningRetainedBlocks))
-		found := false
-		for i := len(rdb.layers) - 1; i >= 0; i-- {
-			if height-rdb.layers[i].tag.Height > minimumNumBlocksToRetain+10 {
-				found = true
-				sourceLayer = rdb.layers[i]
-				break
+		status := &CompactionStatus{
+			Running:     true,
+			Resumed:     resumed,
+			SourceLayer: sourceLayer.name,
+			TargetLayer: targetLayer.name,
+			Height:      progress.Height,
+			StateRoot:   progress.StateRoot,
+			StartTime:   start,
+		}
+		rdb.setCompactionStatus(status)
+
+		// Copying state from source to target. The copy only takes the read lock for each
+		// individual key, so that Get/Put are not blocked for the duration of the copy.
+		logger.Infof("Moving finalized state hash=%v, source=%v, target=%v, resumed=%v",
+			progress.StateRoot.Hex(), sourceLayer.name, targetLayer.name, resumed)
+		copier := newStateCopier(rdb, targetLayer, viper.GetInt(scom.CfgStorageCompactionMaxNodesPerSecond), status)
+		copier.copyState(progress.StateRoot)
+
+		rdb.mu.Lock()
+		remainingLayers := []*DBLayer{}
+		removedLayers := []*DBLayer{}
+		for _, layer := range rdb.layers {
+			// New layers might have been added after `targetLayer`
+			if layer.name <= sourceLayer.name {
+				removedLayers = append(removedLayers, layer)
+			} else {
+				remainingLayers = append(remainingLayers, layer)
 			}
 		}
-		if !found {
-			logger.Info("No layer old enough to cut off")
-			return
+		rdb.layers = remainingLayers
+		rdb.mu.Unlock()
+
+		// The removed layers are no longer reachable from Get/Has, so they can be destroyed
+		// without holding the lock
+		for _, layer := range removedLayers {
+			layer.destroy()
 		}
 
-		if !isRollingHeight(sourceLayer.tag.Height) {
-			// potentially db was not cut off cleanly, keep the layer until one cleancut is made
-			logger.Infof("Compaction canceled: sourceLayer.name=%v, lastLayer.Height=%v", sourceLayer.name, sourceLayer.tag.Height)
-			return
+		rdb.clearCompactionProgress()
+		rdb.finishCompactionStatus(status, "")
+	default:
+		logger.Debugf("Only one active compaction task allowed")
+		return
+	}
+
+}
+
+// findCompactionTask looks for the newest layer that is old enough to be cut off, and the finalized
+// state root at its height that needs to be carried over to the active layer.
+func (rdb *RollingDB) findCompactionTask(height uint64) *compactionProgress {
+	rdb.mu.RLock()
+	layers := make([]*DBLayer, len(rdb.layers))
+	copy(layers, rdb.layers)
+	targetLayer := rdb.activeLayer
+	rdb.mu.RUnlock()
+
+	logger.Debugf("Number of layers: %v", len(layers))
+	if len(layers) == 0 {
+		logger.Infof("No rolling DB layer found, skip compaction")
+		return nil
+	}
+
+	// Look for layers to cut off
+	var sourceLayer *DBLayer
+	minimumNumBlocksToRetain := uint64(viper.GetInt(common.CfgStorageStatePruningRetainedBlocks))
+	for i := len(layers) - 1; i >= 0; i-- {
+		if height-layers[i].tag.Height > minimumNumBlocksToRetain+10 {
+			sourceLayer = layers[i]
+			break
+		}
+	}
+	if sourceLayer == nil {
+		logger.Info("No layer old enough to cut off")
+		return nil
+	}
+
+	if !isRollingHeight(sourceLayer.tag.Height) {
+		// potentially db was not cut off cleanly, keep the layer until one cleancut is made
+		logger.Infof("Compaction canceled: sourceLayer.name=%v, lastLayer.Height=%v", sourceLayer.name, sourceLayer.tag.Height)
+		return nil
+	}
+
+	blocks := rdb.chain.FindBlocksByHeight(sourceLayer.tag.Height)
+	logger.Debugf("Found %v blocks for height %v", len(blocks), sourceLayer.tag.Height)
+
+	for _, block := range blocks {
+		if !block.Status.IsFinalized() {
+			continue
 		}
+		logger.Debugf("Found finalized block: %v", block.Hash().Hex())
+
+		for _, stateRoot := range sourceLayer.tag.StateRoots {
+			logger.Debugf("State root check, stateRoot: %v, block.StateHash: %v", stateRoot.Hex(), block.StateHash.Hex())
 
-		blocks := rdb.chain.FindBlocksByHeight(sourceLayer.tag.Height)
-		logger.Debugf("Found %v blocks for height %v", len(blocks), sourceLayer.tag.Height)
-
-		for _, block := range blocks {
-			if block.Status.IsFinalized() {
-				logger.Debugf("Found finalized block: %v", block.Hash().Hex())
-
-				for _, stateRoot := range sourceLayer.tag.StateRoots {
-					logger.Debugf("State root check, stateRoot: %v, block.StateHash: %v", stateRoot.Hex(), block.StateHash.Hex())
-
-					if stateRoot == block.StateHash {
-						logger.Infof("Moving finalized state hash=%v, source=%v, target=%v", stateRoot.Hex(), sourceLayer.name, targetLayer.name)
-						copyState(rdb, targetLayer.db.NewBatch(), stateRoot)
-
-						rdb.mu.Lock()
-						defer rdb.mu.Unlock()
-
-						remainingLayers := []*DBLayer{}
-						for _, layer := range rdb.layers {
-							// New layers might have been added after `targetLayer`
-							if layer.name <= sourceLayer.name {
-								layer.destroy()
-							} else {
-								remainingLayers = append(remainingLayers, layer)
-							}
-						}
-						rdb.layers = remainingLayers
-						break
-					}
+			if stateRoot == block.StateHash {
+				return &compactionProgress{
+					SourceLayer: uint64(sourceLayer.name),
+					TargetLayer: uint64(targetLayer.name),
+					Height:      sourceLayer.tag.Height,
+					StateRoot:   stateRoot,
 				}
-				break
 			}
 		}
-	default:
-		logger.Debugf("Only one active compaction task allowed")
-		return
+		break
 	}
+	return nil
+}
+
+// hasPendingCompaction returns true if a compaction task was interrupted and needs to be resumed.
+func (rdb *RollingDB) hasPendingCompaction() bool {
+	has, err := rdb.root.Has(compactionProgressKey)
+	return err == nil && has
+}
 
+// isArchiveMode returns true if the node retains the full historical state, in which case
+// no new layers are created and no state is ever compacted away.
+func isArchiveMode() bool {
+	return viper.GetBool(scom.CfgStorageArchiveEnabled)
 }
 
 func isRollingHeight(height uint64) bool {